@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -23,6 +24,16 @@ var (
 )
 
 func main() {
+	// The Retina CLI reads the capture artifacts stored on a HostPath or PersistentVolumeClaim by running the
+	// artifacts commands in a helper Pod, which write to stdout instead of logging.
+	if len(os.Args) > 1 && os.Args[1] == capture.ArtifactsCommand {
+		if err := capture.RunArtifactsCommand(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	lOpts := log.GetDefaultLogOpts()
 	// Set Azure application insights ID if it is provided
 	if applicationInsightsID != "" {
@@ -47,6 +58,17 @@ func main() {
 		tel = telemetry.NewNoopTelemetry()
 	}
 
+	// The operator runs the capture workload in cleanup mode to delete the capture artifacts of an expired Capture.
+	if len(os.Args) > 1 && os.Args[1] == capture.CleanupCommand {
+		cm := capture.NewCaptureManager(l, tel)
+		if err := cm.DeleteCaptureArtifacts(); err != nil {
+			l.Error("Failed to delete capture artifacts", zap.Error(err))
+			os.Exit(1)
+		}
+		l.Info("Done for deleting capture artifacts")
+		return
+	}
+
 	// Create channel to listen for signals.
 	sigChan := make(chan os.Signal, 1)
 	// Notify sigChan for SIGTERM.
@@ -67,5 +89,8 @@ func main() {
 	if err := cm.OutputCapture(srcDir); err != nil {
 		l.Error("Failed to output network traffic", zap.Error(err))
 	}
+	if err := cm.WriteResult(); err != nil {
+		l.Warn("Failed to write capture result", zap.Error(err))
+	}
 	l.Info("Done for capturing network traffic")
 }
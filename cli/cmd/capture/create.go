@@ -35,8 +35,12 @@ var (
 	duration           time.Duration
 	maxSize            int
 	packetSize         int
+	backend            string
+	interfaces         string
+	podNetworkNS       bool
 	nodeSelectors      string
 	podSelectors       string
+	serviceSelectors   string
 	namespaceSelectors string
 	nodeNames          string
 	hostPath           string
@@ -67,12 +71,21 @@ var createExample = templates.Examples(i18n.T(`
 		# Capture network packets on the coredns pods determined by pod-selectors and namespace-selectors
 		kubectl retina capture create --host-path /mnt/capture --namespace capture --pod-selectors="k8s-app=kube-dns" --namespace-selectors="kubernetes.io/metadata.name=kube-system"
 
+		# Capture network packets to and from the Pods backing the kube-dns Service, both before and after they are translated from the Service ClusterIP
+		kubectl retina capture create --host-path /mnt/capture --namespace capture --service-selectors="k8s-app=kube-dns" --namespace-selectors="kubernetes.io/metadata.name=kube-system"
+
 		# Capture network packets on nodes with label "agentpool=agentpool" and "version:v20"
 		kubectl retina capture create --host-path /mnt/capture --node-selectors="agentpool=agentpool,version:v20"
 
 		# Capture network packets on nodes using node-selector with duration 10s
 		kubectl retina capture create --host-path=/mnt/capture --node-selectors="agentpool=agentpool" --duration=10s
 
+		# Capture network packets on the eth0 interface of nodes with the native backend, which does not require tcpdump
+		kubectl retina capture create --host-path /mnt/capture --node-selectors="agentpool=agentpool" --backend=native --interfaces=eth0
+
+		# Capture network packets inside the network namespace of Pods with label "k8s-app=kube-dns", including their loopback traffic, with one capture file per Pod
+		kubectl retina capture create --host-path /mnt/capture --namespace capture --pod-selectors="k8s-app=kube-dns" --namespace-selectors="kubernetes.io/metadata.name=kube-system" --pod-network-namespace
+
 		# Capture network packets on nodes using node-selector and upload the artifacts to blob storage with SAS URL https://testaccount.blob.core.windows.net/<token>
 		kubectl retina capture create --node-selectors="agentpool=agentpool" --blob-upload=https://testaccount.blob.core.windows.net/<token>
 
@@ -252,6 +265,10 @@ func createCaptureF(kubeClient kubernetes.Interface) (*retinav1alpha1.Capture, e
 	if err != nil {
 		return nil, err
 	}
+	serviceSelectorLabelsMap, err := labels.ConvertSelectorToLabelsMap(serviceSelectors)
+	if err != nil {
+		return nil, err
+	}
 	namespaceSelectorLabelsMap, err := labels.ConvertSelectorToLabelsMap(namespaceSelectors)
 	if err != nil {
 		return nil, err
@@ -284,6 +301,11 @@ func createCaptureF(kubeClient kubernetes.Interface) (*retinav1alpha1.Capture, e
 			MatchLabels: podSelectorLabelsMap,
 		}
 	}
+	if len(serviceSelectorLabelsMap) != 0 {
+		capture.Spec.CaptureConfiguration.CaptureTarget.ServiceSelector = &metav1.LabelSelector{
+			MatchLabels: serviceSelectorLabelsMap,
+		}
+	}
 
 	if maxSize != 0 {
 		retinacmd.Logger.Info(fmt.Sprintf("The capture file max size is set to %dMB", maxSize))
@@ -295,6 +317,22 @@ func createCaptureF(kubeClient kubernetes.Interface) (*retinav1alpha1.Capture, e
 		capture.Spec.CaptureConfiguration.CaptureOption.PacketSize = &packetSize
 	}
 
+	if len(backend) != 0 {
+		capture.Spec.CaptureConfiguration.CaptureOption.Backend = retinav1alpha1.CaptureBackend(backend)
+	}
+
+	if len(interfaces) != 0 {
+		capture.Spec.CaptureConfiguration.CaptureOption.Interfaces = strings.Split(interfaces, ",")
+	}
+
+	if podNetworkNS {
+		capture.Spec.CaptureConfiguration.CaptureOption.PodNetworkNamespace = true
+		// Only the native backend captures in the Pod network namespace.
+		if len(backend) == 0 {
+			capture.Spec.CaptureConfiguration.CaptureOption.Backend = retinav1alpha1.CaptureBackendNative
+		}
+	}
+
 	if len(hostPath) != 0 {
 		capture.Spec.OutputConfiguration.HostPath = &hostPath
 	}
@@ -446,12 +484,17 @@ func init() {
 	createCapture.Flags().DurationVar(&duration, "duration", time.Minute, "Duration of capturing packets")
 	createCapture.Flags().IntVar(&maxSize, "max-size", 100, "Limit the capture file to MB in size which works only for Linux") //nolint:gomnd // default
 	createCapture.Flags().IntVar(&packetSize, "packet-size", 0, "Limits the each packet to bytes in size which works only for Linux")
+	createCapture.Flags().StringVar(&backend, "backend", "", "Backend capturing network packets on Linux nodes, either tcpdump or native")
+	createCapture.Flags().StringVar(&interfaces, "interfaces", "", "A comma-separated list of node interfaces to capture network packets on, which works only for the native backend")
+	createCapture.Flags().BoolVar(&podNetworkNS, "pod-network-namespace", false, "Capture network packets inside the network namespace of the selected Pods, on their eth0 and lo interfaces by default, which uses the native backend")
 	createCapture.Flags().StringVar(&nodeNames, "node-names", "", "A comma-separated list of node names to select nodes on which the network capture will be performed")
 	createCapture.Flags().StringVar(&nodeSelectors, "node-selectors", "", "A comma-separated list of node labels to select nodes on which the network capture will be performed")
 	createCapture.Flags().StringVar(&podSelectors, "pod-selectors", "",
 		"A comma-separated list of pod labels to select pods on which the network capture will be performed")
+	createCapture.Flags().StringVar(&serviceSelectors, "service-selectors", "",
+		"A comma-separated list of service labels to select services, whose backing pods, ClusterIPs and NodePorts the network capture will be performed on")
 	createCapture.Flags().StringVar(&namespaceSelectors, "namespace-selectors", "",
-		"A comma-separated list of namespace labels in which to apply the pod-selectors. By default, the pod namespace is specified by the flag namespace")
+		"A comma-separated list of namespace labels in which to apply the pod-selectors or service-selectors. By default, the pod namespace is specified by the flag namespace")
 	createCapture.Flags().StringVar(&hostPath, "host-path", "", "HostPath of the node to store the capture files")
 	createCapture.Flags().StringVar(&pvc, "pvc", "", "PersistentVolumeClaim under the specified or default namespace to store capture files")
 	createCapture.Flags().StringVar(&blobUpload, "blob-upload", "", "Blob SAS URL with write permission to upload capture files")
@@ -4,81 +4,252 @@
 package capture
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"net/url"
 	"os"
-	"strings"
-
-	"github.com/Azure/azure-sdk-for-go/storage"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+
+	retinacmd "github.com/microsoft/retina/cli/cmd"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+	"github.com/microsoft/retina/pkg/capture/outputlocation"
+	captureUtils "github.com/microsoft/retina/pkg/capture/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
 )
 
 const BlobURL = "BLOB_URL"
 
-var ErrEmptyBlobURL = errors.New("BLOB_URL must be set/exported")
+var (
+	downloadOutputDir string
+	downloadLocation  string
+	downloadList      bool
+	downloadMerge     bool
+)
+
+var errNoCaptureArtifacts = errors.New("no capture artifacts found")
+
+var downloadExample = templates.Examples(i18n.T(`
+		# Download the artifacts of the Retina Capture "retina-capture-8v6wd" in namespace "capture" into the current directory
+		kubectl retina capture download --name retina-capture-8v6wd --namespace capture
+
+		# List the artifacts of the Retina Capture in each output location
+		kubectl retina capture download --name retina-capture-8v6wd --namespace capture --list
+
+		# Download the artifacts stored on the node host path into ./captures, and merge the network packets of all nodes into one file
+		kubectl retina capture download --name retina-capture-8v6wd --namespace capture --location host-path --output ./captures --merge
+
+		# Download the artifacts uploaded to the blob SAS URL, without the Capture or its jobs
+		BLOB_URL="https://testaccount.blob.core.windows.net/<token>" kubectl retina capture download --name retina-capture-8v6wd
+		`))
 
 var downloadCapture = &cobra.Command{
-	Use:   "download",
-	Short: "Download Retina Captures",
+	Use:     "download",
+	Short:   "Download Retina Captures",
+	Example: downloadExample,
 	RunE: func(*cobra.Command, []string) error {
-		blobURL := viper.GetString(BlobURL)
-		if blobURL == "" {
-			return ErrEmptyBlobURL
+		// The helper Pods reading the volumes are deleted when the download is interrupted.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		kubeConfig, err := configFlags.ToRESTConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed to compose k8s rest config")
 		}
 
-		u, err := url.Parse(blobURL)
+		kubeClient, err := kubernetes.NewForConfig(kubeConfig)
 		if err != nil {
-			return errors.Wrapf(err, "failed to parse SAS URL %s", blobURL)
+			return errors.Wrap(err, "failed to initialize kubernetes client")
 		}
 
-		// blobService, err := storage.NewAccountSASClientFromEndpointToken(u.String(), u.Query().Encode()).GetBlobService()
-		b, err := storage.NewAccountSASClientFromEndpointToken(u.String(), u.Query().Encode())
+		locations, err := captureArtifactLocations(ctx, kubeConfig, kubeClient)
 		if err != nil {
-			return errors.Wrap(err, "failed to create storage account client")
+			return err
 		}
+		defer func() {
+			// The context of the download is done once interrupted, so the locations are closed with a new one.
+			closeCtx, cancel := context.WithTimeout(context.Background(), captureDownloadCloseTimeout)
+			defer cancel()
+			for _, location := range locations {
+				location.Close(closeCtx)
+			}
+		}()
 
-		blobService := b.GetBlobService()
-		containerPath := strings.TrimLeft(u.Path, "/")
-		splitPath := strings.SplitN(containerPath, "/", 2) //nolint:gomnd // TODO string splitting probably isn't the right way to parse this URL?
-		containerName := splitPath[0]
+		if downloadList {
+			return listCaptureArtifacts(ctx, locations)
+		}
 
-		params := storage.ListBlobsParameters{Prefix: name}
-		blobList, err := blobService.GetContainerReference(containerName).ListBlobs(params)
+		artifactPaths, err := downloadCaptureArtifacts(ctx, locations)
 		if err != nil {
-			return errors.Wrap(err, "failed to list blobstore ")
+			return err
+		}
+		if downloadMerge {
+			return mergeCaptureArtifacts(artifactPaths)
 		}
+		return nil
+	},
+}
 
-		if len(blobList.Blobs) == 0 {
-			return errors.Errorf("no blobs found with prefix: %s", name)
+// captureArtifactLocations returns the output locations of the Capture to download the capture artifacts from, in the
+// order they are tried: the storage services first, then the volumes read through helper Pods.
+func captureArtifactLocations(ctx context.Context, kubeConfig *rest.Config, kubeClient kubernetes.Interface) ([]artifactLocation, error) {
+	blobURL := viper.GetString(BlobURL)
+	output, err := resolveCaptureOutput(ctx, kubeConfig, kubeClient)
+	if errors.Is(err, errCaptureNotFound) && len(blobURL) != 0 {
+		// The artifacts uploaded to a blob SAS URL can be downloaded after the Capture and its jobs are deleted.
+		output, err = &captureOutput{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(output.image) == 0 {
+		output.image = captureUtils.CaptureWorkloadImage(retinacmd.Logger, retinacmd.Version, false, captureUtils.VersionSourceCLIVersion)
+	}
+
+	var locations []artifactLocation
+	config := output.config
+	if len(blobURL) == 0 && config.BlobUpload != nil && len(*config.BlobUpload) != 0 {
+		secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, *config.BlobUpload, metav1.GetOptions{})
+		if err != nil {
+			retinacmd.Logger.Warn("Failed to get blob upload secret, set BLOB_URL to download from blob", zap.String("secret", *config.BlobUpload), zap.Error(err))
+		} else {
+			blobURL = string(secret.Data[captureConstants.CaptureOutputLocationBlobUploadSecretKey])
+		}
+	}
+	if len(blobURL) != 0 {
+		blobSASURL, err := outputlocation.BlobContainerSASURL(blobURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid blob SAS URL")
 		}
+		location, err := newBlobLocation(blobSASURL)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
 
-		for _, v := range blobList.Blobs {
-			blob := blobService.GetContainerReference(containerName).GetBlobReference(v.Name)
-			readCloser, err := blob.Get(&storage.GetBlobOptions{})
+	if config.S3Upload != nil && len(config.S3Upload.Bucket) != 0 {
+		var accessKeyID, secretAccessKey string
+		if len(config.S3Upload.SecretName) != 0 {
+			secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, config.S3Upload.SecretName, metav1.GetOptions{})
 			if err != nil {
-				return errors.Wrap(err, "failed to read from blobstore")
+				retinacmd.Logger.Warn("Failed to get S3 upload secret, using the default AWS credentials", zap.String("secret", config.S3Upload.SecretName), zap.Error(err))
+			} else {
+				accessKeyID = string(secret.Data[captureConstants.CaptureOutputLocationS3UploadAccessKeyID])
+				secretAccessKey = string(secret.Data[captureConstants.CaptureOutputLocationS3UploadSecretAccessKey])
 			}
+		}
+		client, err := outputlocation.NewS3Client(config.S3Upload.Endpoint, config.S3Upload.Region, accessKeyID, secretAccessKey)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, newS3Location(client, config.S3Upload.Bucket, config.S3Upload.Path))
+	}
+
+	if config.PersistentVolumeClaim != nil && len(*config.PersistentVolumeClaim) != 0 {
+		locations = append(locations, newPersistentVolumeClaimLocation(kubeConfig, kubeClient, output.image, *config.PersistentVolumeClaim))
+	}
+
+	if config.HostPath != nil && len(*config.HostPath) != 0 {
+		if len(output.nodeNames) == 0 {
+			retinacmd.Logger.Warn("Nodes of the capture are unknown, skipping host path", zap.String("host path", *config.HostPath))
+		} else {
+			locations = append(locations, newHostPathLocation(kubeConfig, kubeClient, output.image, *config.HostPath, output.nodeNames))
+		}
+	}
 
-			defer readCloser.Close()
-
-			blobData, err := io.ReadAll(readCloser)
-			if err != nil {
-				return errors.Wrap(err, "failed to obtain blob from blobstore")
+	if len(downloadLocation) != 0 {
+		for _, location := range locations {
+			if location.Name() == downloadLocation {
+				return []artifactLocation{location}, nil
 			}
+		}
+		return nil, errors.Errorf("capture %s in namespace %s is not stored to location %s", name, namespace, downloadLocation)
+	}
+	if len(locations) == 0 {
+		return nil, errors.Errorf("capture %s in namespace %s has no output location to download from", name, namespace)
+	}
+	return locations, nil
+}
 
-			err = os.WriteFile(v.Name, blobData, 0o644) //nolint:gosec,gomnd // intentionally permissive bitmask
-			if err != nil {
-				return errors.Wrap(err, "failed to write file")
-			}
-			fmt.Println("Downloaded blob: ", v.Name)
+func listCaptureArtifacts(ctx context.Context, locations []artifactLocation) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0) //nolint:gomnd // padding of the table
+	fmt.Fprintln(w, "LOCATION\tNAME\tSIZE")
+	for _, location := range locations {
+		artifacts, err := location.List(ctx)
+		if err != nil {
+			retinacmd.Logger.Warn("Failed to list capture artifacts", zap.String("location", location.Name()), zap.Error(err))
+			continue
 		}
-		return nil
-	},
+		for _, artifact := range artifacts {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", location.Name(), artifact.name, artifact.size)
+		}
+	}
+	return w.Flush() //nolint:wrapcheck // stdout
+}
+
+// downloadCaptureArtifacts downloads the capture artifacts from the first output location storing any, as each output
+// location stores the same artifacts, and returns their paths.
+func downloadCaptureArtifacts(ctx context.Context, locations []artifactLocation) ([]string, error) {
+	if err := os.MkdirAll(downloadOutputDir, 0o750); err != nil { //nolint:gomnd // directory permissions
+		return nil, errors.Wrap(err, "failed to create output directory")
+	}
+
+	for _, location := range locations {
+		artifacts, err := location.List(ctx)
+		if err != nil {
+			retinacmd.Logger.Warn("Failed to list capture artifacts", zap.String("location", location.Name()), zap.Error(err))
+			continue
+		}
+		if len(artifacts) == 0 {
+			continue
+		}
+
+		if err := location.Download(ctx, artifacts, downloadOutputDir); err != nil {
+			return nil, errors.Wrapf(err, "failed to download capture artifacts from %s", location.Name())
+		}
+		paths := make([]string, 0, len(artifacts))
+		for _, artifact := range artifacts {
+			path := filepath.Join(downloadOutputDir, artifact.name)
+			paths = append(paths, path)
+			fmt.Println("Downloaded capture artifact: ", path)
+		}
+		return paths, nil
+	}
+	return nil, errors.Wrapf(errNoCaptureArtifacts, "capture %s in namespace %s", name, namespace)
+}
+
+func mergeCaptureArtifacts(artifactPaths []string) error {
+	mergedPath := filepath.Join(downloadOutputDir, name+".pcapng")
+	f, err := os.Create(mergedPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to create merged capture file")
+	}
+	defer f.Close()
+
+	packets, err := pkgcapture.MergeCaptureArtifacts(f, artifactPaths)
+	if err != nil {
+		return errors.Wrap(err, "failed to merge capture artifacts")
+	}
+	fmt.Printf("Merged %d packets into %s\n", packets, mergedPath)
+	return nil
 }
 
 func init() {
 	capture.AddCommand(downloadCapture)
+	downloadCapture.Flags().StringVar(&downloadOutputDir, "output", ".", "Directory to download the capture artifacts into")
+	downloadCapture.Flags().StringVar(&downloadLocation, "location", "",
+		"Output location to download the capture artifacts from, one of blob-upload, s3-upload, pvc and host-path. By default the first location storing the artifacts is used")
+	downloadCapture.Flags().BoolVar(&downloadList, "list", false, "List the capture artifacts in each output location instead of downloading them")
+	downloadCapture.Flags().BoolVar(&downloadMerge, "merge", false, "Merge the network packets captured on all nodes into a single pcapng file, ordered by time")
 }
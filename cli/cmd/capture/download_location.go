@@ -0,0 +1,471 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	retinacmd "github.com/microsoft/retina/cli/cmd"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+	"github.com/microsoft/retina/pkg/label"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// Names of the output locations, as the flags of retina capture create.
+const (
+	locationBlobUpload            = "blob-upload"
+	locationS3Upload              = "s3-upload"
+	locationPersistentVolumeClaim = "pvc"
+	locationHostPath              = "host-path"
+)
+
+const (
+	// captureDownloadAppname is the app label of the helper Pods reading the capture artifacts.
+	captureDownloadAppname = "retina-capture-download"
+	// captureDownloadContainername is the name of the container of the helper Pods.
+	captureDownloadContainername = "download"
+	// captureDownloadMountPath is where the helper Pods mount the HostPath or PersistentVolumeClaim.
+	captureDownloadMountPath = "/mnt/capture"
+
+	captureDownloadPodTimeout = 2 * time.Minute
+	// captureDownloadCloseTimeout bounds the deletion of the helper Pods once the download is done or interrupted.
+	captureDownloadCloseTimeout = 30 * time.Second
+)
+
+var errHelperPodNotRunning = errors.New("helper pod is not running")
+
+// captureArtifact is the tarball storing the capture files of a node, in an output location.
+type captureArtifact struct {
+	name string
+	size int64
+	// key identifies the artifact in the output location, like the object key in S3 or the node of the HostPath.
+	key string
+}
+
+// artifactLocation is an output location the capture artifacts are downloaded from.
+type artifactLocation interface {
+	// Name returns the name of the output location.
+	Name() string
+	// List lists the capture artifacts of the Capture in the output location.
+	List(ctx context.Context) ([]captureArtifact, error)
+	// Download downloads the capture artifacts into the directory.
+	Download(ctx context.Context, artifacts []captureArtifact, dir string) error
+	// Close releases the resources used to read the output location.
+	Close(ctx context.Context)
+}
+
+// blobLocation reads the capture artifacts from the blob container of a SAS URL.
+type blobLocation struct {
+	client *container.Client
+}
+
+func newBlobLocation(blobSASURL string) (*blobLocation, error) {
+	client, err := container.NewClientWithNoCredential(blobSASURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create blob container client")
+	}
+	return &blobLocation{client: client}, nil
+}
+
+func (l *blobLocation) Name() string {
+	return locationBlobUpload
+}
+
+func (l *blobLocation) List(ctx context.Context) ([]captureArtifact, error) {
+	var artifacts []captureArtifact
+	pager := l.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &name})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list blobs")
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil || !pkgcapture.IsCaptureArtifact(name, *item.Name) {
+				continue
+			}
+			artifact := captureArtifact{name: *item.Name, key: *item.Name}
+			if item.Properties != nil && item.Properties.ContentLength != nil {
+				artifact.size = *item.Properties.ContentLength
+			}
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	return artifacts, nil
+}
+
+func (l *blobLocation) Download(ctx context.Context, artifacts []captureArtifact, dir string) error {
+	for _, artifact := range artifacts {
+		f, err := os.Create(filepath.Join(dir, artifact.name))
+		if err != nil {
+			return errors.Wrap(err, "failed to create file")
+		}
+		_, err = l.client.NewBlobClient(artifact.key).DownloadFile(ctx, f, nil)
+		f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to download blob %s", artifact.key)
+		}
+	}
+	return nil
+}
+
+func (l *blobLocation) Close(context.Context) {}
+
+// s3Location reads the capture artifacts from an S3 compatible storage service.
+type s3Location struct {
+	client *s3.Client
+	bucket string
+	path   string
+}
+
+func newS3Location(client *s3.Client, bucket, path string) *s3Location {
+	return &s3Location{client: client, bucket: bucket, path: path}
+}
+
+func (l *s3Location) Name() string {
+	return locationS3Upload
+}
+
+func (l *s3Location) List(ctx context.Context) ([]captureArtifact, error) {
+	// The object keys of the capture artifacts are prefixed by the path, followed by the temporary directory of the
+	// capture job, so they are matched by their base name.
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(l.bucket)}
+	if len(l.path) != 0 {
+		input.Prefix = aws.String(strings.TrimSuffix(l.path, "/") + "/")
+	}
+
+	var artifacts []captureArtifact
+	paginator := s3.NewListObjectsV2Paginator(l.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list S3 objects")
+		}
+		for _, object := range page.Contents {
+			if object.Key == nil || !pkgcapture.IsCaptureArtifact(name, path.Base(*object.Key)) {
+				continue
+			}
+			artifact := captureArtifact{name: path.Base(*object.Key), key: *object.Key}
+			if object.Size != nil {
+				artifact.size = *object.Size
+			}
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	return artifacts, nil
+}
+
+func (l *s3Location) Download(ctx context.Context, artifacts []captureArtifact, dir string) error {
+	for _, artifact := range artifacts {
+		object, err := l.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(l.bucket),
+			Key:    aws.String(artifact.key),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get S3 object %s", artifact.key)
+		}
+		err = writeFile(filepath.Join(dir, artifact.name), object.Body)
+		object.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *s3Location) Close(context.Context) {}
+
+// volumeLocation reads the capture artifacts from a HostPath or PersistentVolumeClaim through helper Pods mounting
+// it, which run the capture image and stream the capture artifacts like kubectl cp.
+type volumeLocation struct {
+	name       string
+	kubeConfig *rest.Config
+	kubeClient kubernetes.Interface
+	image      string
+	volume     corev1.Volume
+	// nodeNames are the nodes to read the HostPath of. A PersistentVolumeClaim is read from any node.
+	nodeNames []string
+
+	// pods are the helper Pods by node name, or by the empty name for a PersistentVolumeClaim.
+	pods map[string]*corev1.Pod
+}
+
+func newHostPathLocation(kubeConfig *rest.Config, kubeClient kubernetes.Interface, image, hostPath string, nodeNames []string) *volumeLocation {
+	hostPathType := corev1.HostPathDirectory
+	return &volumeLocation{
+		name:       locationHostPath,
+		kubeConfig: kubeConfig,
+		kubeClient: kubeClient,
+		image:      image,
+		volume: corev1.Volume{
+			Name: captureConstants.CaptureHostPathVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: hostPath, Type: &hostPathType},
+			},
+		},
+		nodeNames: nodeNames,
+		pods:      map[string]*corev1.Pod{},
+	}
+}
+
+func newPersistentVolumeClaimLocation(kubeConfig *rest.Config, kubeClient kubernetes.Interface, image, pvc string) *volumeLocation {
+	return &volumeLocation{
+		name:       locationPersistentVolumeClaim,
+		kubeConfig: kubeConfig,
+		kubeClient: kubeClient,
+		image:      image,
+		volume: corev1.Volume{
+			Name: captureConstants.CapturePVCVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc, ReadOnly: true},
+			},
+		},
+		nodeNames: []string{""},
+		pods:      map[string]*corev1.Pod{},
+	}
+}
+
+func (l *volumeLocation) Name() string {
+	return l.name
+}
+
+func (l *volumeLocation) List(ctx context.Context) ([]captureArtifact, error) {
+	var artifacts []captureArtifact
+	var lastErr error
+	for _, nodeName := range l.nodeNames {
+		nodeArtifacts, err := l.listNode(ctx, nodeName)
+		if err != nil {
+			retinacmd.Logger.Warn("Failed to list capture artifacts", zap.String("location", l.name), zap.String("node", nodeName), zap.Error(err))
+			lastErr = err
+			continue
+		}
+		artifacts = append(artifacts, nodeArtifacts...)
+	}
+	if len(artifacts) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return artifacts, nil
+}
+
+func (l *volumeLocation) listNode(ctx context.Context, nodeName string) ([]captureArtifact, error) {
+	pod, err := l.helperPod(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	if err := l.exec(ctx, pod, []string{pkgcapture.ArtifactsListCommand, captureDownloadMountPath, name}, &stdout); err != nil {
+		return nil, err
+	}
+
+	var artifacts []captureArtifact
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		artifactName, size, found := strings.Cut(scanner.Text(), "\t")
+		if !found {
+			continue
+		}
+		artifact := captureArtifact{name: artifactName, key: nodeName}
+		artifact.size, _ = strconv.ParseInt(size, 10, 64)
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, nil
+}
+
+func (l *volumeLocation) Download(ctx context.Context, artifacts []captureArtifact, dir string) error {
+	nodeArtifacts := map[string][]string{}
+	for _, artifact := range artifacts {
+		nodeArtifacts[artifact.key] = append(nodeArtifacts[artifact.key], artifact.name)
+	}
+	for nodeName, names := range nodeArtifacts {
+		pod, err := l.helperPod(ctx, nodeName)
+		if err != nil {
+			return err
+		}
+		if err := l.copy(ctx, pod, names, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copy streams the capture artifacts from the helper Pod as a tar stream, and extracts them into the directory.
+func (l *volumeLocation) copy(ctx context.Context, pod *corev1.Pod, names []string, dir string) error {
+	reader, writer := io.Pipe()
+	execErr := make(chan error, 1)
+	go func() {
+		command := append([]string{pkgcapture.ArtifactsCopyCommand, captureDownloadMountPath}, names...)
+		err := l.exec(ctx, pod, command, writer)
+		writer.CloseWithError(err)
+		execErr <- err
+	}()
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			reader.CloseWithError(err)
+			<-execErr
+			return errors.Wrap(err, "failed to read capture artifacts")
+		}
+		artifactName := filepath.Base(header.Name)
+		if header.Typeflag != tar.TypeReg || !pkgcapture.IsCaptureArtifact(name, artifactName) {
+			continue
+		}
+		if err := writeFile(filepath.Join(dir, artifactName), tr); err != nil {
+			reader.CloseWithError(err)
+			<-execErr
+			return err
+		}
+	}
+	return <-execErr
+}
+
+// helperPod returns the running helper Pod mounting the volume on the node, and creates it if needed.
+func (l *volumeLocation) helperPod(ctx context.Context, nodeName string) (*corev1.Pod, error) {
+	if pod, ok := l.pods[nodeName]; ok {
+		return pod, nil
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: name + "-download-",
+			Namespace:    namespace,
+			Labels: map[string]string{
+				label.AppLabel:         captureDownloadAppname,
+				label.CaptureNameLabel: name,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      nodeName,
+			NodeSelector:  map[string]string{corev1.LabelOSStable: "linux"},
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:            captureDownloadContainername,
+					Image:           l.image,
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					Command: []string{
+						captureConstants.CaptureContainerEntrypoint, pkgcapture.ArtifactsCommand, pkgcapture.ArtifactsWaitCommand,
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: l.volume.Name, MountPath: captureDownloadMountPath, ReadOnly: true},
+					},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("10m"),
+							corev1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{l.volume},
+			Tolerations: []corev1.Toleration{
+				{Key: "CriticalAddonsOnly", Operator: corev1.TolerationOpExists},
+				{Effect: corev1.TaintEffectNoExecute, Operator: corev1.TolerationOpExists},
+				{Effect: corev1.TaintEffectNoSchedule, Operator: corev1.TolerationOpExists},
+			},
+		},
+	}
+	pod, err := l.kubeClient.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create helper pod")
+	}
+	l.pods[nodeName] = pod
+	retinacmd.Logger.Info("Helper pod is created to read capture artifacts", zap.String("location", l.name), zap.String("pod", pod.Name), zap.String("node", nodeName))
+
+	err = wait.PollUntilContextTimeout(ctx, time.Second, captureDownloadPodTimeout, true, func(ctx context.Context) (bool, error) {
+		current, err := l.kubeClient.CoreV1().Pods(namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get helper pod")
+		}
+		switch current.Status.Phase {
+		case corev1.PodRunning:
+			return true, nil
+		case corev1.PodSucceeded, corev1.PodFailed:
+			return false, errors.Wrapf(errHelperPodNotRunning, "pod %s is %s", pod.Name, current.Status.Phase)
+		default:
+			return false, nil
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "helper pod %s is not running", pod.Name)
+	}
+	return pod, nil
+}
+
+// exec runs the artifacts command of the capture workload in the helper Pod, writing its stdout to the writer.
+func (l *volumeLocation) exec(ctx context.Context, pod *corev1.Pod, args []string, stdout io.Writer) error {
+	command := append([]string{captureConstants.CaptureContainerEntrypoint, pkgcapture.ArtifactsCommand}, args...)
+	req := l.kubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: captureDownloadContainername,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(l.kubeConfig, "POST", req.URL())
+	if err != nil {
+		return errors.Wrap(err, "failed to create executor")
+	}
+	var stderr bytes.Buffer
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: stdout, Stderr: &stderr}); err != nil {
+		return errors.Wrapf(err, "failed to run %q in pod %s: %s", strings.Join(args, " "), pod.Name, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (l *volumeLocation) Close(ctx context.Context) {
+	gracePeriodSeconds := int64(0)
+	for _, pod := range l.pods {
+		if err := l.kubeClient.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{
+			GracePeriodSeconds: &gracePeriodSeconds,
+		}); err != nil {
+			retinacmd.Logger.Warn("Failed to delete helper pod", zap.String("pod", pod.Name), zap.Error(err))
+		}
+	}
+	l.pods = map[string]*corev1.Pod{}
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to create file")
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return errors.Wrapf(err, "failed to write file %s", path)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"sort"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+	captureUtils "github.com/microsoft/retina/pkg/capture/utils"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var errCaptureNotFound = errors.New("capture was not found")
+
+// captureOutput is where the artifacts of a Capture are stored.
+type captureOutput struct {
+	config retinav1alpha1.OutputConfiguration
+	// nodeNames are the nodes the capture ran on, whose HostPath stores the artifacts.
+	nodeNames []string
+	// image is the capture image, run by the helper Pods reading the artifacts on a HostPath or PersistentVolumeClaim.
+	image string
+}
+
+// resolveCaptureOutput resolves the output configuration of the Capture created through the Capture CRD, or by the
+// Retina CLI which only creates the capture jobs.
+func resolveCaptureOutput(ctx context.Context, kubeConfig *rest.Config, kubeClient kubernetes.Interface) (*captureOutput, error) {
+	jobList, err := kubeClient.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(captureUtils.GetJobLabelsFromCaptureName(name)).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list capture jobs")
+	}
+
+	output := &captureOutput{}
+	found := false
+	captureClient, err := newCaptureClient(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	capture, err := getCapture(ctx, captureClient)
+	if err != nil {
+		return nil, err
+	}
+	if capture != nil {
+		found = true
+		output.config = capture.Spec.OutputConfiguration
+		for _, node := range capture.Status.Nodes {
+			output.nodeNames = append(output.nodeNames, node.NodeName)
+		}
+	}
+
+	nodeNames := map[string]struct{}{}
+	for _, nodeName := range output.nodeNames {
+		nodeNames[nodeName] = struct{}{}
+	}
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		if !found {
+			found = true
+			output.config = outputConfigurationFromJob(job)
+		}
+		if len(output.image) == 0 && len(job.Spec.Template.Spec.Containers) != 0 {
+			output.image = job.Spec.Template.Spec.Containers[0].Image
+		}
+		if nodeName := jobEnv(job, captureConstants.NodeHostNameEnvKey); len(nodeName) != 0 {
+			nodeNames[nodeName] = struct{}{}
+		}
+	}
+	if !found {
+		return nil, errors.Wrapf(errCaptureNotFound, "capture %s in namespace %s", name, namespace)
+	}
+
+	output.nodeNames = output.nodeNames[:0]
+	for nodeName := range nodeNames {
+		output.nodeNames = append(output.nodeNames, nodeName)
+	}
+	sort.Strings(output.nodeNames)
+	return output, nil
+}
+
+// newCaptureClient returns a client of the Captures.
+func newCaptureClient(kubeConfig *rest.Config) (client.Client, error) {
+	scheme := runtime.NewScheme()
+	if err := retinav1alpha1.AddToScheme(scheme); err != nil {
+		return nil, errors.Wrap(err, "failed to add Capture to scheme")
+	}
+	c, err := client.New(kubeConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize kubernetes client")
+	}
+	return c, nil
+}
+
+// getCapture returns the Capture, or nil if it does not exist or the Capture CRD is not installed.
+func getCapture(ctx context.Context, c client.Client) (*retinav1alpha1.Capture, error) {
+	capture := &retinav1alpha1.Capture{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, capture)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get capture")
+	}
+	return capture, nil
+}
+
+// outputConfigurationFromJob returns the output configuration the capture job was created with, from its environment
+// variables and the secrets it mounts.
+func outputConfigurationFromJob(job *batchv1.Job) retinav1alpha1.OutputConfiguration {
+	config := retinav1alpha1.OutputConfiguration{}
+	if hostPath := jobEnv(job, string(captureConstants.CaptureOutputLocationEnvKeyHostPath)); len(hostPath) != 0 {
+		config.HostPath = &hostPath
+	}
+	if pvc := jobEnv(job, string(captureConstants.CaptureOutputLocationEnvKeyPersistentVolumeClaim)); len(pvc) != 0 {
+		config.PersistentVolumeClaim = &pvc
+	}
+	if bucket := jobEnv(job, string(captureConstants.CaptureOutputLocationEnvKeyS3Bucket)); len(bucket) != 0 {
+		config.S3Upload = &retinav1alpha1.S3Upload{
+			Endpoint:   jobEnv(job, string(captureConstants.CaptureOutputLocationEnvKeyS3Endpoint)),
+			Bucket:     bucket,
+			Region:     jobEnv(job, string(captureConstants.CaptureOutputLocationEnvKeyS3Region)),
+			Path:       jobEnv(job, string(captureConstants.CaptureOutputLocationEnvKeyS3Path)),
+			SecretName: jobSecretName(job, captureConstants.CaptureOutputLocationS3UploadSecretPath),
+		}
+	}
+	if secretName := jobSecretName(job, captureConstants.CaptureOutputLocationBlobUploadSecretPath); len(secretName) != 0 {
+		config.BlobUpload = &secretName
+	}
+	return config
+}
+
+func jobEnv(job *batchv1.Job, key string) string {
+	for _, container := range job.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == key {
+				return env.Value
+			}
+		}
+	}
+	return ""
+}
+
+// jobSecretName returns the name of the secret the capture job mounts at the path.
+func jobSecretName(job *batchv1.Job, mountPath string) string {
+	for _, container := range job.Spec.Template.Spec.Containers {
+		for _, volumeMount := range container.VolumeMounts {
+			if volumeMount.MountPath != mountPath {
+				continue
+			}
+			for _, volume := range job.Spec.Template.Spec.Volumes {
+				if volume.Name == volumeMount.Name && volume.Secret != nil {
+					return volume.Secret.SecretName
+				}
+			}
+		}
+	}
+	return ""
+}
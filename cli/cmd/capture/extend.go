@@ -0,0 +1,97 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"fmt"
+	"time"
+
+	retinacmd "github.com/microsoft/retina/cli/cmd"
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var extendDuration time.Duration
+
+var extendExample = templates.Examples(i18n.T(`
+		# Capture network packets for 5 more minutes in the Retina Capture "retina-capture-8v6wd" in namespace "capture"
+		kubectl retina capture extend --name retina-capture-8v6wd --namespace capture --duration 5m
+		`))
+
+var extendCapture = &cobra.Command{
+	Use:     "extend",
+	Short:   "Extend the duration of a running Retina capture",
+	Example: extendExample,
+	RunE: func(*cobra.Command, []string) error {
+		if extendDuration <= 0 {
+			return errors.New("duration must be positive")
+		}
+
+		kubeConfig, err := configFlags.ToRESTConfig()
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		err = controlCapture(kubeConfig, func(capture *retinav1alpha1.Capture) error {
+			if capture.Spec.Stop {
+				return errors.Errorf("capture %s is stopping", capture.Name)
+			}
+			duration := capture.Spec.CaptureConfiguration.CaptureOption.Duration
+			if duration == nil {
+				return errors.Errorf("capture %s has no duration", capture.Name)
+			}
+			capture.Spec.CaptureConfiguration.CaptureOption.Duration = &metav1.Duration{Duration: duration.Duration + extendDuration}
+			return nil
+		}, func(pod *corev1.Pod, control *pkgcapture.CaptureJobControl) error {
+			if control.Stop {
+				return errors.Errorf("capture pod %s is stopping", pod.Name)
+			}
+			if control.Duration == 0 {
+				duration, err := captureJobDuration(pod)
+				if err != nil {
+					return err
+				}
+				control.Duration = duration
+			}
+			control.Duration += extendDuration
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		retinacmd.Logger.Info(fmt.Sprintf("Retina Capture %q is extended by %s", name, extendDuration))
+
+		return nil
+	},
+}
+
+// captureJobDuration returns the duration the capture job was created with.
+func captureJobDuration(pod *corev1.Pod) (time.Duration, error) {
+	for _, container := range pod.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name != captureConstants.CaptureDurationEnvKey {
+				continue
+			}
+			duration, err := time.ParseDuration(env.Value)
+			if err != nil {
+				return 0, errors.Wrapf(err, "invalid duration of capture pod %s", pod.Name)
+			}
+			return duration, nil
+		}
+	}
+	return 0, errors.Errorf("capture pod %s has no duration", pod.Name)
+}
+
+func init() {
+	capture.AddCommand(extendCapture)
+	extendCapture.Flags().DurationVar(&extendDuration, "duration", 0, "Duration to extend the capture by")
+	_ = extendCapture.MarkFlagRequired("duration")
+}
@@ -0,0 +1,136 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	retinacmd "github.com/microsoft/retina/cli/cmd"
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+	captureUtils "github.com/microsoft/retina/pkg/capture/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var stopExample = templates.Examples(i18n.T(`
+		# Stop the Retina Capture "retina-capture-8v6wd" in namespace "capture", and store the captured network packets
+		kubectl retina capture stop --name retina-capture-8v6wd --namespace capture
+		`))
+
+var stopCapture = &cobra.Command{
+	Use:     "stop",
+	Short:   "Stop a running Retina capture",
+	Example: stopExample,
+	RunE: func(*cobra.Command, []string) error {
+		kubeConfig, err := configFlags.ToRESTConfig()
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		err = controlCapture(kubeConfig, func(capture *retinav1alpha1.Capture) error {
+			capture.Spec.Stop = true
+			return nil
+		}, func(_ *corev1.Pod, control *pkgcapture.CaptureJobControl) error {
+			control.Stop = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		retinacmd.Logger.Info(fmt.Sprintf("Retina Capture %q is stopping", name))
+
+		return nil
+	},
+}
+
+// controlCapture updates the Capture when it exists, as its controller sets the control of its running capture jobs
+// from its spec and would revert a change of the control of their Pods. Otherwise, the control of the Pods of the
+// capture jobs created by the CLI is updated.
+func controlCapture(kubeConfig *rest.Config, updateCapture func(*retinav1alpha1.Capture) error, updatePod func(*corev1.Pod, *pkgcapture.CaptureJobControl) error) error {
+	ctx := context.TODO()
+	captureClient, err := newCaptureClient(kubeConfig)
+	if err != nil {
+		return err
+	}
+	capture, err := getCapture(ctx, captureClient)
+	if err != nil {
+		return err
+	}
+	if capture != nil {
+		original := capture.DeepCopy()
+		if err := updateCapture(capture); err != nil {
+			return err
+		}
+		if err := captureClient.Patch(ctx, capture, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})); err != nil {
+			return errors.Wrap(err, "failed to update capture")
+		}
+		return nil
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	return controlCapturePods(kubeClient, updatePod)
+}
+
+// controlCapturePods updates the control of the running capture jobs of the Capture, which is set in the annotations
+// of their Pods.
+func controlCapturePods(kubeClient kubernetes.Interface, update func(*corev1.Pod, *pkgcapture.CaptureJobControl) error) error {
+	podList, err := kubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(captureUtils.GetContainerLabelsFromCaptureName(name)).String(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list capture pods")
+	}
+
+	running := 0
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase != corev1.PodPending && pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		running++
+
+		control, err := pkgcapture.CaptureJobControlFromAnnotations(pod.Annotations)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get control of capture pod %s", pod.Name)
+		}
+		if err := update(pod, &control); err != nil {
+			return err
+		}
+		data, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": control.Annotations(),
+			},
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal capture control")
+		}
+		if _, err := kubeClient.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.MergePatchType, data, metav1.PatchOptions{}); err != nil {
+			retinacmd.Logger.Info("Failed to patch pod", zap.String("pod name", pod.Name), zap.Error(err))
+		}
+	}
+	if running == 0 {
+		return errors.Errorf("no running capture %s in namespace %s was found", name, namespace)
+	}
+	return nil
+}
+
+func init() {
+	capture.AddCommand(stopCapture)
+}
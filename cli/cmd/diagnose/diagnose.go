@@ -0,0 +1,320 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package diagnose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	retinacmd "github.com/microsoft/retina/cli/cmd"
+	"github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/server"
+	"github.com/microsoft/retina/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+const (
+	// defaultAgentNamespace is the namespace of the Retina agent Pods, unless --namespace is set.
+	defaultAgentNamespace = "kube-system"
+	// agentLabelSelector selects the Retina agent Pods.
+	agentLabelSelector = "k8s-app=retina"
+	// defaultAgentPort is the port of the agent API.
+	defaultAgentPort = 10093
+
+	// bundleTimeFormat formats the time of the bundle in its name, as the capture artifacts.
+	bundleTimeFormat = "20060102150405UTC"
+)
+
+var (
+	configFlags *genericclioptions.ConfigFlags
+
+	nodeName   string
+	outputDir  string
+	agentPort  int
+	cpuProfile time.Duration
+)
+
+var errNoAgentPod = errors.New("no running Retina agent pod found")
+
+// agentAPIFile is a path of the agent API, collected to the file.
+type agentAPIFile struct {
+	file   string
+	path   string
+	params map[string]string
+}
+
+// agentAPIFiles are the paths of the agent API collected into the bundle.
+var agentAPIFiles = []agentAPIFile{
+	{file: "config.json", path: config.ConfigPath},
+	{file: "healthz.json", path: server.HealthzPath},
+	{file: "readyz.json", path: server.ReadyzPath},
+	{file: "state.json", path: server.DebugStatePath},
+	{file: "metrics.txt", path: "/metrics"},
+	{file: "pprof/heap.pb.gz", path: "/debug/pprof/heap"},
+	{file: "pprof/goroutine.txt", path: "/debug/pprof/goroutine", params: map[string]string{"debug": "2"}},
+}
+
+// nodeCommand is a command run in the agent container, its output collected to the file.
+type nodeCommand struct {
+	file    string
+	command []string
+}
+
+// linuxNodeCommands are the commands run in the agent container on Linux nodes, limited to the tools of its image.
+var linuxNodeCommands = []nodeCommand{
+	{file: "node/uname.txt", command: []string{"uname", "-a"}},
+	{file: "node/links.txt", command: []string{"ip", "-d", "link", "show"}},
+	{file: "node/addresses.txt", command: []string{"ip", "addr", "show"}},
+	{file: "node/routes.txt", command: []string{"ip", "route", "show", "table", "all"}},
+	{file: "node/sockets.txt", command: []string{"ss", "-tanpu"}},
+	{file: "node/iptables-legacy.txt", command: []string{"iptables-legacy-save"}},
+	{file: "node/iptables-nft.txt", command: []string{"iptables-nft-save"}},
+}
+
+// summary describes the bundle, and what could not be collected.
+type summary struct {
+	Node        string    `json:"node"`
+	Pod         string    `json:"pod"`
+	Namespace   string    `json:"namespace"`
+	CollectedAt time.Time `json:"collectedAt"`
+	CLIVersion  string    `json:"cliVersion"`
+	// KernelVersion and OperatingSystem are reported by the kubelet of the node.
+	KernelVersion   string   `json:"kernelVersion"`
+	OperatingSystem string   `json:"operatingSystem"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+var diagnoseExample = templates.Examples(i18n.T(`
+		# Collect the state of the Retina agent running on node "aks-nodepool1-41844487-vmss000000" into a tarball in the current directory
+		kubectl retina diagnose --node aks-nodepool1-41844487-vmss000000
+
+		# Also collect a 30 seconds CPU profile of the agent, into ./diagnose
+		kubectl retina diagnose --node aks-nodepool1-41844487-vmss000000 --cpu-profile 30s --output ./diagnose
+		`))
+
+var diagnose = &cobra.Command{
+	Use:   "diagnose",
+	Short: "Collect the state of the Retina agent of a node into a tarball",
+	Long: templates.LongDesc(i18n.T(`
+		Collect the state of the Retina agent of a node into a tarball: the logs, config, health, internal state,
+		metrics and profiles of the agent, the eBPF programs and maps of the node with the pinned maps and the
+		filter map of Retina, and the kernel, BTF, interfaces, routes and iptables rules of the node.
+
+		The agent runs in namespace kube-system unless --namespace is set. The parts which cannot be collected are
+		listed in summary.json in the tarball.`)),
+	Example: diagnoseExample,
+	RunE: func(*cobra.Command, []string) error {
+		ctx := context.TODO()
+
+		kubeConfig, err := configFlags.ToRESTConfig()
+		if err != nil {
+			return errors.Wrap(err, "failed to compose k8s rest config")
+		}
+		kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+		if err != nil {
+			return errors.Wrap(err, "failed to initialize kubernetes client")
+		}
+		namespace := *configFlags.Namespace
+		if namespace == "" {
+			namespace = defaultAgentNamespace
+		}
+
+		node, err := kubeClient.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get node %s", nodeName)
+		}
+		pod, err := agentPod(ctx, kubeClient, namespace)
+		if err != nil {
+			return err
+		}
+
+		dir, err := os.MkdirTemp("", "retina-diagnose-")
+		if err != nil {
+			return errors.Wrap(err, "failed to create temporary directory")
+		}
+		defer os.RemoveAll(dir)
+
+		c := &collector{kubeConfig: kubeConfig, kubeClient: kubeClient, pod: pod, dir: dir}
+		retinacmd.Logger.Info("Collecting the state of the Retina agent", zap.String("node", nodeName), zap.String("pod", pod.Name))
+		c.collect(ctx, node)
+
+		bundle := filepath.Join(outputDir, fmt.Sprintf("retina-diagnose-%s-%s.tar.gz", nodeName, time.Now().UTC().Format(bundleTimeFormat)))
+		if err := os.MkdirAll(outputDir, 0o755); err != nil { //nolint:gomnd // directory permissions
+			return errors.Wrap(err, "failed to create output directory")
+		}
+		if err := utils.CompressFolderToTarGz(dir, bundle); err != nil {
+			return errors.Wrap(err, "failed to write tarball")
+		}
+		retinacmd.Logger.Info("Wrote diagnose tarball", zap.String("file", bundle), zap.Int("failures", len(c.errs)))
+		return nil
+	},
+}
+
+// agentPod returns the running Retina agent Pod of the node.
+func agentPod(ctx context.Context, kubeClient kubernetes.Interface, namespace string) (*corev1.Pod, error) {
+	pods, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: agentLabelSelector,
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list Retina agent pods")
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, errors.Wrapf(errNoAgentPod, "node %s, namespace %s", nodeName, namespace)
+}
+
+// collector writes the state of the agent Pod and its node to the files of a directory. The failures to collect a
+// part of the state are recorded, so that the rest is still collected.
+type collector struct {
+	kubeConfig *rest.Config
+	kubeClient kubernetes.Interface
+	pod        *corev1.Pod
+	dir        string
+	errs       []string
+}
+
+func (c *collector) collect(ctx context.Context, node *corev1.Node) {
+	container := c.pod.Spec.Containers[0].Name
+
+	c.writeJSON("node.json", node)
+	c.writeJSON("pod.json", c.pod)
+
+	c.collectLogs(ctx, "logs/agent.log", &corev1.PodLogOptions{Container: container})
+	for _, status := range c.pod.Status.ContainerStatuses {
+		if status.Name == container && status.RestartCount > 0 {
+			c.collectLogs(ctx, "logs/agent-previous.log", &corev1.PodLogOptions{Container: container, Previous: true})
+		}
+	}
+
+	for _, f := range agentAPIFiles {
+		c.collectAgentAPI(ctx, f)
+	}
+	if cpuProfile > 0 {
+		seconds := strconv.Itoa(int(cpuProfile.Seconds()))
+		c.collectAgentAPI(ctx, agentAPIFile{file: "pprof/profile.pb.gz", path: "/debug/pprof/profile", params: map[string]string{"seconds": seconds}})
+	}
+
+	if node.Status.NodeInfo.OperatingSystem == "linux" {
+		for _, cmd := range linuxNodeCommands {
+			c.collectCommand(ctx, container, cmd)
+		}
+	}
+
+	c.writeJSON("summary.json", summary{
+		Node:            node.Name,
+		Pod:             c.pod.Name,
+		Namespace:       c.pod.Namespace,
+		CollectedAt:     time.Now(),
+		CLIVersion:      retinacmd.Version,
+		KernelVersion:   node.Status.NodeInfo.KernelVersion,
+		OperatingSystem: node.Status.NodeInfo.OperatingSystem,
+		Errors:          c.errs,
+	})
+}
+
+func (c *collector) collectLogs(ctx context.Context, file string, opts *corev1.PodLogOptions) {
+	data, err := c.kubeClient.CoreV1().Pods(c.pod.Namespace).GetLogs(c.pod.Name, opts).DoRaw(ctx)
+	if err != nil {
+		c.fail(file, err)
+		return
+	}
+	c.write(file, data)
+}
+
+// collectAgentAPI gets the path of the agent API through the API server proxy. The response is written even when the
+// agent fails the request, e.g. for the health checks failing.
+func (c *collector) collectAgentAPI(ctx context.Context, f agentAPIFile) {
+	data, err := c.kubeClient.CoreV1().Pods(c.pod.Namespace).ProxyGet("http", c.pod.Name, strconv.Itoa(agentPort), f.path, f.params).DoRaw(ctx)
+	if err != nil {
+		c.fail(f.file, err)
+	}
+	if len(data) > 0 {
+		c.write(f.file, data)
+	}
+}
+
+func (c *collector) collectCommand(ctx context.Context, container string, cmd nodeCommand) {
+	req := c.kubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(c.pod.Namespace).
+		Name(c.pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd.command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.kubeConfig, "POST", req.URL())
+	if err != nil {
+		c.fail(cmd.file, errors.Wrap(err, "failed to create executor"))
+		return
+	}
+	var stdout, stderr bytes.Buffer
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		c.fail(cmd.file, errors.Wrapf(err, "failed to run %q: %s", strings.Join(cmd.command, " "), strings.TrimSpace(stderr.String())))
+		return
+	}
+	c.write(cmd.file, stdout.Bytes())
+}
+
+func (c *collector) writeJSON(file string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		c.fail(file, err)
+		return
+	}
+	c.write(file, data)
+}
+
+func (c *collector) write(file string, data []byte) {
+	path := filepath.Join(c.dir, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gomnd // directory permissions
+		c.fail(file, err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil { //nolint:gosec,gomnd // no sensitive data
+		c.fail(file, err)
+	}
+}
+
+func (c *collector) fail(file string, err error) {
+	retinacmd.Logger.Warn("Failed to collect", zap.String("file", file), zap.Error(err))
+	c.errs = append(c.errs, fmt.Sprintf("%s: %s", file, err))
+}
+
+func init() {
+	retinacmd.Retina.AddCommand(diagnose)
+	configFlags = genericclioptions.NewConfigFlags(true)
+	configFlags.AddFlags(diagnose.Flags())
+	diagnose.Flags().StringVar(&nodeName, "node", "", "The name of the node to diagnose")
+	_ = diagnose.MarkFlagRequired("node")
+	diagnose.Flags().StringVarP(&outputDir, "output", "o", ".", "The directory to write the tarball to")
+	diagnose.Flags().IntVar(&agentPort, "agent-port", defaultAgentPort, "The port of the Retina agent API")
+	diagnose.Flags().DurationVar(&cpuProfile, "cpu-profile", 0, "The duration of the CPU profile of the agent to collect, none by default")
+}
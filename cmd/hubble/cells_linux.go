@@ -15,6 +15,7 @@ import (
 	"github.com/cilium/proxy/pkg/logging/logfields"
 	"github.com/microsoft/retina/pkg/config"
 	rnode "github.com/microsoft/retina/pkg/controllers/daemon/nodereconciler"
+	"github.com/microsoft/retina/pkg/controllers/daemon/pluginconfiguration"
 	hubbleserver "github.com/microsoft/retina/pkg/hubble"
 	retinak8s "github.com/microsoft/retina/pkg/k8s"
 	"github.com/microsoft/retina/pkg/managers/pluginmanager"
@@ -88,6 +89,9 @@ var (
 
 		pluginmanager.Cell,
 
+		// Enables the plugins of the PluginConfiguration of the node at runtime
+		pluginconfiguration.Cell,
+
 		retinak8s.Cell,
 
 		servermanager.Cell,
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
 	"github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/managers/pluginmanager"
 	"github.com/microsoft/retina/pkg/managers/servermanager"
@@ -48,6 +49,10 @@ var (
 				logger.Error("failed to add corev1 to scheme")
 				return nil, nil, errors.Wrap(err, "failed to add corev1 to scheme")
 			}
+			if err := retinav1alpha1.AddToScheme(scheme); err != nil { //nolint:govet // intentional shadow
+				logger.Error("failed to add retinav1alpha1 to scheme")
+				return nil, nil, errors.Wrap(err, "failed to add retinav1alpha1 to scheme")
+			}
 
 			mgrOption := ctrl.Options{
 				Scheme: scheme,
@@ -104,6 +104,9 @@ func InitGlobalFlags(cmd *cobra.Command, vp *viper.Viper) {
 	flags.StringSlice(option.HubbleMetrics, []string{}, "List of Hubble metrics to enable.")
 	option.BindEnv(vp, option.HubbleMetrics)
 
+	flags.Bool(option.EnableHubbleOpenMetrics, false, "Enable exporting hubble metrics in OpenMetrics format, required for exemplars.")
+	option.BindEnv(vp, option.EnableHubbleOpenMetrics)
+
 	flags.String(option.HubbleFlowlogsConfigFilePath, "", "Filepath with configuration of hubble flowlogs")
 	option.BindEnv(vp, option.HubbleFlowlogsConfigFilePath)
 
@@ -221,7 +224,11 @@ func newDaemonPromise(params daemonParams) promise.Promise[*Daemon] {
 
 func initLogging() {
 	logger := setupDefaultLogger()
-	retinaConfig, _ := getRetinaConfig(logger)
+	retinaConfig, err := getRetinaConfig(logger)
+	if err != nil {
+		// The agent config cell falls back to the default config as well.
+		retinaConfig = config.DefaultRetinaConfig
+	}
 	k8sCfg, _ := sharedconfig.GetK8sConfig()
 	zapLogger := setupZapLogger(retinaConfig, k8sCfg)
 	setupLoggingHooks(logger, zapLogger)
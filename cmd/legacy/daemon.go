@@ -3,9 +3,12 @@
 package legacy
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -30,6 +33,7 @@ import (
 	mcc "github.com/microsoft/retina/pkg/controllers/daemon/metricsconfiguration"
 	namespacecontroller "github.com/microsoft/retina/pkg/controllers/daemon/namespace"
 	nc "github.com/microsoft/retina/pkg/controllers/daemon/node"
+	pcc "github.com/microsoft/retina/pkg/controllers/daemon/pluginconfiguration"
 	pc "github.com/microsoft/retina/pkg/controllers/daemon/pod"
 	kec "github.com/microsoft/retina/pkg/controllers/daemon/retinaendpoint"
 	sc "github.com/microsoft/retina/pkg/controllers/daemon/service"
@@ -38,8 +42,10 @@ import (
 	"github.com/microsoft/retina/pkg/log"
 	cm "github.com/microsoft/retina/pkg/managers/controllermanager"
 	"github.com/microsoft/retina/pkg/managers/filtermanager"
+	pm "github.com/microsoft/retina/pkg/managers/pluginmanager"
 	"github.com/microsoft/retina/pkg/metrics"
 	mm "github.com/microsoft/retina/pkg/module/metrics"
+	"github.com/microsoft/retina/pkg/plugin/api"
 	"github.com/microsoft/retina/pkg/pubsub"
 	"github.com/microsoft/retina/pkg/telemetry"
 )
@@ -55,6 +61,8 @@ const (
 var (
 	scheme = k8sruntime.NewScheme()
 
+	errCacheNotSynced = errors.New("kubernetes cache is not synced")
+
 	// applicationInsightsID is the instrumentation key for Azure Application Insights
 	// It is set during the build process using the -ldflags flag
 	// If it is set, the application will send telemetry to the corresponding Application Insights resource.
@@ -215,12 +223,30 @@ func (d *Daemon) Start() error {
 	ctx := ctrl.SetupSignalHandler()
 	ctrl.SetLogger(zapr.NewLogger(zl.Logger.Named("controller-runtime")))
 
+	var (
+		namespaceController *namespacecontroller.NamespaceReconciler
+		controllerCache     *controllercache.Cache
+		enrich              *enricher.Enricher
+		fm                  *filtermanager.FilterManager
+	)
 	if daemonConfig.EnablePodLevel {
 		pubSub := pubsub.New()
-		controllerCache := controllercache.New(pubSub)
-		enrich := enricher.New(ctx, controllerCache)
-		//nolint:govet // shadowing this err is fine
-		fm, err := filtermanager.Init(5) //nolint:gomnd // defaults
+		controllerCache = controllercache.New(pubSub)
+		controllerCache.SetTombstoneTTL(daemonConfig.CacheTombstoneTTL)
+		// Restore the cache saved before the agent restarted, to enrich flows until the Kubernetes cache is synced.
+		if snapshot := daemonConfig.CacheSnapshot; snapshot.Path != "" {
+			restored, err := controllerCache.LoadSnapshot(snapshot.Path)
+			if err != nil {
+				mainLogger.Warn("failed to restore cache snapshot", zap.Error(err))
+			} else {
+				mainLogger.Info("restored cache snapshot", zap.String("path", snapshot.Path), zap.Int("objects", restored))
+			}
+			if snapshot.Interval > 0 {
+				go controllerCache.RunSnapshots(ctx, snapshot.Path, snapshot.Interval)
+			}
+		}
+		enrich = enricher.New(ctx, controllerCache)
+		fm, err = filtermanager.Init(5) //nolint:gomnd // defaults
 		if err != nil {
 			mainLogger.Fatal("unable to create filter manager", zap.Error(err))
 		}
@@ -259,14 +285,15 @@ func (d *Daemon) Start() error {
 
 		if daemonConfig.EnableAnnotations {
 			mainLogger.Info("Initializing MetricsConfig namespaceController")
-			namespaceController := namespacecontroller.New(mgr.GetClient(), controllerCache, metricsModule)
+			namespaceController = namespacecontroller.New(mgr.GetClient(), controllerCache, metricsModule)
+			namespaceController.SetEnabledMetrics(daemonConfig.EnabledMetrics)
 			if err := namespaceController.SetupWithManager(mgr); err != nil {
 				mainLogger.Fatal("unable to create namespaceController", zap.Error(err))
 			}
 			go namespaceController.Start(ctx)
 		} else {
 			mainLogger.Info("Initializing MetricsConfig controller")
-			metricsConfigController := mcc.New(mgr.GetClient(), mgr.GetScheme(), metricsModule)
+			metricsConfigController := mcc.New(mgr.GetClient(), mgr.GetScheme(), os.Getenv(nodeNameEnvKey), metricsModule)
 			if err := metricsConfigController.SetupWithManager(mgr); err != nil {
 				mainLogger.Fatal("unable to create metricsConfigController", zap.Error(err))
 			}
@@ -280,6 +307,75 @@ func (d *Daemon) Start() error {
 	if err := controllerMgr.Init(ctx); err != nil {
 		mainLogger.Fatal("Failed to initialize controller manager", zap.Error(err))
 	}
+
+	// Give the plugins the cluster, with the nodes of the cache when pod level is enabled.
+	cluster := api.Cluster{Client: cl}
+	if controllerCache != nil {
+		cluster.Nodes = controllerCache
+	}
+	controllerMgr.PluginManager().SetCluster(cluster)
+
+	// Report the health of the agent on /healthz and /readyz, and its internal state on /debug/state.
+	var cacheSynced atomic.Bool
+	go func() {
+		synced := mgr.GetCache().WaitForCacheSync(ctx)
+		if synced && controllerCache != nil && daemonConfig.CacheSnapshot.Path != "" {
+			// Delete the restored objects which were deleted while the agent was down.
+			keys, err := liveCacheKeys(ctx, mgr.GetClient(), daemonConfig)
+			if err != nil {
+				mainLogger.Error("failed to reconcile cache snapshot", zap.Error(err))
+			} else {
+				mainLogger.Info("reconciled cache snapshot", zap.Int("deleted", controllerCache.Reconcile(keys)))
+			}
+		}
+		cacheSynced.Store(synced)
+	}()
+	controllerMgr.AddReadyzCheck("k8s-cache", func() error {
+		if !cacheSynced.Load() {
+			return errCacheNotSynced
+		}
+		return nil
+	})
+	if daemonConfig.EnablePodLevel {
+		controllerMgr.AddReadyzCheck("enricher", enrich.ReadyzCheck)
+		controllerMgr.AddDebugState("cache", func() interface{} {
+			return controllerCache.State()
+		})
+		controllerMgr.AddDebugState("filters", func() interface{} {
+			return fm.State()
+		})
+	}
+
+	// Apply the changes of the hot reloadable settings of the config file, and serve the effective config.
+	reloader := config.NewReloader(d.configFile, daemonConfig)
+	reloader.AddValidator(pm.ValidatePlugins)
+	reloader.OnReload(func(oldCfg, newCfg *config.Config) {
+		if oldCfg.MetricsInterval != newCfg.MetricsInterval {
+			controllerMgr.PluginManager().SetMetricsInterval(newCfg.MetricsInterval)
+		}
+		if namespaceController != nil {
+			namespaceController.SetEnabledMetrics(newCfg.EnabledMetrics)
+		}
+	})
+	controllerMgr.RegisterHandler(config.ConfigPath, reloader.Handler())
+	go func() {
+		if err := reloader.Start(ctx); err != nil {
+			mainLogger.Error("failed to watch config file, config changes require a restart", zap.Error(err))
+		}
+	}()
+
+	if daemonConfig.EnablePluginConfiguration {
+		nodeName := os.Getenv(nodeNameEnvKey)
+		if nodeName == "" {
+			mainLogger.Fatal("failed to get node name from environment variable", zap.String("node name env key", nodeNameEnvKey))
+		}
+		mainLogger.Info("Initializing PluginConfiguration controller")
+		pluginConfigurationController := pcc.New(mgr.GetClient(), nodeName, daemonConfig.EnabledPlugin, controllerMgr.PluginManager())
+		if err := pluginConfigurationController.SetupWithManager(mgr); err != nil {
+			mainLogger.Fatal("unable to create pluginConfigurationController", zap.Error(err))
+		}
+	}
+
 	// Stop is best effort. If it fails, we still want to stop the main process.
 	// This is needed for graceful shutdown of Retina plugins.
 	// Do it in the main thread as graceful shutdown is important.
@@ -297,6 +393,53 @@ func (d *Daemon) Start() error {
 		mainLogger.Fatal("unable to start manager", zap.Error(err))
 	}
 
+	if controllerCache != nil && daemonConfig.CacheSnapshot.Path != "" {
+		if err := controllerCache.SaveSnapshot(daemonConfig.CacheSnapshot.Path); err != nil {
+			mainLogger.Error("failed to save cache snapshot", zap.Error(err))
+		}
+	}
+
 	mainLogger.Info("Network observability exiting. Till next time!")
 	return nil
 }
+
+// liveCacheKeys lists the keys of the pods, services and nodes the controllers update the cache with.
+func liveCacheKeys(ctx context.Context, c client.Reader, daemonConfig *config.Config) (controllercache.Keys, error) {
+	keys := controllercache.Keys{
+		Pods:     make(map[string]struct{}),
+		Services: make(map[string]struct{}),
+		Nodes:    make(map[string]struct{}),
+	}
+	if !daemonConfig.RemoteContext {
+		var pods corev1.PodList
+		if err := c.List(ctx, &pods); err != nil {
+			return keys, fmt.Errorf("failed to list pods: %w", err)
+		}
+		for i := range pods.Items {
+			keys.Pods[pods.Items[i].Namespace+"/"+pods.Items[i].Name] = struct{}{}
+		}
+	} else if daemonConfig.EnableRetinaEndpoint {
+		var retinaEndpoints retinav1alpha1.RetinaEndpointList
+		if err := c.List(ctx, &retinaEndpoints); err != nil {
+			return keys, fmt.Errorf("failed to list RetinaEndpoints: %w", err)
+		}
+		for i := range retinaEndpoints.Items {
+			keys.Pods[retinaEndpoints.Items[i].Namespace+"/"+retinaEndpoints.Items[i].Name] = struct{}{}
+		}
+	}
+	var services corev1.ServiceList
+	if err := c.List(ctx, &services); err != nil {
+		return keys, fmt.Errorf("failed to list services: %w", err)
+	}
+	for i := range services.Items {
+		keys.Services[services.Items[i].Namespace+"/"+services.Items[i].Name] = struct{}{}
+	}
+	var nodes corev1.NodeList
+	if err := c.List(ctx, &nodes); err != nil {
+		return keys, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for i := range nodes.Items {
+		keys.Nodes[nodes.Items[i].Name] = struct{}{}
+	}
+	return keys, nil
+}
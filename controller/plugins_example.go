@@ -0,0 +1,10 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+//go:build example_plugin
+
+package main
+
+// Links the example plugin of the plugin SDK into the agent.
+// Out-of-tree plugins are linked the same way, from a file guarded by their own build tag.
+import _ "github.com/microsoft/retina/pkg/plugin/sdk/example"
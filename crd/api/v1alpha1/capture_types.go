@@ -18,6 +18,103 @@ const (
 	CaptureError CaptureConditionType = "error"
 )
 
+// CaptureBackend is the implementation capturing network packets on Linux nodes.
+type CaptureBackend string
+
+const (
+	// CaptureBackendTcpdump captures network packets by running tcpdump in the capture job.
+	CaptureBackendTcpdump CaptureBackend = "tcpdump"
+	// CaptureBackendNative captures network packets with AF_PACKET sockets and writes them in pcapng format.
+	CaptureBackendNative CaptureBackend = "native"
+)
+
+// CaptureNodePhase is the phase of the capture on a node.
+type CaptureNodePhase string
+
+const (
+	// CaptureNodePending indicates the capture job is not running on the node yet.
+	CaptureNodePending CaptureNodePhase = "Pending"
+	// CaptureNodeRunning indicates the capture job is capturing network packets on the node.
+	CaptureNodeRunning CaptureNodePhase = "Running"
+	// CaptureNodeStopping indicates the capture job is requested to stop, and stores the captured network packets.
+	CaptureNodeStopping CaptureNodePhase = "Stopping"
+	// CaptureNodeSucceeded indicates the capture job completed on the node.
+	CaptureNodeSucceeded CaptureNodePhase = "Succeeded"
+	// CaptureNodeFailed indicates the capture job failed on the node.
+	CaptureNodeFailed CaptureNodePhase = "Failed"
+)
+
+// CaptureFilterProtocol is the protocol a capture filter rule matches.
+type CaptureFilterProtocol string
+
+const (
+	CaptureFilterProtocolTCP    CaptureFilterProtocol = "TCP"
+	CaptureFilterProtocolUDP    CaptureFilterProtocol = "UDP"
+	CaptureFilterProtocolSCTP   CaptureFilterProtocol = "SCTP"
+	CaptureFilterProtocolICMP   CaptureFilterProtocol = "ICMP"
+	CaptureFilterProtocolICMPv6 CaptureFilterProtocol = "ICMPv6"
+)
+
+// CaptureFilterDirection is the end of the connection the addresses and ports of a capture filter rule match.
+type CaptureFilterDirection string
+
+const (
+	// CaptureFilterDirectionAny matches either the source or the destination of the packets.
+	CaptureFilterDirectionAny CaptureFilterDirection = "Any"
+	// CaptureFilterDirectionSource matches the source of the packets.
+	CaptureFilterDirectionSource CaptureFilterDirection = "Source"
+	// CaptureFilterDirectionDestination matches the destination of the packets.
+	CaptureFilterDirectionDestination CaptureFilterDirection = "Destination"
+)
+
+// TCPFlag is a flag of the TCP header.
+// +kubebuilder:validation:Enum=FIN;SYN;RST;PSH;ACK;URG
+type TCPFlag string
+
+const (
+	TCPFlagFIN TCPFlag = "FIN"
+	TCPFlagSYN TCPFlag = "SYN"
+	TCPFlagRST TCPFlag = "RST"
+	TCPFlagPSH TCPFlag = "PSH"
+	TCPFlagACK TCPFlag = "ACK"
+	TCPFlagURG TCPFlag = "URG"
+)
+
+// CaptureUploadStatus describes the result of storing the capture artifact to an output location.
+type CaptureUploadStatus struct {
+	// Location is the name of the output location, e.g. HostPath or BlobUpload.
+	Location string `json:"location"`
+	// Succeeded indicates whether the capture artifact is stored to the output location.
+	Succeeded bool `json:"succeeded"`
+	// Message is the error storing the capture artifact, if any.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CaptureNodeStatus describes the progress of the capture on a node.
+type CaptureNodeStatus struct {
+	// NodeName is the name of the node.
+	NodeName string `json:"nodeName"`
+	// JobName is the name of the capture job running on the node.
+	JobName string `json:"jobName"`
+	// Phase is the phase of the capture on the node.
+	Phase CaptureNodePhase `json:"phase"`
+	// PacketsCaptured is the number of network packets captured, reported when the capture job finishes and
+	// supported by the capture backend.
+	// +optional
+	PacketsCaptured *int64 `json:"packetsCaptured,omitempty"`
+	// BytesCaptured is the size of the capture files, reported when the capture job finishes.
+	// +optional
+	BytesCaptured *int64 `json:"bytesCaptured,omitempty"`
+	// Uploads are the results of storing the capture artifact to the output locations, reported when the capture job
+	// finishes.
+	// +optional
+	Uploads []CaptureUploadStatus `json:"uploads,omitempty"`
+	// Message is a human readable message about the capture on the node.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 // CaptureStatus describes the status of the capture.
 type CaptureStatus struct {
 	// +optional
@@ -47,11 +144,18 @@ type CaptureStatus struct {
 	// The number of failed jobs.
 	// +optional
 	Failed int32 `json:"failed,omitempty" protobuf:"varint,6,opt,name=failed"`
+
+	// Nodes reports the progress of the capture on each node.
+	// +optional
+	// +listType=map
+	// +listMapKey=nodeName
+	Nodes []CaptureNodeStatus `json:"nodes,omitempty"`
 }
 
 // CaptureOption lists the options of the capture.
 type CaptureOption struct {
 	// Duration indicates length of time that the capture should continue for.
+	// It can be changed while the capture is running to extend or shorten the capture.
 	// +kubebuilder:validation:Type=string
 	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
 	// +optional
@@ -65,19 +169,42 @@ type CaptureOption struct {
 	// +kubebuilder:default=100
 	// +optional
 	MaxCaptureSize *int `json:"maxCaptureSize,omitempty"`
+
+	// Backend selects how network packets are captured on Linux nodes.
+	// The tcpdump backend requires tcpdump in the capture image, while the native backend captures packets
+	// with AF_PACKET sockets and a BPF filter compiled by Retina, and writes them in pcapng format.
+	// The native backend does not support TcpdumpFilter.
+	// +kubebuilder:validation:Enum=tcpdump;native
+	// +kubebuilder:default=tcpdump
+	// +optional
+	Backend CaptureBackend `json:"backend,omitempty"`
+
+	// Interfaces lists the names of the node interfaces to capture network packets on.
+	// It is only supported by the native backend, which by default captures on the host interfaces of the selected
+	// Pods, e.g. their veths, or on all interfaces of the selected nodes.
+	// +optional
+	Interfaces []string `json:"interfaces,omitempty"`
+
+	// PodNetworkNamespace captures network packets inside the network namespace of each selected Pod, on its eth0 and
+	// lo interfaces, or on Interfaces if set, and stores one capture file per Pod. Unlike capturing on the node, this
+	// includes the traffic never leaving the Pod, like loopback traffic between containers, and traffic before NAT.
+	// It requires PodSelector and the native backend, and is not supported on Windows nodes.
+	// +optional
+	PodNetworkNamespace bool `json:"podNetworkNamespace,omitempty"`
 }
 
 // CaptureTarget indicates the target on which the network packets capture will be performed.
 type CaptureTarget struct {
 	// NodeSelector is a selector which select the node to capture network packets.
 	// Selector which must match a node's labels.
-	// NodeSelector is incompatible with NamespaceSelector/PodSelector pair.
+	// NodeSelector is incompatible with NamespaceSelector/PodSelector pair and ServiceSelector.
 	// +optional
 	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
 
 	// NamespaceSelector selects Namespaces using cluster-scoped labels. This field follows
 	// standard label selector semantics.
 	// NamespaceSelector and PodSelector pair selects a pod to capture pod network namespace traffic.
+	// NamespaceSelector and ServiceSelector pair selects the Services whose backing Pods to capture.
 	// +optional
 	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 
@@ -85,6 +212,14 @@ type CaptureTarget struct {
 	// selector semantics.
 	// +optional
 	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// ServiceSelector is a label selector which selects Services, whose backing Pods are resolved from their
+	// EndpointSlices to capture network packets. The ClusterIPs and NodePorts of the Services are captured as well,
+	// so packets are captured both before and after they are translated to the backing Pods, and backing Pods added
+	// during the capture are captured for the rest of it.
+	// ServiceSelector is incompatible with NodeSelector and PodSelector.
+	// +optional
+	ServiceSelector *metav1.LabelSelector `json:"serviceSelector,omitempty"`
 }
 
 // CaptureConfiguration indicates the configurations of the network capture.
@@ -125,6 +260,61 @@ type CaptureConfigurationFilters struct {
 	// See Include for detailed explanation.
 	// +optional
 	Exclude []string `json:"exclude,omitempty"`
+
+	// IncludeRules are typed filter rules of the packets included in the capture, in addition to Include.
+	// They are translated together with Include and Exclude into a logic like:
+	// (include1 or includeRule1) and not (exclude1 or excludeRule1)
+	// +optional
+	IncludeRules []CaptureFilterRule `json:"includeRules,omitempty"`
+	// ExcludeRules are typed filter rules of the packets excluded from the capture, in addition to Exclude.
+	// +optional
+	ExcludeRules []CaptureFilterRule `json:"excludeRules,omitempty"`
+}
+
+// CaptureFilterRule matches the packets matching all of its fields, and each field matches any of its values.
+// At least one field must be set. On Windows nodes, only a single include rule with Protocol and addresses is
+// supported, as the netsh filter has no ports, TCP flags, networks or alternatives.
+type CaptureFilterRule struct {
+	// Protocol is the protocol of the packets.
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP;ICMP;ICMPv6
+	// +optional
+	Protocol CaptureFilterProtocol `json:"protocol,omitempty"`
+
+	// CIDRs are the IP addresses or networks in CIDR notation of the packets, e.g. 10.0.0.1 or 10.0.0.0/16.
+	// +optional
+	CIDRs []string `json:"cidrs,omitempty"`
+
+	// Pods are the Pods whose IP addresses are matched, resolved when the capture jobs are created.
+	// +optional
+	Pods []CaptureFilterObjectReference `json:"pods,omitempty"`
+
+	// Services are the Services whose cluster IP addresses and endpoint addresses are matched, resolved when the
+	// capture jobs are created.
+	// +optional
+	Services []CaptureFilterObjectReference `json:"services,omitempty"`
+
+	// Ports are the ports, e.g. 80, or port ranges, e.g. 8000-8080, of TCP, UDP or SCTP packets.
+	// +optional
+	Ports []string `json:"ports,omitempty"`
+
+	// TCPFlags matches TCP packets over IPv4 with any of the flags set. It requires the TCP protocol.
+	// +optional
+	TCPFlags []TCPFlag `json:"tcpFlags,omitempty"`
+
+	// Direction is the end of the packets the addresses and ports are matched against.
+	// +kubebuilder:validation:Enum=Any;Source;Destination
+	// +kubebuilder:default=Any
+	// +optional
+	Direction CaptureFilterDirection `json:"direction,omitempty"`
+}
+
+// CaptureFilterObjectReference references a Pod or Service of a capture filter rule.
+type CaptureFilterObjectReference struct {
+	// Namespace is the namespace of the object, which defaults to the namespace of the Capture.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the object.
+	Name string `json:"name"`
 }
 
 // OutputConfiguration indicates the location capture will be stored.
@@ -168,6 +358,17 @@ type CaptureSpec struct {
 	CaptureConfiguration CaptureConfiguration `json:"captureConfiguration"`
 	// +kubebuilder:validation:Required
 	OutputConfiguration OutputConfiguration `json:"outputConfiguration,omitempty"`
+	// Stop gracefully stops the running capture jobs, which stop capturing network packets and store the captured
+	// network packets to the output locations. A stopped capture cannot be resumed.
+	// +optional
+	Stop bool `json:"stop,omitempty"`
+	// TTL is the time to keep the Capture after it finishes. Once it expires, the capture artifacts stored to the
+	// output locations are deleted, then the Capture and its jobs. Defaults to the captureTTL of the operator, and
+	// the Capture is kept forever if neither is set.
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Pattern="^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,82 @@
+/*
+Copyright (c) Microsoft Corporation.
+Licensed under the MIT license.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CaptureOutputLocation is the name of an output location of the Captures.
+// +kubebuilder:validation:Enum=HostPath;PersistentVolumeClaim;BlobUpload;S3Upload
+type CaptureOutputLocation string
+
+const (
+	CaptureOutputLocationHostPath              CaptureOutputLocation = "HostPath"
+	CaptureOutputLocationPersistentVolumeClaim CaptureOutputLocation = "PersistentVolumeClaim"
+	CaptureOutputLocationBlobUpload            CaptureOutputLocation = "BlobUpload"
+	CaptureOutputLocationS3Upload              CaptureOutputLocation = "S3Upload"
+)
+
+// CapturePolicyTargets indicates the targets the Captures are allowed to capture.
+type CapturePolicyTargets struct {
+	// AllowNodes allows the Captures to select nodes by NodeSelector, which captures the network traffic of all the
+	// Pods on the nodes.
+	// +optional
+	AllowNodes bool `json:"allowNodes,omitempty"`
+	// NamespaceSelector selects the namespaces of the Pods the Captures are allowed to capture, including the Pods
+	// backing the selected Services. When it is nil, the Captures are only allowed to capture the Pods in their own
+	// namespace.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PodSelector selects the Pods in the allowed namespaces the Captures are allowed to capture. When it is nil, all
+	// the Pods in the allowed namespaces are allowed.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// CapturePolicySpec indicates the specification of CapturePolicy.
+type CapturePolicySpec struct {
+	// NamespaceSelector selects the namespaces of the Captures the policy applies to. An empty or nil selector selects
+	// all namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Targets are the targets the Captures are allowed to capture.
+	// +optional
+	Targets CapturePolicyTargets `json:"targets,omitempty"`
+	// AllowedOutputLocations are the output locations the Captures are allowed to store the capture artifacts to.
+	// When it is empty, all output locations are allowed.
+	// +listType=set
+	// +optional
+	AllowedOutputLocations []CaptureOutputLocation `json:"allowedOutputLocations,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories={retina},scope=Cluster
+
+// CapturePolicy restricts the targets and the output locations of the Captures in the namespaces it selects.
+// Captures in namespaces selected by no CapturePolicy are not restricted, otherwise a Capture must be allowed by at
+// least one of the CapturePolicies selecting its namespace.
+type CapturePolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// +optional
+	Spec CapturePolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CapturePolicyList contains a list of CapturePolicy.
+type CapturePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CapturePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CapturePolicy{}, &CapturePolicyList{})
+}
@@ -55,6 +55,25 @@ type MetricsNamespaces struct {
 type MetricsSpec struct {
 	ContextOptions []MetricsContextOptions `json:"contextOptions"`
 	Namespaces     MetricsNamespaces       `json:"namespaces"`
+	// NamespaceSelector selects by their labels the namespaces to collect metrics for, in addition to the
+	// namespaces included by Namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// NodeSelector selects by their labels the nodes to collect metrics on. Metrics are collected on all nodes
+	// when it is not set.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+}
+
+// MetricsConflict indicates a metric of a MetricsConfiguration which is not collected because another
+// MetricsConfiguration collects it with different labels.
+type MetricsConflict struct {
+	// MetricName is the name of the metric in conflict.
+	MetricName string `json:"metricName"`
+	// ConfigurationName is the name of the MetricsConfiguration the metric is collected as configured by.
+	ConfigurationName string `json:"configurationName"`
+	// Reason is why the metric is in conflict.
+	Reason string `json:"reason"`
 }
 
 type MetricsStatus struct {
@@ -63,13 +82,19 @@ type MetricsStatus struct {
 	State         string       `json:"state"`
 	Reason        string       `json:"reason"`
 	LastKnownSpec *MetricsSpec `json:"lastKnownSpec,omitempty"`
+	// Conflicts are the metrics not collected as configured by this MetricsConfiguration, because older
+	// MetricsConfigurations collect them with different labels.
+	// +optional
+	Conflicts []MetricsConflict `json:"conflicts,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:categories={retina},scope=Cluster
 // +kubebuilder:subresource:status
 
-// MetricsConfiguration contains the specification for the retina plugin metrics
+// MetricsConfiguration contains the specification for the retina plugin metrics.
+// Several MetricsConfigurations can coexist, each scoped to namespaces and nodes, and the metrics they configure
+// are collected together.
 type MetricsConfiguration struct {
 	metav1.TypeMeta `json:",inline"`
 	// +optional
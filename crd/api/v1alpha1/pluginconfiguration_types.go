@@ -0,0 +1,84 @@
+/*
+Copyright (c) Microsoft Corporation.
+Licensed under the MIT license.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PluginConfigurationSpec indicates the specification of PluginConfiguration.
+type PluginConfigurationSpec struct {
+	// EnabledPlugins are the plugins enabled on the node, in place of the plugins enabled by the configuration of
+	// the agent.
+	// +listType=set
+	EnabledPlugins []string `json:"enabledPlugins"`
+}
+
+// PluginStatus indicates the status of a plugin running on the node.
+type PluginStatus struct {
+	// Name is the name of the plugin.
+	Name string `json:"name"`
+	// State is the state of the plugin, one of starting, running, degraded or failed.
+	State string `json:"state"`
+	// Restarts is the number of times the plugin was restarted after it failed.
+	// +optional
+	Restarts int `json:"restarts,omitempty"`
+	// LastError is the last error the plugin failed with.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// PluginConfigurationStatus indicates the status of PluginConfiguration.
+type PluginConfigurationStatus struct {
+	// State tells if the plugins of the specification are enabled on the node.
+	// +kubebuilder:validation:Enum=Initialized;Accepted;Errored;Warning
+	// +optional
+	State string `json:"state,omitempty"`
+	// Reason is why the plugins of the specification are not enabled on the node.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// ObservedGeneration is the generation of the specification the state is about.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Plugins are the status of the plugins running on the node.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Plugins []PluginStatus `json:"plugins,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories={retina},scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Plugins",type=string,JSONPath=`.spec.enabledPlugins`
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// PluginConfiguration enables plugins at runtime on the node it is named after, without restarting the agent.
+// Deleting it restores the plugins enabled by the configuration of the agent.
+type PluginConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec PluginConfigurationSpec `json:"spec"`
+	// +optional
+	Status PluginConfigurationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PluginConfigurationList contains a list of PluginConfiguration.
+type PluginConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PluginConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PluginConfiguration{}, &PluginConfigurationList{})
+}
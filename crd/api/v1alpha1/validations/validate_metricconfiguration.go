@@ -7,9 +7,12 @@ package validations
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/microsoft/retina/crd/api/v1alpha1"
 	"github.com/microsoft/retina/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // MetricsConfiguration validates the metrics configuration
@@ -42,9 +45,24 @@ func MetricsSpec(metricsSpec v1alpha1.MetricsSpec) error {
 		}
 	}
 
-	err := MetricsNamespaces(metricsSpec.Namespaces)
-	if err != nil {
-		return err
+	if metricsSpec.NamespaceSelector != nil {
+		if metricsSpec.Namespaces.Exclude != nil {
+			return fmt.Errorf("metrics namespace selector and namespaces exclude are both not nil")
+		}
+		if _, err := metav1.LabelSelectorAsSelector(metricsSpec.NamespaceSelector); err != nil {
+			return fmt.Errorf("metrics namespace selector is invalid: %w", err)
+		}
+	} else {
+		err := MetricsNamespaces(metricsSpec.Namespaces)
+		if err != nil {
+			return err
+		}
+	}
+
+	if metricsSpec.NodeSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(metricsSpec.NodeSelector); err != nil {
+			return fmt.Errorf("metrics node selector is invalid: %w", err)
+		}
 	}
 
 	return nil
@@ -63,6 +81,52 @@ func MetricsNamespaces(mn v1alpha1.MetricsNamespaces) error {
 	return nil
 }
 
+// SortMetricsConfigurations sorts the metrics configurations from the oldest to the newest, by name when they
+// were created at the same time. An older metrics configuration takes precedence over a newer one when they
+// configure the same metric with different labels.
+func SortMetricsConfigurations(metricsConfigs []*v1alpha1.MetricsConfiguration) {
+	sort.SliceStable(metricsConfigs, func(i, j int) bool {
+		ti, tj := metricsConfigs[i].CreationTimestamp, metricsConfigs[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return metricsConfigs[i].Name < metricsConfigs[j].Name
+	})
+}
+
+// MetricsConflicts returns the conflicts of the metrics configurations by name. A metric configured by several
+// metrics configurations with different labels is collected as configured by the oldest one, and is in conflict
+// in the others.
+func MetricsConflicts(metricsConfigs []*v1alpha1.MetricsConfiguration) map[string][]v1alpha1.MetricsConflict {
+	sorted := make([]*v1alpha1.MetricsConfiguration, len(metricsConfigs))
+	copy(sorted, metricsConfigs)
+	SortMetricsConfigurations(sorted)
+
+	type owner struct {
+		configName    string
+		contextOption v1alpha1.MetricsContextOptions
+	}
+	owners := make(map[string]owner)
+	conflicts := make(map[string][]v1alpha1.MetricsConflict)
+	for _, metricsConfig := range sorted {
+		for _, contextOption := range metricsConfig.Spec.ContextOptions {
+			o, ok := owners[contextOption.MetricName]
+			if !ok {
+				owners[contextOption.MetricName] = owner{configName: metricsConfig.Name, contextOption: contextOption}
+				continue
+			}
+			if !MetricsContextOptionsCompare([]v1alpha1.MetricsContextOptions{o.contextOption}, []v1alpha1.MetricsContextOptions{contextOption}) {
+				conflicts[metricsConfig.Name] = append(conflicts[metricsConfig.Name], v1alpha1.MetricsConflict{
+					MetricName:        contextOption.MetricName,
+					ConfigurationName: o.configName,
+					Reason:            fmt.Sprintf("metric %s is collected with different labels as configured by %s", contextOption.MetricName, o.configName),
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
 // CompareMetricsConfig compares two metrics configurations
 func CompareMetricsConfig(old, new *v1alpha1.MetricsConfiguration) bool {
 	if old == nil && new == nil {
@@ -98,6 +162,14 @@ func MetricsSpecCompare(old, new v1alpha1.MetricsSpec) bool {
 		return false
 	}
 
+	if !equality.Semantic.DeepEqual(old.NamespaceSelector, new.NamespaceSelector) {
+		return false
+	}
+
+	if !equality.Semantic.DeepEqual(old.NodeSelector, new.NodeSelector) {
+		return false
+	}
+
 	return true
 }
 
@@ -7,6 +7,7 @@ package validations
 
 import (
 	"testing"
+	"time"
 
 	"github.com/microsoft/retina/crd/api/v1alpha1"
 	"gotest.tools/assert"
@@ -117,6 +118,74 @@ func TestMetricsConfiguration(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid metrics crd with namespace and node selectors",
+			obj: &v1alpha1.MetricsConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "metricsconfig",
+				},
+				Spec: v1alpha1.MetricsSpec{
+					ContextOptions: []v1alpha1.MetricsContextOptions{
+						{
+							MetricName: "drop_count",
+						},
+					},
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "app"},
+					},
+					NodeSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"kubernetes.io/os": "linux"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid metrics crd with namespace selector and exclude",
+			obj: &v1alpha1.MetricsConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "metricsconfig",
+				},
+				Spec: v1alpha1.MetricsSpec{
+					ContextOptions: []v1alpha1.MetricsContextOptions{
+						{
+							MetricName: "drop_count",
+						},
+					},
+					Namespaces: v1alpha1.MetricsNamespaces{
+						Exclude: []string{"kube-system"},
+					},
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "app"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid metrics crd with invalid node selector",
+			obj: &v1alpha1.MetricsConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "metricsconfig",
+				},
+				Spec: v1alpha1.MetricsSpec{
+					ContextOptions: []v1alpha1.MetricsContextOptions{
+						{
+							MetricName: "drop_count",
+						},
+					},
+					Namespaces: v1alpha1.MetricsNamespaces{
+						Include: []string{"default"},
+					},
+					NodeSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "kubernetes.io/os", Operator: "Unknown"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -357,3 +426,46 @@ func TestCompare(t *testing.T) {
 		})
 	}
 }
+
+// TestMetricsConflicts tests the conflicts of metrics configurations configuring the same metrics
+func TestMetricsConflicts(t *testing.T) {
+	now := time.Now()
+	newMetricsConfig := func(name string, created time.Time, contextOptions ...v1alpha1.MetricsContextOptions) *v1alpha1.MetricsConfiguration {
+		return &v1alpha1.MetricsConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: v1alpha1.MetricsSpec{
+				ContextOptions: contextOptions,
+			},
+		}
+	}
+
+	platform := newMetricsConfig("platform", now.Add(-time.Hour),
+		v1alpha1.MetricsContextOptions{MetricName: "drop_count", SourceLabels: []string{"ip"}},
+		v1alpha1.MetricsContextOptions{MetricName: "forward_count", SourceLabels: []string{"ip", "podname"}},
+	)
+	app := newMetricsConfig("app", now,
+		v1alpha1.MetricsContextOptions{MetricName: "drop_count", SourceLabels: []string{"podname"}},
+		v1alpha1.MetricsContextOptions{MetricName: "forward_count", SourceLabels: []string{"podname", "ip"}},
+		v1alpha1.MetricsContextOptions{MetricName: "dns", SourceLabels: []string{"podname"}},
+	)
+
+	conflicts := MetricsConflicts([]*v1alpha1.MetricsConfiguration{app, platform})
+	assert.Equal(t, 0, len(conflicts["platform"]))
+	assert.DeepEqual(t, []v1alpha1.MetricsConflict{
+		{
+			MetricName:        "drop_count",
+			ConfigurationName: "platform",
+			Reason:            "metric drop_count is collected with different labels as configured by platform",
+		},
+	}, conflicts["app"])
+
+	// The name breaks the tie between metrics configurations created at the same time.
+	app.CreationTimestamp = platform.CreationTimestamp
+	conflicts = MetricsConflicts([]*v1alpha1.MetricsConfiguration{platform, app})
+	assert.Equal(t, 0, len(conflicts["app"]))
+	assert.Equal(t, 1, len(conflicts["platform"]))
+	assert.Equal(t, "app", conflicts["platform"][0].ConfigurationName)
+}
@@ -76,6 +76,20 @@ func (in *CaptureConfigurationFilters) DeepCopyInto(out *CaptureConfigurationFil
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IncludeRules != nil {
+		in, out := &in.IncludeRules, &out.IncludeRules
+		*out = make([]CaptureFilterRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludeRules != nil {
+		in, out := &in.ExcludeRules, &out.ExcludeRules
+		*out = make([]CaptureFilterRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureConfigurationFilters.
@@ -88,6 +102,61 @@ func (in *CaptureConfigurationFilters) DeepCopy() *CaptureConfigurationFilters {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CaptureFilterObjectReference) DeepCopyInto(out *CaptureFilterObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureFilterObjectReference.
+func (in *CaptureFilterObjectReference) DeepCopy() *CaptureFilterObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CaptureFilterObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CaptureFilterRule) DeepCopyInto(out *CaptureFilterRule) {
+	*out = *in
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]CaptureFilterObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]CaptureFilterObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TCPFlags != nil {
+		in, out := &in.TCPFlags, &out.TCPFlags
+		*out = make([]TCPFlag, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureFilterRule.
+func (in *CaptureFilterRule) DeepCopy() *CaptureFilterRule {
+	if in == nil {
+		return nil
+	}
+	out := new(CaptureFilterRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CaptureList) DeepCopyInto(out *CaptureList) {
 	*out = *in
@@ -120,6 +189,36 @@ func (in *CaptureList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CaptureNodeStatus) DeepCopyInto(out *CaptureNodeStatus) {
+	*out = *in
+	if in.PacketsCaptured != nil {
+		in, out := &in.PacketsCaptured, &out.PacketsCaptured
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BytesCaptured != nil {
+		in, out := &in.BytesCaptured, &out.BytesCaptured
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Uploads != nil {
+		in, out := &in.Uploads, &out.Uploads
+		*out = make([]CaptureUploadStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureNodeStatus.
+func (in *CaptureNodeStatus) DeepCopy() *CaptureNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CaptureNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CaptureOption) DeepCopyInto(out *CaptureOption) {
 	*out = *in
@@ -138,6 +237,11 @@ func (in *CaptureOption) DeepCopyInto(out *CaptureOption) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.Interfaces != nil {
+		in, out := &in.Interfaces, &out.Interfaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureOption.
@@ -150,11 +254,125 @@ func (in *CaptureOption) DeepCopy() *CaptureOption {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapturePolicy) DeepCopyInto(out *CapturePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapturePolicy.
+func (in *CapturePolicy) DeepCopy() *CapturePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CapturePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CapturePolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapturePolicyList) DeepCopyInto(out *CapturePolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CapturePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapturePolicyList.
+func (in *CapturePolicyList) DeepCopy() *CapturePolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(CapturePolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CapturePolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapturePolicySpec) DeepCopyInto(out *CapturePolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Targets.DeepCopyInto(&out.Targets)
+	if in.AllowedOutputLocations != nil {
+		in, out := &in.AllowedOutputLocations, &out.AllowedOutputLocations
+		*out = make([]CaptureOutputLocation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapturePolicySpec.
+func (in *CapturePolicySpec) DeepCopy() *CapturePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CapturePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapturePolicyTargets) DeepCopyInto(out *CapturePolicyTargets) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapturePolicyTargets.
+func (in *CapturePolicyTargets) DeepCopy() *CapturePolicyTargets {
+	if in == nil {
+		return nil
+	}
+	out := new(CapturePolicyTargets)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CaptureSpec) DeepCopyInto(out *CaptureSpec) {
 	*out = *in
 	in.CaptureConfiguration.DeepCopyInto(&out.CaptureConfiguration)
 	in.OutputConfiguration.DeepCopyInto(&out.OutputConfiguration)
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureSpec.
@@ -185,6 +403,13 @@ func (in *CaptureStatus) DeepCopyInto(out *CaptureStatus) {
 		in, out := &in.CompletionTime, &out.CompletionTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]CaptureNodeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureStatus.
@@ -215,6 +440,11 @@ func (in *CaptureTarget) DeepCopyInto(out *CaptureTarget) {
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ServiceSelector != nil {
+		in, out := &in.ServiceSelector, &out.ServiceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureTarget.
@@ -227,6 +457,21 @@ func (in *CaptureTarget) DeepCopy() *CaptureTarget {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CaptureUploadStatus) DeepCopyInto(out *CaptureUploadStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CaptureUploadStatus.
+func (in *CaptureUploadStatus) DeepCopy() *CaptureUploadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CaptureUploadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Containers) DeepCopyInto(out *Containers) {
 	*out = *in
@@ -321,6 +566,21 @@ func (in *MetricsConfigurationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsConflict) DeepCopyInto(out *MetricsConflict) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsConflict.
+func (in *MetricsConflict) DeepCopy() *MetricsConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsContextOptions) DeepCopyInto(out *MetricsContextOptions) {
 	*out = *in
@@ -387,6 +647,16 @@ func (in *MetricsSpec) DeepCopyInto(out *MetricsSpec) {
 		}
 	}
 	in.Namespaces.DeepCopyInto(&out.Namespaces)
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsSpec.
@@ -407,6 +677,11 @@ func (in *MetricsStatus) DeepCopyInto(out *MetricsStatus) {
 		*out = new(MetricsSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]MetricsConflict, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsStatus.
@@ -469,6 +744,120 @@ func (in *OwnerReference) DeepCopy() *OwnerReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginConfiguration) DeepCopyInto(out *PluginConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginConfiguration.
+func (in *PluginConfiguration) DeepCopy() *PluginConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PluginConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginConfigurationList) DeepCopyInto(out *PluginConfigurationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PluginConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginConfigurationList.
+func (in *PluginConfigurationList) DeepCopy() *PluginConfigurationList {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginConfigurationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PluginConfigurationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginConfigurationSpec) DeepCopyInto(out *PluginConfigurationSpec) {
+	*out = *in
+	if in.EnabledPlugins != nil {
+		in, out := &in.EnabledPlugins, &out.EnabledPlugins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginConfigurationSpec.
+func (in *PluginConfigurationSpec) DeepCopy() *PluginConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginConfigurationStatus) DeepCopyInto(out *PluginConfigurationStatus) {
+	*out = *in
+	if in.Plugins != nil {
+		in, out := &in.Plugins, &out.Plugins
+		*out = make([]PluginStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginConfigurationStatus.
+func (in *PluginConfigurationStatus) DeepCopy() *PluginConfigurationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginConfigurationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PluginStatus) DeepCopyInto(out *PluginStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PluginStatus.
+func (in *PluginStatus) DeepCopy() *PluginStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PluginStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RetinaEndpoint) DeepCopyInto(out *RetinaEndpoint) {
 	*out = *in
@@ -18,6 +18,8 @@ const (
 	RetinaCapturesYAMLpath       = "retina.sh_captures.yaml"
 	RetinaEndpointsYAMLpath      = "retina.sh_retinaendpoints.yaml"
 	MetricsConfigurationYAMLpath = "retina.sh_metricsconfigurations.yaml"
+	CapturePoliciesYAMLpath      = "retina.sh_capturepolicies.yaml"
+	PluginConfigurationsYAMLpath = "retina.sh_pluginconfigurations.yaml"
 )
 
 //go:embed manifests/controller/helm/retina/crds/retina.sh_captures.yaml
@@ -29,6 +31,12 @@ var RetinaEndpointsYAML []byte
 //go:embed manifests/controller/helm/retina/crds/retina.sh_metricsconfigurations.yaml
 var MetricsConfgurationYAML []byte
 
+//go:embed manifests/controller/helm/retina/crds/retina.sh_capturepolicies.yaml
+var CapturePoliciesYAML []byte
+
+//go:embed manifests/controller/helm/retina/crds/retina.sh_pluginconfigurations.yaml
+var PluginConfigurationsYAML []byte
+
 func GetRetinaCapturesCRD() (*apiextensionsv1.CustomResourceDefinition, error) {
 	retinaCapturesCRD := &apiextensionsv1.CustomResourceDefinition{}
 	if err := yaml.Unmarshal(RetinaCapturesYAML, &retinaCapturesCRD); err != nil {
@@ -55,8 +63,24 @@ func GetRetinaMetricsConfigurationCRD() (*apiextensionsv1.CustomResourceDefiniti
 	return retinaMetricsConfigurationCRD, nil
 }
 
+func GetCapturePoliciesCRD() (*apiextensionsv1.CustomResourceDefinition, error) {
+	capturePoliciesCRD := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(CapturePoliciesYAML, &capturePoliciesCRD); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling embedded capturepolicies")
+	}
+	return capturePoliciesCRD, nil
+}
+
+func GetPluginConfigurationsCRD() (*apiextensionsv1.CustomResourceDefinition, error) {
+	pluginConfigurationsCRD := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(PluginConfigurationsYAML, &pluginConfigurationsCRD); err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling embedded pluginconfigurations")
+	}
+	return pluginConfigurationsCRD, nil
+}
+
 func InstallOrUpdateCRDs(ctx context.Context, enableRetinaEndpoint bool, apiExtensionsClient apiextv1.ApiextensionsV1Interface) (map[string]*apiextensionsv1.CustomResourceDefinition, error) {
-	crds := make(map[string]*apiextensionsv1.CustomResourceDefinition, 4)
+	crds := make(map[string]*apiextensionsv1.CustomResourceDefinition, 6)
 
 	retinaCapture, err := GetRetinaCapturesCRD()
 	if err != nil {
@@ -64,6 +88,12 @@ func InstallOrUpdateCRDs(ctx context.Context, enableRetinaEndpoint bool, apiExte
 	}
 	crds[retinaCapture.GetObjectMeta().GetName()] = retinaCapture
 
+	capturePolicies, err := GetCapturePoliciesCRD()
+	if err != nil {
+		return nil, err
+	}
+	crds[capturePolicies.GetObjectMeta().GetName()] = capturePolicies
+
 	if enableRetinaEndpoint {
 		retinaEndpoint, err := GetRetinaEndpointCRD()
 		if err != nil {
@@ -78,6 +108,12 @@ func InstallOrUpdateCRDs(ctx context.Context, enableRetinaEndpoint bool, apiExte
 	}
 	crds[retinaMetricsConfiguration.GetObjectMeta().GetName()] = retinaMetricsConfiguration
 
+	pluginConfigurations, err := GetPluginConfigurationsCRD()
+	if err != nil {
+		return nil, err
+	}
+	crds[pluginConfigurations.GetObjectMeta().GetName()] = pluginConfigurations
+
 	for name, crd := range crds {
 		current, err := apiExtensionsClient.CustomResourceDefinitions().Create(ctx, crd, v1.CreateOptions{})
 		if apierrors.IsAlreadyExists(err) {
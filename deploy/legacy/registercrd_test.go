@@ -23,6 +23,8 @@ func TestGetCRD(t *testing.T) {
 	require.FileExists(t, fmt.Sprintf(full, RetinaCapturesYAMLpath))
 	require.FileExists(t, fmt.Sprintf(full, RetinaEndpointsYAMLpath))
 	require.FileExists(t, fmt.Sprintf(full, MetricsConfigurationYAMLpath))
+	require.FileExists(t, fmt.Sprintf(full, CapturePoliciesYAMLpath))
+	require.FileExists(t, fmt.Sprintf(full, PluginConfigurationsYAMLpath))
 
 	capture, err := GetRetinaCapturesCRD()
 	require.NoError(t, err)
@@ -38,12 +40,24 @@ func TestGetCRD(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, metrics)
 	require.NotEmpty(t, metrics.TypeMeta.Kind)
+
+	capturePolicies, err := GetCapturePoliciesCRD()
+	require.NoError(t, err)
+	require.NotNil(t, capturePolicies)
+	require.NotEmpty(t, capturePolicies.TypeMeta.Kind)
+
+	pluginConfigurations, err := GetPluginConfigurationsCRD()
+	require.NoError(t, err)
+	require.NotNil(t, pluginConfigurations)
+	require.NotEmpty(t, pluginConfigurations.TypeMeta.Kind)
 }
 
 func TestInstallOrUpdateCRDs(t *testing.T) {
 	capture, _ := GetRetinaCapturesCRD()
 	endpoint, _ := GetRetinaEndpointCRD()
 	metrics, _ := GetRetinaMetricsConfigurationCRD()
+	capturePolicies, _ := GetCapturePoliciesCRD()
+	pluginConfigurations, _ := GetPluginConfigurationsCRD()
 
 	tests := []struct {
 		name                 string
@@ -56,8 +70,10 @@ func TestInstallOrUpdateCRDs(t *testing.T) {
 			enableRetinaEndpoint: true,
 			want: map[string]*apiextensionsv1.CustomResourceDefinition{
 				"captures.retina.sh":              capture,
+				"capturepolicies.retina.sh":       capturePolicies,
 				"retinaendpoints.retina.sh":       endpoint,
 				"metricsconfigurations.retina.sh": metrics,
+				"pluginconfigurations.retina.sh":  pluginConfigurations,
 			},
 		},
 		{
@@ -65,7 +81,9 @@ func TestInstallOrUpdateCRDs(t *testing.T) {
 			enableRetinaEndpoint: false,
 			want: map[string]*apiextensionsv1.CustomResourceDefinition{
 				"captures.retina.sh":              capture,
+				"capturepolicies.retina.sh":       capturePolicies,
 				"metricsconfigurations.retina.sh": metrics,
+				"pluginconfigurations.retina.sh":  pluginConfigurations,
 			},
 		},
 	}
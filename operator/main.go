@@ -190,6 +190,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	if oconfig.EnableCaptureWebhook {
+		if err = captureController.NewCaptureValidator(kubeClient, oconfig.CaptureConfig).SetupWebhookWithManager(mgr); err != nil {
+			mainLogger.Error("Unable to setup retina capture webhook with manager", zap.Error(err))
+			os.Exit(1)
+		}
+	}
+
 	ctrlCtx := ctrl.SetupSignalHandler()
 
 	//+kubebuilder:scaffold:builder
@@ -0,0 +1,154 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package bpf
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cilium/ebpf"
+	plugincommon "github.com/microsoft/retina/pkg/plugin/common"
+	"github.com/microsoft/retina/pkg/plugin/filter"
+	"golang.org/x/sys/unix"
+)
+
+// vmlinuxBTFPath is where the kernel exposes its BTF, which CO-RE programs need.
+const vmlinuxBTFPath = "/sys/kernel/btf/vmlinux"
+
+// Program is an eBPF program loaded in the kernel.
+type Program struct {
+	ID     ebpf.ProgramID `json:"id"`
+	Name   string         `json:"name"`
+	Type   string         `json:"type"`
+	Tag    string         `json:"tag"`
+	MapIDs []ebpf.MapID   `json:"mapIDs,omitempty"`
+}
+
+// Map is an eBPF map created in the kernel.
+type Map struct {
+	ID         ebpf.MapID `json:"id"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	KeySize    uint32     `json:"keySize"`
+	ValueSize  uint32     `json:"valueSize"`
+	MaxEntries uint32     `json:"maxEntries"`
+}
+
+// State is the eBPF state of the node: the kernel, the programs and maps loaded by all the processes of the node, the
+// files pinned to the BPF filesystem and the IPs of the filter map of Retina.
+type State struct {
+	KernelRelease string    `json:"kernelRelease"`
+	BTF           bool      `json:"btf"`
+	Programs      []Program `json:"programs"`
+	Maps          []Map     `json:"maps"`
+	Pinned        []string  `json:"pinned"`
+	FilterMapIPs  []string  `json:"filterMapIPs"`
+	// Errors are the parts of the state which could not be read.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// GetState reads the eBPF state of the node. The parts which cannot be read are reported in the Errors of the State.
+func GetState() *State {
+	s := &State{}
+	fail := func(what string, err error) {
+		s.Errors = append(s.Errors, fmt.Sprintf("failed to read %s: %s", what, err))
+	}
+
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		fail("kernel release", err)
+	} else {
+		s.KernelRelease = unix.ByteSliceToString(uname.Release[:])
+	}
+	_, err := os.Stat(vmlinuxBTFPath)
+	s.BTF = err == nil
+
+	// Listing the programs and maps ends with ErrNotExist, which is also returned for the ones unloaded since listed.
+	var id ebpf.ProgramID
+	for id, err = ebpf.ProgramGetNextID(0); err == nil; id, err = ebpf.ProgramGetNextID(id) {
+		prog, progErr := programState(id)
+		if progErr != nil {
+			if !errors.Is(progErr, os.ErrNotExist) {
+				fail(fmt.Sprintf("program %d", id), progErr)
+			}
+			continue
+		}
+		s.Programs = append(s.Programs, prog)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		fail("programs", err)
+	}
+	var mapID ebpf.MapID
+	for mapID, err = ebpf.MapGetNextID(0); err == nil; mapID, err = ebpf.MapGetNextID(mapID) {
+		m, mapErr := mapState(mapID)
+		if mapErr != nil {
+			if !errors.Is(mapErr, os.ErrNotExist) {
+				fail(fmt.Sprintf("map %d", mapID), mapErr)
+			}
+			continue
+		}
+		s.Maps = append(s.Maps, m)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		fail("maps", err)
+	}
+
+	if err := filepath.WalkDir(plugincommon.FilterMapPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			s.Pinned = append(s.Pinned, path)
+		}
+		return nil
+	}); err != nil {
+		fail("pinned files", err)
+	}
+
+	ips, err := filter.PinnedIPs()
+	if err != nil {
+		fail("filter map", err)
+	}
+	for _, ip := range ips {
+		s.FilterMapIPs = append(s.FilterMapIPs, ip.String())
+	}
+	return s
+}
+
+func programState(id ebpf.ProgramID) (Program, error) {
+	prog, err := ebpf.NewProgramFromID(id)
+	if err != nil {
+		return Program{}, err
+	}
+	defer prog.Close()
+	info, err := prog.Info()
+	if err != nil {
+		return Program{}, err
+	}
+	mapIDs, _ := info.MapIDs()
+	return Program{ID: id, Name: info.Name, Type: info.Type.String(), Tag: info.Tag, MapIDs: mapIDs}, nil
+}
+
+func mapState(id ebpf.MapID) (Map, error) {
+	m, err := ebpf.NewMapFromID(id)
+	if err != nil {
+		return Map{}, err
+	}
+	defer m.Close()
+	info, err := m.Info()
+	if err != nil {
+		return Map{}, err
+	}
+	return Map{
+		ID:         id,
+		Name:       info.Name,
+		Type:       info.Type.String(),
+		KeySize:    info.KeySize,
+		ValueSize:  info.ValueSize,
+		MaxEntries: info.MaxEntries,
+	}, nil
+}
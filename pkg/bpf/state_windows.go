@@ -0,0 +1,11 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package bpf
+
+// State is the eBPF state of the node, which Windows nodes do not have.
+type State struct{}
+
+func GetState() *State {
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// CaptureArtifactExtension is the extension of the tarball storing the capture files of a node.
+const CaptureArtifactExtension = ".tar.gz"
+
+// Commands of the capture workload reading the capture artifacts stored on a HostPath or PersistentVolumeClaim, which
+// the Retina CLI runs in a helper Pod as the capture image has no shell or archive tools.
+const (
+	// ArtifactsCommand is the first argument of the capture workload running the artifacts commands.
+	ArtifactsCommand = "artifacts"
+
+	// ArtifactsWaitCommand keeps the helper Pod running until it is deleted.
+	ArtifactsWaitCommand = "wait"
+	// ArtifactsListCommand writes the name and size of each capture artifact of a Capture in a directory, one per
+	// line separated by a tab.
+	ArtifactsListCommand = "list"
+	// ArtifactsCopyCommand writes the given capture artifacts in a directory to stdout as a tar stream.
+	ArtifactsCopyCommand = "copy"
+)
+
+var errInvalidArtifactsCommand = errors.New("invalid artifacts command")
+
+// IsCaptureArtifact returns whether the file is a capture artifact of the Capture, which is named after the Capture,
+// the node and the time the capture started.
+func IsCaptureArtifact(captureName, fileName string) bool {
+	return strings.HasPrefix(fileName, captureName+"-") && strings.HasSuffix(fileName, CaptureArtifactExtension)
+}
+
+// RunArtifactsCommand runs the artifacts command of the capture workload with the arguments following ArtifactsCommand:
+//
+//	wait
+//	list <directory> <capture name>
+//	copy <directory> <artifact name>...
+func RunArtifactsCommand(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errInvalidArtifactsCommand
+	}
+
+	switch {
+	case args[0] == ArtifactsWaitCommand && len(args) == 1:
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
+		<-sigChan
+		return nil
+	case args[0] == ArtifactsListCommand && len(args) == 3:
+		return listCaptureArtifacts(stdout, args[1], args[2])
+	case args[0] == ArtifactsCopyCommand && len(args) >= 2:
+		return copyCaptureArtifacts(stdout, args[1], args[2:])
+	default:
+		return fmt.Errorf("%w: %s", errInvalidArtifactsCommand, strings.Join(args, " "))
+	}
+}
+
+func listCaptureArtifacts(w io.Writer, dir, captureName string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err //nolint:wrapcheck // the path is in the error
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !IsCaptureArtifact(captureName, entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // the path is in the error
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%d\n", entry.Name(), info.Size()); err != nil {
+			return fmt.Errorf("failed to write capture artifact: %w", err)
+		}
+	}
+	return nil
+}
+
+func copyCaptureArtifacts(w io.Writer, dir string, names []string) error {
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		// Only the files of the directory are copied.
+		if filepath.Base(name) != name {
+			return fmt.Errorf("%w: invalid artifact name %s", errInvalidArtifactsCommand, name)
+		}
+		if err := copyCaptureArtifact(tw, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return tw.Close() //nolint:wrapcheck // the tar stream is the only output
+}
+
+func copyCaptureArtifact(tw *tar.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err //nolint:wrapcheck // the path is in the error
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err //nolint:wrapcheck // the path is in the error
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("failed to create tar header of %s: %w", path, err)
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write tar header of %s: %w", path, err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", path, err)
+	}
+	return nil
+}
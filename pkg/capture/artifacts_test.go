@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunArtifactsCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"capture-node1-20240101000000UTC.tar.gz":   "node1",
+		"capture-node2-20240101000000UTC.tar.gz":   "node2 artifact",
+		"capture-2-node1-20240101000000UTC.tar.gz": "",
+		"other-node1-20240101000000UTC.tar.gz":     "other",
+		"capture-node1-20240101000000UTC.pcap":     "pcap",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	if err := RunArtifactsCommand([]string{ArtifactsListCommand, dir, "capture"}, &stdout); err != nil {
+		t.Fatalf("RunArtifactsCommand(list) want no error, got %s", err)
+	}
+	// The artifacts of a Capture whose name starts with the name of another Capture can't be told apart.
+	wantList := "capture-2-node1-20240101000000UTC.tar.gz\t0\n" +
+		"capture-node1-20240101000000UTC.tar.gz\t5\n" +
+		"capture-node2-20240101000000UTC.tar.gz\t14\n"
+	if diff := cmp.Diff(wantList, stdout.String()); diff != "" {
+		t.Errorf("RunArtifactsCommand(list) mismatch (-want, +got):\n%s", diff)
+	}
+
+	stdout.Reset()
+	names := []string{"capture-node1-20240101000000UTC.tar.gz", "capture-node2-20240101000000UTC.tar.gz"}
+	if err := RunArtifactsCommand(append([]string{ArtifactsCopyCommand, dir}, names...), &stdout); err != nil {
+		t.Fatalf("RunArtifactsCommand(copy) want no error, got %s", err)
+	}
+	got := map[string]string{}
+	tr := tar.NewReader(&stdout)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[header.Name] = string(content)
+	}
+	wantCopy := map[string]string{
+		"capture-node1-20240101000000UTC.tar.gz": "node1",
+		"capture-node2-20240101000000UTC.tar.gz": "node2 artifact",
+	}
+	if diff := cmp.Diff(wantCopy, got); diff != "" {
+		t.Errorf("RunArtifactsCommand(copy) mismatch (-want, +got):\n%s", diff)
+	}
+
+	if err := RunArtifactsCommand([]string{ArtifactsCopyCommand, dir, "../capture-node1-20240101000000UTC.tar.gz"}, io.Discard); err == nil {
+		t.Error("RunArtifactsCommand(copy) want error for a file outside the directory, got nil")
+	}
+	if err := RunArtifactsCommand([]string{"unknown"}, io.Discard); err == nil {
+		t.Error("RunArtifactsCommand(unknown) want error, got nil")
+	}
+}
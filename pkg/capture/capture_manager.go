@@ -4,10 +4,8 @@
 package capture
 
 import (
-	"archive/tar"
-	"compress/gzip"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -16,29 +14,105 @@ import (
 
 	"go.uber.org/zap"
 
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
 	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
 	captureOutput "github.com/microsoft/retina/pkg/capture/outputlocation"
 	captureProvider "github.com/microsoft/retina/pkg/capture/provider"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/telemetry"
+	"github.com/microsoft/retina/pkg/utils"
 )
 
+// captureControlInterval is the interval the capture control is read at while capturing network packets.
+var captureControlInterval = 5 * time.Second
+
+// captureStopSignal is sent to the network capture provider to stop a capture, like an OS signal.
+type captureStopSignal string
+
+func (s captureStopSignal) Signal() {}
+
+func (s captureStopSignal) String() string {
+	return string(s)
+}
+
+// capturedPacketsCounter is implemented by the network capture providers counting the captured network packets.
+type capturedPacketsCounter interface {
+	CapturedPackets() *int64
+}
+
 // CaptureManager captures network packets and metadata into tar ball, then send the tar ball to the location(s)
 // specified by users.
 type CaptureManager struct {
 	l                      *log.ZapLogger
 	networkCaptureProvider captureProvider.NetworkCaptureProviderInterface
 	tel                    telemetry.Telemetry
+
+	result CaptureJobResult
 }
 
 func NewCaptureManager(logger *log.ZapLogger, tel telemetry.Telemetry) *CaptureManager {
 	return &CaptureManager{
 		l:                      logger,
-		networkCaptureProvider: captureProvider.NewNetworkCaptureProvider(logger),
+		networkCaptureProvider: newNetworkCaptureProvider(logger),
 		tel:                    tel,
 	}
 }
 
+// newNetworkCaptureProvider returns the network capture provider of the capture backend set for the capture job.
+func newNetworkCaptureProvider(logger *log.ZapLogger) captureProvider.NetworkCaptureProviderInterface {
+	if backend := os.Getenv(captureConstants.CaptureBackendEnvKey); backend != string(retinav1alpha1.CaptureBackendNative) {
+		return captureProvider.NewNetworkCaptureProvider(logger)
+	}
+	// Raw tcpdump filters are rejected by the operator for the native backend, but may still be set on jobs created
+	// by an older operator.
+	if len(os.Getenv(captureConstants.TcpdumpRawFilterEnvKey)) != 0 {
+		logger.Warn("Raw tcpdump filter is not supported by the native capture backend, falling back to tcpdump")
+		return fallbackNetworkCaptureProvider(logger, errRawFilterNotSupported)
+	}
+	networkCaptureProvider, err := captureProvider.NewNativeNetworkCaptureProvider(logger)
+	if err != nil {
+		logger.Warn("Native capture backend is not available, falling back to the default backend", zap.Error(err))
+		return fallbackNetworkCaptureProvider(logger, err)
+	}
+	return networkCaptureProvider
+}
+
+var errRawFilterNotSupported = errors.New("raw tcpdump filter is not supported by the native capture backend")
+
+// fallbackNetworkCaptureProvider returns the tcpdump network capture provider used when the native backend cannot
+// capture. Captures inside the network namespace of Pods fail instead, as the filter of their job is not scoped to
+// the Pod IPs and tcpdump would capture the traffic of the whole node.
+func fallbackNetworkCaptureProvider(logger *log.ZapLogger, cause error) captureProvider.NetworkCaptureProviderInterface {
+	if podNetworkNamespace, _ := strconv.ParseBool(os.Getenv(captureConstants.CapturePodNetworkNamespaceEnvKey)); podNetworkNamespace {
+		logger.Error("Capture in the Pod network namespace requires the native capture backend", zap.Error(cause))
+		return &unavailableNetworkCaptureProvider{
+			err: fmt.Errorf("capture in the Pod network namespace requires the native capture backend: %w", cause),
+		}
+	}
+	return captureProvider.NewNetworkCaptureProvider(logger)
+}
+
+// unavailableNetworkCaptureProvider fails the capture with the reason no network capture provider can perform it.
+type unavailableNetworkCaptureProvider struct {
+	err error
+}
+
+func (p *unavailableNetworkCaptureProvider) Setup(_, _ string) (string, error) {
+	return "", p.err
+}
+
+func (p *unavailableNetworkCaptureProvider) CaptureNetworkPacket(_ string, _, _ int, _ <-chan os.Signal) error {
+	return p.err
+}
+
+func (p *unavailableNetworkCaptureProvider) CollectMetadata() error {
+	return p.err
+}
+
+func (p *unavailableNetworkCaptureProvider) Cleanup() error {
+	return nil
+}
+
 func (cm *CaptureManager) CaptureNetwork(sigChan <-chan os.Signal) (string, error) {
 	tmpLocation, err := cm.networkCaptureProvider.Setup(cm.captureName(), cm.captureNodeHostName())
 	if err != nil {
@@ -57,10 +131,25 @@ func (cm *CaptureManager) CaptureNetwork(sigChan <-chan os.Signal) (string, erro
 		return "", err
 	}
 
-	if err := cm.networkCaptureProvider.CaptureNetworkPacket(captureFilter, captureDuration, captureMaxSizeMB, sigChan); err != nil {
+	// The capture is stopped by controlCapture instead of the provider, as its duration may change while capturing.
+	stopChan := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go cm.controlCapture(captureControlPath(), time.Duration(captureDuration)*time.Second, sigChan, stopChan, done)
+	err = cm.networkCaptureProvider.CaptureNetworkPacket(captureFilter, 0, captureMaxSizeMB, stopChan)
+	close(done)
+	if err != nil {
 		return "", err
 	}
 
+	if bytesCaptured, err := folderSize(tmpLocation); err != nil {
+		cm.l.Warn("Failed to get the size of the capture files", zap.Error(err))
+	} else {
+		cm.result.BytesCaptured = bytesCaptured
+	}
+	if counter, ok := cm.networkCaptureProvider.(capturedPacketsCounter); ok {
+		cm.result.PacketsCaptured = counter.CapturedPackets()
+	}
+
 	if includeMetadata := cm.includeMetadata(); includeMetadata {
 		if err := cm.networkCaptureProvider.CollectMetadata(); err != nil {
 			return "", err
@@ -79,6 +168,82 @@ func (cm *CaptureManager) CaptureNetwork(sigChan <-chan os.Signal) (string, erro
 	return tmpLocation, nil
 }
 
+// controlCapture stops the capture when its duration elapses, when the capture job is requested to stop through the
+// capture control, or when an OS signal is received. The duration of the capture can be changed through the capture
+// control while the capture is running.
+func (cm *CaptureManager) controlCapture(controlPath string, duration time.Duration, sigChan <-chan os.Signal, stopChan chan<- os.Signal, done <-chan struct{}) {
+	startTime := time.Now()
+	ticker := time.NewTicker(captureControlInterval)
+	defer ticker.Stop()
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	setDuration := func(d time.Duration) {
+		if timer != nil {
+			timer.Stop()
+		}
+		duration = d
+		timer = time.NewTimer(time.Until(startTime.Add(d)))
+		timeout = timer.C
+	}
+	if duration != 0 {
+		cm.l.Info(fmt.Sprintf("Capture will stop after %s", duration))
+		setDuration(duration)
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case sig := <-sigChan:
+			stopChan <- sig
+			return
+		case <-timeout:
+			stopChan <- captureStopSignal("capture duration elapsed")
+			return
+		case <-ticker.C:
+			control, err := readCaptureJobControl(controlPath)
+			if err != nil {
+				// The capture control volume is missing in capture jobs created by an older version.
+				if !os.IsNotExist(err) {
+					cm.l.Warn("Failed to read capture control", zap.Error(err))
+				}
+				continue
+			}
+			if control.Stop {
+				stopChan <- captureStopSignal("capture stop requested")
+				return
+			}
+			if control.Duration != 0 && control.Duration != duration {
+				cm.l.Info(fmt.Sprintf("Capture duration is changed from %s to %s", duration, control.Duration))
+				setDuration(control.Duration)
+			}
+		}
+	}
+}
+
+func captureControlPath() string {
+	path := filepath.Join(captureConstants.CaptureControlPath, captureConstants.CaptureControlAnnotationsFile)
+	if runtime.GOOS == "windows" {
+		path = filepath.Join(os.Getenv(captureConstants.ContainerSandboxMountPointEnvKey), path)
+	}
+	return path
+}
+
+// WriteResult writes the result of the capture job to the termination message of the capture container.
+func (cm *CaptureManager) WriteResult() error {
+	path := captureConstants.CaptureJobResultPath
+	if runtime.GOOS == "windows" {
+		path = filepath.Join(os.Getenv(captureConstants.ContainerSandboxMountPointEnvKey), path)
+	}
+	return cm.result.write(path)
+}
+
 func (cm *CaptureManager) Cleanup() error {
 	if err := cm.networkCaptureProvider.Cleanup(); err != nil {
 		cm.l.Error("Failed to cleanup capture job", zap.String("capture name", cm.captureName()), zap.Error(err))
@@ -144,15 +309,19 @@ func (cm *CaptureManager) OutputCapture(srcDir string) error {
 		return fmt.Errorf("capture source directory %s does not exist", srcDir)
 	}
 
-	dstTarGz := srcDir + ".tar.gz"
-	if err := compressFolderToTarGz(srcDir, dstTarGz); err != nil {
+	dstTarGz := srcDir + CaptureArtifactExtension
+	if err := utils.CompressFolderToTarGz(srcDir, dstTarGz); err != nil {
 		return err
 	}
 
 	for _, location := range cm.enabledOutputLocations() {
+		upload := retinav1alpha1.CaptureUploadStatus{Location: location.Name(), Succeeded: true}
 		if err := location.Output(dstTarGz); err != nil {
 			errStr = errStr + fmt.Sprintf("location %q output error: %s\n", location.Name(), err)
+			upload.Succeeded = false
+			upload.Message = err.Error()
 		}
+		cm.result.Uploads = append(cm.result.Uploads, upload)
 	}
 
 	if len(errStr) != 0 {
@@ -184,59 +353,17 @@ func (cm *CaptureManager) enabledOutputLocations() []captureOutput.Location {
 	return locations
 }
 
-func compressFolderToTarGz(src string, dst string) error {
-	// Create the output file
-	out, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	// Create the gzip writer
-	gz := gzip.NewWriter(out)
-	defer gz.Close()
-
-	// Create the tar writer
-	tarWriter := tar.NewWriter(gz)
-	defer tarWriter.Close()
-
-	// Walk the source directory and add files to the tar archive
-	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+// folderSize returns the total size of the regular files in the folder.
+func folderSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-
-		// Create a new tar header
-		header, err := tar.FileInfoHeader(info, info.Name())
-		if err != nil {
-			return err
-		}
-
-		// Set the header name to the relative path
-		relPath, err := filepath.Rel(src, path)
-		if err != nil {
-			return err
-		}
-		header.Name = relPath
-
-		// Write the header and file contents to the tar archive
-		if err = tarWriter.WriteHeader(header); err != nil {
-			return err
-		}
-		if !info.Mode().IsRegular() {
-			return nil
-		}
-		file, err := os.Open(path)
-		if err != nil {
-			return err
+		if info.Mode().IsRegular() {
+			size += info.Size()
 		}
-		defer file.Close()
-		if _, err = io.Copy(tarWriter, file); err != nil {
-			return err
-		}
-
 		return nil
 	})
-
-	return err
+	return size, err //nolint:wrapcheck // the path is in the error
 }
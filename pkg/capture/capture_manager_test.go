@@ -4,12 +4,15 @@
 package capture
 
 import (
-	"fmt"
+	"errors"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
 	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
 	"github.com/microsoft/retina/pkg/capture/provider"
 	"github.com/microsoft/retina/pkg/log"
@@ -22,15 +25,16 @@ func TestCaptureNetwork(t *testing.T) {
 	defer ctrl.Finish()
 
 	networkCaptureProvider := provider.NewMockNetworkCaptureProviderInterface(ctrl)
+	log.SetupZapLogger(log.GetDefaultLogOpts())
 	cm := &CaptureManager{
 		networkCaptureProvider: networkCaptureProvider,
 		tel:                    telemetry.NewNoopTelemetry(),
+		l:                      log.Logger().Named("test"),
 	}
 
 	captureName := "capture-name"
 	nodeHostName := "node-host-name"
 	filter := "-i any"
-	duration := 10
 	maxSize := 100
 	os.Setenv(captureConstants.CaptureNameEnvKey, captureName)
 	os.Setenv(captureConstants.NodeHostNameEnvKey, nodeHostName)
@@ -48,8 +52,9 @@ func TestCaptureNetwork(t *testing.T) {
 
 	sigChanel := make(chan os.Signal, 1)
 
-	networkCaptureProvider.EXPECT().Setup(captureName, nodeHostName).Return(fmt.Sprintf("%s-%s", captureName, nodeHostName), nil).Times(1)
-	networkCaptureProvider.EXPECT().CaptureNetworkPacket(filter, duration, maxSize, sigChanel).Return(nil).Times(1)
+	networkCaptureProvider.EXPECT().Setup(captureName, nodeHostName).Return(t.TempDir(), nil).Times(1)
+	// The capture manager stops the capture itself, as its duration may change while capturing.
+	networkCaptureProvider.EXPECT().CaptureNetworkPacket(filter, 0, maxSize, gomock.Any()).Return(nil).Times(1)
 
 	_, err := cm.CaptureNetwork(sigChanel)
 	if err != nil {
@@ -57,6 +62,80 @@ func TestCaptureNetwork(t *testing.T) {
 	}
 }
 
+func TestControlCapture(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	captureControlInterval = 10 * time.Millisecond
+	defer func() { captureControlInterval = 5 * time.Second }()
+
+	cases := []struct {
+		name     string
+		duration time.Duration
+		control  string
+		signal   os.Signal
+		wantStop string
+		wantMin  time.Duration
+	}{
+		{
+			name:     "stop when the duration elapses",
+			duration: 50 * time.Millisecond,
+			wantStop: "capture duration elapsed",
+			wantMin:  50 * time.Millisecond,
+		},
+		{
+			name:     "stop when requested",
+			duration: time.Hour,
+			control:  captureConstants.CaptureStopAnnotation + "=\"true\"\n",
+			wantStop: "capture stop requested",
+		},
+		{
+			name:     "stop when the extended duration elapses",
+			duration: 50 * time.Millisecond,
+			control:  "other=\"value\"\n" + captureConstants.CaptureDurationAnnotation + "=\"300ms\"\n",
+			wantStop: "capture duration elapsed",
+			wantMin:  300 * time.Millisecond,
+		},
+		{
+			name:     "stop on OS signal",
+			signal:   os.Interrupt,
+			wantStop: os.Interrupt.String(),
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &CaptureManager{l: log.Logger().Named("test")}
+			controlPath := filepath.Join(t.TempDir(), captureConstants.CaptureControlAnnotationsFile)
+			if len(tt.control) != 0 {
+				if err := os.WriteFile(controlPath, []byte(tt.control), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			sigChan := make(chan os.Signal, 1)
+			if tt.signal != nil {
+				sigChan <- tt.signal
+			}
+			stopChan := make(chan os.Signal, 1)
+			done := make(chan struct{})
+			defer close(done)
+
+			start := time.Now()
+			go cm.controlCapture(controlPath, tt.duration, sigChan, stopChan, done)
+			select {
+			case sig := <-stopChan:
+				if sig.String() != tt.wantStop {
+					t.Errorf("controlCapture() want stop %q, got %q", tt.wantStop, sig.String())
+				}
+				if elapsed := time.Since(start); elapsed < tt.wantMin {
+					t.Errorf("controlCapture() stopped after %s, want at least %s", elapsed, tt.wantMin)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("controlCapture() did not stop the capture")
+			}
+		})
+	}
+}
+
 func TestEnabledOutputLocation(t *testing.T) {
 	cases := []struct {
 		name                      string
@@ -137,3 +216,28 @@ func TestCleanup(t *testing.T) {
 		t.Errorf("Cleanup should have not fail with error %s", err)
 	}
 }
+
+func TestNewNetworkCaptureProviderPodNetworkNamespace(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	t.Setenv(captureConstants.CaptureBackendEnvKey, string(retinav1alpha1.CaptureBackendNative))
+	t.Setenv(captureConstants.TcpdumpRawFilterEnvKey, "-i any")
+
+	// Falling back to tcpdump is fine for node captures.
+	if _, ok := newNetworkCaptureProvider(log.Logger().Named("test")).(*unavailableNetworkCaptureProvider); ok {
+		t.Fatal("Node capture should fall back to tcpdump")
+	}
+
+	// Captures in the Pod network namespace must not be widened to the whole node.
+	t.Setenv(captureConstants.CapturePodNetworkNamespaceEnvKey, "true")
+	cm := &CaptureManager{
+		networkCaptureProvider: newNetworkCaptureProvider(log.Logger().Named("test")),
+		tel:                    telemetry.NewNoopTelemetry(),
+		l:                      log.Logger().Named("test"),
+	}
+	if _, err := cm.CaptureNetwork(make(chan os.Signal, 1)); !errors.Is(err, errRawFilterNotSupported) {
+		t.Errorf("CaptureNetwork should fail with %v, got %v", errRawFilterNotSupported, err)
+	}
+	if err := cm.Cleanup(); err != nil {
+		t.Errorf("Cleanup should have not fail with error %s", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"errors"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	captureUtils "github.com/microsoft/retina/pkg/capture/utils"
+)
+
+// CleanupCommand is the first argument of the capture workload deleting the capture artifacts of its node stored to
+// the output locations, instead of capturing network packets.
+const CleanupCommand = "cleanup"
+
+// CaptureArtifactPrefix returns the prefix of the names of the capture artifacts of the Capture on the node.
+func CaptureArtifactPrefix(captureName, nodeName string) string {
+	return fmt.Sprintf("%s-%s-", captureName, nodeName)
+}
+
+// CleanupJobFromCaptureJob returns the job deleting the capture artifacts stored by the capture job. It runs the
+// capture workload on the same node with the same output locations, but is labeled apart from the capture jobs.
+func CleanupJobFromCaptureJob(captureName string, captureJob *batchv1.Job) *batchv1.Job {
+	template := captureJob.Spec.Template.DeepCopy()
+	template.Labels = captureUtils.GetCleanupJobLabelsFromCaptureName(captureName)
+	template.Annotations = nil
+	for i := range template.Spec.Containers {
+		template.Spec.Containers[i].Args = []string{CleanupCommand}
+	}
+
+	backoffLimit := int32(0)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-cleanup-", captureName),
+			Namespace:    captureJob.Namespace,
+			Labels:       captureUtils.GetCleanupJobLabelsFromCaptureName(captureName),
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     *template,
+		},
+	}
+}
+
+// DeleteCaptureArtifacts deletes the capture artifacts of the Capture on this node from each enabled output location.
+func (cm *CaptureManager) DeleteCaptureArtifacts() error {
+	artifactPrefix := CaptureArtifactPrefix(cm.captureName(), cm.captureNodeHostName())
+	var errs []error
+	for _, location := range cm.enabledOutputLocations() {
+		if err := location.Delete(artifactPrefix); err != nil {
+			errs = append(errs, fmt.Errorf("location %q delete error: %w", location.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
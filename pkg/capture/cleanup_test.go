@@ -0,0 +1,62 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+	captureUtils "github.com/microsoft/retina/pkg/capture/utils"
+)
+
+func TestCleanupJobFromCaptureJob(t *testing.T) {
+	captureJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "capture-abcde",
+			Namespace: "default",
+			Labels:    captureUtils.GetJobLabelsFromCaptureName("capture"),
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      captureUtils.GetContainerLabelsFromCaptureName("capture"),
+					Annotations: map[string]string{captureConstants.CaptureStopAnnotation: "true"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: captureConstants.CaptureContainername,
+							Env:  []corev1.EnvVar{{Name: captureConstants.NodeHostNameEnvKey, Value: "node1"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	job := CleanupJobFromCaptureJob("capture", captureJob)
+	if diff := cmp.Diff(captureUtils.GetCleanupJobLabelsFromCaptureName("capture"), job.Labels); diff != "" {
+		t.Errorf("CleanupJobFromCaptureJob() labels mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(captureUtils.GetCleanupJobLabelsFromCaptureName("capture"), job.Spec.Template.Labels); diff != "" {
+		t.Errorf("CleanupJobFromCaptureJob() Pod labels mismatch (-want, +got):\n%s", diff)
+	}
+	if len(job.Spec.Template.Annotations) != 0 {
+		t.Errorf("CleanupJobFromCaptureJob() want no Pod annotations, got %v", job.Spec.Template.Annotations)
+	}
+	if diff := cmp.Diff([]string{CleanupCommand}, job.Spec.Template.Spec.Containers[0].Args); diff != "" {
+		t.Errorf("CleanupJobFromCaptureJob() args mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(captureJob.Spec.Template.Spec.Containers[0].Env, job.Spec.Template.Spec.Containers[0].Env); diff != "" {
+		t.Errorf("CleanupJobFromCaptureJob() env mismatch (-want, +got):\n%s", diff)
+	}
+	if len(captureJob.Spec.Template.Spec.Containers[0].Args) != 0 {
+		t.Errorf("CleanupJobFromCaptureJob() must not change the capture job")
+	}
+}
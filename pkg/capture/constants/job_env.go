@@ -24,6 +24,13 @@ const (
 	CaptureMaxSizeEnvKey  string = "CAPTURE_MAX_SIZE"
 	IncludeMetadataEnvKey string = "INCLUDE_METADATA"
 	PacketSizeEnvKey      string = "CAPTURE_PACKET_SIZE"
+	CaptureBackendEnvKey  string = "CAPTURE_BACKEND"
+	// CaptureInterfacesEnvKey is a comma-separated list of interface names to capture on.
+	CaptureInterfacesEnvKey string = "CAPTURE_INTERFACES"
+	// CapturePodsEnvKey is a comma-separated list of <pod IP>=<namespace>/<pod name> items of the Pods to capture.
+	CapturePodsEnvKey string = "CAPTURE_PODS"
+	// CapturePodNetworkNamespaceEnvKey indicates the capture is performed inside the network namespace of the Pods.
+	CapturePodNetworkNamespaceEnvKey string = "CAPTURE_POD_NETWORK_NAMESPACE"
 
 	TcpdumpFilterEnvKey    string = "TCPDUMP_FILTER"
 	TcpdumpRawFilterEnvKey string = "TCPDUMP_RAW_FILTER"
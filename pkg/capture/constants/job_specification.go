@@ -18,6 +18,8 @@ const (
 
 	CaptureAppname       string = "capture"
 	CaptureContainername string = "capture"
+	// CaptureCleanupAppname is the app label of the jobs deleting the capture artifacts of an expired Capture.
+	CaptureCleanupAppname string = "capture-cleanup"
 
 	// CaptureOutputLocationBlobUploadSecretName is the name of the secret that stores the blob upload url.
 	CaptureOutputLocationBlobUploadSecretName string = "capture-blob-upload-secret"
@@ -35,6 +37,27 @@ const (
 	// CaptureOutputLocationS3UploadSecretAccessKey is the key of the secret that stores the s3 secret access key.
 	CaptureOutputLocationS3UploadSecretAccessKey string = "s3-secret-access-key"
 
+	// CaptureControlVolumeName is the name of the downward API volume exposing the annotations of the capture Pod,
+	// through which running captures are stopped or extended.
+	CaptureControlVolumeName string = "capture-control"
+	// CaptureControlPath is the path of the capture control volume.
+	CaptureControlPath string = "/etc/capture-control"
+	// CaptureControlAnnotationsFile is the file of the capture control volume storing the annotations of the capture Pod.
+	CaptureControlAnnotationsFile string = "annotations"
+	// CaptureStopAnnotation requests the capture job to stop capturing and store the captured network packets.
+	CaptureStopAnnotation string = "retina.sh/capture-stop"
+	// CaptureDurationAnnotation overrides the duration of the running capture, from its start.
+	CaptureDurationAnnotation string = "retina.sh/capture-duration"
+	// CapturePodIPsAnnotation lists the IP addresses of the Pods the capture job captures, in the annotations of the job.
+	CapturePodIPsAnnotation string = "retina.sh/capture-pod-ips"
+	// CaptureDurationOffsetAnnotation is the time since the Capture started when the capture job is created, in the
+	// annotations of the job capturing Pods added to the selected Services during the Capture, which captures for the
+	// rest of the Capture duration.
+	CaptureDurationOffsetAnnotation string = "retina.sh/capture-duration-offset"
+	// CaptureJobResultPath is the termination message path of the capture container, where the capture job writes
+	// its result for the progress of the Capture.
+	CaptureJobResultPath string = "/dev/termination-log"
+
 	// CaptureWorkloadImageName defines the official capture workload image repo and image name
 	CaptureWorkloadImageName string = "ghcr.io/microsoft/retina/retina-agent"
 
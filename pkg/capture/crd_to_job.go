@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -35,6 +36,13 @@ const anyIPOrPort = ""
 type CaptureTarget struct {
 	// PodIpAddresses indicates the capture is performed on the Pods per their IP addresses.
 	PodIpAddresses []string
+	// PodNames maps the IP addresses in PodIpAddresses to the <namespace>/<name> of the Pods owning them.
+	PodNames map[string]string
+	// ServiceIPAddresses are the ClusterIPs of the selected Services backed by the Pods, captured along with the Pods
+	// so packets are captured before they are translated to the Pods.
+	ServiceIPAddresses []string
+	// NodePorts are the NodePorts of the selected Services backed by the Pods.
+	NodePorts []int32
 	// CaptureNodeInterface indicates the capture is performed on the host node interface.
 	CaptureNodeInterface bool
 
@@ -58,6 +66,18 @@ func (cton CaptureTargetsOnNode) AddPod(hostname string, ipAddresses []string) {
 	}
 }
 
+// AddPodName records the <namespace>/<name> of the Pod owning the IP addresses on the node.
+func (cton CaptureTargetsOnNode) AddPodName(hostname, podName string, ipAddresses []string) {
+	captureTarget := cton[hostname]
+	if captureTarget.PodNames == nil {
+		captureTarget.PodNames = make(map[string]string, len(ipAddresses))
+	}
+	for _, ipAddress := range ipAddresses {
+		captureTarget.PodNames[ipAddress] = podName
+	}
+	cton[hostname] = captureTarget
+}
+
 func (cton CaptureTargetsOnNode) AddNodeInterface(hostname string) {
 	cton[hostname] = CaptureTarget{
 		CaptureNodeInterface: true,
@@ -76,6 +96,14 @@ type CaptureToPodTranslator struct {
 	kubeClient           kubernetes.Interface
 	jobTemplate          *batchv1.Job
 	captureWorkloadImage string
+	// netshFilterErr tells why the filter rules of the Capture being translated cannot be rendered for Windows nodes.
+	netshFilterErr error
+	// trackServiceEndpoints tells the Capture being translated selects Services, whose jobs record the Pods they capture
+	// so the Pods added to the Services during the Capture are captured by new jobs.
+	trackServiceEndpoints bool
+	// scopeFiltersToTargets tells the Capture being translated is restricted by CapturePolicies, so its filters only
+	// narrow the traffic of the Pods and Services it selects instead of capturing more traffic along with them.
+	scopeFiltersToTargets bool
 
 	config config.CaptureConfig
 
@@ -113,6 +141,7 @@ func (translator *CaptureToPodTranslator) initJobTemplate(capture *retinav1alpha
 	// NOTE(mainred): We allow the capture pod to run for at most 30 minutes before being deleted to ensure the output is
 	// uploaded, and this happens when the user want to stop a capture on demand by deleting the capture.
 	captureTerminationGracePeriodSeconds := int64(1800)
+	translator.trackServiceEndpoints = capture.Spec.CaptureConfiguration.CaptureTarget.ServiceSelector != nil
 	translator.jobTemplate = &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: fmt.Sprintf("%s-", capture.Name),
@@ -156,6 +185,13 @@ func (translator *CaptureToPodTranslator) initJobTemplate(capture *retinav1alpha
 									Value: translator.Apiserver,
 								},
 							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      captureConstants.CaptureControlVolumeName,
+									MountPath: captureConstants.CaptureControlPath,
+									ReadOnly:  true,
+								},
+							},
 							// Usually, the Capture Pod takes no more than 10m CPU
 							// and 10Mi memory. And considering the Capture Pod
 							// does not consumes much resources as required by
@@ -176,6 +212,23 @@ func (translator *CaptureToPodTranslator) initJobTemplate(capture *retinav1alpha
 						},
 					},
 
+					// The annotations of the capture Pod control the running capture, like stopping or extending it.
+					Volumes: []corev1.Volume{
+						{
+							Name: captureConstants.CaptureControlVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								DownwardAPI: &corev1.DownwardAPIVolumeSource{
+									Items: []corev1.DownwardAPIVolumeFile{
+										{
+											Path:     captureConstants.CaptureControlAnnotationsFile,
+											FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations"},
+										},
+									},
+								},
+							},
+						},
+					},
+
 					RestartPolicy: corev1.RestartPolicyNever,
 
 					Tolerations: []corev1.Toleration{
@@ -418,9 +471,30 @@ func (translator *CaptureToPodTranslator) renderJob(captureTargetOnNode *Capture
 			job.Spec.Template.Spec.Containers[0].Command = []string{captureConstants.CaptureContainerEntrypoint}
 
 			delete(jobEnv, captureConstants.NetshFilterEnvKey)
-			// Update tcpdumpfilter to include target POD IP address.
-			if updatedTcpdumpFilter := updateTcpdumpFilterWithPodIPAddress(target.PodIpAddresses, jobEnv[captureConstants.TcpdumpFilterEnvKey]); len(updatedTcpdumpFilter) != 0 {
-				jobEnv[captureConstants.TcpdumpFilterEnvKey] = updatedTcpdumpFilter
+			// The native backend annotates the capture with the Pods, and captures on their host interfaces by default.
+			if jobEnv[captureConstants.CaptureBackendEnvKey] == string(retinav1alpha1.CaptureBackendNative) {
+				if capturePods := capturePodsEnv(target.PodNames); len(capturePods) != 0 {
+					jobEnv[captureConstants.CapturePodsEnvKey] = capturePods
+				}
+			}
+			// The capture job finds the network namespace of the Pods from the node processes, and no longer needs the Pod
+			// IP addresses in the filter, which would exclude their loopback traffic.
+			if _, ok := jobEnv[captureConstants.CapturePodNetworkNamespaceEnvKey]; ok {
+				job.Spec.Template.Spec.HostPID = true
+				job.Spec.Template.Spec.Containers[0].SecurityContext.Capabilities.Add = append(job.Spec.Template.Spec.Containers[0].SecurityContext.Capabilities.Add, "SYS_PTRACE")
+			} else {
+				// The ClusterIPs and NodePorts of the Services are captured along with the Pods backing them.
+				var updatedTcpdumpFilter string
+				if translator.scopeFiltersToTargets {
+					targetTcpdumpFilter := updateTcpdumpFilterWithNodePorts(target.NodePorts, updateTcpdumpFilterWithPodIPAddress(slices.Concat(target.PodIpAddresses, target.ServiceIPAddresses), ""))
+					updatedTcpdumpFilter = scopeTcpdumpFilterToTargets(targetTcpdumpFilter, jobEnv[captureConstants.TcpdumpFilterEnvKey])
+				} else {
+					updatedTcpdumpFilter = updateTcpdumpFilterWithPodIPAddress(slices.Concat(target.PodIpAddresses, target.ServiceIPAddresses), jobEnv[captureConstants.TcpdumpFilterEnvKey])
+					updatedTcpdumpFilter = updateTcpdumpFilterWithNodePorts(target.NodePorts, updatedTcpdumpFilter)
+				}
+				if len(updatedTcpdumpFilter) != 0 {
+					jobEnv[captureConstants.TcpdumpFilterEnvKey] = updatedTcpdumpFilter
+				}
 			}
 		} else {
 			containerAdministrator := "NT AUTHORITY\\SYSTEM"
@@ -429,6 +503,9 @@ func (translator *CaptureToPodTranslator) renderJob(captureTargetOnNode *Capture
 				HostProcess:   &useHostProcess,
 				RunAsUserName: &containerAdministrator,
 			}
+			if _, ok := jobEnv[captureConstants.CapturePodNetworkNamespaceEnvKey]; ok {
+				return nil, fmt.Errorf("capturing in the Pod network namespace is not supported on Windows node %s", nodeName)
+			}
 			job.Spec.Template.Spec.Containers[0].Command = []string{captureConstants.CaptureContainerEntrypointWin}
 
 			// Update to pvc mount path for Windows pods.
@@ -440,8 +517,20 @@ func (translator *CaptureToPodTranslator) renderJob(captureTargetOnNode *Capture
 			}
 
 			delete(jobEnv, captureConstants.TcpdumpFilterEnvKey)
-			if netshFilter := getNetshFilterWithPodIPAddress(target.PodIpAddresses); len(netshFilter) != 0 {
-				jobEnv[captureConstants.NetshFilterEnvKey] = netshFilter
+			// netsh is the only capture backend on Windows.
+			delete(jobEnv, captureConstants.CaptureBackendEnvKey)
+			delete(jobEnv, captureConstants.CaptureInterfacesEnvKey)
+			if translator.netshFilterErr != nil {
+				return nil, CaptureFilterInvalidError{Reason: fmt.Sprintf("Windows node %s: %s", nodeName, translator.netshFilterErr)}
+			}
+			// netsh filters no ports, so only the ClusterIPs of the Services are captured along with the Pods.
+			if podNetshFilter := getNetshFilterWithPodIPAddress(slices.Concat(target.PodIpAddresses, target.ServiceIPAddresses)); len(podNetshFilter) != 0 {
+				// netsh ANDs its filters, so the addresses of the filter rule would conflict with the Pod addresses.
+				ruleNetshFilter := jobEnv[captureConstants.NetshFilterEnvKey]
+				if strings.Contains(ruleNetshFilter, "Address=") {
+					return nil, CaptureFilterInvalidError{Reason: fmt.Sprintf("Windows node %s: addresses of filter rules cannot be combined with PodSelector", nodeName)}
+				}
+				jobEnv[captureConstants.NetshFilterEnvKey] = strings.TrimSpace(ruleNetshFilter + " " + podNetshFilter)
 			}
 		}
 
@@ -449,6 +538,9 @@ func (translator *CaptureToPodTranslator) renderJob(captureTargetOnNode *Capture
 			job.Spec.Template.Spec.Containers[0].Env = append(job.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: k, Value: v})
 		}
 		job.Spec.Template.Spec.Containers[0].Env = append(job.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: captureConstants.NodeHostNameEnvKey, Value: nodeName})
+		if translator.trackServiceEndpoints {
+			job.Annotations = map[string]string{captureConstants.CapturePodIPsAnnotation: strings.Join(target.PodIpAddresses, ",")}
+		}
 
 		jobs = append(jobs, job)
 	}
@@ -474,6 +566,46 @@ func updateTcpdumpFilterWithPodIPAddress(podIPAddresses []string, tcpdumpFilter
 	return fmt.Sprintf("%s or %s", tcpdumpFilter, filterGroup)
 }
 
+// updateTcpdumpFilterWithNodePorts captures the network traffic of any of the NodePorts, along with the traffic
+// matched by the tcpdump filter.
+func updateTcpdumpFilterWithNodePorts(nodePorts []int32, tcpdumpFilter string) string {
+	if len(nodePorts) == 0 {
+		return tcpdumpFilter
+	}
+	nodePortFilterArray := make([]string, 0, len(nodePorts))
+	for _, nodePort := range nodePorts {
+		nodePortFilterArray = append(nodePortFilterArray, fmt.Sprintf("port %d", nodePort))
+	}
+
+	filterGroup := fmt.Sprintf("(%s)", strings.Join(nodePortFilterArray, " or "))
+	if len(tcpdumpFilter) == 0 {
+		return filterGroup
+	}
+	return fmt.Sprintf("%s or %s", tcpdumpFilter, filterGroup)
+}
+
+// scopeTcpdumpFilterToTargets captures the network traffic matched by the tcpdump filter only when it is also the
+// traffic of the targets, so the filter cannot capture traffic of other Pods on the node.
+func scopeTcpdumpFilterToTargets(targetTcpdumpFilter, tcpdumpFilter string) string {
+	if len(targetTcpdumpFilter) == 0 {
+		return tcpdumpFilter
+	}
+	if len(tcpdumpFilter) == 0 {
+		return targetTcpdumpFilter
+	}
+	return fmt.Sprintf("(%s) and (%s)", targetTcpdumpFilter, tcpdumpFilter)
+}
+
+// capturePodsEnv formats the Pod names by IP address as <pod IP>=<namespace>/<pod name> items sorted by IP address.
+func capturePodsEnv(podNames map[string]string) string {
+	items := make([]string, 0, len(podNames))
+	for ip, podName := range podNames {
+		items = append(items, fmt.Sprintf("%s=%s", ip, podName))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
 func getNetshFilterWithPodIPAddress(podIPAddresses []string) string {
 	if len(podIPAddresses) == 0 {
 		return ""
@@ -516,11 +648,14 @@ func getNetshFilterWithPodIPAddress(podIPAddresses []string) string {
 // validateTargetSelector validate target selectors defined in the capture.
 func (translator *CaptureToPodTranslator) validateTargetSelector(captureTarget retinav1alpha1.CaptureTarget) error {
 	// When NamespaceSelector is nil while PodSelector is specified, the namespace will be determined by capture.Namespace.
-	if captureTarget.NodeSelector == nil && captureTarget.PodSelector == nil {
-		return fmt.Errorf("Neither NodeSelector nor NamespaceSelector&PodSelector is set.")
+	if captureTarget.NodeSelector == nil && captureTarget.PodSelector == nil && captureTarget.ServiceSelector == nil {
+		return fmt.Errorf("Neither NodeSelector nor NamespaceSelector&PodSelector nor NamespaceSelector&ServiceSelector is set.")
+	}
+	if captureTarget.NodeSelector != nil && (captureTarget.NamespaceSelector != nil || captureTarget.PodSelector != nil || captureTarget.ServiceSelector != nil) {
+		return fmt.Errorf("NodeSelector is not compatible with NamespaceSelector&PodSelector or NamespaceSelector&ServiceSelector.")
 	}
-	if captureTarget.NodeSelector != nil && (captureTarget.NamespaceSelector != nil || captureTarget.PodSelector != nil) {
-		return fmt.Errorf("NodeSelector is not compatible with NamespaceSelector&PodSelector.")
+	if captureTarget.PodSelector != nil && captureTarget.ServiceSelector != nil {
+		return fmt.Errorf("PodSelector is not compatible with ServiceSelector.")
 	}
 	return nil
 }
@@ -541,6 +676,22 @@ func (translator *CaptureToPodTranslator) validateCapture(capture *retinav1alpha
 		capture.Spec.OutputConfiguration.S3Upload == nil {
 		return fmt.Errorf("At least one output configuration should be set")
 	}
+
+	captureOption := capture.Spec.CaptureConfiguration.CaptureOption
+	if captureOption.Backend == retinav1alpha1.CaptureBackendNative && capture.Spec.CaptureConfiguration.TcpdumpFilter != nil {
+		return fmt.Errorf("TcpdumpFilter is not supported by the native capture backend")
+	}
+	if len(captureOption.Interfaces) != 0 && captureOption.Backend != retinav1alpha1.CaptureBackendNative {
+		return fmt.Errorf("Interfaces are only supported by the native capture backend")
+	}
+	if captureOption.PodNetworkNamespace {
+		if capture.Spec.CaptureConfiguration.CaptureTarget.PodSelector == nil && capture.Spec.CaptureConfiguration.CaptureTarget.ServiceSelector == nil {
+			return fmt.Errorf("PodNetworkNamespace requires PodSelector or ServiceSelector to select the Pods to capture")
+		}
+		if captureOption.Backend != retinav1alpha1.CaptureBackendNative {
+			return fmt.Errorf("PodNetworkNamespace is only supported by the native capture backend")
+		}
+	}
 	return nil
 }
 
@@ -557,9 +708,14 @@ func (translator *CaptureToPodTranslator) getCaptureTargetsOnNode(captureTarget
 			return nil, err
 		}
 	}
+	if captureTarget.ServiceSelector != nil {
+		if captureTargetsOnNode, err = translator.calculateCaptureTargetsByServiceSelector(captureTarget); err != nil {
+			return nil, err
+		}
+	}
 
 	if len(*captureTargetsOnNode) == 0 {
-		return nil, fmt.Errorf("no targets are selected by node selector or pod selector")
+		return nil, fmt.Errorf("no targets are selected by node selector, pod selector or service selector")
 	}
 	return captureTargetsOnNode, nil
 }
@@ -634,8 +790,9 @@ func (translator *CaptureToPodTranslator) calculateCaptureTargetsByNodeSelector(
 	return captureTargetOnNode, nil
 }
 
-func (translator *CaptureToPodTranslator) calculateCaptureTargetsByPodSelector(captureTarget retinav1alpha1.CaptureTarget) (*CaptureTargetsOnNode, error) {
-	captureTargetOnNode := &CaptureTargetsOnNode{}
+// selectNamespaces returns the Namespaces selected by the NamespaceSelector of the capture target, or the default
+// Namespace if it is not set.
+func (translator *CaptureToPodTranslator) selectNamespaces(captureTarget retinav1alpha1.CaptureTarget) (*corev1.NamespaceList, error) {
 	nsList := &corev1.NamespaceList{Items: []corev1.Namespace{
 		{
 			ObjectMeta: metav1.ObjectMeta{
@@ -660,6 +817,15 @@ func (translator *CaptureToPodTranslator) calculateCaptureTargetsByPodSelector(c
 			return nil, err
 		}
 	}
+	return nsList, nil
+}
+
+func (translator *CaptureToPodTranslator) calculateCaptureTargetsByPodSelector(captureTarget retinav1alpha1.CaptureTarget) (*CaptureTargetsOnNode, error) {
+	captureTargetOnNode := &CaptureTargetsOnNode{}
+	nsList, err := translator.selectNamespaces(captureTarget)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, ns := range nsList.Items {
 		labelSelector, _ := labels.Parse(metav1.FormatLabelSelector(captureTarget.PodSelector))
@@ -681,20 +847,18 @@ func (translator *CaptureToPodTranslator) calculateCaptureTargetsByPodSelector(c
 				podIPs = append(podIPs, podIP.IP)
 			}
 			captureTargetOnNode.AddPod(pod.Spec.NodeName, podIPs)
+			captureTargetOnNode.AddPodName(pod.Spec.NodeName, pod.Namespace+"/"+pod.Name, podIPs)
 		}
 	}
 
 	return captureTargetOnNode, nil
 }
 
-// For tcpdump, we put each filter(ip:port) into parentheses, and all include filters will be grouped in parentheses,
-// same case for exclude filters, finally the filters overall will be like:
+// For tcpdump, we put each filter(ip:port) and typed filter rule into parentheses, and all include filters will be
+// grouped in parentheses, same case for exclude filters, finally the filters overall will be like:
 // ((include1) or (include2)) and not ((exclude1) or (exclude2))
-func tcpdumpFiltersFromIncludeAndExcludeFilters(includeIPPortsFilters, excludeIPPortsFilters map[string][]string) string {
-	getFilterGroupFunc := func(ipPortsFilters map[string][]string) string {
-		if len(ipPortsFilters) == 0 {
-			return ""
-		}
+func tcpdumpFiltersFromIncludeAndExcludeFilters(includeIPPortsFilters, excludeIPPortsFilters map[string][]string, includeRules, excludeRules []filterRule) string {
+	getFilterGroupFunc := func(ipPortsFilters map[string][]string, rules []filterRule) string {
 		ips := make([]string, 0)
 
 		for ip := range ipPortsFilters {
@@ -723,6 +887,9 @@ func tcpdumpFiltersFromIncludeAndExcludeFilters(includeIPPortsFilters, excludeIP
 				filterArray = append(filterArray, filter)
 			}
 		}
+		for i := range rules {
+			filterArray = append(filterArray, rules[i].tcpdump())
+		}
 		if len(filterArray) == 0 {
 			return ""
 		}
@@ -730,8 +897,8 @@ func tcpdumpFiltersFromIncludeAndExcludeFilters(includeIPPortsFilters, excludeIP
 		return filterGroup
 	}
 
-	includeFilterGroup := getFilterGroupFunc(includeIPPortsFilters)
-	excludeFilterGroup := getFilterGroupFunc(excludeIPPortsFilters)
+	includeFilterGroup := getFilterGroupFunc(includeIPPortsFilters, includeRules)
+	excludeFilterGroup := getFilterGroupFunc(excludeIPPortsFilters, excludeRules)
 
 	if len(includeFilterGroup) == 0 && len(excludeFilterGroup) == 0 {
 		return ""
@@ -855,26 +1022,40 @@ func filterToIPPortsMap(filters []string) (map[string][]string, error) {
 	return filterIPPortsMap, nil
 }
 
-func (translator *CaptureToPodTranslator) obtainTcpdumpFilters(captureConfig retinav1alpha1.CaptureConfiguration) (string, error) {
+// obtainCaptureFilters renders the IP:Port filters and the typed filter rules of the Capture as the tcpdump filter of
+// Linux nodes, and the typed filter rules as the netsh filter of Windows nodes.
+func (translator *CaptureToPodTranslator) obtainCaptureFilters(namespace string, captureConfig retinav1alpha1.CaptureConfiguration) (captureFilters, error) {
 	if captureConfig.Filters == nil && captureConfig.TcpdumpFilter == nil && captureConfig.CaptureOption.PacketSize == nil {
-		return "", nil
+		return captureFilters{}, nil
 	}
 	includeIPPortFilters, excludeIPPortFilters, err := parseIncludeAndExcludeFilters(captureConfig.Filters)
 	if err != nil {
-		return "", err
+		return captureFilters{}, CaptureFilterInvalidError{Reason: err.Error()}
+	}
+
+	var includeRules, excludeRules []filterRule
+	if captureConfig.Filters != nil {
+		if includeRules, err = translator.resolveFilterRules(namespace, captureConfig.Filters.IncludeRules); err != nil {
+			return captureFilters{}, err
+		}
+		if excludeRules, err = translator.resolveFilterRules(namespace, captureConfig.Filters.ExcludeRules); err != nil {
+			return captureFilters{}, err
+		}
 	}
 
-	tcpdumpFilter := tcpdumpFiltersFromIncludeAndExcludeFilters(includeIPPortFilters, excludeIPPortFilters)
-	if len(tcpdumpFilter) != 0 {
+	filters := captureFilters{}
+	filters.tcpdump = tcpdumpFiltersFromIncludeAndExcludeFilters(includeIPPortFilters, excludeIPPortFilters, includeRules, excludeRules)
+	if len(filters.tcpdump) != 0 {
 		translator.l.Info("Get the parsed filter from include and include filters",
-			zap.String("parsed filter", tcpdumpFilter),
+			zap.String("parsed filter", filters.tcpdump),
 			zap.String("Include filters", strings.Join(captureConfig.Filters.Include, ",")),
 			zap.String("Exclude filters", strings.Join(captureConfig.Filters.Exclude, ",")),
 		)
 	}
+	filters.netsh, filters.netshErr = netshFilter(includeRules, excludeRules)
 
-	translator.l.Info(fmt.Sprintf("The Parsed tcpdump filter is %q", tcpdumpFilter))
-	return tcpdumpFilter, nil
+	translator.l.Info(fmt.Sprintf("The Parsed tcpdump filter is %q", filters.tcpdump))
+	return filters, nil
 }
 
 func (translator *CaptureToPodTranslator) obtainCaptureOutputEnv(outputConfiguration retinav1alpha1.OutputConfiguration) (map[captureConstants.CaptureOutputLocationEnvKey]string, error) {
@@ -908,6 +1089,15 @@ func (translator *CaptureToPodTranslator) obtainCaptureOptionEnv(option retinav1
 	if option.MaxCaptureSize != nil {
 		outputEnv[captureConstants.CaptureMaxSizeEnvKey] = strconv.Itoa(*option.MaxCaptureSize)
 	}
+	if len(option.Backend) != 0 {
+		outputEnv[captureConstants.CaptureBackendEnvKey] = string(option.Backend)
+	}
+	if len(option.Interfaces) != 0 {
+		outputEnv[captureConstants.CaptureInterfacesEnvKey] = strings.Join(option.Interfaces, ",")
+	}
+	if option.PodNetworkNamespace {
+		outputEnv[captureConstants.CapturePodNetworkNamespaceEnvKey] = strconv.FormatBool(option.PodNetworkNamespace)
+	}
 	return outputEnv, nil
 }
 
@@ -931,13 +1121,18 @@ func (translator *CaptureToPodTranslator) ObtainCaptureJobPodEnv(capture retinav
 		jobPodEnv[string(key)] = val
 	}
 
-	tcpdumpFilter, err := translator.obtainTcpdumpFilters(capture.Spec.CaptureConfiguration)
+	filters, err := translator.obtainCaptureFilters(capture.Namespace, capture.Spec.CaptureConfiguration)
 	if err != nil {
 		return nil, err
 	}
-	if len(tcpdumpFilter) != 0 {
-		jobPodEnv[captureConstants.TcpdumpFilterEnvKey] = tcpdumpFilter
+	if len(filters.tcpdump) != 0 {
+		jobPodEnv[captureConstants.TcpdumpFilterEnvKey] = filters.tcpdump
+	}
+	// The netsh filter is only kept for Windows nodes, which fail to render if it cannot express the filter rules.
+	if len(filters.netsh) != 0 {
+		jobPodEnv[captureConstants.NetshFilterEnvKey] = filters.netsh
 	}
+	translator.netshFilterErr = filters.netshErr
 
 	if capture.Spec.CaptureConfiguration.CaptureOption.PacketSize != nil {
 		jobPodEnv[captureConstants.PacketSizeEnvKey] = strconv.Itoa(*capture.Spec.CaptureConfiguration.CaptureOption.PacketSize)
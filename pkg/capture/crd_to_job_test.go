@@ -268,6 +268,7 @@ func Test_CaptureToPodTranslator_GetCaptureTargetsOnNode(t *testing.T) {
 			wantCaptureTargetsOnNode: &CaptureTargetsOnNode{
 				"node1": CaptureTarget{
 					PodIpAddresses: []string{"10.225.0.4"},
+					PodNames:       map[string]string{"10.225.0.4": "test-capture-ns/test-capture-pod"},
 				},
 			},
 			wantErr: false,
@@ -303,6 +304,10 @@ func Test_CaptureToPodTranslator_GetCaptureTargetsOnNode(t *testing.T) {
 			wantCaptureTargetsOnNode: &CaptureTargetsOnNode{
 				"node1": CaptureTarget{
 					PodIpAddresses: []string{"10.225.0.4", "fd5c:d9f1:79c5:fd83::21e"},
+					PodNames: map[string]string{
+						"10.225.0.4":               "test-capture-ns/test-capture-pod",
+						"fd5c:d9f1:79c5:fd83::21e": "test-capture-ns/test-capture-pod",
+					},
 				},
 			},
 			wantErr: false,
@@ -343,6 +348,10 @@ func Test_CaptureToPodTranslator_GetCaptureTargetsOnNode(t *testing.T) {
 			wantCaptureTargetsOnNode: &CaptureTargetsOnNode{
 				"node1": CaptureTarget{
 					PodIpAddresses: []string{"10.225.0.4", "10.225.0.5"},
+					PodNames: map[string]string{
+						"10.225.0.4": "test-capture-ns/test-capture-pod1",
+						"10.225.0.5": "test-capture-ns/test-capture-pod2",
+					},
 				},
 			},
 			wantErr: false,
@@ -703,9 +712,124 @@ func Test_CaptureToPodTranslator_RenderJob_NodeSelected(t *testing.T) {
 	}
 }
 
+func Test_CaptureToPodTranslator_RenderJob_NativeBackend(t *testing.T) {
+	captureTargetOnNode := &CaptureTargetsOnNode{
+		"node1": {
+			PodIpAddresses: []string{"10.225.0.5", "10.225.0.4"},
+			PodNames: map[string]string{
+				"10.225.0.5": "default/pod2",
+				"10.225.0.4": "default/pod1",
+			},
+			OS: "linux",
+		},
+		"node2": {
+			PodIpAddresses: []string{"10.225.1.4"},
+			PodNames:       map[string]string{"10.225.1.4": "default/pod3"},
+			OS:             "windows",
+		},
+	}
+	env := map[string]string{
+		captureConstants.CaptureBackendEnvKey:    string(retinav1alpha1.CaptureBackendNative),
+		captureConstants.CaptureInterfacesEnvKey: "eth0",
+	}
+
+	k8sClient := fakeclientset.NewSimpleClientset()
+	captureToPodTranslator := NewCaptureToPodTranslatorForTest(k8sClient)
+	if err := captureToPodTranslator.initJobTemplate(&retinav1alpha1.Capture{}); err != nil {
+		t.Fatalf("initJobTemplate() want no error, got error %s", err)
+	}
+	jobs, err := captureToPodTranslator.renderJob(captureTargetOnNode, env)
+	if err != nil {
+		t.Fatalf("renderJob() want no error, got error %s", err)
+	}
+
+	wantJobEnv := map[string]map[string]string{
+		"node1": {
+			captureConstants.CaptureBackendEnvKey:    string(retinav1alpha1.CaptureBackendNative),
+			captureConstants.CaptureInterfacesEnvKey: "eth0",
+			captureConstants.CapturePodsEnvKey:       "10.225.0.4=default/pod1,10.225.0.5=default/pod2",
+			captureConstants.TcpdumpFilterEnvKey:     "(host 10.225.0.5 or host 10.225.0.4)",
+		},
+		"node2": {
+			captureConstants.NetshFilterEnvKey: "IPv4.Address=(10.225.1.4)",
+		},
+	}
+	gotJobEnv := map[string]map[string]string{}
+	for _, job := range jobs {
+		jobEnv := map[string]string{}
+		var nodeName string
+		for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+			switch e.Name {
+			case captureConstants.NodeHostNameEnvKey:
+				nodeName = e.Value
+			case telemetry.EnvPodName, captureConstants.ApiserverEnvKey:
+			default:
+				jobEnv[e.Name] = e.Value
+			}
+		}
+		gotJobEnv[nodeName] = jobEnv
+	}
+	if diff := cmp.Diff(wantJobEnv, gotJobEnv); diff != "" {
+		t.Errorf("renderJob() mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func Test_CaptureToPodTranslator_RenderJob_PodNetworkNamespace(t *testing.T) {
+	captureTargetOnNode := &CaptureTargetsOnNode{
+		"node1": {
+			PodIpAddresses: []string{"10.225.0.4"},
+			PodNames:       map[string]string{"10.225.0.4": "default/pod1"},
+			OS:             "linux",
+		},
+	}
+	env := map[string]string{
+		captureConstants.CaptureBackendEnvKey:             string(retinav1alpha1.CaptureBackendNative),
+		captureConstants.CapturePodNetworkNamespaceEnvKey: "true",
+	}
+
+	k8sClient := fakeclientset.NewSimpleClientset()
+	captureToPodTranslator := NewCaptureToPodTranslatorForTest(k8sClient)
+	if err := captureToPodTranslator.initJobTemplate(&retinav1alpha1.Capture{}); err != nil {
+		t.Fatalf("initJobTemplate() want no error, got error %s", err)
+	}
+	jobs, err := captureToPodTranslator.renderJob(captureTargetOnNode, env)
+	if err != nil {
+		t.Fatalf("renderJob() want no error, got error %s", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("renderJob() want 1 job, got %d", len(jobs))
+	}
+
+	podSpec := jobs[0].Spec.Template.Spec
+	if !podSpec.HostPID {
+		t.Errorf("renderJob() want job sharing the PID namespace of the node")
+	}
+	wantCapabilities := []corev1.Capability{"NET_ADMIN", "SYS_ADMIN", "SYS_PTRACE"}
+	if diff := cmp.Diff(wantCapabilities, podSpec.Containers[0].SecurityContext.Capabilities.Add); diff != "" {
+		t.Errorf("renderJob() capabilities mismatch (-want, +got):\n%s", diff)
+	}
+	for _, e := range podSpec.Containers[0].Env {
+		switch e.Name {
+		case captureConstants.CapturePodsEnvKey:
+			if e.Value != "10.225.0.4=default/pod1" {
+				t.Errorf("renderJob() want Pods %q, got %q", "10.225.0.4=default/pod1", e.Value)
+			}
+		case captureConstants.TcpdumpFilterEnvKey:
+			t.Errorf("renderJob() want no Pod IP addresses in the capture filter, got %q", e.Value)
+		}
+	}
+
+	// Windows nodes cannot capture in the Pod network namespace.
+	(*captureTargetOnNode)["node1"] = CaptureTarget{PodIpAddresses: []string{"10.225.0.4"}, OS: "windows"}
+	if _, err := captureToPodTranslator.renderJob(captureTargetOnNode, env); err == nil {
+		t.Errorf("renderJob() want error for Windows node, got no error")
+	}
+}
+
 func Test_CaptureToPodTranslator_ValidateCapture(t *testing.T) {
 	captureName := "capture-test"
 	hostPath := "/tmp/capture"
+	tcpdumpFilter := "-i any"
 	nodeName := "node-name"
 	cases := []struct {
 		name    string
@@ -802,6 +926,147 @@ func Test_CaptureToPodTranslator_ValidateCapture(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "raise error when tcpdump filter is set for the native backend",
+			capture: retinav1alpha1.Capture{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: captureName,
+				},
+				Spec: retinav1alpha1.CaptureSpec{
+					CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+						CaptureTarget: retinav1alpha1.CaptureTarget{
+							NodeSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"nodename": nodeName,
+								},
+							},
+						},
+						TcpdumpFilter: &tcpdumpFilter,
+						CaptureOption: retinav1alpha1.CaptureOption{
+							Duration: &metav1.Duration{Duration: 10 * time.Second},
+							Backend:  retinav1alpha1.CaptureBackendNative,
+						},
+					},
+					OutputConfiguration: retinav1alpha1.OutputConfiguration{
+						HostPath: &hostPath,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "raise error when interfaces are set for the tcpdump backend",
+			capture: retinav1alpha1.Capture{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: captureName,
+				},
+				Spec: retinav1alpha1.CaptureSpec{
+					CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+						CaptureTarget: retinav1alpha1.CaptureTarget{
+							NodeSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"nodename": nodeName,
+								},
+							},
+						},
+						CaptureOption: retinav1alpha1.CaptureOption{
+							Duration:   &metav1.Duration{Duration: 10 * time.Second},
+							Backend:    retinav1alpha1.CaptureBackendTcpdump,
+							Interfaces: []string{"eth0"},
+						},
+					},
+					OutputConfiguration: retinav1alpha1.OutputConfiguration{
+						HostPath: &hostPath,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "raise error when capturing in the Pod network namespace of nodes",
+			capture: retinav1alpha1.Capture{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: captureName,
+				},
+				Spec: retinav1alpha1.CaptureSpec{
+					CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+						CaptureTarget: retinav1alpha1.CaptureTarget{
+							NodeSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"nodename": nodeName,
+								},
+							},
+						},
+						CaptureOption: retinav1alpha1.CaptureOption{
+							Duration:            &metav1.Duration{Duration: 10 * time.Second},
+							Backend:             retinav1alpha1.CaptureBackendNative,
+							PodNetworkNamespace: true,
+						},
+					},
+					OutputConfiguration: retinav1alpha1.OutputConfiguration{
+						HostPath: &hostPath,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "raise error when capturing in the Pod network namespace with the tcpdump backend",
+			capture: retinav1alpha1.Capture{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: captureName,
+				},
+				Spec: retinav1alpha1.CaptureSpec{
+					CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+						CaptureTarget: retinav1alpha1.CaptureTarget{
+							NamespaceSelector: &metav1.LabelSelector{},
+							PodSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "test",
+								},
+							},
+						},
+						CaptureOption: retinav1alpha1.CaptureOption{
+							Duration:            &metav1.Duration{Duration: 10 * time.Second},
+							Backend:             retinav1alpha1.CaptureBackendTcpdump,
+							PodNetworkNamespace: true,
+						},
+					},
+					OutputConfiguration: retinav1alpha1.OutputConfiguration{
+						HostPath: &hostPath,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "capture in the Pod network namespace with the native backend",
+			capture: retinav1alpha1.Capture{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: captureName,
+				},
+				Spec: retinav1alpha1.CaptureSpec{
+					CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+						CaptureTarget: retinav1alpha1.CaptureTarget{
+							NamespaceSelector: &metav1.LabelSelector{},
+							PodSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{
+									"app": "test",
+								},
+							},
+						},
+						CaptureOption: retinav1alpha1.CaptureOption{
+							Duration:            &metav1.Duration{Duration: 10 * time.Second},
+							Backend:             retinav1alpha1.CaptureBackendNative,
+							PodNetworkNamespace: true,
+						},
+					},
+					OutputConfiguration: retinav1alpha1.OutputConfiguration{
+						HostPath: &hostPath,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range cases {
@@ -1705,8 +1970,23 @@ func Test_CaptureToPodTranslator_TranslateCaptureToJobs(t *testing.T) {
 			}
 			job := commonJob.DeepCopy()
 			job.Spec.Template.Spec.Containers[0].Env = tt.podEnv
-			job.Spec.Template.Spec.Containers[0].VolumeMounts = tt.volumeMounts
-			job.Spec.Template.Spec.Volumes = tt.volumes
+			// Every capture job mounts the capture control volume.
+			job.Spec.Template.Spec.Containers[0].VolumeMounts = append([]corev1.VolumeMount{{
+				Name:      captureConstants.CaptureControlVolumeName,
+				MountPath: captureConstants.CaptureControlPath,
+				ReadOnly:  true,
+			}}, tt.volumeMounts...)
+			job.Spec.Template.Spec.Volumes = append([]corev1.Volume{{
+				Name: captureConstants.CaptureControlVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					DownwardAPI: &corev1.DownwardAPIVolumeSource{
+						Items: []corev1.DownwardAPIVolumeFile{{
+							Path:     captureConstants.CaptureControlAnnotationsFile,
+							FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations"},
+						}},
+					},
+				},
+			}}, tt.volumes...)
 
 			if tt.isWindows {
 				containerAdministrator := "NT AUTHORITY\\SYSTEM"
@@ -1946,7 +2226,7 @@ func Test_CaptureToPodTranslator_ValidateTargetSelector(t *testing.T) {
 	}
 }
 
-func Test_CaptureToPodTranslator_obtainTcpdumpFilters(t *testing.T) {
+func Test_CaptureToPodTranslator_obtainCaptureFilters(t *testing.T) {
 	cases := []struct {
 		name                string
 		captureConfig       retinav1alpha1.CaptureConfiguration
@@ -2056,12 +2336,12 @@ func Test_CaptureToPodTranslator_obtainTcpdumpFilters(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			k8sClient := fakeclientset.NewSimpleClientset()
 			captureToPodTranslator := NewCaptureToPodTranslatorForTest(k8sClient)
-			tcpdumpFilter, err := captureToPodTranslator.obtainTcpdumpFilters(tt.captureConfig)
+			filters, err := captureToPodTranslator.obtainCaptureFilters("default", tt.captureConfig)
 			if tt.wantErr != (err != nil) {
-				t.Errorf("obtainTcpdumpFilters() want(%t) error, got error %s", tt.wantErr, err)
+				t.Errorf("obtainCaptureFilters() want(%t) error, got error %s", tt.wantErr, err)
 			}
 
-			if diff := cmp.Diff(tt.wantedTcpdumpFilter, tcpdumpFilter); diff != "" {
+			if diff := cmp.Diff(tt.wantedTcpdumpFilter, filters.tcpdump); diff != "" {
 				t.Errorf("TranslateCaptureToJobs() mismatch (-want, +got):\n%s", diff)
 			}
 		})
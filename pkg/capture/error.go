@@ -3,7 +3,10 @@
 
 package capture
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 var _ error = SecretNotFoundError{}
 
@@ -27,3 +30,26 @@ type CaptureJobNumExceedLimitError struct {
 func (err CaptureJobNumExceedLimitError) Error() string {
 	return fmt.Sprintf("the number of capture jobs %d exceeds the limit %d", err.CurrentNum, err.Limit)
 }
+
+// CaptureFilterInvalidError is returned when the filters of a Capture are invalid or cannot be rendered for a node.
+var _ error = CaptureFilterInvalidError{}
+
+type CaptureFilterInvalidError struct {
+	Reason string
+}
+
+func (err CaptureFilterInvalidError) Error() string {
+	return fmt.Sprintf("invalid capture filter: %s", err.Reason)
+}
+
+// CapturePolicyDeniedError is returned when a Capture is not allowed by any of the CapturePolicies selecting its
+// namespace.
+var _ error = CapturePolicyDeniedError{}
+
+type CapturePolicyDeniedError struct {
+	Reasons []string
+}
+
+func (err CapturePolicyDeniedError) Error() string {
+	return fmt.Sprintf("capture is denied by CapturePolicies: %s", strings.Join(err.Reasons, "; "))
+}
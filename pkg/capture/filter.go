@@ -0,0 +1,371 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+)
+
+var (
+	tcpdumpProtocols = map[retinav1alpha1.CaptureFilterProtocol]string{
+		retinav1alpha1.CaptureFilterProtocolTCP:    "tcp",
+		retinav1alpha1.CaptureFilterProtocolUDP:    "udp",
+		retinav1alpha1.CaptureFilterProtocolSCTP:   "sctp",
+		retinav1alpha1.CaptureFilterProtocolICMP:   "icmp",
+		retinav1alpha1.CaptureFilterProtocolICMPv6: "icmp6",
+	}
+
+	// netshProtocols are the IP protocol numbers netsh filters on.
+	netshProtocols = map[retinav1alpha1.CaptureFilterProtocol]int{
+		retinav1alpha1.CaptureFilterProtocolTCP:    6,   //nolint:gomnd // IP protocol number
+		retinav1alpha1.CaptureFilterProtocolUDP:    17,  //nolint:gomnd // IP protocol number
+		retinav1alpha1.CaptureFilterProtocolSCTP:   132, //nolint:gomnd // IP protocol number
+		retinav1alpha1.CaptureFilterProtocolICMP:   1,
+		retinav1alpha1.CaptureFilterProtocolICMPv6: 58, //nolint:gomnd // IP protocol number
+	}
+
+	tcpdumpTCPFlags = map[retinav1alpha1.TCPFlag]string{
+		retinav1alpha1.TCPFlagFIN: "tcp-fin",
+		retinav1alpha1.TCPFlagSYN: "tcp-syn",
+		retinav1alpha1.TCPFlagRST: "tcp-rst",
+		retinav1alpha1.TCPFlagPSH: "tcp-push",
+		retinav1alpha1.TCPFlagACK: "tcp-ack",
+		retinav1alpha1.TCPFlagURG: "tcp-urg",
+	}
+)
+
+// captureFilters are the filters of a Capture rendered for each operating system.
+type captureFilters struct {
+	// tcpdump is the pcap-filter expression used by both capture backends on Linux nodes.
+	tcpdump string
+	// netsh is the filter of netsh trace on Windows nodes.
+	netsh string
+	// netshErr tells why the filter rules cannot be expressed by netsh, which fails the capture on Windows nodes.
+	netshErr error
+}
+
+// portRange is an inclusive range of ports.
+type portRange struct {
+	lo, hi uint64
+}
+
+// filterRule is a typed filter rule with its Pods and Services resolved to their IP addresses.
+type filterRule struct {
+	protocol  retinav1alpha1.CaptureFilterProtocol
+	nets      []*net.IPNet
+	ports     []portRange
+	tcpFlags  []retinav1alpha1.TCPFlag
+	direction retinav1alpha1.CaptureFilterDirection
+}
+
+// resolveFilterRules validates the typed filter rules, and resolves the Pods and Services they reference in the
+// namespace of the Capture by default.
+func (translator *CaptureToPodTranslator) resolveFilterRules(namespace string, rules []retinav1alpha1.CaptureFilterRule) ([]filterRule, error) {
+	resolved := make([]filterRule, 0, len(rules))
+	for i := range rules {
+		rule, err := translator.resolveFilterRule(namespace, &rules[i])
+		if err != nil {
+			return nil, CaptureFilterInvalidError{Reason: fmt.Sprintf("rule %d: %s", i, err)}
+		}
+		resolved = append(resolved, rule)
+	}
+	return resolved, nil
+}
+
+func (translator *CaptureToPodTranslator) resolveFilterRule(namespace string, rule *retinav1alpha1.CaptureFilterRule) (filterRule, error) {
+	resolved := filterRule{protocol: rule.Protocol, tcpFlags: rule.TCPFlags, direction: rule.Direction}
+	if len(rule.Protocol) != 0 {
+		if _, ok := tcpdumpProtocols[rule.Protocol]; !ok {
+			return filterRule{}, fmt.Errorf("unsupported protocol %q", rule.Protocol)
+		}
+	}
+	switch rule.Direction {
+	case "":
+		resolved.direction = retinav1alpha1.CaptureFilterDirectionAny
+	case retinav1alpha1.CaptureFilterDirectionAny, retinav1alpha1.CaptureFilterDirectionSource, retinav1alpha1.CaptureFilterDirectionDestination:
+	default:
+		return filterRule{}, fmt.Errorf("unsupported direction %q", rule.Direction)
+	}
+
+	seen := map[string]struct{}{}
+	addNet := func(ipNet *net.IPNet) {
+		if _, ok := seen[ipNet.String()]; ok {
+			return
+		}
+		seen[ipNet.String()] = struct{}{}
+		resolved.nets = append(resolved.nets, ipNet)
+	}
+	for _, cidr := range rule.CIDRs {
+		ipNet, err := parseFilterCIDR(cidr)
+		if err != nil {
+			return filterRule{}, err
+		}
+		addNet(ipNet)
+	}
+	for _, ref := range rule.Pods {
+		ips, err := translator.podIPs(objectNamespace(ref, namespace), ref.Name)
+		if err != nil {
+			return filterRule{}, err
+		}
+		for _, ip := range ips {
+			addNet(hostNet(ip))
+		}
+	}
+	for _, ref := range rule.Services {
+		ips, err := translator.serviceIPs(objectNamespace(ref, namespace), ref.Name)
+		if err != nil {
+			return filterRule{}, err
+		}
+		for _, ip := range ips {
+			addNet(hostNet(ip))
+		}
+	}
+
+	for _, port := range rule.Ports {
+		r, err := parseFilterPortRange(port)
+		if err != nil {
+			return filterRule{}, err
+		}
+		resolved.ports = append(resolved.ports, r)
+	}
+	if len(resolved.ports) != 0 && (rule.Protocol == retinav1alpha1.CaptureFilterProtocolICMP || rule.Protocol == retinav1alpha1.CaptureFilterProtocolICMPv6) {
+		return filterRule{}, fmt.Errorf("ports cannot be matched for protocol %s", rule.Protocol)
+	}
+
+	for _, flag := range rule.TCPFlags {
+		if _, ok := tcpdumpTCPFlags[flag]; !ok {
+			return filterRule{}, fmt.Errorf("unsupported TCP flag %q", flag)
+		}
+	}
+	if len(rule.TCPFlags) != 0 && rule.Protocol != retinav1alpha1.CaptureFilterProtocolTCP {
+		return filterRule{}, fmt.Errorf("TCP flags require protocol %s", retinav1alpha1.CaptureFilterProtocolTCP)
+	}
+
+	if resolved.direction != retinav1alpha1.CaptureFilterDirectionAny && len(resolved.nets) == 0 && len(resolved.ports) == 0 {
+		return filterRule{}, fmt.Errorf("direction %s requires addresses or ports", resolved.direction)
+	}
+	if len(rule.Protocol) == 0 && len(resolved.nets) == 0 && len(resolved.ports) == 0 {
+		return filterRule{}, fmt.Errorf("rule matches all packets")
+	}
+	return resolved, nil
+}
+
+func objectNamespace(ref retinav1alpha1.CaptureFilterObjectReference, namespace string) string {
+	if len(ref.Namespace) != 0 {
+		return ref.Namespace
+	}
+	return namespace
+}
+
+func (translator *CaptureToPodTranslator) podIPs(namespace, name string) ([]net.IP, error) {
+	pod, err := translator.kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Pod %s/%s: %w", namespace, name, err)
+	}
+	var ips []net.IP
+	for _, podIP := range pod.Status.PodIPs {
+		if ip := net.ParseIP(podIP.IP); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("Pod %s/%s has no IP addresses", namespace, name)
+	}
+	return ips, nil
+}
+
+// serviceIPs returns the cluster IP addresses of the Service and the addresses of its endpoints.
+func (translator *CaptureToPodTranslator) serviceIPs(namespace, name string) ([]net.IP, error) {
+	svc, err := translator.kubeClient.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Service %s/%s: %w", namespace, name, err)
+	}
+	var ips []net.IP
+	for _, clusterIP := range svc.Spec.ClusterIPs {
+		if ip := net.ParseIP(clusterIP); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+
+	endpointSlices, err := translator.kubeClient.DiscoveryV1().EndpointSlices(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", discoveryv1.LabelServiceName, name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EndpointSlices of Service %s/%s: %w", namespace, name, err)
+	}
+	for i := range endpointSlices.Items {
+		for _, endpoint := range endpointSlices.Items[i].Endpoints {
+			for _, address := range endpoint.Addresses {
+				if ip := net.ParseIP(address); ip != nil {
+					ips = append(ips, ip)
+				}
+			}
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("Service %s/%s has no IP addresses", namespace, name)
+	}
+	return ips, nil
+}
+
+// parseFilterCIDR parses an IP address or a network in CIDR notation, whose host bits are ignored.
+func parseFilterCIDR(cidr string) (*net.IPNet, error) {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", cidr)
+		}
+		return hostNet(ip), nil
+	}
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q", cidr)
+	}
+	if ip4 := ipNet.IP.To4(); ip4 != nil {
+		ipNet.IP = ip4
+	}
+	return ipNet, nil
+}
+
+func hostNet(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(8*net.IPv4len, 8*net.IPv4len)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(8*net.IPv6len, 8*net.IPv6len)}
+}
+
+func isHostNet(ipNet *net.IPNet) bool {
+	ones, bits := ipNet.Mask.Size()
+	return ones == bits
+}
+
+// parseFilterPortRange parses a port, e.g. 80, or a port range, e.g. 8000-8080.
+func parseFilterPortRange(port string) (portRange, error) {
+	bounds := strings.SplitN(port, "-", 2) //nolint:gomnd // lower and upper bounds
+	lo, err := strconv.ParseUint(bounds[0], 10, 16)
+	if err != nil {
+		return portRange{}, fmt.Errorf("invalid port %q", port)
+	}
+	hi := lo
+	if len(bounds) == 2 { //nolint:gomnd // lower and upper bounds
+		if hi, err = strconv.ParseUint(bounds[1], 10, 16); err != nil || hi < lo {
+			return portRange{}, fmt.Errorf("invalid port range %q", port)
+		}
+	}
+	return portRange{lo: lo, hi: hi}, nil
+}
+
+// tcpdump renders the rule as a pcap-filter expression in parentheses, like the IP:Port filters.
+func (rule *filterRule) tcpdump() string {
+	dir := ""
+	switch rule.direction {
+	case retinav1alpha1.CaptureFilterDirectionSource:
+		dir = "src "
+	case retinav1alpha1.CaptureFilterDirectionDestination:
+		dir = "dst "
+	}
+
+	var terms []string
+	if len(rule.protocol) != 0 {
+		terms = append(terms, tcpdumpProtocols[rule.protocol])
+	}
+	if len(rule.nets) != 0 {
+		alternatives := make([]string, 0, len(rule.nets))
+		for _, ipNet := range rule.nets {
+			if isHostNet(ipNet) {
+				alternatives = append(alternatives, fmt.Sprintf("%shost %s", dir, ipNet.IP))
+			} else {
+				alternatives = append(alternatives, fmt.Sprintf("%snet %s", dir, ipNet))
+			}
+		}
+		terms = append(terms, tcpdumpAlternatives(alternatives))
+	}
+	if len(rule.ports) != 0 {
+		alternatives := make([]string, 0, len(rule.ports))
+		for _, r := range rule.ports {
+			if r.lo == r.hi {
+				alternatives = append(alternatives, fmt.Sprintf("%sport %d", dir, r.lo))
+			} else {
+				alternatives = append(alternatives, fmt.Sprintf("%sportrange %d-%d", dir, r.lo, r.hi))
+			}
+		}
+		terms = append(terms, tcpdumpAlternatives(alternatives))
+	}
+	if len(rule.tcpFlags) != 0 {
+		flags := make([]string, 0, len(rule.tcpFlags))
+		for _, flag := range rule.tcpFlags {
+			flags = append(flags, tcpdumpTCPFlags[flag])
+		}
+		terms = append(terms, fmt.Sprintf("tcp[tcpflags] & (%s) != 0", strings.Join(flags, "|")))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(terms, " and "))
+}
+
+func tcpdumpAlternatives(alternatives []string) string {
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+	return fmt.Sprintf("(%s)", strings.Join(alternatives, " or "))
+}
+
+// netshFilter renders the typed filter rules as a netsh trace filter. netsh ANDs its filters, and matches lists of
+// IP addresses and protocols only, so a single include rule of protocol and addresses is supported.
+// Check `netsh trace show capturefilterhelp` for the detail.
+func netshFilter(includeRules, excludeRules []filterRule) (string, error) {
+	if len(excludeRules) != 0 {
+		return "", fmt.Errorf("exclude rules are not supported by netsh")
+	}
+	if len(includeRules) == 0 {
+		return "", nil
+	}
+	if len(includeRules) > 1 {
+		return "", fmt.Errorf("only a single include rule is supported by netsh")
+	}
+	rule := includeRules[0]
+	if len(rule.ports) != 0 || len(rule.tcpFlags) != 0 {
+		return "", fmt.Errorf("ports and TCP flags are not supported by netsh")
+	}
+
+	var filters []string
+	if len(rule.protocol) != 0 {
+		filters = append(filters, fmt.Sprintf("Protocol=%d", netshProtocols[rule.protocol]))
+	}
+	var ipv4Addresses, ipv6Addresses []string
+	for _, ipNet := range rule.nets {
+		if !isHostNet(ipNet) {
+			return "", fmt.Errorf("network %s is not supported by netsh, which matches IP addresses only", ipNet)
+		}
+		if ipNet.IP.To4() != nil {
+			ipv4Addresses = append(ipv4Addresses, ipNet.IP.String())
+		} else {
+			ipv6Addresses = append(ipv6Addresses, ipNet.IP.String())
+		}
+	}
+	if len(ipv4Addresses) != 0 && len(ipv6Addresses) != 0 {
+		return "", fmt.Errorf("IPv4 and IPv6 addresses cannot be matched by the same netsh filter")
+	}
+	field := "Address"
+	switch rule.direction {
+	case retinav1alpha1.CaptureFilterDirectionSource:
+		field = "SourceAddress"
+	case retinav1alpha1.CaptureFilterDirectionDestination:
+		field = "DestinationAddress"
+	}
+	if len(ipv4Addresses) != 0 {
+		filters = append(filters, fmt.Sprintf("IPv4.%s=(%s)", field, strings.Join(ipv4Addresses, ",")))
+	}
+	if len(ipv6Addresses) != 0 {
+		filters = append(filters, fmt.Sprintf("IPv6.%s=(%s)", field, strings.Join(ipv6Addresses, ",")))
+	}
+	return strings.Join(filters, " "), nil
+}
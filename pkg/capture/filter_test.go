@@ -0,0 +1,190 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+)
+
+func TestObtainCaptureFiltersRules(t *testing.T) {
+	k8sClient := fakeclientset.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "client", Namespace: "default"},
+			Status:     corev1.PodStatus{PodIPs: []corev1.PodIP{{IP: "10.224.0.10"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"},
+			Spec:       corev1.ServiceSpec{ClusterIPs: []string{"10.0.0.20"}},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web-abcde",
+				Namespace: "app",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "web"},
+			},
+			Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"10.224.0.30", "10.224.0.31"}}},
+		},
+	)
+
+	cases := []struct {
+		name        string
+		filters     *retinav1alpha1.CaptureConfigurationFilters
+		wantTcpdump string
+		wantNetsh   string
+		wantNetshOK bool
+		wantErr     bool
+	}{
+		{
+			name: "protocol, CIDRs and ports",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{
+					Protocol: retinav1alpha1.CaptureFilterProtocolTCP,
+					CIDRs:    []string{"10.1.2.3/16", "fd00::1"},
+					Ports:    []string{"443", "8000-8080"},
+				}},
+			},
+			wantTcpdump: "((tcp and (net 10.1.0.0/16 or host fd00::1) and (port 443 or portrange 8000-8080)))",
+		},
+		{
+			name: "direction and TCP flags",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{
+					Protocol:  retinav1alpha1.CaptureFilterProtocolTCP,
+					Ports:     []string{"80"},
+					TCPFlags:  []retinav1alpha1.TCPFlag{retinav1alpha1.TCPFlagSYN, retinav1alpha1.TCPFlagRST},
+					Direction: retinav1alpha1.CaptureFilterDirectionDestination,
+				}},
+			},
+			wantTcpdump: "((tcp and dst port 80 and tcp[tcpflags] & (tcp-syn|tcp-rst) != 0))",
+		},
+		{
+			name: "Pod and Service references",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{
+					Pods:      []retinav1alpha1.CaptureFilterObjectReference{{Name: "client"}},
+					Services:  []retinav1alpha1.CaptureFilterObjectReference{{Namespace: "app", Name: "web"}},
+					Direction: retinav1alpha1.CaptureFilterDirectionSource,
+				}},
+			},
+			wantTcpdump: "(((src host 10.224.0.10 or src host 10.0.0.20 or src host 10.224.0.30 or src host 10.224.0.31)))",
+			wantNetsh:   "IPv4.SourceAddress=(10.224.0.10,10.0.0.20,10.224.0.30,10.224.0.31)",
+			wantNetshOK: true,
+		},
+		{
+			name: "rules with IP:Port filters",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				Include:      []string{"192.168.0.1:80"},
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{Protocol: retinav1alpha1.CaptureFilterProtocolUDP}},
+				ExcludeRules: []retinav1alpha1.CaptureFilterRule{{Protocol: retinav1alpha1.CaptureFilterProtocolICMP, CIDRs: []string{"10.0.0.1"}}},
+			},
+			wantTcpdump: "((host 192.168.0.1 and port 80) or (udp)) and not ((icmp and host 10.0.0.1))",
+		},
+		{
+			name: "protocol only for netsh",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{Protocol: retinav1alpha1.CaptureFilterProtocolUDP, CIDRs: []string{"fd00::1"}}},
+			},
+			wantTcpdump: "((udp and host fd00::1))",
+			wantNetsh:   "Protocol=17 IPv6.Address=(fd00::1)",
+			wantNetshOK: true,
+		},
+		{
+			name: "empty rule",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid CIDR",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{CIDRs: []string{"10.0.0.0/33"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid port range",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{Ports: []string{"8080-8000"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ports of ICMP",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{Protocol: retinav1alpha1.CaptureFilterProtocolICMP, Ports: []string{"80"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "TCP flags without TCP",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{Ports: []string{"80"}, TCPFlags: []retinav1alpha1.TCPFlag{retinav1alpha1.TCPFlagSYN}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "direction without addresses or ports",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{
+					Protocol:  retinav1alpha1.CaptureFilterProtocolTCP,
+					Direction: retinav1alpha1.CaptureFilterDirectionSource,
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Pod without IP addresses",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{Pods: []retinav1alpha1.CaptureFilterObjectReference{{Name: "pending"}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing Service",
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{Services: []retinav1alpha1.CaptureFilterObjectReference{{Name: "web"}}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			captureToPodTranslator := NewCaptureToPodTranslatorForTest(k8sClient)
+			filters, err := captureToPodTranslator.obtainCaptureFilters("default", retinav1alpha1.CaptureConfiguration{Filters: tt.filters})
+			if tt.wantErr {
+				var invalidErr CaptureFilterInvalidError
+				if !errors.As(err, &invalidErr) {
+					t.Fatalf("obtainCaptureFilters() want CaptureFilterInvalidError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("obtainCaptureFilters() want no error, got %s", err)
+			}
+			if diff := cmp.Diff(tt.wantTcpdump, filters.tcpdump); diff != "" {
+				t.Errorf("obtainCaptureFilters() tcpdump filter mismatch (-want, +got):\n%s", diff)
+			}
+			if tt.wantNetshOK != (filters.netshErr == nil) {
+				t.Errorf("obtainCaptureFilters() want netsh filter supported %t, got error %v", tt.wantNetshOK, filters.netshErr)
+			}
+			if diff := cmp.Diff(tt.wantNetsh, filters.netsh); diff != "" {
+				t.Errorf("obtainCaptureFilters() netsh filter mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+)
+
+// maxCaptureUploadMessageLength bounds the upload errors in the capture job result, as the termination message of a
+// container is limited to 4096 bytes.
+const maxCaptureUploadMessageLength = 256
+
+// CaptureJobControl controls a running capture job. It is set in the annotations of the capture Pod, which are exposed
+// to the capture job through the capture control volume.
+type CaptureJobControl struct {
+	// Stop requests the capture job to stop capturing and store the captured network packets.
+	Stop bool
+	// Duration overrides the duration of the capture from its start, if not zero.
+	Duration time.Duration
+}
+
+// Annotations returns the annotations of the capture Pod for the control.
+func (c CaptureJobControl) Annotations() map[string]string {
+	annotations := map[string]string{}
+	if c.Stop {
+		annotations[captureConstants.CaptureStopAnnotation] = strconv.FormatBool(c.Stop)
+	}
+	if c.Duration != 0 {
+		annotations[captureConstants.CaptureDurationAnnotation] = c.Duration.String()
+	}
+	return annotations
+}
+
+// CaptureJobControlFromAnnotations returns the control set in the annotations of the capture Pod.
+func CaptureJobControlFromAnnotations(annotations map[string]string) (CaptureJobControl, error) {
+	control := CaptureJobControl{}
+	if stop, ok := annotations[captureConstants.CaptureStopAnnotation]; ok {
+		var err error
+		if control.Stop, err = strconv.ParseBool(stop); err != nil {
+			return control, fmt.Errorf("invalid annotation %s: %w", captureConstants.CaptureStopAnnotation, err)
+		}
+	}
+	if duration, ok := annotations[captureConstants.CaptureDurationAnnotation]; ok {
+		var err error
+		if control.Duration, err = time.ParseDuration(duration); err != nil {
+			return control, fmt.Errorf("invalid annotation %s: %w", captureConstants.CaptureDurationAnnotation, err)
+		}
+	}
+	return control, nil
+}
+
+// readCaptureJobControl reads the control from the annotations file of the capture control volume, in which the
+// kubelet writes each annotation as a key="value" line with the value quoted.
+func readCaptureJobControl(path string) (CaptureJobControl, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return CaptureJobControl{}, err //nolint:wrapcheck // the path is in the error
+	}
+	defer f.Close()
+
+	annotations := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+		if value, err = strconv.Unquote(value); err != nil {
+			continue
+		}
+		annotations[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return CaptureJobControl{}, fmt.Errorf("failed to read capture control: %w", err)
+	}
+	return CaptureJobControlFromAnnotations(annotations)
+}
+
+// CaptureJobResult is the result of a capture job. The capture job writes it to the termination message of the capture
+// container, from which the capture reconciler reports the progress of the Capture on the node.
+type CaptureJobResult struct {
+	// PacketsCaptured is only set by the capture backends counting the captured network packets.
+	PacketsCaptured *int64                               `json:"packetsCaptured,omitempty"`
+	BytesCaptured   int64                                `json:"bytesCaptured"`
+	Uploads         []retinav1alpha1.CaptureUploadStatus `json:"uploads,omitempty"`
+}
+
+// ParseCaptureJobResult parses the result from the termination message of the capture container.
+func ParseCaptureJobResult(message string) (*CaptureJobResult, error) {
+	result := &CaptureJobResult{}
+	if err := json.Unmarshal([]byte(message), result); err != nil {
+		return nil, fmt.Errorf("invalid capture job result: %w", err)
+	}
+	return result, nil
+}
+
+func (r *CaptureJobResult) write(path string) error {
+	for i := range r.Uploads {
+		if len(r.Uploads[i].Message) > maxCaptureUploadMessageLength {
+			r.Uploads[i].Message = r.Uploads[i].Message[:maxCaptureUploadMessageLength]
+		}
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal capture job result: %w", err)
+	}
+	return os.WriteFile(path, data, 0o600) //nolint:wrapcheck // the path is in the error
+}
@@ -0,0 +1,82 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+)
+
+func TestCaptureJobControlAnnotations(t *testing.T) {
+	cases := []struct {
+		name    string
+		control CaptureJobControl
+	}{
+		{
+			name:    "no control",
+			control: CaptureJobControl{},
+		},
+		{
+			name:    "stop",
+			control: CaptureJobControl{Stop: true},
+		},
+		{
+			name:    "extend",
+			control: CaptureJobControl{Duration: 90 * time.Second},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CaptureJobControlFromAnnotations(tt.control.Annotations())
+			if err != nil {
+				t.Fatalf("CaptureJobControlFromAnnotations() want no error, got %s", err)
+			}
+			if diff := cmp.Diff(tt.control, got); diff != "" {
+				t.Errorf("CaptureJobControlFromAnnotations() mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := CaptureJobControlFromAnnotations(map[string]string{captureConstants.CaptureDurationAnnotation: "forever"}); err == nil {
+		t.Errorf("CaptureJobControlFromAnnotations() want error for invalid duration, got no error")
+	}
+}
+
+func TestCaptureJobResult(t *testing.T) {
+	packets := int64(10)
+	result := &CaptureJobResult{
+		PacketsCaptured: &packets,
+		BytesCaptured:   1024,
+		Uploads: []retinav1alpha1.CaptureUploadStatus{
+			{Location: "HostPath", Succeeded: true},
+			{Location: "BlobUpload", Message: string(make([]byte, 2*maxCaptureUploadMessageLength))},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "termination-log")
+	if err := result.write(path); err != nil {
+		t.Fatalf("write() want no error, got %s", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseCaptureJobResult(string(got))
+	if err != nil {
+		t.Fatalf("ParseCaptureJobResult() want no error, got %s", err)
+	}
+	if len(parsed.Uploads[1].Message) != maxCaptureUploadMessageLength {
+		t.Errorf("ParseCaptureJobResult() want upload message truncated to %d bytes, got %d", maxCaptureUploadMessageLength, len(parsed.Uploads[1].Message))
+	}
+	if diff := cmp.Diff(result, parsed); diff != "" {
+		t.Errorf("ParseCaptureJobResult() mismatch (-want, +got):\n%s", diff)
+	}
+}
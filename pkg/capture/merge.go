@@ -0,0 +1,270 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// pcapngMagic is the block type of the section header block starting a pcapng file, in either byte order.
+var pcapngMagic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+// ErrNoCaptureFiles is returned when the capture artifacts contain no pcap or pcapng files to merge.
+var ErrNoCaptureFiles = errors.New("no pcap or pcapng files in the capture artifacts")
+
+// MergeCaptureArtifacts merges the pcap and pcapng files in the capture artifacts of all nodes into a single pcapng
+// file written to w, ordering the network packets by their timestamp. Each interface of the merged file has the name
+// of the capture file it comes from as comment, to tell the nodes and Pods apart. It returns the number of merged
+// network packets.
+func MergeCaptureArtifacts(w io.Writer, artifactPaths []string) (int, error) {
+	tmpDir, err := os.MkdirTemp("", "retina-capture-merge")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var files []string
+	for _, artifactPath := range artifactPaths {
+		extracted, err := extractCaptureFiles(artifactPath, tmpDir)
+		if err != nil {
+			return 0, err
+		}
+		files = append(files, extracted...)
+	}
+	if len(files) == 0 {
+		return 0, ErrNoCaptureFiles
+	}
+
+	sources := make(packetSourceHeap, 0, len(files))
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return 0, err //nolint:wrapcheck // the path is in the error
+		}
+		defer f.Close()
+
+		source, err := newPacketSource(f, filepath.Base(file))
+		if err != nil {
+			return 0, err
+		}
+		if source == nil {
+			continue
+		}
+		if ok, err := source.next(); err != nil {
+			return 0, err
+		} else if ok {
+			sources = append(sources, source)
+		}
+	}
+	heap.Init(&sources)
+
+	bw := bufio.NewWriter(w)
+	var ngw *pcapgo.NgWriter
+	packets := 0
+	for sources.Len() != 0 {
+		source := sources[0]
+		intf, err := source.intf(source.ci.InterfaceIndex)
+		if err != nil {
+			return packets, err
+		}
+		if ngw == nil {
+			if ngw, err = pcapgo.NewNgWriterInterface(bw, intf, pcapgo.DefaultNgWriterOptions); err != nil {
+				return packets, fmt.Errorf("failed to create pcapng writer: %w", err)
+			}
+			source.interfaces[source.ci.InterfaceIndex] = 0
+		}
+		id, ok := source.interfaces[source.ci.InterfaceIndex]
+		if !ok {
+			if id, err = ngw.AddInterface(intf); err != nil {
+				return packets, fmt.Errorf("failed to add interface of %s: %w", source.name, err)
+			}
+			source.interfaces[source.ci.InterfaceIndex] = id
+		}
+
+		ci := source.ci
+		ci.InterfaceIndex = id
+		if err := ngw.WritePacket(ci, source.data); err != nil {
+			return packets, fmt.Errorf("failed to write packet: %w", err)
+		}
+		packets++
+
+		if ok, err := source.next(); err != nil {
+			return packets, err
+		} else if ok {
+			heap.Fix(&sources, 0)
+		} else {
+			heap.Pop(&sources)
+		}
+	}
+	if ngw == nil {
+		return 0, nil
+	}
+	if err := ngw.Flush(); err != nil {
+		return packets, fmt.Errorf("failed to flush pcapng writer: %w", err)
+	}
+	return packets, bw.Flush() //nolint:wrapcheck // the writer is owned by the caller
+}
+
+// extractCaptureFiles extracts the pcap and pcapng files of a capture artifact into the directory, and returns their
+// paths. The files are named after the artifact and their path in it.
+func extractCaptureFiles(artifactPath, dir string) ([]string, error) {
+	f, err := os.Open(artifactPath)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the path is in the error
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture artifact %s: %w", artifactPath, err)
+	}
+	defer gz.Close()
+
+	artifactName := strings.TrimSuffix(filepath.Base(artifactPath), CaptureArtifactExtension)
+	var files []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read capture artifact %s: %w", artifactPath, err)
+		}
+		if header.Typeflag != tar.TypeReg || !strings.Contains(filepath.Base(header.Name), ".pcap") {
+			continue
+		}
+
+		name := artifactName + "_" + strings.ReplaceAll(filepath.ToSlash(filepath.Clean(header.Name)), "/", "_")
+		path := filepath.Join(dir, name)
+		out, err := os.Create(path)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // the path is in the error
+		}
+		//nolint:gosec // the capture files are bounded by the size of the capture artifact
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return nil, fmt.Errorf("failed to extract %s from capture artifact %s: %w", header.Name, artifactPath, err)
+		}
+		if err := out.Close(); err != nil {
+			return nil, err //nolint:wrapcheck // the path is in the error
+		}
+		files = append(files, path)
+	}
+}
+
+// packetSource reads the network packets of a pcap or pcapng file.
+type packetSource struct {
+	name   string
+	reader gopacket.PacketDataSource
+	// ng is only set for pcapng files, which may have several interfaces.
+	ng *pcapgo.NgReader
+	// pcap is only set for pcap files.
+	pcap *pcapgo.Reader
+	// interfaces maps the interfaces of the file to the interfaces of the merged file.
+	interfaces map[int]int
+
+	data []byte
+	ci   gopacket.CaptureInfo
+}
+
+// newPacketSource returns the packet source of a pcap or pcapng file, or nil if the file has no packets.
+func newPacketSource(r io.Reader, name string) (*packetSource, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(pcapngMagic))
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	source := &packetSource{name: name, interfaces: map[int]int{}}
+	if bytes.Equal(magic, pcapngMagic) {
+		ngOptions := pcapgo.DefaultNgReaderOptions
+		ngOptions.WantMixedLinkType = true
+		source.ng, err = pcapgo.NewNgReader(br, ngOptions)
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		source.reader = source.ng
+	} else {
+		source.pcap, err = pcapgo.NewReader(br)
+		source.reader = source.pcap
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return source, nil
+}
+
+// next reads the next network packet, and returns false at the end of the file.
+func (s *packetSource) next() (bool, error) {
+	data, ci, err := s.reader.ReadPacketData()
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		// A capture file may be truncated when the capture is stopped.
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read packet of %s: %w", s.name, err)
+	}
+	s.data, s.ci = data, ci
+	return true, nil
+}
+
+// intf returns the interface of the file with the given index, to be added to the merged file.
+func (s *packetSource) intf(index int) (pcapgo.NgInterface, error) {
+	if s.pcap != nil {
+		return pcapgo.NgInterface{
+			Comment:    s.name,
+			LinkType:   s.pcap.LinkType(),
+			SnapLength: s.pcap.Snaplen(),
+		}, nil
+	}
+	intf, err := s.ng.Interface(index)
+	if err != nil {
+		return pcapgo.NgInterface{}, fmt.Errorf("failed to get interface %d of %s: %w", index, s.name, err)
+	}
+	return pcapgo.NgInterface{
+		Name:        intf.Name,
+		Comment:     s.name,
+		Description: intf.Description,
+		Filter:      intf.Filter,
+		OS:          intf.OS,
+		LinkType:    intf.LinkType,
+		SnapLength:  intf.SnapLength,
+	}, nil
+}
+
+// packetSourceHeap orders the packet sources by the timestamp of their next network packet.
+type packetSourceHeap []*packetSource
+
+func (h packetSourceHeap) Len() int { return len(h) }
+
+func (h packetSourceHeap) Less(i, j int) bool { return h[i].ci.Timestamp.Before(h[j].ci.Timestamp) }
+
+func (h packetSourceHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *packetSourceHeap) Push(x any) { *h = append(*h, x.(*packetSource)) }
+
+func (h *packetSourceHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
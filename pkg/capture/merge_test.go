@@ -0,0 +1,154 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/microsoft/retina/pkg/utils"
+)
+
+// writeTestCaptureArtifact writes the capture artifact of the node, with a capture file holding a packet at each of
+// the timestamps, in pcapng format if ng is set.
+func writeTestCaptureArtifact(t *testing.T, dir, nodeName string, ng bool, timestamps ...time.Time) string {
+	t.Helper()
+
+	srcDir := filepath.Join(dir, "capture-"+nodeName+"-20240101000000UTC")
+	if err := os.Mkdir(srcDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "ip-resources.txt"), []byte("metadata"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	fileName := filepath.Base(srcDir) + ".pcap"
+	if ng {
+		fileName += "ng"
+		w, err := pcapgo.NewNgWriter(&buf, layers.LinkTypeEthernet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ts := range timestamps {
+			if err := w.WritePacket(testCaptureInfo(ts, nodeName), []byte(nodeName)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		w := pcapgo.NewWriterNanos(&buf)
+		if err := w.WriteFileHeader(65535, layers.LinkTypeEthernet); err != nil {
+			t.Fatal(err)
+		}
+		for _, ts := range timestamps {
+			if err := w.WritePacket(testCaptureInfo(ts, nodeName), []byte(nodeName)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, fileName), buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	artifactPath := srcDir + CaptureArtifactExtension
+	if err := utils.CompressFolderToTarGz(srcDir, artifactPath); err != nil {
+		t.Fatal(err)
+	}
+	return artifactPath
+}
+
+func testCaptureInfo(ts time.Time, data string) gopacket.CaptureInfo {
+	return gopacket.CaptureInfo{Timestamp: ts, CaptureLength: len(data), Length: len(data)}
+}
+
+func TestMergeCaptureArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Unix(1700000000, 0).UTC()
+	at := func(seconds int) time.Time {
+		return start.Add(time.Duration(seconds) * time.Second)
+	}
+	artifactPaths := []string{
+		writeTestCaptureArtifact(t, dir, "node1", false, at(1), at(3)),
+		writeTestCaptureArtifact(t, dir, "node2", true, at(2), at(4)),
+	}
+
+	var merged bytes.Buffer
+	packets, err := MergeCaptureArtifacts(&merged, artifactPaths)
+	if err != nil {
+		t.Fatalf("MergeCaptureArtifacts() want no error, got %s", err)
+	}
+	if packets != 4 {
+		t.Errorf("MergeCaptureArtifacts() want 4 packets, got %d", packets)
+	}
+
+	r, err := pcapgo.NewNgReader(&merged, pcapgo.DefaultNgReaderOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		ts      time.Time
+		node    string
+		comment string
+	}{
+		{at(1), "node1", "capture-node1-20240101000000UTC_capture-node1-20240101000000UTC.pcap"},
+		{at(2), "node2", "capture-node2-20240101000000UTC_capture-node2-20240101000000UTC.pcapng"},
+		{at(3), "node1", "capture-node1-20240101000000UTC_capture-node1-20240101000000UTC.pcap"},
+		{at(4), "node2", "capture-node2-20240101000000UTC_capture-node2-20240101000000UTC.pcapng"},
+	}
+	for i := 0; ; i++ {
+		data, ci, err := r.ReadPacketData()
+		if errors.Is(err, io.EOF) {
+			if i != len(want) {
+				t.Fatalf("want %d packets, got %d", len(want), i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(want) {
+			t.Fatalf("want %d packets, got more", len(want))
+		}
+		if !ci.Timestamp.Equal(want[i].ts) || string(data) != want[i].node {
+			t.Errorf("packet %d: want %s from %s, got %s from %s", i, want[i].ts, want[i].node, ci.Timestamp, data)
+		}
+		intf, err := r.Interface(ci.InterfaceIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if intf.Comment != want[i].comment {
+			t.Errorf("packet %d: want interface comment %s, got %s", i, want[i].comment, intf.Comment)
+		}
+	}
+}
+
+func TestMergeCaptureArtifactsNoCaptureFiles(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "capture-node1-20240101000000UTC")
+	if err := os.Mkdir(srcDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "ip-resources.txt"), []byte("metadata"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	artifactPath := srcDir + CaptureArtifactExtension
+	if err := utils.CompressFolderToTarGz(srcDir, artifactPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MergeCaptureArtifacts(io.Discard, []string{artifactPath}); !errors.Is(err, ErrNoCaptureFiles) {
+		t.Errorf("MergeCaptureArtifacts() want ErrNoCaptureFiles, got %v", err)
+	}
+}
@@ -85,6 +85,38 @@ func (bu *BlobUpload) Output(srcFilePath string) error {
 	return nil
 }
 
+func (bu *BlobUpload) Delete(artifactPrefix string) error {
+	blobURL, err := readBlobSASURL()
+	if err != nil {
+		return err
+	}
+	if err = validateBlobSASURL(blobURL); err != nil {
+		return err
+	}
+	azClient, err := azblob.NewClientWithNoCredential(blobURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create blob client: %w", err)
+	}
+
+	pager := azClient.NewListBlobsFlatPager("", &azblob.ListBlobsFlatOptions{Prefix: &artifactPrefix})
+	for pager.More() {
+		page, err := pager.NextPage(context.TODO())
+		if err != nil {
+			return fmt.Errorf("failed to list blobs: %w", err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil || !isCaptureArtifact(artifactPrefix, *item.Name) {
+				continue
+			}
+			if _, err := azClient.DeleteBlob(context.TODO(), "", *item.Name, nil); err != nil {
+				return fmt.Errorf("failed to delete blob %s: %w", *item.Name, err)
+			}
+			bu.l.Info("Deleted capture file", zap.String("location", bu.Name()), zap.String("blob name", *item.Name))
+		}
+	}
+	return nil
+}
+
 func trimBlobSASURL(blobSASURL string) string {
 	// Blob SAS URL from the secret created from a file can have a newline and is surrounded by double quotes,
 	// so we need to trim \" and \n and trimming spaces is for unexpected spaces in the URL by customers.
@@ -96,6 +128,16 @@ func trimBlobSASURL(blobSASURL string) string {
 	return trimedSecret
 }
 
+// BlobContainerSASURL returns the SAS URL of the blob container stored in the blob upload secret, to which the capture
+// artifacts are uploaded.
+func BlobContainerSASURL(secret string) (string, error) {
+	blobSASURL := trimBlobSASURL(secret)
+	if err := validateBlobSASURL(blobSASURL); err != nil {
+		return "", err
+	}
+	return blobSASURL, nil
+}
+
 func readBlobSASURL() (string, error) {
 	secretPath := filepath.Join(captureConstants.CaptureOutputLocationBlobUploadSecretPath, captureConstants.CaptureOutputLocationBlobUploadSecretKey)
 	if runtime.GOOS == "windows" {
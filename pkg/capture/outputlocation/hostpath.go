@@ -62,3 +62,12 @@ func (hp *HostPath) Output(srcFilePath string) error {
 	_, err = io.Copy(destFile, srcFile)
 	return err
 }
+
+func (hp *HostPath) Delete(artifactPrefix string) error {
+	hostPath := os.Getenv(string(captureConstants.CaptureOutputLocationEnvKeyHostPath))
+	deleted, err := deleteCaptureArtifacts(hostPath, artifactPrefix)
+	for _, fileName := range deleted {
+		hp.l.Info("Deleted capture file", zap.String("location", hp.Name()), zap.String("file name", fileName))
+	}
+	return err
+}
@@ -3,6 +3,17 @@
 
 package outputlocation
 
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// captureArtifactSuffixRegexp matches the rest of the file name of a capture artifact following the prefix of the
+// Capture and the node, which is the time the capture started.
+var captureArtifactSuffixRegexp = regexp.MustCompile(`^[0-9]{14}UTC\.tar\.gz$`)
+
 type Location interface {
 	// Name returns the name of the output location.
 	Name() string
@@ -10,4 +21,35 @@ type Location interface {
 	Enabled() bool
 	// Output outputs source file to the location specified by the users.
 	Output(srcFilePath string) error
+	// Delete deletes the capture artifacts stored to the location whose file names start with the prefix, which is
+	// made of the names of the Capture and the node.
+	Delete(artifactPrefix string) error
+}
+
+// isCaptureArtifact returns whether the file is a capture artifact with the prefix.
+func isCaptureArtifact(artifactPrefix, fileName string) bool {
+	return strings.HasPrefix(fileName, artifactPrefix) && captureArtifactSuffixRegexp.MatchString(fileName[len(artifactPrefix):])
+}
+
+// deleteCaptureArtifacts deletes the capture artifacts with the prefix in the directory, and returns the names of the
+// deleted files.
+func deleteCaptureArtifacts(dir, artifactPrefix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err //nolint:wrapcheck // the path is in the error
+	}
+	var deleted []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isCaptureArtifact(artifactPrefix, entry.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return deleted, err //nolint:wrapcheck // the path is in the error
+		}
+		deleted = append(deleted, entry.Name())
+	}
+	return deleted, nil
 }
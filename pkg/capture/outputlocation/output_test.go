@@ -5,6 +5,7 @@ package outputlocation
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -127,3 +128,35 @@ func TestOutput(t *testing.T) {
 		})
 	}
 }
+
+func TestHostPathDelete(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	dir := t.TempDir()
+	t.Setenv(string(captureConstants.CaptureOutputLocationEnvKeyHostPath), dir)
+
+	files := map[string]bool{
+		"capture-node1-20240101000000UTC.tar.gz":       true,
+		"capture-node1-20240102000000UTC.tar.gz":       true,
+		"capture-node2-20240101000000UTC.tar.gz":       false,
+		"capture-node1-2-20240101000000UTC.tar.gz":     false,
+		"capture-node1-20240101000000UTC.pcap":         false,
+		"other-capture-node1-20240101000000UTC.tar.gz": false,
+	}
+	for name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hp := NewHostPath(log.Logger().Named(string(captureConstants.CaptureOutputLocationEnvKeyHostPath)))
+	if err := hp.Delete("capture-node1-"); err != nil {
+		t.Fatalf("Delete() want no error, got %s", err)
+	}
+	for name, deleted := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.Equal(t, deleted, os.IsNotExist(err), "Delete() of %s", name)
+	}
+
+	t.Setenv(string(captureConstants.CaptureOutputLocationEnvKeyHostPath), filepath.Join(dir, "missing"))
+	assert.NoError(t, hp.Delete("capture-node1-"), "Delete() of a missing host path")
+}
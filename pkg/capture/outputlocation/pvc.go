@@ -61,3 +61,11 @@ func (pvc *PersistentVolumeClaim) Output(srcFilePath string) error {
 	_, err = io.Copy(destFile, srcFile)
 	return err
 }
+
+func (pvc *PersistentVolumeClaim) Delete(artifactPrefix string) error {
+	deleted, err := deleteCaptureArtifacts(captureConstants.PersistentVolumeClaimVolumeMountPathLinux, artifactPrefix)
+	for _, fileName := range deleted {
+		pvc.l.Info("Deleted capture file", zap.String("location", pvc.Name()), zap.String("file name", fileName))
+	}
+	return err
+}
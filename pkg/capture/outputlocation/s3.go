@@ -11,6 +11,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -117,15 +118,55 @@ func (su *S3Upload) Output(srcFilePath string) error {
 	return nil
 }
 
+func (su *S3Upload) Delete(artifactPrefix string) error {
+	s3Client, err := su.getClient()
+	if err != nil {
+		return err
+	}
+
+	// The object keys of the capture artifacts are the path followed by the path of the tarball in the capture job,
+	// so they are matched by their base name.
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(su.bucket)}
+	if su.path != "" {
+		input.Prefix = aws.String(strings.TrimSuffix(su.path, "/") + "/")
+	}
+	paginator := s3.NewListObjectsV2Paginator(s3Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return fmt.Errorf("failed to list S3 objects: %w", err)
+		}
+		for _, object := range page.Contents {
+			if object.Key == nil || !isCaptureArtifact(artifactPrefix, path.Base(*object.Key)) {
+				continue
+			}
+			if _, err := s3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+				Bucket: aws.String(su.bucket),
+				Key:    object.Key,
+			}); err != nil {
+				return fmt.Errorf("failed to delete S3 object %s: %w", *object.Key, err)
+			}
+			su.l.Info("Deleted capture file", zap.String("location", su.Name()), zap.String("objectKey", *object.Key))
+		}
+	}
+	return nil
+}
+
 func (su *S3Upload) getClient() (*s3.Client, error) {
+	return NewS3Client(su.endpoint, su.region, su.accessKeyID, su.secretAccessKey)
+}
+
+// NewS3Client returns the client of the S3 compatible storage service at the endpoint, or of AWS S3 in the region if
+// the endpoint is empty. The default AWS credentials are used if the access key ID is empty.
+func NewS3Client(endpoint, region, accessKeyID, secretAccessKey string) (*s3.Client, error) {
 	var opts []func(options *config.LoadOptions) error
 
-	if su.endpoint != "" {
+	if endpoint != "" {
 		opts = append(opts,
 			config.WithEndpointResolverWithOptions(
 				aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
 					return aws.Endpoint{
-						URL:               su.endpoint,
+						URL:               endpoint,
 						HostnameImmutable: true,
 					}, nil
 				}),
@@ -133,16 +174,16 @@ func (su *S3Upload) getClient() (*s3.Client, error) {
 		)
 	}
 
-	if su.region != "" {
-		opts = append(opts, config.WithRegion(su.region))
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
 	} else {
 		opts = append(opts, config.WithRegion("auto"))
 	}
 
-	if su.accessKeyID != "" {
+	if accessKeyID != "" {
 		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			su.accessKeyID,
-			su.secretAccessKey,
+			accessKeyID,
+			secretAccessKey,
 			"",
 		)))
 	}
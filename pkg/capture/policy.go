@@ -0,0 +1,219 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+)
+
+// capturedPods are the Pods a Capture selects by PodSelector or ServiceSelector.
+type capturedPods struct {
+	// names are the <namespace>/<name> of the Pods.
+	names []string
+	// unknownIPs are the IP addresses of the selected Service endpoints not backed by known Pods.
+	unknownIPs []string
+}
+
+// EnforceCapturePolicies checks the Capture is allowed by at least one of the CapturePolicies selecting its namespace,
+// and returns a CapturePolicyDeniedError with the reasons of each CapturePolicy otherwise. Captures in namespaces
+// selected by no CapturePolicy are allowed. The filters of a Capture allowed by a CapturePolicy only narrow the
+// traffic of its targets once it is translated, instead of capturing more traffic along with them.
+func (translator *CaptureToPodTranslator) EnforceCapturePolicies(capture *retinav1alpha1.Capture, policies []retinav1alpha1.CapturePolicy) error {
+	translator.scopeFiltersToTargets = false
+	if len(policies) == 0 {
+		return nil
+	}
+	namespaceLabels := map[string]labels.Set{}
+	getNamespaceLabels := func(name string) (labels.Set, error) {
+		if nsLabels, ok := namespaceLabels[name]; ok {
+			return nsLabels, nil
+		}
+		namespace, err := translator.kubeClient.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get namespace %s: %w", name, err)
+		}
+		namespaceLabels[name] = namespace.Labels
+		return namespace.Labels, nil
+	}
+
+	captureNamespaceLabels, err := getNamespaceLabels(capture.Namespace)
+	if err != nil {
+		return err
+	}
+	var selectedPolicies []*retinav1alpha1.CapturePolicy
+	for i := range policies {
+		selected, err := selectorMatches(policies[i].Spec.NamespaceSelector, captureNamespaceLabels)
+		if err != nil {
+			return fmt.Errorf("invalid namespaceSelector of CapturePolicy %s: %w", policies[i].Name, err)
+		}
+		if selected {
+			selectedPolicies = append(selectedPolicies, &policies[i])
+		}
+	}
+	if len(selectedPolicies) == 0 {
+		return nil
+	}
+	translator.scopeFiltersToTargets = true
+
+	pods, err := translator.capturedPods(capture.Spec.CaptureConfiguration.CaptureTarget)
+	if err != nil {
+		return err
+	}
+	var reasons []string
+	for _, policy := range selectedPolicies {
+		reason, err := translator.capturePolicyDenies(capture, policy, pods, getNamespaceLabels)
+		if err != nil {
+			return err
+		}
+		if len(reason) == 0 {
+			translator.l.Info(fmt.Sprintf("Capture %s/%s is allowed by CapturePolicy %s", capture.Namespace, capture.Name, policy.Name))
+			return nil
+		}
+		reasons = append(reasons, fmt.Sprintf("CapturePolicy %s: %s", policy.Name, reason))
+	}
+	return CapturePolicyDeniedError{Reasons: reasons}
+}
+
+// capturedPods returns the Pods the capture target selects by PodSelector or ServiceSelector.
+func (translator *CaptureToPodTranslator) capturedPods(captureTarget retinav1alpha1.CaptureTarget) (capturedPods, error) {
+	if captureTarget.PodSelector == nil && captureTarget.ServiceSelector == nil {
+		return capturedPods{}, nil
+	}
+	if err := translator.validateTargetSelector(captureTarget); err != nil {
+		return capturedPods{}, err
+	}
+	captureTargetsOnNode, err := translator.getCaptureTargetsOnNode(captureTarget)
+	if err != nil {
+		return capturedPods{}, err
+	}
+
+	names := sets.New[string]()
+	var unknownIPs []string
+	for _, target := range *captureTargetsOnNode {
+		for _, podIP := range target.PodIpAddresses {
+			if podName, ok := target.PodNames[podIP]; ok {
+				names.Insert(podName)
+			} else {
+				unknownIPs = append(unknownIPs, podIP)
+			}
+		}
+	}
+	slices.Sort(unknownIPs)
+	return capturedPods{names: sets.List(names), unknownIPs: unknownIPs}, nil
+}
+
+// capturePolicyDenies returns why the CapturePolicy does not allow the Capture to capture the Pods, or an empty reason
+// when it is allowed.
+func (translator *CaptureToPodTranslator) capturePolicyDenies(capture *retinav1alpha1.Capture, policy *retinav1alpha1.CapturePolicy, pods capturedPods, getNamespaceLabels func(string) (labels.Set, error)) (string, error) {
+	if allowed := policy.Spec.AllowedOutputLocations; len(allowed) != 0 {
+		for _, outputLocation := range OutputLocations(capture.Spec.OutputConfiguration) {
+			if !slices.Contains(allowed, outputLocation) {
+				return fmt.Sprintf("output location %s is not allowed", outputLocation), nil
+			}
+		}
+	}
+
+	targets := policy.Spec.Targets
+	if capture.Spec.CaptureConfiguration.CaptureTarget.NodeSelector != nil && !targets.AllowNodes {
+		return "capturing nodes is not allowed", nil
+	}
+	if len(pods.unknownIPs) != 0 {
+		return fmt.Sprintf("endpoints %s are not backed by known Pods", strings.Join(pods.unknownIPs, ",")), nil
+	}
+	// The raw tcpdump filter is passed to tcpdump as is, so it cannot be restricted to the traffic of the targets.
+	if capture.Spec.CaptureConfiguration.TcpdumpFilter != nil {
+		return "raw tcpdump filter is not allowed", nil
+	}
+
+	namespaceAllowed := func(namespace string) (bool, error) {
+		if targets.NamespaceSelector == nil {
+			return namespace == capture.Namespace, nil
+		}
+		nsLabels, err := getNamespaceLabels(namespace)
+		if err != nil {
+			return false, err
+		}
+		allowed, err := selectorMatches(targets.NamespaceSelector, nsLabels)
+		if err != nil {
+			return false, fmt.Errorf("invalid targets.namespaceSelector of CapturePolicy %s: %w", policy.Name, err)
+		}
+		return allowed, nil
+	}
+
+	// The Pods and Services of the filter rules are resolved to their IP addresses, which must not be read from
+	// namespaces the Capture is not allowed to capture.
+	if filters := capture.Spec.CaptureConfiguration.Filters; filters != nil {
+		for _, rule := range slices.Concat(filters.IncludeRules, filters.ExcludeRules) {
+			for _, ref := range slices.Concat(rule.Pods, rule.Services) {
+				namespace := objectNamespace(ref, capture.Namespace)
+				allowed, err := namespaceAllowed(namespace)
+				if err != nil {
+					return "", err
+				}
+				if !allowed {
+					return fmt.Sprintf("%s/%s of the filter rules is not in an allowed namespace", namespace, ref.Name), nil
+				}
+			}
+		}
+	}
+
+	// The Pods allowed by the CapturePolicy in each namespace, listed once per namespace.
+	allowedPods := map[string]sets.Set[string]{}
+	for _, pod := range pods.names {
+		namespace, name, _ := strings.Cut(pod, "/")
+		allowed, err := namespaceAllowed(namespace)
+		if err != nil {
+			return "", err
+		}
+		if !allowed {
+			if targets.NamespaceSelector == nil {
+				return fmt.Sprintf("Pod %s is not in namespace %s of the Capture", pod, capture.Namespace), nil
+			}
+			return fmt.Sprintf("Pod %s is not in an allowed namespace", pod), nil
+		}
+
+		if targets.PodSelector == nil {
+			continue
+		}
+		if _, ok := allowedPods[namespace]; !ok {
+			podSelector, err := metav1.LabelSelectorAsSelector(targets.PodSelector)
+			if err != nil {
+				return "", fmt.Errorf("invalid targets.podSelector of CapturePolicy %s: %w", policy.Name, err)
+			}
+			podList, err := translator.kubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: podSelector.String()})
+			if err != nil {
+				return "", fmt.Errorf("failed to list Pods in namespace %s: %w", namespace, err)
+			}
+			allowedPods[namespace] = sets.New[string]()
+			for i := range podList.Items {
+				allowedPods[namespace].Insert(podList.Items[i].Name)
+			}
+		}
+		if !allowedPods[namespace].Has(name) {
+			return fmt.Sprintf("Pod %s is not selected by the allowed Pod selector", pod), nil
+		}
+	}
+	return "", nil
+}
+
+// selectorMatches tells if the label selector selects the labels, where a nil selector selects everything.
+func selectorMatches(labelSelector *metav1.LabelSelector, set labels.Set) (bool, error) {
+	if labelSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(set), nil
+}
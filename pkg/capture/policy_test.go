@@ -0,0 +1,305 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	pointerUtil "k8s.io/utils/pointer"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+)
+
+func policyTestObjects() []runtime.Object {
+	pod := func(namespace, name, ip, app string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": app}},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{PodIP: ip, PodIPs: []corev1.PodIP{{IP: ip}}},
+		}
+	}
+	return []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"tenant": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b", Labels: map[string]string{"tenant": "b"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared", Labels: map[string]string{"shared": "true"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{corev1.LabelHostname: "node1", corev1.LabelOSStable: "linux"}}},
+		pod("tenant-a", "web", "10.224.0.10", "web"),
+		pod("tenant-a", "db", "10.224.0.11", "db"),
+		pod("tenant-b", "web", "10.224.0.20", "web"),
+		pod("shared", "web", "10.224.0.30", "web"),
+	}
+}
+
+func TestEnforceCapturePolicies(t *testing.T) {
+	tenantPolicy := retinav1alpha1.CapturePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenants"},
+		Spec: retinav1alpha1.CapturePolicySpec{
+			NamespaceSelector:      &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tenant", Operator: metav1.LabelSelectorOpExists}}},
+			Targets:                retinav1alpha1.CapturePolicyTargets{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			AllowedOutputLocations: []retinav1alpha1.CaptureOutputLocation{retinav1alpha1.CaptureOutputLocationBlobUpload},
+		},
+	}
+	sharedPolicy := retinav1alpha1.CapturePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec: retinav1alpha1.CapturePolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+			Targets: retinav1alpha1.CapturePolicyTargets{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"shared": "true"}},
+			},
+		},
+	}
+
+	cases := []struct {
+		name       string
+		namespace  string
+		target        retinav1alpha1.CaptureTarget
+		filters       *retinav1alpha1.CaptureConfigurationFilters
+		tcpdumpFilter *string
+		output        retinav1alpha1.OutputConfiguration
+		policies      []retinav1alpha1.CapturePolicy
+		wantDenied    bool
+	}{
+		{
+			name:      "no policies",
+			namespace: "tenant-a",
+			target:    retinav1alpha1.CaptureTarget{NodeSelector: &metav1.LabelSelector{}},
+			output:    retinav1alpha1.OutputConfiguration{HostPath: pointerUtil.String("/tmp/capture")},
+		},
+		{
+			name:      "namespace not selected by the policies",
+			namespace: "shared",
+			target:    retinav1alpha1.CaptureTarget{NodeSelector: &metav1.LabelSelector{}},
+			output:    retinav1alpha1.OutputConfiguration{HostPath: pointerUtil.String("/tmp/capture")},
+			policies:  []retinav1alpha1.CapturePolicy{tenantPolicy},
+		},
+		{
+			name:      "allowed Pods in the namespace of the Capture",
+			namespace: "tenant-a",
+			target: retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			output:   retinav1alpha1.OutputConfiguration{BlobUpload: pointerUtil.String("blob-sas")},
+			policies: []retinav1alpha1.CapturePolicy{tenantPolicy},
+		},
+		{
+			name:      "Pods not selected by the policy",
+			namespace: "tenant-a",
+			target: retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+				PodSelector:       &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpExists}}},
+			},
+			output:     retinav1alpha1.OutputConfiguration{BlobUpload: pointerUtil.String("blob-sas")},
+			policies:   []retinav1alpha1.CapturePolicy{tenantPolicy},
+			wantDenied: true,
+		},
+		{
+			name:      "Pods in another namespace",
+			namespace: "tenant-a",
+			target: retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "b"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			output:     retinav1alpha1.OutputConfiguration{BlobUpload: pointerUtil.String("blob-sas")},
+			policies:   []retinav1alpha1.CapturePolicy{tenantPolicy, sharedPolicy},
+			wantDenied: true,
+		},
+		{
+			name:      "Pods in a namespace allowed by another policy",
+			namespace: "tenant-a",
+			target: retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"shared": "true"}},
+				PodSelector:       &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpExists}}},
+			},
+			output:   retinav1alpha1.OutputConfiguration{HostPath: pointerUtil.String("/tmp/capture")},
+			policies: []retinav1alpha1.CapturePolicy{tenantPolicy, sharedPolicy},
+		},
+		{
+			name:      "output location not allowed",
+			namespace: "tenant-b",
+			target: retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "b"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			output:     retinav1alpha1.OutputConfiguration{HostPath: pointerUtil.String("/tmp/capture")},
+			policies:   []retinav1alpha1.CapturePolicy{tenantPolicy},
+			wantDenied: true,
+		},
+		{
+			name:       "nodes not allowed",
+			namespace:  "tenant-b",
+			target:     retinav1alpha1.CaptureTarget{NodeSelector: &metav1.LabelSelector{}},
+			output:     retinav1alpha1.OutputConfiguration{BlobUpload: pointerUtil.String("blob-sas")},
+			policies:   []retinav1alpha1.CapturePolicy{tenantPolicy},
+			wantDenied: true,
+		},
+		{
+			name:          "raw tcpdump filter",
+			namespace:     "tenant-a",
+			target:        retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			tcpdumpFilter: pointerUtil.String("-i any"),
+			output:        retinav1alpha1.OutputConfiguration{BlobUpload: pointerUtil.String("blob-sas")},
+			policies:      []retinav1alpha1.CapturePolicy{tenantPolicy},
+			wantDenied:    true,
+		},
+		{
+			name:      "filter rule Pod in another namespace",
+			namespace: "tenant-a",
+			target:    retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{Pods: []retinav1alpha1.CaptureFilterObjectReference{{Namespace: "tenant-b", Name: "web"}}}},
+			},
+			output:     retinav1alpha1.OutputConfiguration{BlobUpload: pointerUtil.String("blob-sas")},
+			policies:   []retinav1alpha1.CapturePolicy{tenantPolicy},
+			wantDenied: true,
+		},
+		{
+			name:      "filter rule Service in another namespace",
+			namespace: "tenant-a",
+			target:    retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				ExcludeRules: []retinav1alpha1.CaptureFilterRule{{Services: []retinav1alpha1.CaptureFilterObjectReference{{Namespace: "kube-system", Name: "kube-dns"}}}},
+			},
+			output:     retinav1alpha1.OutputConfiguration{BlobUpload: pointerUtil.String("blob-sas")},
+			policies:   []retinav1alpha1.CapturePolicy{tenantPolicy},
+			wantDenied: true,
+		},
+		{
+			name:      "filters within the namespace of the Capture",
+			namespace: "tenant-a",
+			target:    retinav1alpha1.CaptureTarget{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			filters: &retinav1alpha1.CaptureConfigurationFilters{
+				Include:      []string{"10.224.0.20:80", "*:443"},
+				IncludeRules: []retinav1alpha1.CaptureFilterRule{{CIDRs: []string{"0.0.0.0/0"}}, {Pods: []retinav1alpha1.CaptureFilterObjectReference{{Name: "db"}}}},
+			},
+			output:   retinav1alpha1.OutputConfiguration{BlobUpload: pointerUtil.String("blob-sas")},
+			policies: []retinav1alpha1.CapturePolicy{tenantPolicy},
+		},
+	}
+
+	captureToPodTranslator := NewCaptureToPodTranslatorForTest(fakeclientset.NewSimpleClientset(policyTestObjects()...))
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			capture := &retinav1alpha1.Capture{
+				ObjectMeta: metav1.ObjectMeta{Name: "capture-test", Namespace: tt.namespace},
+				Spec: retinav1alpha1.CaptureSpec{
+					CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+						CaptureTarget: tt.target,
+						Filters:       tt.filters,
+						TcpdumpFilter: tt.tcpdumpFilter,
+					},
+					OutputConfiguration: tt.output,
+				},
+			}
+			err := captureToPodTranslator.EnforceCapturePolicies(capture, tt.policies)
+			var deniedErr CapturePolicyDeniedError
+			if denied := errors.As(err, &deniedErr); denied != tt.wantDenied {
+				t.Errorf("EnforceCapturePolicies() want denied %t, got %v", tt.wantDenied, err)
+			}
+			if err != nil && !tt.wantDenied {
+				t.Errorf("EnforceCapturePolicies() want no error, got %s", err)
+			}
+			if tt.wantDenied && len(deniedErr.Reasons) != len(tt.policies) {
+				t.Errorf("EnforceCapturePolicies() want a reason per policy, got %v", deniedErr.Reasons)
+			}
+		})
+	}
+}
+
+func TestEnforceCapturePoliciesScopesFilters(t *testing.T) {
+	policy := retinav1alpha1.CapturePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenants"},
+		Spec: retinav1alpha1.CapturePolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tenant", Operator: metav1.LabelSelectorOpExists}}},
+		},
+	}
+	newCapture := func(namespace string) *retinav1alpha1.Capture {
+		return &retinav1alpha1.Capture{
+			ObjectMeta: metav1.ObjectMeta{Name: "capture-test", Namespace: namespace},
+			Spec: retinav1alpha1.CaptureSpec{
+				CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+					CaptureTarget: retinav1alpha1.CaptureTarget{
+						NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: namespace}},
+						PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+					},
+					Filters: &retinav1alpha1.CaptureConfigurationFilters{
+						Include:      []string{"*:443"},
+						IncludeRules: []retinav1alpha1.CaptureFilterRule{{CIDRs: []string{"10.0.0.0/8"}}},
+					},
+					CaptureOption: retinav1alpha1.CaptureOption{Duration: &metav1.Duration{Duration: time.Minute}},
+				},
+				OutputConfiguration: retinav1alpha1.OutputConfiguration{HostPath: pointerUtil.String("/tmp/capture")},
+			},
+		}
+	}
+
+	cases := []struct {
+		name       string
+		namespace  string
+		wantFilter string
+	}{
+		{
+			// The filters of Captures restricted by CapturePolicies only narrow the traffic of the Pods.
+			name:       "namespace selected by the policy",
+			namespace:  "tenant-a",
+			wantFilter: "((host 10.224.0.10)) and (((port 443) or (net 10.0.0.0/8)))",
+		},
+		{
+			name:       "namespace selected by no policy",
+			namespace:  "shared",
+			wantFilter: "((port 443) or (net 10.0.0.0/8)) or (host 10.224.0.30)",
+		},
+	}
+
+	objects := policyTestObjects()
+	for _, obj := range objects {
+		if namespace, ok := obj.(*corev1.Namespace); ok {
+			namespace.Labels[corev1.LabelMetadataName] = namespace.Name
+		}
+	}
+	captureToPodTranslator := NewCaptureToPodTranslatorForTest(fakeclientset.NewSimpleClientset(objects...))
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			capture := newCapture(tt.namespace)
+			if err := captureToPodTranslator.EnforceCapturePolicies(capture, []retinav1alpha1.CapturePolicy{policy}); err != nil {
+				t.Fatalf("EnforceCapturePolicies() want no error, got %s", err)
+			}
+			jobs, err := captureToPodTranslator.TranslateCaptureToJobs(capture)
+			if err != nil {
+				t.Fatalf("TranslateCaptureToJobs() want no error, got %s", err)
+			}
+			if len(jobs) != 1 {
+				t.Fatalf("TranslateCaptureToJobs() want 1 job, got %d", len(jobs))
+			}
+			var gotFilter string
+			for _, env := range jobs[0].Spec.Template.Spec.Containers[0].Env {
+				if env.Name == captureConstants.TcpdumpFilterEnvKey {
+					gotFilter = env.Value
+				}
+			}
+			if gotFilter != tt.wantFilter {
+				t.Errorf("tcpdump filter want %q, got %q", tt.wantFilter, gotFilter)
+			}
+		})
+	}
+}
@@ -0,0 +1,723 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/bpf"
+)
+
+// This file compiles the capture filters generated by Retina into classic BPF programs for the native capture backend.
+// It supports the subset of the pcap-filter(7) syntax used by Retina:
+//
+//	expr      := and ("or" and)*
+//	and       := unary ("and" unary)*
+//	unary     := "not" unary | "(" expr ")" | primitive
+//	primitive := [proto] [src|dst] (host ADDR | net CIDR | port N | portrange N-M) | proto | tcpflags
+//	proto     := ip | ip6 | tcp | udp | sctp | icmp | icmp6
+//	tcpflags  := "tcp[tcpflags] & (" FLAG ("|" FLAG)* ") != 0"
+//	FLAG      := tcp-fin | tcp-syn | tcp-rst | tcp-push | tcp-ack | tcp-urg
+//
+// "&&", "||" and "!" are accepted as aliases of "and", "or" and "not". Like tcpdump, ports are only matched on
+// unfragmented IPv4 packets and IPv6 packets without extension headers, and TCP flags on unfragmented IPv4 packets.
+
+const (
+	// bpfAcceptLength is returned by the filter for accepted packets. The packets are truncated in user space,
+	// so the original length of the packets is still reported by the socket.
+	bpfAcceptLength = 0x40000
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+
+	ipProtoICMP   = 1
+	ipProtoTCP    = 6
+	ipProtoUDP    = 17
+	ipProtoICMPv6 = 58
+	ipProtoSCTP   = 132
+
+	// maxShortJump is the longest distance of the conditional jumps of classic BPF.
+	maxShortJump = 255
+)
+
+var errUnexpectedEndOfFilter = errors.New("unexpected end of filter")
+
+// tcpFlagBits are the bits of the TCP flags in the 14th byte of the TCP header.
+var tcpFlagBits = map[string]uint32{
+	"tcp-fin":  0x01,
+	"tcp-syn":  0x02,
+	"tcp-rst":  0x04,
+	"tcp-push": 0x08,
+	"tcp-ack":  0x10,
+	"tcp-urg":  0x20,
+}
+
+// linkLayer is the link layer of the packets the filter runs against.
+type linkLayer int
+
+const (
+	// linkLayerEthernet packets start with an Ethernet header, as on Ethernet, veth and loopback interfaces.
+	linkLayerEthernet linkLayer = iota
+	// linkLayerRaw packets start with the IP header, as on tun and WireGuard interfaces.
+	linkLayerRaw
+)
+
+func (ll linkLayer) headerLength() uint32 {
+	if ll == linkLayerEthernet {
+		return 14 //nolint:gomnd // Ethernet header length
+	}
+	return 0
+}
+
+type ipFamily int
+
+const (
+	ipv4 ipFamily = iota
+	ipv6
+)
+
+type direction int
+
+const (
+	srcOrDst direction = iota
+	src
+	dst
+)
+
+// compileFilter compiles the capture filter to a BPF program for packets of the given link layer.
+// An empty filter accepts all packets.
+func compileFilter(filter string, ll linkLayer) ([]bpf.RawInstruction, error) {
+	p := &filterParser{tokens: tokenizeFilter(filter)}
+	var expr filterExpr
+	if len(p.tokens) != 0 {
+		var err error
+		if expr, err = p.parseOr(); err != nil {
+			return nil, fmt.Errorf("invalid capture filter %q: %w", filter, err)
+		}
+		if tok, ok := p.peek(); ok {
+			return nil, fmt.Errorf("invalid capture filter %q: unexpected %q", filter, tok)
+		}
+	}
+
+	c := &bpfCompiler{ll: ll}
+	accept, reject := c.newLabel(), c.newLabel()
+	if expr != nil {
+		expr.compile(c, accept, reject)
+	}
+	c.placeLabel(accept)
+	c.emit(bpf.RetConstant{Val: bpfAcceptLength})
+	c.placeLabel(reject)
+	c.emit(bpf.RetConstant{Val: 0})
+
+	prog, err := bpf.Assemble(c.resolve())
+	if err != nil {
+		return nil, fmt.Errorf("failed to assemble capture filter %q: %w", filter, err)
+	}
+	return prog, nil
+}
+
+func tokenizeFilter(filter string) []string {
+	var tokens []string
+	// The "!=" of the TCP flags primitive is not the "!" operator.
+	for i, part := range strings.Split(filter, "!=") {
+		if i != 0 {
+			tokens = append(tokens, "!=")
+		}
+		for _, op := range []string{"(", ")", "&&", "||", "!"} {
+			part = strings.ReplaceAll(part, op, " "+op+" ")
+		}
+		tokens = append(tokens, strings.Fields(part)...)
+	}
+	return tokens
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *filterParser) next() (string, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return "", errUnexpectedEndOfFilter
+	}
+	p.pos++
+	return tok, nil
+}
+
+func (p *filterParser) accept(tokens ...string) bool {
+	tok, ok := p.peek()
+	if !ok {
+		return false
+	}
+	for _, t := range tokens {
+		if tok == t {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and", "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if p.accept("not", "!") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		return expr, nil
+	}
+	return p.parsePrimitive()
+}
+
+func (p *filterParser) parsePrimitive() (filterExpr, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok == "tcp[tcpflags]" {
+		return p.parseTCPFlags()
+	}
+
+	var protos []uint32
+	proto := ""
+	switch tok {
+	case "ip", "ip6", "tcp", "udp", "sctp", "icmp", "icmp6":
+		proto = tok
+		// A protocol which doesn't qualify a primitive matches all packets of the protocol, e.g. "tcp and port 80".
+		next, ok := p.peek()
+		if !ok || !isQualifiable(next) {
+			return protoExpr(proto), nil
+		}
+		tok, _ = p.next()
+	}
+	switch proto {
+	case "tcp":
+		protos = []uint32{ipProtoTCP}
+	case "udp":
+		protos = []uint32{ipProtoUDP}
+	case "sctp":
+		protos = []uint32{ipProtoSCTP}
+	}
+
+	dir := srcOrDst
+	switch tok {
+	case "src":
+		dir = src
+	case "dst":
+		dir = dst
+	}
+	if dir != srcOrDst {
+		if tok, err = p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch tok {
+	case "host", "net":
+		if protos != nil || proto == "icmp" || proto == "icmp6" {
+			return nil, fmt.Errorf("%q cannot qualify %q", proto, tok)
+		}
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		return parseAddrExpr(tok, value, dir, proto)
+	case "port", "portrange":
+		if proto != "" && protos == nil {
+			return nil, fmt.Errorf("%q cannot qualify %q", proto, tok)
+		}
+		if protos == nil {
+			protos = []uint32{ipProtoTCP, ipProtoUDP, ipProtoSCTP}
+		}
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		return parsePortExpr(tok, value, dir, protos)
+	}
+
+	return nil, fmt.Errorf("unsupported filter primitive %q", tok)
+}
+
+// parseTCPFlags parses the rest of the TCP flags primitive, matching TCP packets with any of the flags set.
+func (p *filterParser) parseTCPFlags() (filterExpr, error) {
+	if !p.accept("&") {
+		return nil, errors.New(`expected "&" after "tcp[tcpflags]"`)
+	}
+	paren := p.accept("(")
+	flags, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if paren && !p.accept(")") {
+		return nil, errors.New("missing closing parenthesis")
+	}
+	if !p.accept("!=") || !p.accept("0") {
+		return nil, errors.New(`expected "!= 0" after the TCP flags`)
+	}
+
+	var mask uint32
+	for _, flag := range strings.Split(flags, "|") {
+		bit, ok := tcpFlagBits[flag]
+		if !ok {
+			return nil, fmt.Errorf("invalid TCP flag %q", flag)
+		}
+		mask |= bit
+	}
+	return andExpr{
+		andExpr{andExpr{familyExpr{ipv4}, ipProtoExpr{ipv4, []uint32{ipProtoTCP}}}, notExpr{fragmentExpr{}}},
+		tcpFlagsExpr{mask},
+	}, nil
+}
+
+func isQualifiable(tok string) bool {
+	switch tok {
+	case "src", "dst", "host", "net", "port", "portrange":
+		return true
+	}
+	return false
+}
+
+// protoExpr matches packets of the protocol.
+func protoExpr(proto string) filterExpr {
+	switch proto {
+	case "ip":
+		return familyExpr{ipv4}
+	case "ip6":
+		return familyExpr{ipv6}
+	case "icmp":
+		return andExpr{familyExpr{ipv4}, ipProtoExpr{ipv4, []uint32{ipProtoICMP}}}
+	case "icmp6":
+		return andExpr{familyExpr{ipv6}, ipProtoExpr{ipv6, []uint32{ipProtoICMPv6}}}
+	case "tcp":
+		return ipProtoOfAnyFamily(ipProtoTCP)
+	case "udp":
+		return ipProtoOfAnyFamily(ipProtoUDP)
+	default:
+		return ipProtoOfAnyFamily(ipProtoSCTP)
+	}
+}
+
+func ipProtoOfAnyFamily(proto uint32) filterExpr {
+	return orExpr{
+		andExpr{familyExpr{ipv4}, ipProtoExpr{ipv4, []uint32{proto}}},
+		andExpr{familyExpr{ipv6}, ipProtoExpr{ipv6, []uint32{proto}}},
+	}
+}
+
+func parseAddrExpr(kind, value string, dir direction, proto string) (filterExpr, error) {
+	var ipNet *net.IPNet
+	if kind == "host" {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid host %q", value)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			bits = 8 * net.IPv4len
+		}
+		ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	} else {
+		var err error
+		if _, ipNet, err = net.ParseCIDR(value); err != nil {
+			return nil, fmt.Errorf("invalid net %q: %w", value, err)
+		}
+	}
+
+	family, addr := ipv6, ipNet.IP.To16()
+	if ip4 := ipNet.IP.To4(); ip4 != nil {
+		family, addr = ipv4, ip4
+	}
+	if (proto == "ip" && family != ipv4) || (proto == "ip6" && family != ipv6) {
+		return nil, fmt.Errorf("%q does not match the address family of %q", proto, value)
+	}
+
+	match := func(d direction) filterExpr {
+		return addrExpr{family: family, dir: d, addr: addr, mask: ipNet.Mask}
+	}
+	return andExpr{familyExpr{family}, eitherDirection(dir, match)}, nil
+}
+
+func parsePortExpr(kind, value string, dir direction, protos []uint32) (filterExpr, error) {
+	var lo, hi uint64
+	var err error
+	if kind == "port" {
+		if lo, err = strconv.ParseUint(value, 10, 16); err != nil {
+			return nil, fmt.Errorf("invalid port %q", value)
+		}
+		hi = lo
+	} else {
+		bounds := strings.SplitN(value, "-", 2) //nolint:gomnd // lower and upper bounds
+		if len(bounds) != 2 {                   //nolint:gomnd // lower and upper bounds
+			return nil, fmt.Errorf("invalid port range %q", value)
+		}
+		lo, err = strconv.ParseUint(bounds[0], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port range %q", value)
+		}
+		hi, err = strconv.ParseUint(bounds[1], 10, 16)
+		if err != nil || hi < lo {
+			return nil, fmt.Errorf("invalid port range %q", value)
+		}
+	}
+
+	portOf := func(family ipFamily) filterExpr {
+		match := func(d direction) filterExpr {
+			return portExpr{family: family, dir: d, lo: uint32(lo), hi: uint32(hi)}
+		}
+		expr := andExpr{familyExpr{family}, ipProtoExpr{family, protos}}
+		if family == ipv4 {
+			expr = andExpr{expr, notExpr{fragmentExpr{}}}
+		}
+		return andExpr{expr, eitherDirection(dir, match)}
+	}
+	return orExpr{portOf(ipv4), portOf(ipv6)}, nil
+}
+
+func eitherDirection(dir direction, match func(direction) filterExpr) filterExpr {
+	if dir != srcOrDst {
+		return match(dir)
+	}
+	return orExpr{match(src), match(dst)}
+}
+
+// filterExpr is a node of a parsed filter, which compiles to code jumping to t when the packet matches, and to f otherwise.
+type filterExpr interface {
+	compile(c *bpfCompiler, t, f label)
+}
+
+type andExpr struct{ left, right filterExpr }
+
+func (e andExpr) compile(c *bpfCompiler, t, f label) {
+	next := c.newLabel()
+	e.left.compile(c, next, f)
+	c.placeLabel(next)
+	e.right.compile(c, t, f)
+}
+
+type orExpr struct{ left, right filterExpr }
+
+func (e orExpr) compile(c *bpfCompiler, t, f label) {
+	next := c.newLabel()
+	e.left.compile(c, t, next)
+	c.placeLabel(next)
+	e.right.compile(c, t, f)
+}
+
+type notExpr struct{ expr filterExpr }
+
+func (e notExpr) compile(c *bpfCompiler, t, f label) {
+	e.expr.compile(c, f, t)
+}
+
+// familyExpr matches IPv4 or IPv6 packets.
+type familyExpr struct{ family ipFamily }
+
+func (e familyExpr) compile(c *bpfCompiler, t, f label) {
+	if c.ll == linkLayerEthernet {
+		c.emit(bpf.LoadAbsolute{Off: 12, Size: 2}) //nolint:gomnd // EtherType offset
+		if e.family == ipv4 {
+			c.jumpIf(bpf.JumpEqual, etherTypeIPv4, t, f)
+		} else {
+			c.jumpIf(bpf.JumpEqual, etherTypeIPv6, t, f)
+		}
+		return
+	}
+	c.emit(bpf.LoadAbsolute{Off: 0, Size: 1})
+	c.emit(bpf.ALUOpConstant{Op: bpf.ALUOpAnd, Val: 0xf0}) //nolint:gomnd // IP version
+	if e.family == ipv4 {
+		c.jumpIf(bpf.JumpEqual, 0x40, t, f) //nolint:gomnd // IP version 4
+	} else {
+		c.jumpIf(bpf.JumpEqual, 0x60, t, f) //nolint:gomnd // IP version 6
+	}
+}
+
+// ipProtoExpr matches the protocol of IP packets, and must follow a familyExpr.
+type ipProtoExpr struct {
+	family ipFamily
+	protos []uint32
+}
+
+func (e ipProtoExpr) compile(c *bpfCompiler, t, f label) {
+	off := c.ll.headerLength() + 9 //nolint:gomnd // IPv4 protocol offset
+	if e.family == ipv6 {
+		off = c.ll.headerLength() + 6 //nolint:gomnd // IPv6 next header offset
+	}
+	c.emit(bpf.LoadAbsolute{Off: off, Size: 1})
+	for i, proto := range e.protos {
+		if i == len(e.protos)-1 {
+			c.jumpIf(bpf.JumpEqual, proto, t, f)
+			continue
+		}
+		next := c.newLabel()
+		c.jumpIf(bpf.JumpEqual, proto, t, next)
+		c.placeLabel(next)
+	}
+}
+
+// fragmentExpr matches IPv4 packets which are not the first fragment, and must follow a familyExpr.
+type fragmentExpr struct{}
+
+func (fragmentExpr) compile(c *bpfCompiler, t, f label) {
+	c.emit(bpf.LoadAbsolute{Off: c.ll.headerLength() + 6, Size: 2}) //nolint:gomnd // IPv4 flags and fragment offset
+	c.jumpIf(bpf.JumpBitsSet, 0x1fff, t, f)                         //nolint:gomnd // fragment offset
+}
+
+// addrExpr matches the source or destination address of IP packets against a network, and must follow a familyExpr.
+type addrExpr struct {
+	family ipFamily
+	dir    direction
+	addr   net.IP
+	mask   net.IPMask
+}
+
+func (e addrExpr) compile(c *bpfCompiler, t, f label) {
+	off := c.ll.headerLength() + 12 //nolint:gomnd // IPv4 source address offset
+	if e.family == ipv6 {
+		off = c.ll.headerLength() + 8 //nolint:gomnd // IPv6 source address offset
+	}
+	if e.dir == dst {
+		off += uint32(len(e.addr))
+	}
+
+	// Compare the address a 32-bit word at a time, skipping the words fully masked out.
+	words := make([]int, 0, len(e.addr)/4)
+	for i := 0; i < len(e.addr); i += 4 {
+		if binary.BigEndian.Uint32(e.mask[i:]) != 0 {
+			words = append(words, i)
+		}
+	}
+	if len(words) == 0 {
+		c.jump(t)
+		return
+	}
+	for n, i := range words {
+		mask := binary.BigEndian.Uint32(e.mask[i:])
+		c.emit(bpf.LoadAbsolute{Off: off + uint32(i), Size: 4}) //nolint:gomnd // word size
+		if mask != 0xffffffff {
+			c.emit(bpf.ALUOpConstant{Op: bpf.ALUOpAnd, Val: mask})
+		}
+		value := binary.BigEndian.Uint32(e.addr[i:]) & mask
+		if n == len(words)-1 {
+			c.jumpIf(bpf.JumpEqual, value, t, f)
+			continue
+		}
+		next := c.newLabel()
+		c.jumpIf(bpf.JumpEqual, value, next, f)
+		c.placeLabel(next)
+	}
+}
+
+// portExpr matches the source or destination port of TCP, UDP or SCTP packets against a range,
+// and must follow a familyExpr and an ipProtoExpr.
+type portExpr struct {
+	family ipFamily
+	dir    direction
+	lo, hi uint32
+}
+
+func (e portExpr) compile(c *bpfCompiler, t, f label) {
+	off := uint32(0)
+	if e.dir == dst {
+		off = 2
+	}
+	if e.family == ipv4 {
+		// The IPv4 header has a variable length.
+		c.emit(bpf.LoadMemShift{Off: c.ll.headerLength()})
+		c.emit(bpf.LoadIndirect{Off: c.ll.headerLength() + off, Size: 2}) //nolint:gomnd // port size
+	} else {
+		c.emit(bpf.LoadAbsolute{Off: c.ll.headerLength() + 40 + off, Size: 2}) //nolint:gomnd // IPv6 header length, port size
+	}
+	if e.lo == e.hi {
+		c.jumpIf(bpf.JumpEqual, e.lo, t, f)
+		return
+	}
+	next := c.newLabel()
+	c.jumpIf(bpf.JumpGreaterOrEqual, e.lo, next, f)
+	c.placeLabel(next)
+	c.jumpIf(bpf.JumpGreaterThan, e.hi, f, t)
+}
+
+// tcpFlagsExpr matches TCP packets over IPv4 with any of the flags of the mask set, and must follow a familyExpr,
+// an ipProtoExpr and a fragmentExpr.
+type tcpFlagsExpr struct {
+	mask uint32
+}
+
+func (e tcpFlagsExpr) compile(c *bpfCompiler, t, f label) {
+	c.emit(bpf.LoadMemShift{Off: c.ll.headerLength()})
+	c.emit(bpf.LoadIndirect{Off: c.ll.headerLength() + 13, Size: 1}) //nolint:gomnd // TCP flags offset
+	c.jumpIf(bpf.JumpBitsSet, e.mask, t, f)
+}
+
+type label int
+
+// bpfCompiler collects the instructions of a program, with jumps to labels resolved once the program is complete.
+type bpfCompiler struct {
+	ll     linkLayer
+	items  []bpfItem
+	labels int
+}
+
+// bpfItem is either an instruction, a label, or a jump to labels.
+type bpfItem struct {
+	ins bpf.Instruction
+
+	isLabel bool
+	label   label
+
+	isJump bool
+	cond   bpf.JumpTest
+	val    uint32
+	// always jumps to t unconditionally.
+	always bool
+	t, f   label
+	// long conditional jumps don't fit in 8 bits, and jump through two unconditional jumps.
+	long bool
+}
+
+func (c *bpfCompiler) newLabel() label {
+	c.labels++
+	return label(c.labels)
+}
+
+func (c *bpfCompiler) placeLabel(l label) {
+	c.items = append(c.items, bpfItem{isLabel: true, label: l})
+}
+
+func (c *bpfCompiler) emit(ins bpf.Instruction) {
+	c.items = append(c.items, bpfItem{ins: ins})
+}
+
+func (c *bpfCompiler) jumpIf(cond bpf.JumpTest, val uint32, t, f label) {
+	c.items = append(c.items, bpfItem{isJump: true, cond: cond, val: val, t: t, f: f})
+}
+
+func (c *bpfCompiler) jump(t label) {
+	c.items = append(c.items, bpfItem{isJump: true, always: true, t: t})
+}
+
+func (it *bpfItem) size() int {
+	switch {
+	case it.isLabel:
+		return 0
+	case it.isJump && it.long:
+		return 3 //nolint:gomnd // conditional jump and two unconditional jumps
+	default:
+		return 1
+	}
+}
+
+// resolve lays the program out and turns jumps to labels into relative jumps.
+// Labels are always placed after the jumps to them, since classic BPF can only jump forward.
+func (c *bpfCompiler) resolve() []bpf.Instruction {
+	var pos []int
+	labels := map[label]int{}
+	layout := func() {
+		pos = make([]int, len(c.items))
+		n := 0
+		for i := range c.items {
+			pos[i] = n
+			if c.items[i].isLabel {
+				labels[c.items[i].label] = n
+			}
+			n += c.items[i].size()
+		}
+	}
+
+	// Widen conditional jumps which are too long until the layout is stable.
+	for changed := true; changed; {
+		layout()
+		changed = false
+		for i := range c.items {
+			it := &c.items[i]
+			if !it.isJump || it.always || it.long {
+				continue
+			}
+			if labels[it.t]-pos[i]-1 > maxShortJump || labels[it.f]-pos[i]-1 > maxShortJump {
+				it.long = true
+				changed = true
+			}
+		}
+	}
+
+	prog := make([]bpf.Instruction, 0, len(c.items))
+	for i, it := range c.items {
+		switch {
+		case it.isLabel:
+		case !it.isJump:
+			prog = append(prog, it.ins)
+		case it.always:
+			prog = append(prog, bpf.Jump{Skip: uint32(labels[it.t] - pos[i] - 1)})
+		case it.long:
+			prog = append(prog,
+				bpf.JumpIf{Cond: it.cond, Val: it.val, SkipTrue: 0, SkipFalse: 1},
+				bpf.Jump{Skip: uint32(labels[it.t] - pos[i] - 2)}, //nolint:gomnd // from the first unconditional jump
+				bpf.Jump{Skip: uint32(labels[it.f] - pos[i] - 3)}, //nolint:gomnd // from the second unconditional jump
+			)
+		default:
+			prog = append(prog, bpf.JumpIf{
+				Cond:      it.cond,
+				Val:       it.val,
+				SkipTrue:  uint8(labels[it.t] - pos[i] - 1),
+				SkipFalse: uint8(labels[it.f] - pos[i] - 1),
+			})
+		}
+	}
+	return prog
+}
@@ -0,0 +1,268 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/bpf"
+)
+
+type testPacket struct {
+	src, dst         string
+	proto            layers.IPProtocol
+	srcPort, dstPort uint16
+	syn              bool
+	fragmented       bool
+	ipOptions        bool
+}
+
+func (tp testPacket) serialize(t *testing.T, ll linkLayer) []byte {
+	t.Helper()
+
+	srcIP, dstIP := net.ParseIP(tp.src), net.ParseIP(tp.dst)
+	var l4 gopacket.SerializableLayer
+	var ip gopacket.NetworkLayer
+	switch tp.proto {
+	case layers.IPProtocolTCP:
+		l4 = &layers.TCP{SrcPort: layers.TCPPort(tp.srcPort), DstPort: layers.TCPPort(tp.dstPort), SYN: tp.syn, ACK: !tp.syn}
+	case layers.IPProtocolUDP:
+		l4 = &layers.UDP{SrcPort: layers.UDPPort(tp.srcPort), DstPort: layers.UDPPort(tp.dstPort)}
+	default:
+		l4 = gopacket.Payload([]byte{8, 0, 0, 0})
+	}
+
+	etherType := layers.EthernetTypeIPv4
+	if srcIP.To4() != nil {
+		ip4 := &layers.IPv4{Version: 4, TTL: 64, Protocol: tp.proto, SrcIP: srcIP, DstIP: dstIP}
+		if tp.fragmented {
+			ip4.FragOffset = 100
+		}
+		if tp.ipOptions {
+			ip4.Options = []layers.IPv4Option{{OptionType: 1}, {OptionType: 1}, {OptionType: 1}, {OptionType: 0}}
+		}
+		ip = ip4
+	} else {
+		etherType = layers.EthernetTypeIPv6
+		ip = &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: tp.proto, SrcIP: srcIP, DstIP: dstIP}
+	}
+	if tcp, ok := l4.(*layers.TCP); ok {
+		require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+	}
+
+	toSerialize := []gopacket.SerializableLayer{ip.(gopacket.SerializableLayer), l4}
+	if ll == linkLayerEthernet {
+		eth := &layers.Ethernet{
+			SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+			DstMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 6},
+			EthernetType: etherType,
+		}
+		toSerialize = append([]gopacket.SerializableLayer{eth}, toSerialize...)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	require.NoError(t, gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, toSerialize...))
+	return buf.Bytes()
+}
+
+func runFilter(t *testing.T, filter string, ll linkLayer, pkt []byte) bool {
+	t.Helper()
+
+	raw, err := compileFilter(filter, ll)
+	require.NoError(t, err)
+	prog := make([]bpf.Instruction, 0, len(raw))
+	for _, ins := range raw {
+		prog = append(prog, ins.Disassemble())
+	}
+	vm, err := bpf.NewVM(prog)
+	require.NoError(t, err)
+	n, err := vm.Run(pkt)
+	require.NoError(t, err)
+	return n > 0
+}
+
+func TestCompileFilter(t *testing.T) {
+	tcp4 := testPacket{src: "10.0.0.1", dst: "192.168.0.1", proto: layers.IPProtocolTCP, srcPort: 40000, dstPort: 80}
+	udp4 := testPacket{src: "10.0.0.2", dst: "192.168.0.2", proto: layers.IPProtocolUDP, srcPort: 53, dstPort: 40000}
+	icmp4 := testPacket{src: "10.0.0.1", dst: "192.168.0.1", proto: layers.IPProtocolICMPv4}
+	tcp6 := testPacket{src: "fd00::1", dst: "fd01::1", proto: layers.IPProtocolTCP, srcPort: 443, dstPort: 40000}
+	udp6 := testPacket{src: "fd00::2", dst: "fd01::2", proto: layers.IPProtocolUDP, srcPort: 40000, dstPort: 80}
+	fragment4 := tcp4
+	fragment4.fragmented = true
+	options4 := tcp4
+	options4.ipOptions = true
+	syn4 := tcp4
+	syn4.syn = true
+	synOptions4 := options4
+	synOptions4.syn = true
+	syn6 := tcp6
+	syn6.syn = true
+
+	cases := []struct {
+		name     string
+		filter   string
+		matching []testPacket
+		others   []testPacket
+	}{
+		{
+			name:     "empty filter",
+			filter:   "",
+			matching: []testPacket{tcp4, udp4, icmp4, tcp6, udp6},
+		},
+		{
+			name:     "IPv4 host",
+			filter:   "host 10.0.0.1",
+			matching: []testPacket{tcp4, icmp4},
+			others:   []testPacket{udp4, tcp6, udp6},
+		},
+		{
+			name:     "IPv4 destination host",
+			filter:   "dst host 192.168.0.1",
+			matching: []testPacket{tcp4},
+			others:   []testPacket{udp4, tcp6},
+		},
+		{
+			name:   "IPv4 source host",
+			filter: "src host 192.168.0.1",
+			others: []testPacket{tcp4, udp4, tcp6},
+		},
+		{
+			name:     "IPv6 host",
+			filter:   "host fd01::2",
+			matching: []testPacket{udp6},
+			others:   []testPacket{tcp4, tcp6},
+		},
+		{
+			name:     "IPv4 net",
+			filter:   "net 192.168.0.0/30",
+			matching: []testPacket{tcp4, udp4},
+			others:   []testPacket{tcp6},
+		},
+		{
+			name:     "IPv6 net",
+			filter:   "src net fd00::/16",
+			matching: []testPacket{tcp6, udp6},
+			others:   []testPacket{tcp4},
+		},
+		{
+			name:     "port",
+			filter:   "port 80",
+			matching: []testPacket{tcp4, udp6, options4},
+			others:   []testPacket{udp4, tcp6, icmp4, fragment4},
+		},
+		{
+			name:     "destination port",
+			filter:   "dst port 40000",
+			matching: []testPacket{udp4, tcp6},
+			others:   []testPacket{tcp4, udp6},
+		},
+		{
+			name:     "port range",
+			filter:   "portrange 50-100",
+			matching: []testPacket{tcp4, udp4, udp6},
+			others:   []testPacket{tcp6},
+		},
+		{
+			name:     "protocol qualified port",
+			filter:   "udp port 80",
+			matching: []testPacket{udp6},
+			others:   []testPacket{tcp4},
+		},
+		{
+			name:     "protocols",
+			filter:   "icmp or (tcp and ip6)",
+			matching: []testPacket{icmp4, tcp6},
+			others:   []testPacket{tcp4, udp4, udp6},
+		},
+		{
+			name:     "retina include and exclude filters",
+			filter:   "((port 443) or (host 10.0.0.1 and port 80) or (host 10.0.0.2)) and not ((host 192.168.0.2 and port 53))",
+			matching: []testPacket{tcp4, tcp6},
+			others:   []testPacket{udp4, udp6, icmp4},
+		},
+		{
+			name:     "TCP flags",
+			filter:   "tcp[tcpflags] & (tcp-syn|tcp-fin) != 0",
+			matching: []testPacket{syn4, synOptions4},
+			others:   []testPacket{tcp4, udp4, icmp4, syn6},
+		},
+		{
+			name:     "typed filter rule",
+			filter:   "((tcp and dst port 80 and tcp[tcpflags] & tcp-ack != 0))",
+			matching: []testPacket{tcp4, options4},
+			others:   []testPacket{syn4, udp6, fragment4},
+		},
+		{
+			name:     "operator aliases",
+			filter:   "!host 10.0.0.1 && (udp || icmp6)",
+			matching: []testPacket{udp4, udp6},
+			others:   []testPacket{tcp4, icmp4, tcp6},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, ll := range []linkLayer{linkLayerEthernet, linkLayerRaw} {
+				for _, pkt := range tt.matching {
+					require.True(t, runFilter(t, tt.filter, ll, pkt.serialize(t, ll)), "packet %+v should match", pkt)
+				}
+				for _, pkt := range tt.others {
+					require.False(t, runFilter(t, tt.filter, ll, pkt.serialize(t, ll)), "packet %+v should not match", pkt)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileFilterLongJumps(t *testing.T) {
+	// Enough hosts for the jumps to the end of the program not to fit in 8 bits.
+	hosts := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		hosts = append(hosts, fmt.Sprintf("(host 172.16.%d.%d)", i/250, i%250+1))
+	}
+	filter := fmt.Sprintf("(%s) or port 80", strings.Join(hosts, " or "))
+	prog, err := compileFilter(filter, linkLayerEthernet)
+	require.NoError(t, err)
+	require.Greater(t, len(prog), maxShortJump)
+
+	first := testPacket{src: "172.16.0.1", dst: "10.0.0.1", proto: layers.IPProtocolICMPv4}
+	last := testPacket{src: "10.0.0.1", dst: "172.16.0.200", proto: layers.IPProtocolICMPv4}
+	port := testPacket{src: "10.0.0.1", dst: "10.0.0.2", proto: layers.IPProtocolTCP, srcPort: 40000, dstPort: 80}
+	other := testPacket{src: "10.0.0.1", dst: "10.0.0.2", proto: layers.IPProtocolTCP, srcPort: 40000, dstPort: 8080}
+
+	require.True(t, runFilter(t, filter, linkLayerEthernet, first.serialize(t, linkLayerEthernet)))
+	require.True(t, runFilter(t, filter, linkLayerEthernet, last.serialize(t, linkLayerEthernet)))
+	require.True(t, runFilter(t, filter, linkLayerEthernet, port.serialize(t, linkLayerEthernet)))
+	require.False(t, runFilter(t, filter, linkLayerEthernet, other.serialize(t, linkLayerEthernet)))
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	for _, filter := range []string{
+		"host",
+		"host example.com",
+		"net 10.0.0.0",
+		"port 65536",
+		"portrange 100",
+		"portrange 200-100",
+		"tcp host 10.0.0.1",
+		"icmp port 80",
+		"ip6 host 10.0.0.1",
+		"(host 10.0.0.1",
+		"host 10.0.0.1 port 80",
+		"vlan 100",
+		"tcp[tcpflags] & (tcp-syn) = 0",
+		"tcp[tcpflags] & (tcp-bad) != 0",
+		"tcp[tcpflags] (tcp-syn) != 0",
+		"not",
+	} {
+		_, err := compileFilter(filter, linkLayerEthernet)
+		require.Error(t, err, "filter %q should be rejected", filter)
+	}
+}
@@ -0,0 +1,759 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"go.uber.org/zap"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+	"github.com/microsoft/retina/pkg/log"
+)
+
+const (
+	// defaultSnapLength is the default maximum number of bytes captured of each packet, same as tcpdump.
+	defaultSnapLength = 262144
+	// socketReadTimeout bounds how long a capture socket blocks, so reading stops soon after the capture is stopped.
+	socketReadTimeout = 500 * time.Millisecond
+	// socketReceiveBufferSize absorbs bursts of packets while the capture file is written.
+	socketReceiveBufferSize = 4 << 20
+	// capturedPacketsBufferSize is the number of packets read from the sockets but not yet written to the capture file.
+	capturedPacketsBufferSize = 1024
+)
+
+// procDir lists the processes of the node, as capture jobs in the Pod network namespaces share its PID namespace.
+const procDir = "/proc"
+
+var (
+	errNoCaptureInterfaces = errors.New("no interfaces to capture network packets on")
+	errNoCapturePods       = errors.New("no Pods to capture network packets of")
+	errNoPodNetNamespaces  = errors.New("no network namespaces of the Pods to capture network packets in")
+
+	// defaultPodCaptureInterfaces are the interfaces captured on in the Pod network namespaces.
+	defaultPodCaptureInterfaces = []string{"eth0", "lo"}
+)
+
+// captureInterface is an interface on which network packets are captured, and its description in the capture file.
+type captureInterface struct {
+	name        string
+	index       int
+	linkLayer   linkLayer
+	description string
+	// loopback interfaces see the packets they send both as outgoing and incoming.
+	loopback bool
+}
+
+// captureGroup is a set of interfaces captured into the same capture file.
+type captureGroup struct {
+	// name is appended to the name of the capture file if not empty.
+	name    string
+	comment string
+	// netns is the network namespace of the interfaces, or not open for the network namespace of the capture job.
+	netns netns.NsHandle
+	intfs []captureInterface
+}
+
+// captureSocket is a socket capturing on an interface, and the capture file its packets are written to.
+type captureSocket struct {
+	fd   int
+	intf captureInterface
+	// file is the index of the capture file, and id the index of the interface in the capture file.
+	file int
+	id   int
+}
+
+type capturedPacket struct {
+	ci     gopacket.CaptureInfo
+	data   []byte
+	socket int
+}
+
+// NativeNetworkCaptureProvider captures network packets with AF_PACKET sockets, filtered by a BPF program compiled
+// from the capture filter, and writes them in pcapng format with one interface block per captured interface.
+// It does not require tcpdump in the capture image, and shares the metadata collection with NetworkCaptureProvider.
+type NativeNetworkCaptureProvider struct {
+	NetworkCaptureProvider
+}
+
+var _ NetworkCaptureProviderInterface = &NativeNetworkCaptureProvider{}
+
+func NewNativeNetworkCaptureProvider(logger *log.ZapLogger) (NetworkCaptureProviderInterface, error) {
+	return &NativeNetworkCaptureProvider{
+		NetworkCaptureProvider: NetworkCaptureProvider{
+			NetworkCaptureProviderCommon: NetworkCaptureProviderCommon{l: logger},
+			l:                            logger,
+		},
+	}, nil
+}
+
+func (ncp *NativeNetworkCaptureProvider) CaptureNetworkPacket(filter string, duration, maxSizeMB int, sigChan <-chan os.Signal) error {
+	snapLength := defaultSnapLength
+	if packetSize := os.Getenv(captureConstants.PacketSizeEnvKey); len(packetSize) != 0 {
+		size, err := strconv.Atoi(packetSize)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("invalid capture packet size %q", packetSize)
+		}
+		snapLength = size
+	}
+
+	var groups []captureGroup
+	var err error
+	if podNetworkNamespace, _ := strconv.ParseBool(os.Getenv(captureConstants.CapturePodNetworkNamespaceEnvKey)); podNetworkNamespace {
+		groups, err = ncp.podCaptureGroups(procDir)
+	} else {
+		groups, err = ncp.nodeCaptureGroups()
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for i := range groups {
+			groups[i].netns.Close() //nolint:errcheck // best effort
+		}
+	}()
+
+	captureFileName := ncp.NetworkCaptureProviderCommon.CaptureNodetimestampName(ncp.CaptureName, ncp.NodeHostName)
+	var capturedBytes int64
+	files := make([]*pcapgo.NgWriter, 0, len(groups))
+	var sockets []captureSocket
+	defer func() {
+		for _, socket := range sockets {
+			unix.Close(socket.fd) //nolint:errcheck // best effort
+		}
+	}()
+
+	// Compile the filter once per link layer, and open the sockets before writing the interfaces to the capture files,
+	// so an invalid filter or interface fails the capture early.
+	progs := map[linkLayer][]bpf.RawInstruction{}
+	for _, group := range groups {
+		for _, intf := range group.intfs {
+			if _, ok := progs[intf.linkLayer]; ok {
+				continue
+			}
+			prog, err := compileFilter(filter, intf.linkLayer)
+			if err != nil {
+				return err
+			}
+			progs[intf.linkLayer] = prog
+		}
+	}
+
+	for _, group := range groups {
+		var fds []int
+		err := inNetNamespace(group.netns, func() error {
+			for _, intf := range group.intfs {
+				fd, err := openPacketSocket(intf.index, progs[intf.linkLayer])
+				if err != nil {
+					return fmt.Errorf("failed to capture on interface %s: %w", intf.name, err)
+				}
+				fds = append(fds, fd)
+			}
+			return nil
+		})
+		for id, fd := range fds {
+			sockets = append(sockets, captureSocket{fd: fd, intf: group.intfs[id], file: len(files), id: id})
+		}
+		if err != nil {
+			return err
+		}
+
+		fileName := captureFileName
+		if len(group.name) != 0 {
+			fileName = fmt.Sprintf("%s-%s", captureFileName, group.name)
+		}
+		captureFilePath := filepath.Join(ncp.TmpCaptureDir, fmt.Sprintf("%s.pcapng", fileName))
+		captureFile, err := os.Create(captureFilePath)
+		if err != nil {
+			ncp.l.Error("Failed to create capture file", zap.String("capture file path", captureFilePath), zap.Error(err))
+			return err
+		}
+		defer captureFile.Close()
+
+		w, err := newCaptureFileWriter(&countingWriter{w: captureFile, n: &capturedBytes}, group, filter, snapLength)
+		if err != nil {
+			return fmt.Errorf("failed to write capture file: %w", err)
+		}
+		files = append(files, w)
+		for _, intf := range group.intfs {
+			ncp.l.Info("Capturing network packets on interface", zap.String("interface", intf.name), zap.String("description", intf.description), zap.String("capture file", captureFilePath))
+		}
+	}
+
+	startTime := time.Now()
+	stopChan := make(chan struct{})
+	packetChan := make(chan capturedPacket, capturedPacketsBufferSize)
+	errChan := make(chan error, len(sockets))
+	var wg sync.WaitGroup
+	for i := range sockets {
+		wg.Add(1)
+		go func(socket int) {
+			defer wg.Done()
+			if err := readPackets(sockets[socket].fd, socket, snapLength, sockets[socket].intf.loopback, packetChan, stopChan); err != nil {
+				errChan <- fmt.Errorf("failed to read packets from interface %s: %w", sockets[socket].intf.name, err)
+			}
+		}(i)
+	}
+	stopReading := func() {
+		close(stopChan)
+		wg.Wait()
+		close(packetChan)
+	}
+	var capturedPackets int64
+	ncp.capturedPackets = &capturedPackets
+	writePacket := func(pkt capturedPacket) error {
+		socket := sockets[pkt.socket]
+		pkt.ci.InterfaceIndex = socket.id
+		if err := files[socket.file].WritePacket(pkt.ci, pkt.data); err != nil {
+			return fmt.Errorf("failed to write packet to capture file: %w", err)
+		}
+		capturedPackets++
+		return nil
+	}
+
+	var timeout <-chan time.Time
+	if duration != 0 {
+		ncp.l.Info(fmt.Sprintf("Native capture will stop after %v seconds", duration))
+		timer := time.NewTimer(time.Second * time.Duration(duration))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	maxSizeBytes := int64(maxSizeMB) * 1024 * 1024
+	if maxSizeMB != 0 {
+		ncp.l.Info(fmt.Sprintf("Native capture will stop when the capture files size reaches %dMB.", maxSizeMB))
+	}
+
+	var captureErr error
+capture:
+	for {
+		select {
+		case pkt := <-packetChan:
+			if captureErr = writePacket(pkt); captureErr != nil {
+				break capture
+			}
+			if maxSizeBytes != 0 && capturedBytes >= maxSizeBytes {
+				ncp.l.Info("Capture files reached the maximum size")
+				break capture
+			}
+		case <-timeout:
+			break capture
+		case sig := <-sigChan:
+			ncp.l.Info("Got OS signal, native capture will be stopped", zap.String("signal", sig.String()))
+			break capture
+		case captureErr = <-errChan:
+			break capture
+		}
+	}
+	ncp.l.Info("Stop native capture")
+	stopReading()
+
+	// Write the packets already read, unless the capture files are full.
+	for pkt := range packetChan {
+		if captureErr != nil || (maxSizeBytes != 0 && capturedBytes >= maxSizeBytes) {
+			continue
+		}
+		captureErr = writePacket(pkt)
+	}
+
+	endTime := time.Now()
+	for _, socket := range sockets {
+		stats, err := unix.GetsockoptTpacketStats(socket.fd, unix.SOL_PACKET, unix.PACKET_STATISTICS)
+		if err != nil {
+			ncp.l.Warn("Failed to get capture statistics", zap.String("interface", socket.intf.name), zap.Error(err))
+			continue
+		}
+		ncp.l.Info("Captured network packets", zap.String("interface", socket.intf.name), zap.String("description", socket.intf.description), zap.Uint32("packets", stats.Packets), zap.Uint32("dropped", stats.Drops))
+		if err := files[socket.file].WriteInterfaceStats(socket.id, pcapgo.NgInterfaceStatistics{
+			LastUpdate:      endTime,
+			StartTime:       startTime,
+			EndTime:         endTime,
+			PacketsReceived: uint64(stats.Packets),
+			PacketsDropped:  uint64(stats.Drops),
+		}); err != nil {
+			ncp.l.Warn("Failed to write capture statistics", zap.String("interface", socket.intf.name), zap.Error(err))
+		}
+	}
+
+	for _, w := range files {
+		if err := w.Flush(); err != nil && captureErr == nil {
+			captureErr = fmt.Errorf("failed to flush capture file: %w", err)
+		}
+	}
+	return captureErr
+}
+
+// newCaptureFileWriter writes the header of the capture file of the group, with one interface block per interface.
+func newCaptureFileWriter(cw *countingWriter, group captureGroup, filter string, snapLength int) (*pcapgo.NgWriter, error) {
+	var w *pcapgo.NgWriter
+	for id, intf := range group.intfs {
+		ngIntf := pcapgo.NgInterface{
+			Name:                intf.name,
+			Description:         intf.description,
+			Filter:              filter,
+			OS:                  "linux",
+			LinkType:            layers.LinkTypeEthernet,
+			SnapLength:          uint32(snapLength),
+			TimestampResolution: pcapgo.DefaultNgInterface.TimestampResolution,
+		}
+		if intf.linkLayer == linkLayerRaw {
+			ngIntf.LinkType = layers.LinkTypeRaw
+		}
+		var err error
+		if id == 0 {
+			w, err = pcapgo.NewNgWriterInterface(cw, ngIntf, pcapgo.NgWriterOptions{
+				SectionInfo: pcapgo.NgSectionInfo{
+					OS:          "linux",
+					Application: "retina",
+					Comment:     group.comment,
+				},
+			})
+		} else {
+			_, err = w.AddInterface(ngIntf)
+		}
+		if err != nil {
+			return nil, err //nolint:wrapcheck // wrapped by the caller
+		}
+	}
+	return w, nil
+}
+
+// nodeCaptureGroups returns the interfaces of the node to capture on into a single capture file. They are in order of
+// precedence: the interfaces listed in the capture job environment, the host interfaces routing to the target Pods,
+// e.g. their veths, and all interfaces of the node which are up.
+func (ncp *NativeNetworkCaptureProvider) nodeCaptureGroups() ([]captureGroup, error) {
+	intfs, err := ncp.captureInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	return []captureGroup{{
+		comment: fmt.Sprintf("Capture %s on node %s", ncp.CaptureName, ncp.NodeHostName),
+		netns:   netns.None(),
+		intfs:   intfs,
+	}}, nil
+}
+
+// podCaptureGroups returns the interfaces to capture on inside the network namespace of each target Pod, which are
+// the interfaces listed in the capture job environment, or eth0 and lo, with one capture file per Pod.
+func (ncp *NativeNetworkCaptureProvider) podCaptureGroups(procDir string) ([]captureGroup, error) {
+	pods, err := capturePods()
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, errNoCapturePods
+	}
+	names := defaultPodCaptureInterfaces
+	if env := os.Getenv(captureConstants.CaptureInterfacesEnvKey); len(env) != 0 {
+		names = strings.Split(env, ",")
+	}
+
+	namespaces, err := podNetNamespaces(procDir, pods)
+	if err != nil {
+		return nil, err
+	}
+	podNames := make([]string, 0, len(namespaces))
+	for _, podName := range pods {
+		if _, ok := namespaces[podName]; !ok {
+			ncp.l.Warn("Failed to find the network namespace of the Pod", zap.String("pod", podName))
+			continue
+		}
+		podNames = append(podNames, podName)
+	}
+	sort.Strings(podNames)
+	podNames = slices.Compact(podNames)
+
+	groups := make([]captureGroup, 0, len(podNames))
+	for _, podName := range podNames {
+		ns := namespaces[podName]
+		intfs, err := podCaptureInterfaces(ns, names, "Pod: "+podName)
+		if err == nil && len(intfs) == 0 {
+			err = errNoCaptureInterfaces
+		}
+		if err != nil {
+			ns.Close() //nolint:errcheck // best effort
+			ncp.l.Warn("Failed to find the interfaces of the Pod", zap.String("pod", podName), zap.Error(err))
+			continue
+		}
+		groups = append(groups, captureGroup{
+			// Namespace names don't contain underscores, so the file name identifies the Pod.
+			name:    strings.Replace(podName, "/", "_", 1),
+			comment: fmt.Sprintf("Capture %s of Pod %s on node %s", ncp.CaptureName, podName, ncp.NodeHostName),
+			netns:   ns,
+			intfs:   intfs,
+		})
+	}
+	if len(groups) == 0 {
+		return nil, errNoPodNetNamespaces
+	}
+	return groups, nil
+}
+
+// podCaptureInterfaces returns the interfaces of the names in the network namespace.
+func podCaptureInterfaces(ns netns.NsHandle, names []string, description string) ([]captureInterface, error) {
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netlink handle in network namespace: %w", err)
+	}
+	defer h.Close()
+
+	intfs := make([]captureInterface, 0, len(names))
+	for _, name := range names {
+		link, err := h.LinkByName(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find capture interface %q: %w", name, err)
+		}
+		intf, err := linkCaptureInterface(link, description)
+		if err != nil {
+			return nil, err
+		}
+		intfs = append(intfs, intf)
+	}
+	return intfs, nil
+}
+
+// capturePods returns the names of the target Pods by IP address.
+func capturePods() (map[string]string, error) {
+	pods := map[string]string{}
+	env := os.Getenv(captureConstants.CapturePodsEnvKey)
+	if len(env) == 0 {
+		return pods, nil
+	}
+	for _, item := range strings.Split(env, ",") {
+		ip, podName, found := strings.Cut(item, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid capture Pod %q", item)
+		}
+		pods[ip] = podName
+	}
+	return pods, nil
+}
+
+// captureInterfaces returns the interfaces of the node to capture on.
+func (ncp *NativeNetworkCaptureProvider) captureInterfaces() ([]captureInterface, error) {
+	var links []netlink.Link
+	descriptions := map[int]string{}
+
+	pods, err := capturePods()
+	if err != nil {
+		return nil, err
+	}
+	if names := os.Getenv(captureConstants.CaptureInterfacesEnvKey); len(names) != 0 {
+		for _, name := range strings.Split(names, ",") {
+			link, err := netlink.LinkByName(strings.TrimSpace(name))
+			if err != nil {
+				return nil, fmt.Errorf("failed to find capture interface %q: %w", name, err)
+			}
+			links = append(links, link)
+		}
+	} else if len(pods) != 0 {
+		ips := make([]string, 0, len(pods))
+		for ip := range pods {
+			ips = append(ips, ip)
+		}
+		sort.Strings(ips)
+		podsByLink := map[int][]string{}
+		for _, ip := range ips {
+			podName := pods[ip]
+			link, err := podHostLink(net.ParseIP(ip))
+			if err != nil {
+				ncp.l.Warn("Failed to find the host interface of the Pod", zap.String("pod", podName), zap.String("ip", ip), zap.Error(err))
+				continue
+			}
+			if _, ok := podsByLink[link.Attrs().Index]; !ok {
+				links = append(links, link)
+			}
+			podsByLink[link.Attrs().Index] = append(podsByLink[link.Attrs().Index], fmt.Sprintf("%s (%s)", podName, ip))
+		}
+		for index, pods := range podsByLink {
+			sort.Strings(pods)
+			descriptions[index] = "Pods: " + strings.Join(pods, ", ")
+		}
+	}
+
+	if len(links) == 0 {
+		all, err := netlink.LinkList()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list interfaces: %w", err)
+		}
+		for _, link := range all {
+			if link.Attrs().Flags&net.FlagUp == 0 || link.Attrs().Flags&net.FlagLoopback != 0 {
+				continue
+			}
+			links = append(links, link)
+		}
+	}
+
+	intfs := make([]captureInterface, 0, len(links))
+	for _, link := range links {
+		intf, err := linkCaptureInterface(link, descriptions[link.Attrs().Index])
+		if err != nil {
+			ncp.l.Warn("Skipping interface", zap.String("interface", link.Attrs().Name), zap.Error(err))
+			continue
+		}
+		intfs = append(intfs, intf)
+	}
+	if len(intfs) == 0 {
+		return nil, errNoCaptureInterfaces
+	}
+	return intfs, nil
+}
+
+// linkCaptureInterface returns the capture interface of the link, if its link type is supported.
+func linkCaptureInterface(link netlink.Link, description string) (captureInterface, error) {
+	attrs := link.Attrs()
+	intf := captureInterface{
+		name:        attrs.Name,
+		index:       attrs.Index,
+		description: description,
+		loopback:    attrs.Flags&net.FlagLoopback != 0,
+	}
+	switch attrs.EncapType {
+	case "ether", "loopback":
+		intf.linkLayer = linkLayerEthernet
+	case "none":
+		intf.linkLayer = linkLayerRaw
+	default:
+		return intf, fmt.Errorf("unsupported link type %q of interface %s", attrs.EncapType, attrs.Name)
+	}
+	return intf, nil
+}
+
+// podHostLink returns the host interface routing to the Pod IP, which is the Pod's veth with most CNIs.
+func podHostLink(ip net.IP) (netlink.Link, error) {
+	if ip == nil {
+		return nil, errors.New("invalid IP address")
+	}
+	routes, err := netlink.RouteGet(ip)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+	if len(routes) == 0 || routes[0].LinkIndex == 0 {
+		return nil, errors.New("no route to the Pod")
+	}
+	return netlink.LinkByIndex(routes[0].LinkIndex) //nolint:wrapcheck // wrapped by the caller
+}
+
+// podNetNamespaces finds the network namespaces of the Pods by IP address from the processes of the node, which the
+// capture job sees as it shares the PID namespace of the node, and returns them by Pod name.
+func podNetNamespaces(procDir string, pods map[string]string) (map[string]netns.NsHandle, error) {
+	self, err := netns.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the network namespace of the capture job: %w", err)
+	}
+	defer self.Close()
+
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	namespaces := map[string]netns.NsHandle{}
+	seen := map[[2]uint64]struct{}{}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		// Processes may exit while they are listed, and kernel threads have no network namespace to open.
+		path := filepath.Join(procDir, entry.Name(), "ns", "net")
+		var stat unix.Stat_t
+		if err := unix.Stat(path, &stat); err != nil {
+			continue
+		}
+		key := [2]uint64{stat.Dev, stat.Ino}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		ns, err := netns.GetFromPath(path)
+		if err != nil {
+			continue
+		}
+		podName := netNamespacePod(ns, pods)
+		if ns.Equal(self) || len(podName) == 0 {
+			ns.Close() //nolint:errcheck // best effort
+			continue
+		}
+		if _, ok := namespaces[podName]; ok {
+			ns.Close() //nolint:errcheck // best effort
+			continue
+		}
+		namespaces[podName] = ns
+	}
+	return namespaces, nil
+}
+
+// netNamespacePod returns the name of the Pod owning one of the IP addresses of the network namespace.
+func netNamespacePod(ns netns.NsHandle, pods map[string]string) string {
+	h, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return ""
+	}
+	defer h.Close()
+	addrs, err := h.AddrList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if podName, ok := pods[addr.IP.String()]; ok {
+			return podName
+		}
+	}
+	return ""
+}
+
+// inNetNamespace runs f in the network namespace, or in the current one if the handle is not open. Sockets opened by
+// f keep capturing in the network namespace afterwards.
+func inNetNamespace(ns netns.NsHandle, f func() error) error {
+	if !ns.IsOpen() {
+		return f()
+	}
+
+	runtime.LockOSThread()
+	origin, err := netns.Get()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to get the current network namespace: %w", err)
+	}
+	defer origin.Close()
+	if err = netns.Set(ns); err != nil {
+		runtime.UnlockOSThread()
+		return fmt.Errorf("failed to enter network namespace: %w", err)
+	}
+	fErr := f()
+	if err = netns.Set(origin); err != nil {
+		// The thread is left locked, so it is terminated rather than reused in the wrong network namespace.
+		return fmt.Errorf("failed to restore network namespace: %w", err)
+	}
+	runtime.UnlockOSThread()
+	return fErr
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// openPacketSocket opens an AF_PACKET socket capturing the packets of the interface matching the BPF program.
+func openPacketSocket(ifindex int, prog []bpf.RawInstruction) (int, error) {
+	// The socket doesn't receive any packet until it is bound to a protocol, so the filter applies to all packets.
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open packet socket: %w", err)
+	}
+
+	filter := make([]unix.SockFilter, len(prog))
+	for i, ins := range prog {
+		filter[i] = unix.SockFilter{Code: ins.Op, Jt: ins.Jt, Jf: ins.Jf, K: ins.K}
+	}
+	fprog := &unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err = unix.SetsockoptSockFprog(fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, fprog); err != nil {
+		unix.Close(fd) //nolint:errcheck // best effort
+		return -1, fmt.Errorf("failed to attach capture filter: %w", err)
+	}
+	if err = unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMPNS, 1); err != nil {
+		unix.Close(fd) //nolint:errcheck // best effort
+		return -1, fmt.Errorf("failed to enable packet timestamps: %w", err)
+	}
+	tv := unix.NsecToTimeval(socketReadTimeout.Nanoseconds())
+	if err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd) //nolint:errcheck // best effort
+		return -1, fmt.Errorf("failed to set packet socket timeout: %w", err)
+	}
+	// A larger buffer is only an optimization.
+	_ = unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_RCVBUF, socketReceiveBufferSize)
+
+	if err = unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ALL), Ifindex: ifindex}); err != nil {
+		unix.Close(fd) //nolint:errcheck // best effort
+		return -1, fmt.Errorf("failed to bind packet socket: %w", err)
+	}
+	// Capture the packets of other hosts as well, like tcpdump. The membership is dropped when the socket is closed.
+	mreq := &unix.PacketMreq{Ifindex: int32(ifindex), Type: unix.PACKET_MR_PROMISC}
+	_ = unix.SetsockoptPacketMreq(fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, mreq)
+	return fd, nil
+}
+
+// readPackets reads packets from the socket until stopChan is closed. On a loopback interface, only the incoming
+// packets are read, as every packet is seen twice, like libpcap does.
+func readPackets(fd, socket, snapLength int, loopback bool, packetChan chan<- capturedPacket, stopChan <-chan struct{}) error {
+	buf := make([]byte, snapLength)
+	oob := make([]byte, unix.CmsgSpace(int(unsafe.Sizeof(unix.Timespec{}))))
+	for {
+		select {
+		case <-stopChan:
+			return nil
+		default:
+		}
+
+		// With MSG_TRUNC, the original length of the packet is returned even if it is truncated to the buffer.
+		n, oobn, _, from, err := unix.Recvmsg(fd, buf, oob, unix.MSG_TRUNC)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped by the caller
+		}
+		if sa, ok := from.(*unix.SockaddrLinklayer); ok && loopback && sa.Pkttype == unix.PACKET_OUTGOING {
+			continue
+		}
+
+		ci := gopacket.CaptureInfo{
+			Timestamp:     packetTimestamp(oob[:oobn]),
+			CaptureLength: min(n, len(buf)),
+			Length:        n,
+		}
+		data := make([]byte, ci.CaptureLength)
+		copy(data, buf)
+		select {
+		case packetChan <- capturedPacket{ci: ci, data: data, socket: socket}:
+		case <-stopChan:
+			return nil
+		}
+	}
+}
+
+// packetTimestamp returns the kernel timestamp of the packet, or the current time if it is missing.
+func packetTimestamp(oob []byte) time.Time {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return time.Now()
+	}
+	for _, msg := range msgs {
+		if msg.Header.Level == unix.SOL_SOCKET && msg.Header.Type == unix.SCM_TIMESTAMPNS && len(msg.Data) >= int(unsafe.Sizeof(unix.Timespec{})) {
+			ts := (*unix.Timespec)(unsafe.Pointer(&msg.Data[0])) //nolint:gosec // the kernel writes a timespec
+			return time.Unix(ts.Unix())
+		}
+	}
+	return time.Now()
+}
+
+// countingWriter counts the bytes written to the capture files, to stop the capture at its maximum size.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	*cw.n += int64(n)
+	return n, err //nolint:wrapcheck // io.Writer
+}
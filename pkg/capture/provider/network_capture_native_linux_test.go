@@ -0,0 +1,308 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+	"github.com/microsoft/retina/pkg/log"
+)
+
+func TestNativeCaptureNetworkPacket(t *testing.T) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		t.Skipf("AF_PACKET sockets are not permitted: %s", err)
+	}
+	unix.Close(fd)
+
+	log.SetupZapLogger(log.GetDefaultLogOpts()) //nolint:errcheck // test
+	t.Setenv(captureConstants.CaptureInterfacesEnvKey, "lo")
+	t.Setenv(captureConstants.PacketSizeEnvKey, "64")
+
+	ncp, err := NewNativeNetworkCaptureProvider(log.Logger().Named("test"))
+	require.NoError(t, err)
+	dir, err := ncp.Setup("capture", "node")
+	require.NoError(t, err)
+	defer ncp.Cleanup() //nolint:errcheck // test
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	sigChan := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- ncp.CaptureNetworkPacket("udp and dst port "+strconv.Itoa(port), 0, 0, sigChan)
+	}()
+
+	// Send packets until the capture has surely started, then stop it.
+	payload := make([]byte, 100)
+	for i := 0; i < 10; i++ {
+		_, err = conn.WriteToUDP(payload, conn.LocalAddr().(*net.UDPAddr))
+		require.NoError(t, err)
+		// Not matching the filter.
+		_, err = conn.WriteToUDP(payload, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9})
+		require.NoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+	}
+	sigChan <- os.Interrupt
+	require.NoError(t, <-done)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pcapng"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	require.NoError(t, err)
+	packets := 0
+	for {
+		data, ci, err := r.ReadPacketData()
+		if err != nil {
+			break
+		}
+		packets++
+		require.Equal(t, 64, ci.CaptureLength)
+		require.Greater(t, ci.Length, ci.CaptureLength)
+		pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+		udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		require.True(t, ok)
+		require.Equal(t, layers.UDPPort(port), udp.DstPort)
+	}
+	require.Positive(t, packets)
+
+	intf, err := r.Interface(0)
+	require.NoError(t, err)
+	require.Equal(t, "lo", intf.Name)
+	require.Equal(t, layers.LinkTypeEthernet, intf.LinkType)
+}
+
+func TestNativeCaptureInterfaces(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts()) //nolint:errcheck // test
+	ncp := &NativeNetworkCaptureProvider{NetworkCaptureProvider: NetworkCaptureProvider{l: log.Logger().Named("test")}}
+
+	t.Setenv(captureConstants.CaptureInterfacesEnvKey, "lo")
+	intfs, err := ncp.captureInterfaces()
+	require.NoError(t, err)
+	require.Len(t, intfs, 1)
+	require.Equal(t, "lo", intfs[0].name)
+	require.Equal(t, linkLayerEthernet, intfs[0].linkLayer)
+
+	t.Setenv(captureConstants.CaptureInterfacesEnvKey, "does-not-exist")
+	_, err = ncp.captureInterfaces()
+	require.Error(t, err)
+
+	// Local addresses are routed through the loopback interface.
+	t.Setenv(captureConstants.CaptureInterfacesEnvKey, "")
+	t.Setenv(captureConstants.CapturePodsEnvKey, "127.0.0.2=default/pod1,127.0.0.3=default/pod2")
+	intfs, err = ncp.captureInterfaces()
+	require.NoError(t, err)
+	require.Len(t, intfs, 1)
+	require.Equal(t, "lo", intfs[0].name)
+	require.Equal(t, "Pods: default/pod1 (127.0.0.2), default/pod2 (127.0.0.3)", intfs[0].description)
+}
+
+// captureLoopbackPackets sends count packets from conn to itself, and returns the number of packets read from the
+// loopback interface of the network namespace.
+func captureLoopbackPackets(t *testing.T, ns netns.NsHandle, conn *net.UDPConn, count int) int {
+	t.Helper()
+
+	prog, err := compileFilter("udp and dst port "+strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port), linkLayerEthernet)
+	require.NoError(t, err)
+	var intf captureInterface
+	fd := -1
+	require.NoError(t, inNetNamespace(ns, func() error {
+		lo, err := netlink.LinkByName("lo")
+		if err != nil {
+			return err
+		}
+		if intf, err = linkCaptureInterface(lo, ""); err != nil {
+			return err
+		}
+		fd, err = openPacketSocket(intf.index, prog)
+		return err
+	}))
+	defer unix.Close(fd)
+	require.True(t, intf.loopback)
+
+	payload := make([]byte, 100)
+	for range count {
+		_, err = conn.WriteToUDP(payload, conn.LocalAddr().(*net.UDPAddr))
+		require.NoError(t, err)
+	}
+
+	packetChan := make(chan capturedPacket, 2*count)
+	stopChan := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- readPackets(fd, 0, 64, intf.loopback, packetChan, stopChan)
+	}()
+	// Leave time to read the packets which would be read twice.
+	time.Sleep(500 * time.Millisecond)
+	close(stopChan)
+	require.NoError(t, <-done)
+	return len(packetChan)
+}
+
+func TestReadPacketsLoopback(t *testing.T) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		t.Skipf("AF_PACKET sockets are not permitted: %s", err)
+	}
+	unix.Close(fd)
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The packets sent on the loopback interface are only captured once.
+	require.Equal(t, 5, captureLoopbackPackets(t, netns.None(), conn, 5))
+}
+
+// newPodNetNamespace creates a network namespace with a process in it, like a Pod, with the IP address on its loopback
+// interface and an eth0 interface, and returns the network namespace and a UDP socket bound in it.
+func newPodNetNamespace(t *testing.T, ip string) (netns.NsHandle, *net.UDPConn) {
+	t.Helper()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	origin, err := netns.Get()
+	require.NoError(t, err)
+	defer origin.Close()
+	podNs, err := netns.New()
+	if err != nil {
+		t.Skipf("Creating network namespaces is not permitted: %s", err)
+	}
+	t.Cleanup(func() { podNs.Close() })
+	defer func() {
+		require.NoError(t, netns.Set(origin))
+	}()
+
+	lo, err := netlink.LinkByName("lo")
+	require.NoError(t, err)
+	require.NoError(t, netlink.LinkSetUp(lo))
+	addr, err := netlink.ParseAddr(ip + "/32")
+	require.NoError(t, err)
+	require.NoError(t, netlink.AddrAdd(lo, addr))
+	eth0 := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eth0"}, PeerName: "veth0"}
+	require.NoError(t, netlink.LinkAdd(eth0))
+	require.NoError(t, netlink.LinkSetUp(eth0))
+
+	cmd := exec.Command("sleep", "60")
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		cmd.Process.Kill() //nolint:errcheck // test
+		cmd.Wait()         //nolint:errcheck // test
+	})
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return podNs, conn
+}
+
+func TestNativeCapturePodNetworkNamespace(t *testing.T) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		t.Skipf("AF_PACKET sockets are not permitted: %s", err)
+	}
+	unix.Close(fd)
+
+	log.SetupZapLogger(log.GetDefaultLogOpts()) //nolint:errcheck // test
+	_, conn := newPodNetNamespace(t, "10.244.0.10")
+	t.Setenv(captureConstants.CapturePodNetworkNamespaceEnvKey, "true")
+	t.Setenv(captureConstants.CapturePodsEnvKey, "10.244.0.10=default/pod1,10.244.0.11=default/pod2")
+
+	ncp, err := NewNativeNetworkCaptureProvider(log.Logger().Named("test"))
+	require.NoError(t, err)
+	dir, err := ncp.Setup("capture", "node")
+	require.NoError(t, err)
+	defer ncp.Cleanup() //nolint:errcheck // test
+
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	sigChan := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- ncp.CaptureNetworkPacket("udp port "+strconv.Itoa(port), 0, 0, sigChan)
+	}()
+
+	// Each packet is numbered by the first byte of its payload.
+	for i := 0; i < 10; i++ {
+		payload := make([]byte, 100)
+		payload[0] = byte(i)
+		_, err = conn.WriteToUDP(payload, conn.LocalAddr().(*net.UDPAddr))
+		require.NoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+	}
+	sigChan <- os.Interrupt
+	require.NoError(t, <-done)
+
+	// Only the Pod with a network namespace is captured.
+	files, err := filepath.Glob(filepath.Join(dir, "*.pcapng"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Contains(t, filepath.Base(files[0]), "-default_pod1.pcapng")
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	require.NoError(t, err)
+	packets := map[byte]int{}
+	for {
+		data, ci, err := r.ReadPacketData()
+		if err != nil {
+			break
+		}
+		// The packets are sent on the loopback interface.
+		require.Equal(t, 1, ci.InterfaceIndex)
+		pkt := gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default)
+		udp, ok := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
+		require.True(t, ok)
+		packets[udp.Payload[0]]++
+	}
+	require.NotEmpty(t, packets)
+	for i, n := range packets {
+		require.Equal(t, 1, n, "Expected packet %d to be captured once", i)
+	}
+
+	// The default interfaces are captured.
+	for id, name := range []string{"eth0", "lo"} {
+		intf, err := r.Interface(id)
+		require.NoError(t, err)
+		require.Equal(t, name, intf.Name)
+		require.Equal(t, "Pod: default/pod1", intf.Description)
+	}
+}
+
+func TestReadPacketsPodLoopback(t *testing.T) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0)
+	if err != nil {
+		t.Skipf("AF_PACKET sockets are not permitted: %s", err)
+	}
+	unix.Close(fd)
+
+	podNs, conn := newPodNetNamespace(t, "10.244.0.10")
+	// The packets sent on the loopback interface of the Pod are only captured once.
+	require.Equal(t, 5, captureLoopbackPackets(t, podNs, conn, 5))
+}
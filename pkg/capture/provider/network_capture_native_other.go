@@ -0,0 +1,18 @@
+//go:build !linux
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"errors"
+
+	"github.com/microsoft/retina/pkg/log"
+)
+
+var errNativeCaptureUnsupported = errors.New("the native capture backend is only supported on Linux")
+
+func NewNativeNetworkCaptureProvider(_ *log.ZapLogger) (NetworkCaptureProviderInterface, error) {
+	return nil, errNativeCaptureUnsupported
+}
@@ -6,10 +6,13 @@
 package provider
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -27,8 +30,13 @@ type NetworkCaptureProvider struct {
 	NodeHostName  string
 
 	l *log.ZapLogger
+	// capturedPackets is the number of network packets captured, if known.
+	capturedPackets *int64
 }
 
+// tcpdumpCapturedPacketsRegexp matches the number of captured packets tcpdump prints when it exits.
+var tcpdumpCapturedPacketsRegexp = regexp.MustCompile(`(?m)^(\d+) packets? captured`)
+
 var _ NetworkCaptureProviderInterface = &NetworkCaptureProvider{}
 
 func NewNetworkCaptureProvider(logger *log.ZapLogger) NetworkCaptureProviderInterface {
@@ -105,6 +113,7 @@ func (ncp *NetworkCaptureProvider) CaptureNetworkPacket(filter string, duration,
 			ncp.l.Warn("Failed to read tcpdump log", zap.Error(err))
 		} else {
 			ncp.l.Info(fmt.Sprintf("Tcpdump command output: %s", string(tcpdumpLog)))
+			ncp.capturedPackets = tcpdumpCapturedPackets(string(tcpdumpLog))
 		}
 		tcpdumpLogFile.Close()
 	}()
@@ -187,167 +196,46 @@ func (ncp *NetworkCaptureProvider) CaptureNetworkPacket(filter string, duration,
 	return nil
 }
 
-type command struct {
-	name        string
-	args        []string
-	description string
+// CapturedPackets returns the number of network packets captured, or nil if it is unknown.
+func (ncp *NetworkCaptureProvider) CapturedPackets() *int64 {
+	return ncp.capturedPackets
 }
 
+func tcpdumpCapturedPackets(tcpdumpLog string) *int64 {
+	match := tcpdumpCapturedPacketsRegexp.FindStringSubmatch(tcpdumpLog)
+	if match == nil {
+		return nil
+	}
+	packets, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &packets
+}
+
+// CollectMetadata collects the network metadata of the node, like routes, neighbors, conntrack entries, sockets and
+// iptables rules, and stores it as JSON so the metadata of different nodes and captures can be diffed.
 func (ncp *NetworkCaptureProvider) CollectMetadata() error {
 	ncp.l.Info("Start to collect network metadata")
 
 	iptablesMode := obtainIptablesMode()
 	ncp.l.Info(fmt.Sprintf("Iptables mode %s is used", iptablesMode))
-	iptablesSaveCmdName := fmt.Sprintf("iptables-%s-save", iptablesMode)
-	iptablesCmdName := fmt.Sprintf("iptables-%s", iptablesMode)
-
-	metadataList := []struct {
-		commands []command
-		fileName string
-	}{
-		{
-			commands: []command{
-				{
-					name:        "ip",
-					args:        []string{"-d", "-j", "addr", "show"},
-					description: "IP address configuration",
-				},
-				{
-					name:        "ip",
-					args:        []string{"-d", "-j", "neighbor", "show"},
-					description: "IP neighbor status",
-				},
-				{
-					name:        "ip",
-					args:        []string{"rule", "list"},
-					description: "Policy routing list",
-				},
-				{
-					name:        "ip",
-					args:        []string{"route", "show", "table", "all"},
-					description: "Routes of all route tables",
-				},
-			},
-			fileName: "ip-resources.txt",
-		},
-		{
-			commands: []command{
-				{
-					name:        iptablesSaveCmdName,
-					description: "IPtables rules",
-				},
-				{
-					name:        iptablesCmdName,
-					args:        []string{"-vnx", "-L"},
-					description: "IPtables rules and stats in filter table",
-				},
-				{
-					name:        iptablesCmdName,
-					args:        []string{"-vnx", "-L", "-t", "nat"},
-					description: "IPtables rules and stats in nat table",
-				},
-				{
-					name:        iptablesCmdName,
-					args:        []string{"-vnx", "-L", "-t", "mangle"},
-					description: "IPtables rules and stats in mangle table",
-				},
-			},
-			fileName: "iptables-rules.txt",
-		},
-		{
-			commands: []command{
-				{
-					name:        "ss",
-					args:        []string{"-s"},
-					description: "Socket statistics summary",
-				},
-				{
-					name:        "ss",
-					args:        []string{"-tapionume"},
-					description: "Socket statistics details",
-				},
-			},
-			fileName: "socket-stats.txt",
-		},
-		{
-			commands: []command{
-				{
-					name: "cp",
-					// '/proc/net' is a symbolic link to /proc/self/net since Linux 2.6.25 to honor network stack
-					// virtualization as the advent of network namespaces.
-					// https://man7.org/linux/man-pages/man5/proc.5.html
-					// NOTE(qinhao): We now clone only node host network net(self) stats here even when the capture
-					// target is container(s), for simplicity.
-					args:        []string{"-r", "/proc/self/net", filepath.Join(ncp.TmpCaptureDir, "proc-net")},
-					description: "networking stats",
-				},
-				{
-					name:        "cp",
-					args:        []string{"-r", "/proc/sys/net", filepath.Join(ncp.TmpCaptureDir, "proc-sys-net")},
-					description: "kernel networking configuration",
-				},
-			},
-		},
-	}
-
-	for _, metadata := range metadataList {
-		if len(metadata.fileName) != 0 {
-			captureMetadataFilePath := filepath.Join(ncp.TmpCaptureDir, metadata.fileName)
-			outfile, err := os.OpenFile(captureMetadataFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-			if err != nil {
-				ncp.l.Error("Failed to create metadata file", zap.String("metadata file path", captureMetadataFilePath), zap.Error(err))
-				continue
-			}
-			defer outfile.Close()
-
-			if _, err := outfile.WriteString("Summary:\n\n"); err != nil {
-				ncp.l.Error("Failed to write summary to file", zap.String("file", outfile.Name()), zap.Error(err))
-			}
-
-			// Print headlines for all commands in output file.
-			cmds := []*exec.Cmd{}
-			for _, command := range metadata.commands {
-				cmd := exec.Command(command.name, command.args...)
-				cmds = append(cmds, cmd)
-				commandSummary := fmt.Sprintf("%s(%s)\n", cmd.String(), command.description)
-				if _, err := outfile.WriteString(commandSummary); err != nil {
-					ncp.l.Error("Failed to write command description to file", zap.String("file", outfile.Name()), zap.Error(err))
-				}
-			}
-
-			if _, err := outfile.WriteString("\nExecute:\n\n"); err != nil {
-				ncp.l.Error("Failed to write command output to file", zap.String("file", outfile.Name()), zap.Error(err))
-			}
 
-			// Write command stdout and stderr to output file
-			for _, cmd := range cmds {
-				if _, err := outfile.WriteString(fmt.Sprintf("%s\n\n", cmd.String())); err != nil {
-					ncp.l.Error("Failed to write string to file", zap.String("file", outfile.Name()), zap.Error(err))
-				}
+	metadata := collectNetworkMetadata(iptablesMode)
+	for name, errMsg := range metadata.Errors {
+		// Don't return for error to continue capturing network packets, and the error is kept in the metadata file
+		// because the capture job can be recycled automatically leaving no info to debug.
+		ncp.l.Error("Failed to collect network metadata", zap.String("metadata", name), zap.String("error", errMsg))
+	}
 
-				cmd.Stdout = outfile
-				cmd.Stderr = outfile
-				if err := cmd.Run(); err != nil {
-					// Don't return for error to continue capturing following network metadata.
-					ncp.l.Error("Failed to execute command", zap.String("command", cmd.String()), zap.Error(err))
-					// Log the error in output file because this error does not stop capture job pod from finishing,
-					// and the job can be recycled automatically leaving no info to debug.
-					if _, err = outfile.WriteString(fmt.Sprintf("Failed to run %q, error: %s)", cmd.String(), err.Error())); err != nil {
-						ncp.l.Error("Failed to write command run failure", zap.String("command", cmd.String()), zap.Error(err))
-					}
-				}
-			}
-		} else {
-			for _, command := range metadata.commands {
-				cmd := exec.Command(command.name, command.args...)
-				// Errors will when copying kernel networking configuration for not all files under /proc/sys/net are
-				// readable, like '/proc/sys/net/ipv4/route/flush', which doesn't implement the read function.
-				if output, err := cmd.CombinedOutput(); err != nil {
-					// Don't return for error to continue capturing following network metadata.
-					ncp.l.Error("Failed to execute command", zap.String("command", cmd.String()), zap.String("output", string(output)), zap.Error(err))
-				}
-			}
-		}
+	metadataFilePath := filepath.Join(ncp.TmpCaptureDir, NetworkMetadataFileName)
+	content, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode network metadata: %w", err)
+	}
+	if err := os.WriteFile(metadataFilePath, content, 0o644); err != nil {
+		ncp.l.Error("Failed to write metadata file", zap.String("metadata file path", metadataFilePath), zap.Error(err))
+		return err
 	}
 
 	ncp.l.Info("Done for collecting network metadata")
@@ -0,0 +1,267 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NetworkMetadataFileName is the name of the file storing the network metadata of the node in the capture artifact.
+const NetworkMetadataFileName = "network-metadata.json"
+
+// NetworkMetadata is the network metadata of a node collected by the capture job. Its lists are sorted so the
+// metadata of different nodes and captures can be diffed.
+type NetworkMetadata struct {
+	CollectedAt  time.Time          `json:"collectedAt"`
+	Interfaces   []InterfaceInfo    `json:"interfaces"`
+	Addresses    []AddressInfo      `json:"addresses"`
+	Routes       []RouteInfo        `json:"routes"`
+	RoutingRules []RoutingRuleInfo  `json:"routingRules"`
+	Neighbors    []NeighborInfo     `json:"neighbors"`
+	Conntrack    []ConntrackEntry   `json:"conntrack"`
+	Sockets      []SocketInfo       `json:"sockets"`
+	IptablesMode string             `json:"iptablesMode,omitempty"`
+	Iptables     []IptablesTable    `json:"iptables"`
+	Ip6tables    []IptablesTable    `json:"ip6tables"`
+	Sysctls      map[string]string  `json:"sysctls"`
+	Counters     map[string]Counter `json:"counters"`
+	// Errors maps the metadata that failed to be collected to the error, as a failure does not stop the capture.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// InterfaceInfo is a network interface of the node.
+type InterfaceInfo struct {
+	Index        int    `json:"index"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	HardwareAddr string `json:"hardwareAddr,omitempty"`
+	MTU          int    `json:"mtu"`
+	State        string `json:"state"`
+	Master       string `json:"master,omitempty"`
+}
+
+// AddressInfo is an IP address assigned to a network interface.
+type AddressInfo struct {
+	Interface string `json:"interface"`
+	Address   string `json:"address"`
+	Scope     int    `json:"scope"`
+	Flags     int    `json:"flags,omitempty"`
+}
+
+// RouteInfo is a route of any routing table.
+type RouteInfo struct {
+	Table       int      `json:"table"`
+	Type        int      `json:"type"`
+	Destination string   `json:"destination"`
+	Gateways    []string `json:"gateways,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Interface   string   `json:"interface,omitempty"`
+	Protocol    int      `json:"protocol"`
+	Scope       int      `json:"scope"`
+	Priority    int      `json:"priority,omitempty"`
+}
+
+// RoutingRuleInfo is a policy routing rule.
+type RoutingRuleInfo struct {
+	Family            string `json:"family"`
+	Priority          int    `json:"priority"`
+	Table             int    `json:"table"`
+	Source            string `json:"source,omitempty"`
+	Destination       string `json:"destination,omitempty"`
+	InputInterface    string `json:"inputInterface,omitempty"`
+	OutputInterface   string `json:"outputInterface,omitempty"`
+	Mark              int    `json:"mark,omitempty"`
+	Mask              int    `json:"mask,omitempty"`
+	Invert            bool   `json:"invert,omitempty"`
+	SuppressPrefixLen int    `json:"suppressPrefixLen,omitempty"`
+}
+
+// NeighborInfo is an entry of the ARP or NDP neighbor table.
+type NeighborInfo struct {
+	Interface    string `json:"interface"`
+	IP           string `json:"ip"`
+	HardwareAddr string `json:"hardwareAddr,omitempty"`
+	State        string `json:"state"`
+}
+
+// ConntrackEntry is a connection tracked by netfilter.
+type ConntrackEntry struct {
+	Protocol string         `json:"protocol"`
+	Original ConntrackTuple `json:"original"`
+	Reply    ConntrackTuple `json:"reply"`
+	Mark     uint32         `json:"mark,omitempty"`
+	Zone     uint16         `json:"zone,omitempty"`
+	Timeout  uint32         `json:"timeout"`
+}
+
+// ConntrackTuple is one direction of a tracked connection.
+type ConntrackTuple struct {
+	Source          string `json:"source"`
+	Destination     string `json:"destination"`
+	SourcePort      uint16 `json:"sourcePort,omitempty"`
+	DestinationPort uint16 `json:"destinationPort,omitempty"`
+	Packets         uint64 `json:"packets,omitempty"`
+	Bytes           uint64 `json:"bytes,omitempty"`
+}
+
+// SocketInfo is a TCP or UDP socket of the node.
+type SocketInfo struct {
+	Protocol    string `json:"protocol"`
+	State       string `json:"state"`
+	Local       string `json:"local"`
+	Remote      string `json:"remote"`
+	Interface   string `json:"interface,omitempty"`
+	ReceiveQ    uint32 `json:"receiveQueue"`
+	SendQ       uint32 `json:"sendQueue"`
+	UID         uint32 `json:"uid"`
+	Inode       uint32 `json:"inode"`
+	Retransmits uint8  `json:"retransmits,omitempty"`
+}
+
+// IptablesTable is an iptables table with its chains and rules.
+type IptablesTable struct {
+	Name   string          `json:"name"`
+	Chains []IptablesChain `json:"chains"`
+}
+
+// IptablesChain is an iptables chain with its rules in order.
+type IptablesChain struct {
+	Name string `json:"name"`
+	// Policy is the policy of a built-in chain, or empty for a user-defined chain.
+	Policy  string         `json:"policy,omitempty"`
+	Packets uint64         `json:"packets"`
+	Bytes   uint64         `json:"bytes"`
+	Rules   []IptablesRule `json:"rules"`
+}
+
+// IptablesRule is an iptables rule in iptables-save format without the chain.
+type IptablesRule struct {
+	Rule    string `json:"rule"`
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// Counter is a group of network statistics counters, like Tcp of /proc/net/snmp.
+type Counter map[string]int64
+
+var (
+	// iptablesChainRegexp matches a chain line of iptables-save output, like ":INPUT ACCEPT [12:3456]".
+	iptablesChainRegexp = regexp.MustCompile(`^:(\S+) (\S+)(?: \[(\d+):(\d+)\])?$`)
+	// iptablesRuleRegexp matches a rule line of iptables-save output with counters, like "[1:60] -A INPUT -j ACCEPT".
+	iptablesRuleRegexp = regexp.MustCompile(`^(?:\[(\d+):(\d+)\] )?-A (\S+)(?: (.*))?$`)
+)
+
+// parseIptablesSave parses the output of iptables-save, with or without counters, to iptables tables.
+func parseIptablesSave(output string) ([]IptablesTable, error) {
+	var tables []IptablesTable
+	var table *IptablesTable
+	chainIndex := map[string]int{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "*"):
+			tables = append(tables, IptablesTable{Name: line[1:], Chains: []IptablesChain{}})
+			table = &tables[len(tables)-1]
+			chainIndex = map[string]int{}
+		case line == "COMMIT":
+			table = nil
+		case table == nil:
+			return nil, fmt.Errorf("line %d: %q is out of a table", lineNum, line)
+		case strings.HasPrefix(line, ":"):
+			match := iptablesChainRegexp.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: invalid chain %q", lineNum, line)
+			}
+			chain := IptablesChain{Name: match[1], Rules: []IptablesRule{}}
+			if match[2] != "-" {
+				chain.Policy = match[2]
+			}
+			chain.Packets, _ = strconv.ParseUint(match[3], 10, 64)
+			chain.Bytes, _ = strconv.ParseUint(match[4], 10, 64)
+			chainIndex[chain.Name] = len(table.Chains)
+			table.Chains = append(table.Chains, chain)
+		default:
+			match := iptablesRuleRegexp.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: invalid rule %q", lineNum, line)
+			}
+			i, ok := chainIndex[match[3]]
+			if !ok {
+				return nil, fmt.Errorf("line %d: rule of undeclared chain %q", lineNum, match[3])
+			}
+			rule := IptablesRule{Rule: match[4]}
+			rule.Packets, _ = strconv.ParseUint(match[1], 10, 64)
+			rule.Bytes, _ = strconv.ParseUint(match[2], 10, 64)
+			table.Chains[i].Rules = append(table.Chains[i].Rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// parseProcNetSNMP parses the counters of /proc/net/snmp or /proc/net/netstat, where each group takes a line of
+// counter names followed by a line of values, both prefixed by the group name, into counters.
+func parseProcNetSNMP(content string, counters map[string]Counter) error {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines)%2 != 0 {
+		return fmt.Errorf("odd number of lines %d", len(lines))
+	}
+	for i := 0; i < len(lines); i += 2 {
+		names := strings.Fields(lines[i])
+		values := strings.Fields(lines[i+1])
+		if len(names) == 0 || len(names) != len(values) || names[0] != values[0] {
+			return fmt.Errorf("line %d: mismatched counter names and values", i+1)
+		}
+		group := strings.TrimSuffix(names[0], ":")
+		if counters[group] == nil {
+			counters[group] = Counter{}
+		}
+		for j := 1; j < len(names); j++ {
+			value, err := strconv.ParseInt(values[j], 10, 64)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid value of counter %s: %w", i+2, names[j], err)
+			}
+			counters[group][names[j]] = value
+		}
+	}
+	return nil
+}
+
+// parseProcNetSNMP6 parses the counters of /proc/net/snmp6, where each line is a counter name and its value, into
+// counters grouped by the prefix of the name, like Ip6 of Ip6InReceives.
+func parseProcNetSNMP6(content string, counters map[string]Counter) error {
+	for i, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("line %d: invalid counter %q", i+1, line)
+		}
+		value, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid value of counter %s: %w", i+1, fields[0], err)
+		}
+		group := "Ip6"
+		for _, prefix := range []string{"Icmp6", "Udp6", "UdpLite6", "Ip6"} {
+			if strings.HasPrefix(fields[0], prefix) {
+				group = prefix
+				break
+			}
+		}
+		if counters[group] == nil {
+			counters[group] = Counter{}
+		}
+		counters[group][strings.TrimPrefix(fields[0], group)] = value
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	procSysNetDir = "/proc/sys/net"
+	// procNetDir is the network stats of the network namespace of the capture workload, which is the host network
+	// namespace of the node.
+	procNetDir = "/proc/self/net"
+)
+
+// tcpStates are the names of the socket states of the kernel, indexed by the state.
+// https://github.com/torvalds/linux/blob/master/include/net/tcp_states.h
+var tcpStates = []string{
+	"UNKNOWN", "ESTABLISHED", "SYN_SENT", "SYN_RECV", "FIN_WAIT1", "FIN_WAIT2", "TIME_WAIT",
+	"CLOSE", "CLOSE_WAIT", "LAST_ACK", "LISTEN", "CLOSING", "NEW_SYN_RECV",
+}
+
+// neighborStates are the names of the neighbor states of the kernel.
+var neighborStates = []struct {
+	state int
+	name  string
+}{
+	{netlink.NUD_INCOMPLETE, "INCOMPLETE"},
+	{netlink.NUD_REACHABLE, "REACHABLE"},
+	{netlink.NUD_STALE, "STALE"},
+	{netlink.NUD_DELAY, "DELAY"},
+	{netlink.NUD_PROBE, "PROBE"},
+	{netlink.NUD_FAILED, "FAILED"},
+	{netlink.NUD_NOARP, "NOARP"},
+	{netlink.NUD_PERMANENT, "PERMANENT"},
+}
+
+// networkMetadataCollector collects the network metadata of the node through netlink and procfs.
+type networkMetadataCollector struct {
+	metadata NetworkMetadata
+	// linkNames maps the index of network interfaces to their names.
+	linkNames map[int]string
+}
+
+// collectNetworkMetadata collects the network metadata of the node. The metadata failing to be collected is recorded
+// in the errors of the metadata instead of stopping the collection.
+func collectNetworkMetadata(iptablesMode string) *NetworkMetadata {
+	c := &networkMetadataCollector{
+		metadata: NetworkMetadata{
+			CollectedAt:  time.Now().UTC(),
+			IptablesMode: iptablesMode,
+			Sysctls:      map[string]string{},
+			Counters:     map[string]Counter{},
+			Errors:       map[string]string{},
+		},
+		linkNames: map[int]string{},
+	}
+
+	collectors := []struct {
+		name    string
+		collect func() error
+	}{
+		{name: "interfaces", collect: c.collectInterfaces},
+		{name: "addresses", collect: c.collectAddresses},
+		{name: "routes", collect: c.collectRoutes},
+		{name: "routingRules", collect: c.collectRoutingRules},
+		{name: "neighbors", collect: c.collectNeighbors},
+		{name: "conntrack", collect: c.collectConntrack},
+		{name: "sockets", collect: c.collectSockets},
+		{name: "iptables", collect: func() error { return c.collectIptables("iptables", &c.metadata.Iptables) }},
+		{name: "ip6tables", collect: func() error { return c.collectIptables("ip6tables", &c.metadata.Ip6tables) }},
+		{name: "sysctls", collect: c.collectSysctls},
+		{name: "counters", collect: c.collectCounters},
+	}
+	for _, collector := range collectors {
+		if err := collector.collect(); err != nil {
+			c.metadata.Errors[collector.name] = err.Error()
+		}
+	}
+	return &c.metadata
+}
+
+func (c *networkMetadataCollector) collectInterfaces() error {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list links: %w", err)
+	}
+	for _, link := range links {
+		c.linkNames[link.Attrs().Index] = link.Attrs().Name
+	}
+	for _, link := range links {
+		attrs := link.Attrs()
+		c.metadata.Interfaces = append(c.metadata.Interfaces, InterfaceInfo{
+			Index:        attrs.Index,
+			Name:         attrs.Name,
+			Type:         link.Type(),
+			HardwareAddr: attrs.HardwareAddr.String(),
+			MTU:          attrs.MTU,
+			State:        attrs.OperState.String(),
+			Master:       c.linkNames[attrs.MasterIndex],
+		})
+	}
+	sort.Slice(c.metadata.Interfaces, func(i, j int) bool {
+		return c.metadata.Interfaces[i].Index < c.metadata.Interfaces[j].Index
+	})
+	return nil
+}
+
+func (c *networkMetadataCollector) collectAddresses() error {
+	addrs, err := netlink.AddrList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list addresses: %w", err)
+	}
+	for i := range addrs {
+		c.metadata.Addresses = append(c.metadata.Addresses, AddressInfo{
+			Interface: c.linkName(addrs[i].LinkIndex),
+			Address:   addrs[i].IPNet.String(),
+			Scope:     addrs[i].Scope,
+			Flags:     addrs[i].Flags,
+		})
+	}
+	sort.Slice(c.metadata.Addresses, func(i, j int) bool {
+		a, b := c.metadata.Addresses[i], c.metadata.Addresses[j]
+		if a.Interface != b.Interface {
+			return a.Interface < b.Interface
+		}
+		return a.Address < b.Address
+	})
+	return nil
+}
+
+func (c *networkMetadataCollector) collectRoutes() error {
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{Table: unix.RT_TABLE_UNSPEC}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return fmt.Errorf("failed to list routes: %w", err)
+	}
+	for i := range routes {
+		route := &routes[i]
+		info := RouteInfo{
+			Table:       route.Table,
+			Type:        route.Type,
+			Destination: "default",
+			Interface:   c.linkName(route.LinkIndex),
+			Protocol:    int(route.Protocol),
+			Scope:       int(route.Scope),
+			Priority:    route.Priority,
+		}
+		if route.Dst != nil {
+			info.Destination = route.Dst.String()
+		}
+		if route.Src != nil {
+			info.Source = route.Src.String()
+		}
+		if route.Gw != nil {
+			info.Gateways = append(info.Gateways, route.Gw.String())
+		}
+		for _, nexthop := range route.MultiPath {
+			if nexthop.Gw != nil {
+				info.Gateways = append(info.Gateways, fmt.Sprintf("%s dev %s", nexthop.Gw, c.linkName(nexthop.LinkIndex)))
+			}
+		}
+		c.metadata.Routes = append(c.metadata.Routes, info)
+	}
+	sort.SliceStable(c.metadata.Routes, func(i, j int) bool {
+		a, b := c.metadata.Routes[i], c.metadata.Routes[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.Destination < b.Destination
+	})
+	return nil
+}
+
+func (c *networkMetadataCollector) collectRoutingRules() error {
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		rules, err := netlink.RuleList(family)
+		if err != nil {
+			return fmt.Errorf("failed to list routing rules of %s: %w", familyName(family), err)
+		}
+		for i := range rules {
+			rule := &rules[i]
+			info := RoutingRuleInfo{
+				Family:            familyName(family),
+				Priority:          rule.Priority,
+				Table:             rule.Table,
+				InputInterface:    rule.IifName,
+				OutputInterface:   rule.OifName,
+				Mark:              rule.Mark,
+				Invert:            rule.Invert,
+				SuppressPrefixLen: max(rule.SuppressPrefixlen, 0),
+			}
+			if rule.Mask > 0 {
+				info.Mask = rule.Mask
+			}
+			if rule.Src != nil {
+				info.Source = rule.Src.String()
+			}
+			if rule.Dst != nil {
+				info.Destination = rule.Dst.String()
+			}
+			c.metadata.RoutingRules = append(c.metadata.RoutingRules, info)
+		}
+	}
+	return nil
+}
+
+func (c *networkMetadataCollector) collectNeighbors() error {
+	neighs, err := netlink.NeighList(0, netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list neighbors: %w", err)
+	}
+	for i := range neighs {
+		info := NeighborInfo{
+			Interface: c.linkName(neighs[i].LinkIndex),
+			IP:        neighs[i].IP.String(),
+			State:     neighborState(neighs[i].State),
+		}
+		if len(neighs[i].HardwareAddr) != 0 {
+			info.HardwareAddr = neighs[i].HardwareAddr.String()
+		}
+		c.metadata.Neighbors = append(c.metadata.Neighbors, info)
+	}
+	sort.Slice(c.metadata.Neighbors, func(i, j int) bool {
+		a, b := c.metadata.Neighbors[i], c.metadata.Neighbors[j]
+		if a.Interface != b.Interface {
+			return a.Interface < b.Interface
+		}
+		return a.IP < b.IP
+	})
+	return nil
+}
+
+func (c *networkMetadataCollector) collectConntrack() error {
+	for _, family := range []netlink.InetFamily{unix.AF_INET, unix.AF_INET6} {
+		flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, family)
+		if err != nil {
+			return fmt.Errorf("failed to list conntrack entries of %s: %w", familyName(int(family)), err)
+		}
+		for _, flow := range flows {
+			c.metadata.Conntrack = append(c.metadata.Conntrack, ConntrackEntry{
+				Protocol: protocolName(flow.Forward.Protocol),
+				Original: ConntrackTuple{
+					Source:          flow.Forward.SrcIP.String(),
+					Destination:     flow.Forward.DstIP.String(),
+					SourcePort:      flow.Forward.SrcPort,
+					DestinationPort: flow.Forward.DstPort,
+					Packets:         flow.Forward.Packets,
+					Bytes:           flow.Forward.Bytes,
+				},
+				Reply: ConntrackTuple{
+					Source:          flow.Reverse.SrcIP.String(),
+					Destination:     flow.Reverse.DstIP.String(),
+					SourcePort:      flow.Reverse.SrcPort,
+					DestinationPort: flow.Reverse.DstPort,
+					Packets:         flow.Reverse.Packets,
+					Bytes:           flow.Reverse.Bytes,
+				},
+				Mark:    flow.Mark,
+				Zone:    flow.Zone,
+				Timeout: flow.TimeOut,
+			})
+		}
+	}
+	sort.SliceStable(c.metadata.Conntrack, func(i, j int) bool {
+		a, b := c.metadata.Conntrack[i].Original, c.metadata.Conntrack[j].Original
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Destination != b.Destination {
+			return a.Destination < b.Destination
+		}
+		if a.SourcePort != b.SourcePort {
+			return a.SourcePort < b.SourcePort
+		}
+		return a.DestinationPort < b.DestinationPort
+	})
+	return nil
+}
+
+func (c *networkMetadataCollector) collectSockets() error {
+	var errs []error
+	for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		tcpSockets, err := netlink.SocketDiagTCP(family)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list TCP sockets of %s: %w", familyName(int(family)), err))
+		}
+		c.appendSockets("tcp", tcpSockets)
+
+		udpSockets, err := netlink.SocketDiagUDP(family)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list UDP sockets of %s: %w", familyName(int(family)), err))
+		}
+		c.appendSockets("udp", udpSockets)
+	}
+	sort.SliceStable(c.metadata.Sockets, func(i, j int) bool {
+		a, b := c.metadata.Sockets[i], c.metadata.Sockets[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.Local != b.Local {
+			return a.Local < b.Local
+		}
+		return a.Remote < b.Remote
+	})
+	return errors.Join(errs...)
+}
+
+func (c *networkMetadataCollector) appendSockets(protocol string, sockets []*netlink.Socket) {
+	for _, socket := range sockets {
+		info := SocketInfo{
+			Protocol:    protocol,
+			State:       "UNKNOWN",
+			Local:       net.JoinHostPort(socket.ID.Source.String(), strconv.Itoa(int(socket.ID.SourcePort))),
+			Remote:      net.JoinHostPort(socket.ID.Destination.String(), strconv.Itoa(int(socket.ID.DestinationPort))),
+			ReceiveQ:    socket.RQueue,
+			SendQ:       socket.WQueue,
+			UID:         socket.UID,
+			Inode:       socket.INode,
+			Retransmits: socket.Retrans,
+		}
+		if int(socket.State) < len(tcpStates) {
+			info.State = tcpStates[socket.State]
+		}
+		if socket.ID.Interface != 0 {
+			info.Interface = c.linkName(int(socket.ID.Interface))
+		}
+		c.metadata.Sockets = append(c.metadata.Sockets, info)
+	}
+}
+
+// collectIptables collects the rules of all tables of iptables or ip6tables. Legacy iptables tables are not exposed
+// through netlink, so the rules are parsed from the output of iptables-save or ip6tables-save of the iptables mode in
+// use.
+func (c *networkMetadataCollector) collectIptables(command string, tables *[]IptablesTable) error {
+	cmd := exec.Command(fmt.Sprintf("%s-%s-save", command, c.metadata.IptablesMode), "--counters")
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to run %q: %w", cmd.String(), err)
+	}
+	parsed, err := parseIptablesSave(string(output))
+	if err != nil {
+		return fmt.Errorf("failed to parse output of %q: %w", cmd.String(), err)
+	}
+	sort.SliceStable(parsed, func(i, j int) bool { return parsed[i].Name < parsed[j].Name })
+	*tables = parsed
+	return nil
+}
+
+// collectSysctls collects the kernel networking configuration under /proc/sys/net, keyed by the sysctl name like
+// net.ipv4.ip_forward. Files not readable, like /proc/sys/net/ipv4/route/flush, are skipped.
+func (c *networkMetadataCollector) collectSysctls() error {
+	return filepath.WalkDir(procSysNetDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == procSysNetDir {
+				return fmt.Errorf("failed to read %s: %w", procSysNetDir, err)
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		name := strings.ReplaceAll(strings.TrimPrefix(path, "/proc/sys/"), "/", ".")
+		c.metadata.Sysctls[name] = strings.Join(strings.Fields(string(value)), " ")
+		return nil
+	})
+}
+
+// collectCounters collects the network statistics counters of the node from procfs.
+func (c *networkMetadataCollector) collectCounters() error {
+	var errs []error
+	for _, counterFile := range []struct {
+		name  string
+		parse func(string, map[string]Counter) error
+	}{
+		{name: "snmp", parse: parseProcNetSNMP},
+		{name: "netstat", parse: parseProcNetSNMP},
+		{name: "snmp6", parse: parseProcNetSNMP6},
+	} {
+		path := filepath.Join(procNetDir, counterFile.name)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := counterFile.parse(string(content), c.metadata.Counters); err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse %s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *networkMetadataCollector) linkName(index int) string {
+	if name, ok := c.linkNames[index]; ok {
+		return name
+	}
+	if index == 0 {
+		return ""
+	}
+	return strconv.Itoa(index)
+}
+
+func familyName(family int) string {
+	if family == netlink.FAMILY_V6 {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+func protocolName(protocol uint8) string {
+	if name, ok := nl.L4ProtoMap[protocol]; ok {
+		return name
+	}
+	return strconv.Itoa(int(protocol))
+}
+
+func neighborState(state int) string {
+	if state == netlink.NUD_NONE {
+		return "NONE"
+	}
+	var names []string
+	for _, s := range neighborStates {
+		if state&s.state != 0 {
+			names = append(names, s.name)
+		}
+	}
+	if len(names) == 0 {
+		return strconv.Itoa(state)
+	}
+	return strings.Join(names, ",")
+}
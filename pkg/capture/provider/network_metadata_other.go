@@ -0,0 +1,20 @@
+//go:build unix && !linux
+
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"runtime"
+	"time"
+)
+
+// collectNetworkMetadata reports that network metadata is only collected through netlink on Linux.
+func collectNetworkMetadata(iptablesMode string) *NetworkMetadata {
+	return &NetworkMetadata{
+		CollectedAt:  time.Now().UTC(),
+		IptablesMode: iptablesMode,
+		Errors:       map[string]string{"all": "network metadata collection is not supported on " + runtime.GOOS},
+	}
+}
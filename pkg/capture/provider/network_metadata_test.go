@@ -0,0 +1,102 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseIptablesSave(t *testing.T) {
+	output := `# Generated by iptables-nft-save v1.8.7 on Mon Mar 20 01:36:00 2023
+*nat
+:PREROUTING ACCEPT [10:600]
+:KUBE-SERVICES - [0:0]
+[3:180] -A PREROUTING -m comment --comment "kubernetes service portals" -j KUBE-SERVICES
+-A KUBE-SERVICES -d 10.0.0.10/32 -p udp -m udp --dport 53 -j KUBE-SVC-TCOU7JCQXEZGVUNU
+COMMIT
+*filter
+:INPUT DROP [1:60]
+COMMIT
+`
+	want := []IptablesTable{
+		{
+			Name: "nat",
+			Chains: []IptablesChain{
+				{
+					Name:    "PREROUTING",
+					Policy:  "ACCEPT",
+					Packets: 10,
+					Bytes:   600,
+					Rules: []IptablesRule{
+						{Rule: `-m comment --comment "kubernetes service portals" -j KUBE-SERVICES`, Packets: 3, Bytes: 180},
+					},
+				},
+				{
+					Name:  "KUBE-SERVICES",
+					Rules: []IptablesRule{{Rule: "-d 10.0.0.10/32 -p udp -m udp --dport 53 -j KUBE-SVC-TCOU7JCQXEZGVUNU"}},
+				},
+			},
+		},
+		{
+			Name:   "filter",
+			Chains: []IptablesChain{{Name: "INPUT", Policy: "DROP", Packets: 1, Bytes: 60, Rules: []IptablesRule{}}},
+		},
+	}
+
+	got, err := parseIptablesSave(output)
+	if err != nil {
+		t.Fatalf("parseIptablesSave() want no error, got %s", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseIptablesSave() mismatch (-want, +got):\n%s", diff)
+	}
+
+	for _, invalid := range []string{
+		"-A INPUT -j ACCEPT",
+		"*filter\n-A INPUT -j ACCEPT\nCOMMIT",
+		"*filter\n:INPUT\nCOMMIT",
+	} {
+		if _, err := parseIptablesSave(invalid); err == nil {
+			t.Errorf("parseIptablesSave(%q) want error, got nil", invalid)
+		}
+	}
+}
+
+func TestParseProcNetCounters(t *testing.T) {
+	snmp := `Ip: Forwarding DefaultTTL InReceives
+Ip: 1 64 1234
+Tcp: RtoAlgorithm ActiveOpens CurrEstab
+Tcp: 1 56 -1
+`
+	snmp6 := `Ip6InReceives                   	100
+Icmp6InMsgs                     	2
+Udp6InDatagrams                 	30
+UdpLite6InDatagrams             	0
+`
+	want := map[string]Counter{
+		"Ip":       {"Forwarding": 1, "DefaultTTL": 64, "InReceives": 1234},
+		"Tcp":      {"RtoAlgorithm": 1, "ActiveOpens": 56, "CurrEstab": -1},
+		"Ip6":      {"InReceives": 100},
+		"Icmp6":    {"InMsgs": 2},
+		"Udp6":     {"InDatagrams": 30},
+		"UdpLite6": {"InDatagrams": 0},
+	}
+
+	got := map[string]Counter{}
+	if err := parseProcNetSNMP(snmp, got); err != nil {
+		t.Fatalf("parseProcNetSNMP() want no error, got %s", err)
+	}
+	if err := parseProcNetSNMP6(snmp6, got); err != nil {
+		t.Fatalf("parseProcNetSNMP6() want no error, got %s", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parsed counters mismatch (-want, +got):\n%s", diff)
+	}
+
+	if err := parseProcNetSNMP("Ip: Forwarding\nTcp: 1\n", map[string]Counter{}); err == nil {
+		t.Errorf("parseProcNetSNMP() want error for mismatched names and values, got nil")
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+)
+
+// AddServiceEndpoint adds the IP addresses of the Pod backing the Services on the node, along with the ClusterIPs and
+// NodePorts of the Services. An address added more than once is only kept once.
+func (cton CaptureTargetsOnNode) AddServiceEndpoint(hostname, podName string, ipAddresses, serviceIPAddresses []string, nodePorts []int32) {
+	captureTarget := cton[hostname]
+	for _, ipAddress := range ipAddresses {
+		if !slices.Contains(captureTarget.PodIpAddresses, ipAddress) {
+			captureTarget.PodIpAddresses = append(captureTarget.PodIpAddresses, ipAddress)
+		}
+	}
+	for _, serviceIPAddress := range serviceIPAddresses {
+		if !slices.Contains(captureTarget.ServiceIPAddresses, serviceIPAddress) {
+			captureTarget.ServiceIPAddresses = append(captureTarget.ServiceIPAddresses, serviceIPAddress)
+		}
+	}
+	for _, nodePort := range nodePorts {
+		if !slices.Contains(captureTarget.NodePorts, nodePort) {
+			captureTarget.NodePorts = append(captureTarget.NodePorts, nodePort)
+		}
+	}
+	cton[hostname] = captureTarget
+	if len(podName) != 0 {
+		cton.AddPodName(hostname, podName, ipAddresses)
+	}
+}
+
+// calculateCaptureTargetsByServiceSelector selects the Pods backing the selected Services from the EndpointSlices of the
+// Services, on the nodes hosting them.
+func (translator *CaptureToPodTranslator) calculateCaptureTargetsByServiceSelector(captureTarget retinav1alpha1.CaptureTarget) (*CaptureTargetsOnNode, error) {
+	captureTargetOnNode := &CaptureTargetsOnNode{}
+	nsList, err := translator.selectNamespaces(captureTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	labelSelector, err := labels.Parse(metav1.FormatLabelSelector(captureTarget.ServiceSelector))
+	if err != nil {
+		translator.l.Error("Failed to parse service selector to label", zap.String("serviceSelector", captureTarget.ServiceSelector.String()), zap.Error(err))
+		return nil, err
+	}
+	for _, ns := range nsList.Items {
+		serviceList, err := translator.kubeClient.CoreV1().Services(ns.Name).List(context.TODO(), metav1.ListOptions{
+			LabelSelector: labelSelector.String(),
+		})
+		if err != nil {
+			translator.l.Error("Failed to list Service", zap.String("serviceSelector", captureTarget.ServiceSelector.String()), zap.Error(err))
+			return nil, err
+		}
+		for i := range serviceList.Items {
+			if err := translator.addServiceEndpoints(captureTargetOnNode, &serviceList.Items[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return captureTargetOnNode, nil
+}
+
+// addServiceEndpoints adds the Pods backing the Service to the capture targets on their nodes. Endpoints without a node,
+// like the external endpoints of Services without selectors, are not captured.
+func (translator *CaptureToPodTranslator) addServiceEndpoints(captureTargetOnNode *CaptureTargetsOnNode, service *corev1.Service) error {
+	var serviceIPs []string
+	for _, clusterIP := range service.Spec.ClusterIPs {
+		if len(clusterIP) != 0 && clusterIP != corev1.ClusterIPNone {
+			serviceIPs = append(serviceIPs, clusterIP)
+		}
+	}
+	var nodePorts []int32
+	for _, port := range service.Spec.Ports {
+		if port.NodePort != 0 {
+			nodePorts = append(nodePorts, port.NodePort)
+		}
+	}
+
+	endpointSlices, err := translator.kubeClient.DiscoveryV1().EndpointSlices(service.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: service.Name}).String(),
+	})
+	if err != nil {
+		translator.l.Error("Failed to list EndpointSlice", zap.String("Service", service.Namespace+"/"+service.Name), zap.Error(err))
+		return err
+	}
+	for _, endpointSlice := range endpointSlices.Items {
+		for _, endpoint := range endpointSlice.Endpoints {
+			if endpoint.NodeName == nil || len(*endpoint.NodeName) == 0 {
+				continue
+			}
+			var podName string
+			if endpoint.TargetRef != nil && endpoint.TargetRef.Kind == "Pod" {
+				podName = endpoint.TargetRef.Namespace + "/" + endpoint.TargetRef.Name
+			}
+			captureTargetOnNode.AddServiceEndpoint(*endpoint.NodeName, podName, endpoint.Addresses, serviceIPs, nodePorts)
+		}
+	}
+	return nil
+}
+
+// TranslateCaptureToJobsOfNewEndpoints translates the Capture selecting Services to the capture jobs of the Pods
+// backing the Services but not captured by the existing capture jobs, like Pods scaled out or rescheduled since the
+// Capture started durationOffset ago. The jobs capture for the rest of the Capture duration.
+func (translator *CaptureToPodTranslator) TranslateCaptureToJobsOfNewEndpoints(capture *retinav1alpha1.Capture, captureJobs []batchv1.Job, durationOffset time.Duration) ([]*batchv1.Job, error) {
+	captureTarget := capture.Spec.CaptureConfiguration.CaptureTarget
+	if captureTarget.ServiceSelector == nil {
+		return nil, nil
+	}
+	if err := translator.validateCapture(capture); err != nil {
+		return nil, err
+	}
+	if err := translator.initJobTemplate(capture); err != nil {
+		return nil, err
+	}
+
+	captureTargetsOnNode, err := translator.calculateCaptureTargetsByServiceSelector(captureTarget)
+	if err != nil {
+		return nil, err
+	}
+	capturedIPs := capturedPodIPs(captureJobs)
+	for nodeName, target := range *captureTargetsOnNode {
+		var newPodIPs []string
+		for _, podIP := range target.PodIpAddresses {
+			if !capturedIPs[nodeName][podIP] {
+				newPodIPs = append(newPodIPs, podIP)
+			}
+		}
+		if len(newPodIPs) == 0 {
+			delete(*captureTargetsOnNode, nodeName)
+			continue
+		}
+		target.PodIpAddresses = newPodIPs
+		for podIP := range target.PodNames {
+			if capturedIPs[nodeName][podIP] {
+				delete(target.PodNames, podIP)
+			}
+		}
+		(*captureTargetsOnNode)[nodeName] = target
+	}
+	if len(*captureTargetsOnNode) == 0 {
+		return nil, nil
+	}
+	if err := translator.updateCaptureTargetsOSOnNode(captureTargetsOnNode); err != nil {
+		return nil, err
+	}
+	if err := translator.validateNoRunningWindowsCapture(captureTargetsOnNode); err != nil {
+		return nil, err
+	}
+
+	jobPodEnv, err := translator.ObtainCaptureJobPodEnv(*capture)
+	if err != nil {
+		return nil, err
+	}
+	if duration := capture.Spec.CaptureConfiguration.CaptureOption.Duration; duration != nil {
+		if duration.Duration <= durationOffset {
+			return nil, nil
+		}
+		jobPodEnv[captureConstants.CaptureDurationEnvKey] = (duration.Duration - durationOffset).String()
+	}
+
+	jobs, err := translator.renderJob(captureTargetsOnNode, jobPodEnv)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.Annotations == nil {
+			job.Annotations = map[string]string{}
+		}
+		job.Annotations[captureConstants.CaptureDurationOffsetAnnotation] = durationOffset.String()
+	}
+
+	if jobNum := len(captureJobs) + len(jobs); translator.config.CaptureJobNumLimit != 0 && jobNum > translator.config.CaptureJobNumLimit {
+		return nil, CaptureJobNumExceedLimitError{CurrentNum: jobNum, Limit: translator.config.CaptureJobNumLimit}
+	}
+	return jobs, nil
+}
+
+// capturedPodIPs returns the IP addresses of the Pods captured by the capture jobs by the node of the jobs.
+func capturedPodIPs(captureJobs []batchv1.Job) map[string]map[string]bool {
+	capturedIPs := map[string]map[string]bool{}
+	for i := range captureJobs {
+		podIPs, ok := captureJobs[i].Annotations[captureConstants.CapturePodIPsAnnotation]
+		if !ok {
+			continue
+		}
+		nodeName := captureJobNodeName(&captureJobs[i])
+		if capturedIPs[nodeName] == nil {
+			capturedIPs[nodeName] = map[string]bool{}
+		}
+		for _, podIP := range strings.Split(podIPs, ",") {
+			capturedIPs[nodeName][podIP] = true
+		}
+	}
+	return capturedIPs
+}
+
+// captureJobNodeName returns the name of the node the capture job is rendered for.
+func captureJobNodeName(captureJob *batchv1.Job) string {
+	for _, container := range captureJob.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == captureConstants.NodeHostNameEnvKey {
+				return env.Value
+			}
+		}
+	}
+	return ""
+}
+
+// CaptureJobDurationOffset returns the time since the Capture started when the capture job was created, which is zero
+// for the capture jobs created when the Capture started.
+func CaptureJobDurationOffset(captureJob *batchv1.Job) (time.Duration, error) {
+	offset, ok := captureJob.Annotations[captureConstants.CaptureDurationOffsetAnnotation]
+	if !ok {
+		return 0, nil
+	}
+	durationOffset, err := time.ParseDuration(offset)
+	if err != nil {
+		return 0, fmt.Errorf("invalid annotation %s: %w", captureConstants.CaptureDurationOffsetAnnotation, err)
+	}
+	return durationOffset, nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+)
+
+func serviceTestObjects() []runtime.Object {
+	node1, node2 := "node1", "node2"
+	return []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: node1, Labels: map[string]string{corev1.LabelHostname: node1, corev1.LabelOSStable: "linux"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: node2, Labels: map[string]string{corev1.LabelHostname: node2, corev1.LabelOSStable: "linux"}}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec: corev1.ServiceSpec{
+				ClusterIPs: []string{"10.0.0.20"},
+				Ports:      []corev1.ServicePort{{Port: 80, NodePort: 30080}},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-headless", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone, ClusterIPs: []string{corev1.ClusterIPNone}},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-abcde", Namespace: "default", Labels: map[string]string{discoveryv1.LabelServiceName: "web"}},
+			Endpoints: []discoveryv1.Endpoint{
+				{Addresses: []string{"10.224.0.10"}, NodeName: &node1, TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-1"}},
+				{Addresses: []string{"10.224.1.10"}, NodeName: &node2, TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-2"}},
+				{Addresses: []string{"192.168.0.1"}},
+			},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-headless-abcde", Namespace: "default", Labels: map[string]string{discoveryv1.LabelServiceName: "web-headless"}},
+			Endpoints: []discoveryv1.Endpoint{
+				{Addresses: []string{"10.224.0.10"}, NodeName: &node1, TargetRef: &corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-1"}},
+			},
+		},
+	}
+}
+
+func TestCalculateCaptureTargetsByServiceSelector(t *testing.T) {
+	captureToPodTranslator := NewCaptureToPodTranslatorForTest(fakeclientset.NewSimpleClientset(serviceTestObjects()...))
+	captureTarget := retinav1alpha1.CaptureTarget{
+		ServiceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+	}
+
+	got, err := captureToPodTranslator.CalculateCaptureTargetsOnNode(captureTarget)
+	if err != nil {
+		t.Fatalf("CalculateCaptureTargetsOnNode() want no error, got %s", err)
+	}
+	want := &CaptureTargetsOnNode{
+		"node1": {
+			PodIpAddresses:     []string{"10.224.0.10"},
+			PodNames:           map[string]string{"10.224.0.10": "default/web-1"},
+			ServiceIPAddresses: []string{"10.0.0.20"},
+			NodePorts:          []int32{30080},
+			OS:                 "linux",
+		},
+		"node2": {
+			PodIpAddresses:     []string{"10.224.1.10"},
+			PodNames:           map[string]string{"10.224.1.10": "default/web-2"},
+			ServiceIPAddresses: []string{"10.0.0.20"},
+			NodePorts:          []int32{30080},
+			OS:                 "linux",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CalculateCaptureTargetsOnNode() mismatch (-want, +got):\n%s", diff)
+	}
+
+	captureTarget.PodSelector = &metav1.LabelSelector{}
+	if _, err := captureToPodTranslator.CalculateCaptureTargetsOnNode(captureTarget); err == nil {
+		t.Errorf("CalculateCaptureTargetsOnNode() want error for ServiceSelector with PodSelector, got nil")
+	}
+}
+
+func TestTranslateCaptureToJobsOfNewEndpoints(t *testing.T) {
+	captureToPodTranslator := NewCaptureToPodTranslatorForTest(fakeclientset.NewSimpleClientset(serviceTestObjects()...))
+	hostPath := "/tmp/capture"
+	capture := &retinav1alpha1.Capture{
+		ObjectMeta: metav1.ObjectMeta{Name: "capture-web", Namespace: "default"},
+		Spec: retinav1alpha1.CaptureSpec{
+			CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+				CaptureTarget: retinav1alpha1.CaptureTarget{
+					ServiceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+				CaptureOption: retinav1alpha1.CaptureOption{Duration: &metav1.Duration{Duration: time.Minute}},
+			},
+			OutputConfiguration: retinav1alpha1.OutputConfiguration{HostPath: &hostPath},
+		},
+	}
+
+	jobs, err := captureToPodTranslator.TranslateCaptureToJobs(capture)
+	if err != nil {
+		t.Fatalf("TranslateCaptureToJobs() want no error, got %s", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("TranslateCaptureToJobs() want 2 jobs, got %d", len(jobs))
+	}
+	var captureJobs []batchv1.Job
+	for _, job := range jobs {
+		if captureJobNodeName(job) == "node1" {
+			captureJobs = append(captureJobs, *job)
+		}
+	}
+
+	newJobs, err := captureToPodTranslator.TranslateCaptureToJobsOfNewEndpoints(capture, captureJobs, 20*time.Second)
+	if err != nil {
+		t.Fatalf("TranslateCaptureToJobsOfNewEndpoints() want no error, got %s", err)
+	}
+	if len(newJobs) != 1 || captureJobNodeName(newJobs[0]) != "node2" {
+		t.Fatalf("TranslateCaptureToJobsOfNewEndpoints() want a job on node2, got %d jobs", len(newJobs))
+	}
+	wantAnnotations := map[string]string{
+		captureConstants.CapturePodIPsAnnotation:         "10.224.1.10",
+		captureConstants.CaptureDurationOffsetAnnotation: "20s",
+	}
+	if diff := cmp.Diff(wantAnnotations, newJobs[0].Annotations); diff != "" {
+		t.Errorf("TranslateCaptureToJobsOfNewEndpoints() annotations mismatch (-want, +got):\n%s", diff)
+	}
+	wantEnv := map[string]string{
+		captureConstants.CaptureDurationEnvKey: "40s",
+		captureConstants.TcpdumpFilterEnvKey:   "(host 10.224.1.10 or host 10.0.0.20) or (port 30080)",
+	}
+	gotEnv := map[string]string{}
+	for _, env := range newJobs[0].Spec.Template.Spec.Containers[0].Env {
+		gotEnv[env.Name] = env.Value
+	}
+	for name, want := range wantEnv {
+		if gotEnv[name] != want {
+			t.Errorf("TranslateCaptureToJobsOfNewEndpoints() env %s want %q, got %q", name, want, gotEnv[name])
+		}
+	}
+
+	for _, job := range jobs {
+		captureJobs = append(captureJobs, *job)
+	}
+	if newJobs, err := captureToPodTranslator.TranslateCaptureToJobsOfNewEndpoints(capture, captureJobs[1:], 20*time.Second); err != nil || len(newJobs) != 0 {
+		t.Errorf("TranslateCaptureToJobsOfNewEndpoints() want no jobs when all Pods are captured, got %d jobs and error %v", len(newJobs), err)
+	}
+}
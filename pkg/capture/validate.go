@@ -0,0 +1,272 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+)
+
+// anyNamespace matches the namespaces not listed in the allowed output locations of the cluster policy.
+const anyNamespace = "*"
+
+// tcpdumpReservedOptions are the tcpdump options set by the capture jobs to write and rotate the capture file, which
+// cannot be overridden by the tcpdump filter of the Capture.
+var tcpdumpReservedOptions = []string{"-w", "-r", "-W", "-C", "-G", "-Z", "-F", "-V", "--relinquish-privileges"}
+
+// tcpdumpOptionsWithValue are the tcpdump options taking the next argument as their value.
+var tcpdumpOptionsWithValue = []string{"-i", "-s", "-c", "-B", "-E", "-j", "-M", "-Q", "-T", "-y", "-z"}
+
+// ValidateCapture validates the Capture without selecting its targets or resolving the objects it references, so it
+// can be validated when it is created or updated, like when it is admitted. The Capture is also checked against the
+// cluster policy of the operator.
+func (translator *CaptureToPodTranslator) ValidateCapture(capture *retinav1alpha1.Capture) error {
+	if err := translator.validateCapture(capture); err != nil {
+		return err
+	}
+	if err := validateOutputConfiguration(capture.Spec.OutputConfiguration); err != nil {
+		return err
+	}
+	if tcpdumpFilter := capture.Spec.CaptureConfiguration.TcpdumpFilter; tcpdumpFilter != nil {
+		if err := validateTcpdumpFilter(*tcpdumpFilter, capture.Spec.CaptureConfiguration.CaptureOption.PacketSize != nil); err != nil {
+			return CaptureFilterInvalidError{Reason: err.Error()}
+		}
+	}
+	if _, _, err := parseIncludeAndExcludeFilters(capture.Spec.CaptureConfiguration.Filters); err != nil {
+		return CaptureFilterInvalidError{Reason: err.Error()}
+	}
+	return translator.validateCapturePolicy(capture)
+}
+
+// ValidateCaptureTargets validates the Capture against the targets it selects and the objects it references, which
+// fails the Capture when it is translated to capture jobs, or is silently ignored on the selected Windows nodes.
+func (translator *CaptureToPodTranslator) ValidateCaptureTargets(capture *retinav1alpha1.Capture) error {
+	filters, err := translator.obtainCaptureFilters(capture.Namespace, capture.Spec.CaptureConfiguration)
+	if err != nil {
+		return err
+	}
+
+	captureTargetsOnNode, err := translator.getCaptureTargetsOnNode(capture.Spec.CaptureConfiguration.CaptureTarget)
+	if err != nil {
+		return err
+	}
+	if err := translator.updateCaptureTargetsOSOnNode(captureTargetsOnNode); err != nil {
+		return err
+	}
+
+	var windowsNodes []string
+	for nodeName, target := range *captureTargetsOnNode {
+		if target.OS == "windows" {
+			windowsNodes = append(windowsNodes, nodeName)
+		}
+	}
+	if len(windowsNodes) == 0 {
+		return nil
+	}
+	slices.Sort(windowsNodes)
+
+	captureConfig := capture.Spec.CaptureConfiguration
+	var linuxOnlyOptions []string
+	if captureConfig.CaptureOption.PacketSize != nil {
+		linuxOnlyOptions = append(linuxOnlyOptions, "packetSize")
+	}
+	if captureConfig.TcpdumpFilter != nil {
+		linuxOnlyOptions = append(linuxOnlyOptions, "tcpdumpFilter")
+	}
+	if len(captureConfig.CaptureOption.Interfaces) != 0 {
+		linuxOnlyOptions = append(linuxOnlyOptions, "interfaces")
+	}
+	if captureConfig.CaptureOption.PodNetworkNamespace {
+		linuxOnlyOptions = append(linuxOnlyOptions, "podNetworkNamespace")
+	}
+	if len(linuxOnlyOptions) != 0 {
+		return fmt.Errorf("%s only supported on Linux nodes, but Windows nodes %s are selected", strings.Join(linuxOnlyOptions, ", "), windowsNodes)
+	}
+	if filters.netshErr != nil {
+		return CaptureFilterInvalidError{Reason: fmt.Sprintf("Windows nodes %s: %s", windowsNodes, filters.netshErr)}
+	}
+	return nil
+}
+
+// validateOutputConfiguration validates the output locations do not conflict with each other, or with the volumes
+// mounted into the capture jobs.
+func validateOutputConfiguration(outputConfiguration retinav1alpha1.OutputConfiguration) error {
+	if outputConfiguration.HostPath != nil {
+		hostPath := *outputConfiguration.HostPath
+		if len(hostPath) == 0 {
+			return fmt.Errorf("HostPath of the output configuration is empty")
+		}
+		reservedPaths := []string{
+			captureConstants.CaptureControlPath,
+			captureConstants.CaptureOutputLocationBlobUploadSecretPath,
+			captureConstants.CaptureOutputLocationS3UploadSecretPath,
+		}
+		if outputConfiguration.PersistentVolumeClaim != nil {
+			reservedPaths = append(reservedPaths, captureConstants.PersistentVolumeClaimVolumeMountPathLinux)
+		}
+		for _, reservedPath := range reservedPaths {
+			if pathsOverlap(hostPath, reservedPath) {
+				return fmt.Errorf("HostPath %s of the output configuration conflicts with path %s of the capture job", hostPath, reservedPath)
+			}
+		}
+	}
+	if outputConfiguration.PersistentVolumeClaim != nil && len(*outputConfiguration.PersistentVolumeClaim) == 0 {
+		return fmt.Errorf("PersistentVolumeClaim of the output configuration is empty")
+	}
+
+	// The secrets are mounted as the volumes named after them.
+	volumeNames := []string{
+		captureConstants.CaptureControlVolumeName,
+		captureConstants.CaptureHostPathVolumeName,
+		captureConstants.CapturePVCVolumeName,
+	}
+	if outputConfiguration.BlobUpload != nil {
+		secretName := *outputConfiguration.BlobUpload
+		if len(secretName) == 0 {
+			return fmt.Errorf("BlobUpload of the output configuration is empty")
+		}
+		if slices.Contains(volumeNames, secretName) {
+			return fmt.Errorf("BlobUpload secret %s of the output configuration conflicts with volume %s of the capture job", secretName, secretName)
+		}
+		volumeNames = append(volumeNames, secretName)
+	}
+	if outputConfiguration.S3Upload != nil {
+		secretName := outputConfiguration.S3Upload.SecretName
+		if len(secretName) == 0 {
+			return fmt.Errorf("secret of S3Upload of the output configuration is empty")
+		}
+		if slices.Contains(volumeNames, secretName) {
+			return fmt.Errorf("S3Upload secret %s of the output configuration conflicts with volume %s of the capture job", secretName, secretName)
+		}
+	}
+	return nil
+}
+
+// pathsOverlap tells if one of the paths is the other one or contains it.
+func pathsOverlap(path1, path2 string) bool {
+	path1, path2 = path.Clean(path1), path.Clean(path2)
+	return path1 == path2 ||
+		strings.HasPrefix(path2, strings.TrimSuffix(path1, "/")+"/") ||
+		strings.HasPrefix(path1, strings.TrimSuffix(path2, "/")+"/")
+}
+
+// validateTcpdumpFilter validates the tcpdump options of the filter do not override the options set by the capture jobs,
+// and the filter expression following them is well-formed, with balanced parentheses and the logical operators placed
+// between the primitives.
+func validateTcpdumpFilter(tcpdumpFilter string, packetSizeSet bool) error {
+	tokens := strings.Fields(tcpdumpFilter)
+	i := 0
+	for ; i < len(tokens) && strings.HasPrefix(tokens[i], "-"); i++ {
+		option := tokens[i]
+		for _, reserved := range tcpdumpReservedOptions {
+			if option == reserved || (len(reserved) == 2 && strings.HasPrefix(option, reserved)) || strings.HasPrefix(option, reserved+"=") {
+				return fmt.Errorf("tcpdump option %s is set by the capture job", option)
+			}
+		}
+		if packetSizeSet && strings.HasPrefix(option, "-s") {
+			return fmt.Errorf("tcpdump option %s conflicts with packetSize", option)
+		}
+		if slices.Contains(tcpdumpOptionsWithValue, option) {
+			if i+1 == len(tokens) {
+				return fmt.Errorf("tcpdump option %s requires a value", option)
+			}
+			i++
+		}
+	}
+
+	depth := 0
+	expectOperand := true
+	for _, token := range tokens[i:] {
+		for _, r := range token {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth < 0 {
+				return fmt.Errorf("unbalanced parentheses in tcpdump filter %q", tcpdumpFilter)
+			}
+		}
+
+		switch strings.Trim(token, "()") {
+		case "and", "or", "&&", "||":
+			if expectOperand {
+				return fmt.Errorf("missing operand before %q in tcpdump filter %q", token, tcpdumpFilter)
+			}
+			expectOperand = true
+		case "not", "!":
+			if !expectOperand {
+				return fmt.Errorf("missing operator before %q in tcpdump filter %q", token, tcpdumpFilter)
+			}
+		case "":
+		default:
+			expectOperand = false
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("unbalanced parentheses in tcpdump filter %q", tcpdumpFilter)
+	}
+	if expectOperand && len(tokens) > i {
+		return fmt.Errorf("missing operand at the end of tcpdump filter %q", tcpdumpFilter)
+	}
+	return nil
+}
+
+// validateCapturePolicy validates the Capture is allowed by the cluster policy of the operator.
+func (translator *CaptureToPodTranslator) validateCapturePolicy(capture *retinav1alpha1.Capture) error {
+	captureOption := capture.Spec.CaptureConfiguration.CaptureOption
+	if maxDuration := translator.config.CaptureMaxDuration; maxDuration != 0 {
+		if captureOption.Duration == nil {
+			return fmt.Errorf("duration is required by the cluster policy, which allows at most %s", maxDuration)
+		}
+		if captureOption.Duration.Duration > maxDuration {
+			return fmt.Errorf("duration %s exceeds %s allowed by the cluster policy", captureOption.Duration.Duration, maxDuration)
+		}
+	}
+	if maxSize := translator.config.CaptureMaxSize; maxSize != 0 {
+		if captureOption.MaxCaptureSize == nil {
+			return fmt.Errorf("maxCaptureSize is required by the cluster policy, which allows at most %dMB", maxSize)
+		}
+		if *captureOption.MaxCaptureSize > maxSize {
+			return fmt.Errorf("maxCaptureSize %dMB exceeds %dMB allowed by the cluster policy", *captureOption.MaxCaptureSize, maxSize)
+		}
+	}
+
+	allowedOutputLocations, ok := translator.config.CaptureAllowedOutputLocations[capture.Namespace]
+	if !ok {
+		if allowedOutputLocations, ok = translator.config.CaptureAllowedOutputLocations[anyNamespace]; !ok {
+			return nil
+		}
+	}
+	for _, outputLocation := range OutputLocations(capture.Spec.OutputConfiguration) {
+		if !slices.ContainsFunc(allowedOutputLocations, func(allowed string) bool { return strings.EqualFold(allowed, string(outputLocation)) }) {
+			return fmt.Errorf("output location %s is not allowed in namespace %s by the cluster policy, which allows %v", outputLocation, capture.Namespace, allowedOutputLocations)
+		}
+	}
+	return nil
+}
+
+// OutputLocations returns the output locations set in the output configuration.
+func OutputLocations(outputConfiguration retinav1alpha1.OutputConfiguration) []retinav1alpha1.CaptureOutputLocation {
+	var outputLocations []retinav1alpha1.CaptureOutputLocation
+	if outputConfiguration.HostPath != nil {
+		outputLocations = append(outputLocations, retinav1alpha1.CaptureOutputLocationHostPath)
+	}
+	if outputConfiguration.PersistentVolumeClaim != nil {
+		outputLocations = append(outputLocations, retinav1alpha1.CaptureOutputLocationPersistentVolumeClaim)
+	}
+	if outputConfiguration.BlobUpload != nil {
+		outputLocations = append(outputLocations, retinav1alpha1.CaptureOutputLocationBlobUpload)
+	}
+	if outputConfiguration.S3Upload != nil {
+		outputLocations = append(outputLocations, retinav1alpha1.CaptureOutputLocationS3Upload)
+	}
+	return outputLocations
+}
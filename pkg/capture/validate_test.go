@@ -0,0 +1,231 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	pointerUtil "k8s.io/utils/pointer"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+)
+
+func validateTestCapture(mutate func(capture *retinav1alpha1.Capture)) *retinav1alpha1.Capture {
+	capture := &retinav1alpha1.Capture{
+		ObjectMeta: metav1.ObjectMeta{Name: "capture-test", Namespace: "tenant"},
+		Spec: retinav1alpha1.CaptureSpec{
+			CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+				CaptureTarget: retinav1alpha1.CaptureTarget{
+					NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"agentpool": "pool"}},
+				},
+				CaptureOption: retinav1alpha1.CaptureOption{
+					Duration:       &metav1.Duration{Duration: time.Minute},
+					MaxCaptureSize: pointerUtil.Int(100),
+				},
+			},
+			OutputConfiguration: retinav1alpha1.OutputConfiguration{HostPath: pointerUtil.String("/tmp/capture")},
+		},
+	}
+	if mutate != nil {
+		mutate(capture)
+	}
+	return capture
+}
+
+func TestValidateCapture(t *testing.T) {
+	cases := []struct {
+		name    string
+		capture *retinav1alpha1.Capture
+		wantErr bool
+	}{
+		{
+			name:    "valid capture",
+			capture: validateTestCapture(nil),
+		},
+		{
+			name: "empty target selectors",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.CaptureTarget = retinav1alpha1.CaptureTarget{}
+			}),
+			wantErr: true,
+		},
+		{
+			name: "HostPath conflicting with the PVC mount path",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.OutputConfiguration.HostPath = pointerUtil.String("/mnt/azure/")
+				capture.Spec.OutputConfiguration.PersistentVolumeClaim = pointerUtil.String("pvc")
+			}),
+			wantErr: true,
+		},
+		{
+			name: "HostPath containing the capture control path",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.OutputConfiguration.HostPath = pointerUtil.String("/etc")
+			}),
+			wantErr: true,
+		},
+		{
+			name: "BlobUpload and S3Upload sharing a secret",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.OutputConfiguration.BlobUpload = pointerUtil.String("upload")
+				capture.Spec.OutputConfiguration.S3Upload = &retinav1alpha1.S3Upload{Bucket: "bucket", SecretName: "upload"}
+			}),
+			wantErr: true,
+		},
+		{
+			name: "valid tcpdump filter",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.TcpdumpFilter = pointerUtil.String("-i eth0 -n (tcp port 80 or udp) and not host 10.0.0.1")
+			}),
+		},
+		{
+			name: "tcpdump filter with unbalanced parentheses",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.TcpdumpFilter = pointerUtil.String("(tcp port 80 or udp")
+			}),
+			wantErr: true,
+		},
+		{
+			name: "tcpdump filter with a dangling operator",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.TcpdumpFilter = pointerUtil.String("tcp port 80 and")
+			}),
+			wantErr: true,
+		},
+		{
+			name: "tcpdump filter overriding the capture file",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.TcpdumpFilter = pointerUtil.String("-w /tmp/other.pcap")
+			}),
+			wantErr: true,
+		},
+		{
+			name: "tcpdump snapshot length conflicting with packetSize",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.TcpdumpFilter = pointerUtil.String("-s 64")
+				capture.Spec.CaptureConfiguration.CaptureOption.PacketSize = pointerUtil.Int(96)
+			}),
+			wantErr: true,
+		},
+		{
+			name: "invalid IP:Port filter",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.Filters = &retinav1alpha1.CaptureConfigurationFilters{Include: []string{"10.0.0.1:port"}}
+			}),
+			wantErr: true,
+		},
+		{
+			name: "duration exceeding the policy",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.CaptureOption.Duration = &metav1.Duration{Duration: time.Hour}
+			}),
+			wantErr: true,
+		},
+		{
+			name: "duration missing with the policy",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.CaptureOption.Duration = nil
+			}),
+			wantErr: true,
+		},
+		{
+			name: "maxCaptureSize exceeding the policy",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.CaptureOption.MaxCaptureSize = pointerUtil.Int(1000)
+			}),
+			wantErr: true,
+		},
+		{
+			name: "output location not allowed in the namespace",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Spec.OutputConfiguration.BlobUpload = pointerUtil.String("blob-sas")
+			}),
+			wantErr: true,
+		},
+		{
+			name: "output location allowed in namespaces not listed",
+			capture: validateTestCapture(func(capture *retinav1alpha1.Capture) {
+				capture.Namespace = "default"
+				capture.Spec.OutputConfiguration.BlobUpload = pointerUtil.String("blob-sas")
+			}),
+		},
+	}
+
+	captureToPodTranslator := NewCaptureToPodTranslatorForTest(fakeclientset.NewSimpleClientset())
+	captureToPodTranslator.config.CaptureMaxDuration = 10 * time.Minute
+	captureToPodTranslator.config.CaptureMaxSize = 500
+	captureToPodTranslator.config.CaptureAllowedOutputLocations = map[string][]string{
+		"tenant": {"hostpath"},
+		"*":      {"HostPath", "BlobUpload"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := captureToPodTranslator.ValidateCapture(tt.capture)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("ValidateCapture() want error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateCaptureTargets(t *testing.T) {
+	cases := []struct {
+		name    string
+		mutate  func(capture *retinav1alpha1.Capture)
+		wantErr bool
+	}{
+		{
+			name: "Linux and Windows nodes",
+		},
+		{
+			name: "packetSize on Windows nodes",
+			mutate: func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.CaptureOption.PacketSize = pointerUtil.Int(96)
+			},
+			wantErr: true,
+		},
+		{
+			name: "packetSize on Linux nodes",
+			mutate: func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.CaptureTarget.NodeSelector = &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelOSStable: "linux"}}
+				capture.Spec.CaptureConfiguration.CaptureOption.PacketSize = pointerUtil.Int(96)
+			},
+		},
+		{
+			name: "no nodes selected",
+			mutate: func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.CaptureTarget.NodeSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"agentpool": "none"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "filter rule not supported by netsh on Windows nodes",
+			mutate: func(capture *retinav1alpha1.Capture) {
+				capture.Spec.CaptureConfiguration.Filters = &retinav1alpha1.CaptureConfigurationFilters{
+					IncludeRules: []retinav1alpha1.CaptureFilterRule{{Protocol: retinav1alpha1.CaptureFilterProtocolTCP, Ports: []string{"80"}}},
+				}
+			},
+			wantErr: true,
+		},
+	}
+
+	objects := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{corev1.LabelHostname: "node1", corev1.LabelOSStable: "linux", "agentpool": "pool"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{corev1.LabelHostname: "node2", corev1.LabelOSStable: "windows", "agentpool": "pool"}}},
+	}
+	kubeClient := fakeclientset.NewSimpleClientset(objects[0], objects[1])
+	captureToPodTranslator := NewCaptureToPodTranslatorForTest(kubeClient)
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := captureToPodTranslator.ValidateCaptureTargets(validateTestCapture(tt.mutate))
+			if tt.wantErr != (err != nil) {
+				t.Errorf("ValidateCaptureTargets() want error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
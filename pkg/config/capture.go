@@ -3,6 +3,8 @@
 package config
 
 import (
+	"time"
+
 	captureUtils "github.com/microsoft/retina/pkg/capture/utils"
 )
 
@@ -22,4 +24,21 @@ type CaptureConfig struct {
 
 	// JobNumLimit indicates the maximum number of jobs that can be created for each Capture.
 	CaptureJobNumLimit int `yaml:"captureJobNumLimit"`
+
+	// CaptureTTL is the default time to keep a Capture and its artifacts after it finishes, for the Captures not
+	// setting their TTL. Zero keeps them forever.
+	CaptureTTL time.Duration `yaml:"captureTTL"`
+
+	// EnableCaptureWebhook indicates whether the operator serves the admission webhook validating Captures.
+	EnableCaptureWebhook bool `yaml:"enableCaptureWebhook"`
+	// Cluster policy enforced by the admission webhook on the Captures.
+	//
+	// CaptureMaxDuration is the longest duration of a Capture, which is required to set its duration. Zero sets no limit.
+	CaptureMaxDuration time.Duration `yaml:"captureMaxDuration"`
+	// CaptureMaxSize is the largest maxCaptureSize of a Capture in MB. Zero sets no limit.
+	CaptureMaxSize int `yaml:"captureMaxSize"`
+	// CaptureAllowedOutputLocations maps namespaces to the output locations their Captures may use, like HostPath or
+	// BlobUpload, where "*" matches the namespaces not listed. Captures of namespaces matching no entry may use any
+	// output location.
+	CaptureAllowedOutputLocations map[string][]string `yaml:"captureAllowedOutputLocations"`
 }
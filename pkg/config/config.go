@@ -4,54 +4,132 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Server struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host string `yaml:"host" json:"host"`
+	Port int    `yaml:"port" json:"port"`
+}
+
+// NodeConnectivityProbe configures the nodeconnectivity plugin.
+type NodeConnectivityProbe struct {
+	// Protocol is the probe protocol, one of icmp, tcp or udp.
+	Protocol string `yaml:"protocol" json:"protocol"`
+	// Port is the destination port for tcp and udp probes.
+	Port int `yaml:"port" json:"port"`
+	// SampleSize is the number of nodes probed each interval, 0 probes all nodes.
+	SampleSize int `yaml:"sampleSize" json:"sampleSize"`
+}
+
+// CacheSnapshot configures the snapshots of the cache of pods, services and nodes, which the agent restores when it
+// restarts so that it enriches flows before its Kubernetes cache synced.
+type CacheSnapshot struct {
+	// Path is the node-local file the cache is saved to, disabled when empty.
+	Path string `yaml:"path" json:"path"`
+	// Interval is how often, in seconds, the cache is saved besides when the agent stops. 0 saves it only then.
+	Interval time.Duration `yaml:"interval" json:"interval"`
 }
 
 type Config struct {
-	ApiServer                Server        `yaml:"apiServer"`
-	LogLevel                 string        `yaml:"logLevel"`
-	EnabledPlugin            []string      `yaml:"enabledPlugin"`
-	MetricsInterval          time.Duration `yaml:"metricsInterval"`
-	EnableTelemetry          bool          `yaml:"enableTelemetry"`
-	EnableRetinaEndpoint     bool          `yaml:"enableRetinaEndpoint"`
-	EnablePodLevel           bool          `yaml:"enablePodLevel"`
-	RemoteContext            bool          `yaml:"remoteContext"`
-	EnableAnnotations        bool          `yaml:"enableAnnotations"`
-	BypassLookupIPOfInterest bool          `yaml:"bypassLookupIPOfInterest"`
+	ApiServer                Server                `yaml:"apiServer" json:"apiServer"`
+	LogLevel                 string                `yaml:"logLevel" json:"logLevel"`
+	EnabledPlugin            []string              `yaml:"enabledPlugin" json:"enabledPlugin"`
+	MetricsInterval          time.Duration         `yaml:"metricsInterval" json:"metricsInterval"`
+	EnableTelemetry          bool                  `yaml:"enableTelemetry" json:"enableTelemetry"`
+	EnableRetinaEndpoint     bool                  `yaml:"enableRetinaEndpoint" json:"enableRetinaEndpoint"`
+	EnablePodLevel           bool                  `yaml:"enablePodLevel" json:"enablePodLevel"`
+	RemoteContext            bool                  `yaml:"remoteContext" json:"remoteContext"`
+	EnableAnnotations        bool                  `yaml:"enableAnnotations" json:"enableAnnotations"`
+	BypassLookupIPOfInterest bool                  `yaml:"bypassLookupIPOfInterest" json:"bypassLookupIPOfInterest"`
+	DisableRingBuffer        bool                  `yaml:"disableRingBuffer" json:"disableRingBuffer"`
+	NodeConnectivityProbe    NodeConnectivityProbe `yaml:"nodeConnectivityProbe" json:"nodeConnectivityProbe"`
+	// EnablePluginConfiguration enables the plugins of the PluginConfiguration named after the node in place of EnabledPlugin.
+	EnablePluginConfiguration bool `yaml:"enablePluginConfiguration" json:"enablePluginConfiguration"`
+	// EnabledMetrics are the advanced metrics enabled for the namespaces annotated by enableAnnotations, all the
+	// default advanced metrics when empty.
+	EnabledMetrics []string `yaml:"enabledMetrics" json:"enabledMetrics,omitempty"`
+	// CacheSnapshot configures the snapshots of the cache of pods, services and nodes, with enablePodLevel.
+	CacheSnapshot CacheSnapshot `yaml:"cacheSnapshot" json:"cacheSnapshot"`
+	// CacheTombstoneTTL is how long, in seconds, the pods, services and nodes deleted from the cache still enrich the
+	// flows of their IPs, disabled when 0.
+	CacheTombstoneTTL time.Duration `yaml:"cacheTombstoneTTL" json:"cacheTombstoneTTL"`
+}
+
+// Source tells where the config was loaded from.
+type Source struct {
+	// File is the config file read.
+	File string `json:"file"`
+	// EnvOverrides are the environment variables overriding settings of the file.
+	EnvOverrides []string `json:"envOverrides,omitempty"`
+}
+
+const envPrefix = "retina"
+
+// DeepCopy returns a copy of the config.
+func (c *Config) DeepCopy() *Config {
+	out := *c
+	out.EnabledPlugin = slices.Clone(c.EnabledPlugin)
+	out.EnabledMetrics = slices.Clone(c.EnabledMetrics)
+	return &out
 }
 
+// GetConfig reads the config file, the settings of which can be overridden with RETINA_<SETTING> environment
+// variables, and validates it.
 func GetConfig(cfgFilename string) (*Config, error) {
+	config, _, err := loadConfig(cfgFilename)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+func loadConfig(cfgFilename string) (*Config, Source, error) {
+	v := viper.New()
 	if cfgFilename != "" {
-		viper.SetConfigFile(cfgFilename)
+		v.SetConfigFile(cfgFilename)
 	} else {
-		viper.SetConfigName("config")
-		viper.AddConfigPath("/retina/config")
+		v.SetConfigName("config")
+		v.AddConfigPath("/retina/config")
 	}
 
-	viper.SetEnvPrefix("retina")
-	viper.AutomaticEnv()
+	v.SetEnvPrefix(envPrefix)
+	v.AutomaticEnv()
 	// NOTE(mainred): RetinaEndpoint is currently the only supported solution to cache Pod, and before an alternative is implemented,
 	// we make EnableRetinaEndpoint true and cannot be configurable.
-	viper.SetDefault("EnableRetinaEndpoint", true)
+	v.SetDefault("EnableRetinaEndpoint", true)
 
-	err := viper.ReadInConfig()
+	err := v.ReadInConfig()
 	if err != nil {
-		return nil, fmt.Errorf("fatal error config file: %s", err)
+		return nil, Source{}, fmt.Errorf("fatal error config file: %s", err)
 	}
 	var config Config
-	err = viper.Unmarshal(&config)
+	err = v.Unmarshal(&config)
 	if err != nil {
-		return nil, fmt.Errorf("fatal error config file: %s", err)
+		return nil, Source{}, fmt.Errorf("fatal error config file: %s", err)
 	}
 	// Convert to second.
 	config.MetricsInterval = config.MetricsInterval * time.Second
+	config.CacheSnapshot.Interval = config.CacheSnapshot.Interval * time.Second
+	config.CacheTombstoneTTL = config.CacheTombstoneTTL * time.Second
+
+	source := Source{File: v.ConfigFileUsed()}
+	for _, key := range v.AllKeys() {
+		env := strings.ToUpper(envPrefix + "_" + key)
+		if _, ok := os.LookupEnv(env); ok {
+			source.EnvOverrides = append(source.EnvOverrides, env)
+		}
+	}
+	sort.Strings(source.EnvOverrides)
 
-	return &config, nil
+	return &config, source, nil
 }
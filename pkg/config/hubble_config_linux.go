@@ -3,6 +3,7 @@
 package config
 
 import (
+	"context"
 	"path/filepath"
 
 	"github.com/cilium/cilium/pkg/hive/cell"
@@ -64,8 +65,7 @@ var (
 		cell.Config(DefaultRetinaHubbleConfig),
 
 		cell.Provide(func(logger logrus.FieldLogger) (Config, error) {
-			retinaConfigFile := filepath.Join(option.Config.ConfigDir, configFileName)
-			conf, err := GetConfig(retinaConfigFile)
+			conf, err := GetConfig(hubbleConfigFile())
 			if err != nil {
 				logger.Error(err)
 				conf = DefaultRetinaConfig
@@ -73,6 +73,36 @@ var (
 			logger.Info(conf)
 			return *conf, nil
 		}),
+
+		cell.Provide(newHubbleReloader),
 		sharedconfig.Cell,
 	)
 )
+
+func hubbleConfigFile() string {
+	return filepath.Join(option.Config.ConfigDir, configFileName)
+}
+
+// newHubbleReloader watches the config file for the lifetime of the agent.
+func newHubbleReloader(lc cell.Lifecycle, logger logrus.FieldLogger, conf Config) *Reloader {
+	reloader := NewReloader(hubbleConfigFile(), &conf)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(cell.Hook{
+		OnStart: func(cell.HookContext) error {
+			go func() {
+				defer close(done)
+				if err := reloader.Start(ctx); err != nil {
+					logger.WithError(err).Error("Failed to watch config file, config changes require a restart")
+				}
+			}()
+			return nil
+		},
+		OnStop: func(cell.HookContext) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+	return reloader
+}
@@ -0,0 +1,243 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ConfigPath is the path of the agent API serving the effective config.
+const ConfigPath = "/config"
+
+// reloadDelay is how long the Reloader waits for the changes of the config file to settle before reloading it.
+// Updating a mounted ConfigMap swaps a symlink, which comes as several events.
+var reloadDelay = time.Second
+
+// hotReloadable are the settings applied without restarting the agent, by their name in the config file.
+var hotReloadable = map[string]struct{}{
+	"logLevel":        {},
+	"metricsInterval": {},
+	"enabledMetrics":  {},
+}
+
+var errNoConfigFile = errors.New("no config file to watch")
+
+// Status is the effective config of the agent, where it was loaded from and how its reloads went.
+type Status struct {
+	Source Source `json:"source"`
+	// LoadedAt is when the config file was last loaded successfully.
+	LoadedAt time.Time `json:"loadedAt"`
+	// Reloads is the number of times the config file was reloaded successfully after a change.
+	Reloads int `json:"reloads"`
+	// LastReloadError is the error of the last reload, empty when it succeeded.
+	LastReloadError string `json:"lastReloadError,omitempty"`
+	// PendingRestart are the settings changed in the config file which take effect once the agent restarts.
+	PendingRestart []string `json:"pendingRestart,omitempty"`
+	Config         Config   `json:"config"`
+}
+
+// Reloader watches the config file and applies the changes of the settings which can change while the agent runs:
+// the log level, the metrics interval and the enabled metrics. The changes of the other settings are reported as
+// pending a restart.
+type Reloader struct {
+	l *log.ZapLogger
+
+	mu         sync.RWMutex
+	status     Status
+	validators []func(*Config) error
+	handlers   []func(oldCfg, newCfg *Config)
+}
+
+// NewReloader returns a Reloader of cfg, the config the agent runs with, which was read from filename.
+func NewReloader(filename string, cfg *Config) *Reloader {
+	r := &Reloader{
+		l: log.Logger().Named("config-reloader"),
+		status: Status{
+			Source:   Source{File: filename},
+			LoadedAt: time.Now(),
+			Config:   *cfg.DeepCopy(),
+		},
+	}
+	if fileCfg, source, err := loadConfig(filename); err == nil {
+		r.status.Source = source
+		r.status.PendingRestart = pendingRestart(cfg, fileCfg)
+	}
+	return r
+}
+
+// AddValidator adds a check of the config reloaded, in addition to Validate. A config failing it is not applied.
+func (r *Reloader) AddValidator(validate func(*Config) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.validators = append(r.validators, validate)
+}
+
+// OnReload adds a handler called with the previous and the new effective config whenever a reload changes it.
+// The log level is applied by the Reloader itself.
+func (r *Reloader) OnReload(handler func(oldCfg, newCfg *Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, handler)
+}
+
+// Config returns the effective config.
+func (r *Reloader) Config() *Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status.Config.DeepCopy()
+}
+
+// Status returns the effective config and the state of its reloads.
+func (r *Reloader) Status() Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	status := r.status
+	status.Config = *r.status.Config.DeepCopy()
+	status.Source.EnvOverrides = slices.Clone(r.status.Source.EnvOverrides)
+	status.PendingRestart = slices.Clone(r.status.PendingRestart)
+	return status
+}
+
+// Reload reads and validates the config file, then applies the settings which can change while the agent runs.
+// Nothing is applied when the config is invalid.
+func (r *Reloader) Reload() error {
+	r.mu.RLock()
+	filename := r.status.Source.File
+	validators := r.validators
+	r.mu.RUnlock()
+
+	fileCfg, source, err := loadConfig(filename)
+	if err == nil {
+		errs := []error{fileCfg.Validate()}
+		for _, validate := range validators {
+			errs = append(errs, validate(fileCfg))
+		}
+		err = errors.Join(errs...)
+	}
+	if err != nil {
+		r.mu.Lock()
+		r.status.LastReloadError = err.Error()
+		r.mu.Unlock()
+		return fmt.Errorf("failed to reload config: %w", err)
+	}
+
+	r.mu.Lock()
+	old := r.status.Config.DeepCopy()
+	updated := r.status.Config.DeepCopy()
+	updated.LogLevel = fileCfg.LogLevel
+	updated.MetricsInterval = fileCfg.MetricsInterval
+	updated.EnabledMetrics = fileCfg.EnabledMetrics
+	r.status.Config = *updated
+	r.status.Source = source
+	r.status.LoadedAt = time.Now()
+	r.status.Reloads++
+	r.status.LastReloadError = ""
+	pending := pendingRestart(updated, fileCfg)
+	r.status.PendingRestart = pending
+	handlers := r.handlers
+	r.mu.Unlock()
+
+	if len(pending) > 0 {
+		r.l.Warn("config changes take effect once the agent restarts", zap.String("settings", strings.Join(pending, ",")))
+	}
+	if reflect.DeepEqual(old, updated) {
+		return nil
+	}
+	r.l.Info("applying reloaded config",
+		zap.String("logLevel", updated.LogLevel),
+		zap.Duration("metricsInterval", updated.MetricsInterval),
+		zap.String("enabledMetrics", strings.Join(updated.EnabledMetrics, ",")))
+	if old.LogLevel != updated.LogLevel {
+		if err := log.Logger().SetLevel(updated.LogLevel); err != nil {
+			r.l.Error("failed to set log level", zap.Error(err))
+		}
+	}
+	for _, handler := range handlers {
+		handler(old, updated)
+	}
+	return nil
+}
+
+// Start watches the directory of the config file, which is how changes of a mounted ConfigMap are seen, and reloads
+// the config on changes until the context is done.
+func (r *Reloader) Start(ctx context.Context) error {
+	r.mu.RLock()
+	filename := r.status.Source.File
+	r.mu.RUnlock()
+	if filename == "" {
+		return errNoConfigFile
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config file watcher: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(filename)); err != nil {
+		return fmt.Errorf("failed to watch config file %s: %w", filename, err)
+	}
+	r.l.Info("watching config file", zap.String("file", filename))
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			reload = time.After(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.l.Error("config file watcher failed", zap.Error(err))
+		case <-reload:
+			reload = nil
+			if err := r.Reload(); err != nil {
+				r.l.Error("invalid config, keeping the current one", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Handler serves the Status as JSON.
+func (r *Reloader) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if err := utils.EncodeResponseBody(w, r.Status()); err != nil {
+			r.l.Error("failed to encode config", zap.Error(err))
+		}
+	}
+}
+
+// pendingRestart returns the name of the settings differing between the effective and the file configs, which are
+// not hot reloadable.
+func pendingRestart(effective, file *Config) []string {
+	var settings []string
+	ev, fv := reflect.ValueOf(*effective), reflect.ValueOf(*file)
+	for i := 0; i < ev.NumField(); i++ {
+		name, _, _ := strings.Cut(ev.Type().Field(i).Tag.Get("json"), ",")
+		if _, ok := hotReloadable[name]; ok {
+			continue
+		}
+		if !reflect.DeepEqual(ev.Field(i).Interface(), fv.Field(i).Interface()) {
+			settings = append(settings, name)
+		}
+	}
+	return settings
+}
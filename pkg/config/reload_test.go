@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadTestConfig = `apiServer:
+  host: "0.0.0.0"
+  port: 10093
+logLevel: info
+enabledPlugin: ["dropreason", "packetforward"]
+metricsInterval: 10
+enablePodLevel: true
+`
+
+func writeConfig(t *testing.T, filename, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+}
+
+func TestReload(t *testing.T) {
+	_, err := log.SetupZapLogger(log.GetDefaultLogOpts())
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, filename, reloadTestConfig)
+	cfg, err := GetConfig(filename)
+	require.NoError(t, err)
+
+	r := NewReloader(filename, cfg)
+	var old, updated *Config
+	r.OnReload(func(o, n *Config) {
+		old, updated = o, n
+	})
+
+	// The hot reloadable settings are applied, the other ones are pending a restart.
+	writeConfig(t, filename, `apiServer:
+  host: "0.0.0.0"
+  port: 10093
+logLevel: debug
+enabledPlugin: ["dropreason", "dns"]
+metricsInterval: 30
+enablePodLevel: true
+enableAnnotations: true
+enabledMetrics: ["dns_request_count"]
+`)
+	require.NoError(t, r.Reload())
+	require.NotNil(t, updated)
+	assert.Equal(t, 10*time.Second, old.MetricsInterval)
+	assert.Equal(t, 30*time.Second, updated.MetricsInterval)
+	assert.Equal(t, "debug", updated.LogLevel)
+	assert.Equal(t, []string{"dns_request_count"}, updated.EnabledMetrics)
+	assert.Equal(t, []string{"dropreason", "packetforward"}, updated.EnabledPlugin)
+	assert.False(t, updated.EnableAnnotations)
+	assert.Equal(t, "debug", log.Logger().Level())
+
+	status := r.Status()
+	assert.Equal(t, filename, status.Source.File)
+	assert.Equal(t, 1, status.Reloads)
+	assert.Empty(t, status.LastReloadError)
+	assert.Equal(t, []string{"enabledPlugin", "enableAnnotations"}, status.PendingRestart)
+
+	// An invalid config is not applied.
+	updated = nil
+	writeConfig(t, filename, strings.NewReplacer("info", "verbose", "metricsInterval: 10", "metricsInterval: 0").Replace(reloadTestConfig))
+	require.Error(t, r.Reload())
+	assert.Nil(t, updated)
+	status = r.Status()
+	assert.Contains(t, status.LastReloadError, "logLevel")
+	assert.Equal(t, 30*time.Second, status.Config.MetricsInterval)
+
+	// A config failing a validator is not applied.
+	r.AddValidator(func(*Config) error {
+		return errors.New("rejected")
+	})
+	writeConfig(t, filename, reloadTestConfig)
+	require.Error(t, r.Reload())
+	assert.Nil(t, updated)
+	assert.Equal(t, "debug", log.Logger().Level())
+	require.NoError(t, log.Logger().SetLevel("info"))
+}
+
+func TestReloaderStart(t *testing.T) {
+	_, err := log.SetupZapLogger(log.GetDefaultLogOpts())
+	require.NoError(t, err)
+	delay := reloadDelay
+	reloadDelay = 10 * time.Millisecond
+	t.Cleanup(func() { reloadDelay = delay })
+
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, filename, reloadTestConfig)
+	cfg, err := GetConfig(filename)
+	require.NoError(t, err)
+
+	r := NewReloader(filename, cfg)
+	intervals := make(chan time.Duration, 1)
+	r.OnReload(func(_, n *Config) {
+		intervals <- n.MetricsInterval
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		assert.NoError(t, r.Start(ctx))
+	}()
+
+	require.Eventually(t, func() bool {
+		writeConfig(t, filename, strings.Replace(reloadTestConfig, "metricsInterval: 10", "metricsInterval: 20", 1))
+		select {
+		case interval := <-intervals:
+			return interval == 20*time.Second
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReloaderHandler(t *testing.T) {
+	_, err := log.SetupZapLogger(log.GetDefaultLogOpts())
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, filename, reloadTestConfig)
+	t.Setenv("RETINA_ENABLEPODLEVEL", "false")
+	cfg, err := GetConfig(filename)
+	require.NoError(t, err)
+	assert.False(t, cfg.EnablePodLevel)
+
+	r := NewReloader(filename, cfg)
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ConfigPath, http.NoBody))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status Status
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.Equal(t, filename, status.Source.File)
+	assert.Equal(t, []string{"RETINA_ENABLEPODLEVEL"}, status.Source.EnvOverrides)
+	assert.Equal(t, 10*time.Second, status.Config.MetricsInterval)
+	assert.Equal(t, []string{"dropreason", "packetforward"}, status.Config.EnabledPlugin)
+}
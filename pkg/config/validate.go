@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/microsoft/retina/pkg/utils"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// MinMetricsInterval and MaxMetricsInterval bound the interval plugins scrape and publish metrics at.
+	MinMetricsInterval = time.Second
+	MaxMetricsInterval = time.Hour
+	// MaxCacheTombstoneTTL bounds how long deleted objects enrich flows, as their IPs get reused.
+	MaxCacheTombstoneTTL = 5 * time.Minute
+)
+
+var nodeConnectivityProtocols = []string{"icmp", "tcp", "udp"}
+
+// Validate checks the values of the config and the combinations of them Retina does not support. It returns all the
+// problems found, each prefixed with the name of the setting in the config file.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.LogLevel != "" {
+		if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+			invalid("logLevel", "unknown level %q, must be one of debug, info, warn, error, dpanic, panic or fatal", c.LogLevel)
+		}
+	}
+	if c.MetricsInterval < MinMetricsInterval || c.MetricsInterval > MaxMetricsInterval {
+		invalid("metricsInterval", "must be between %s and %s, got %s", MinMetricsInterval, MaxMetricsInterval, c.MetricsInterval)
+	}
+	if c.ApiServer.Port < 1 || c.ApiServer.Port > 65535 {
+		invalid("apiServer.port", "must be between 1 and 65535, got %d", c.ApiServer.Port)
+	}
+
+	seen := make(map[string]struct{}, len(c.EnabledPlugin))
+	for _, name := range c.EnabledPlugin {
+		if name == "" {
+			invalid("enabledPlugin", "plugin names cannot be empty")
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			invalid("enabledPlugin", "plugin %s is enabled more than once", name)
+		}
+		seen[name] = struct{}{}
+	}
+
+	if c.RemoteContext && !c.EnablePodLevel {
+		invalid("remoteContext", "requires enablePodLevel")
+	}
+	if c.EnableAnnotations && !c.EnablePodLevel {
+		invalid("enableAnnotations", "requires enablePodLevel")
+	}
+	if len(c.EnabledMetrics) > 0 && !c.EnableAnnotations {
+		invalid("enabledMetrics", "requires enableAnnotations, the advanced metrics are set by MetricsConfigurations otherwise")
+	}
+	for _, name := range c.EnabledMetrics {
+		if !utils.IsAdvancedMetric(name) {
+			invalid("enabledMetrics", "unknown advanced metric %q", name)
+		}
+	}
+
+	if c.CacheSnapshot.Path != "" {
+		if !c.EnablePodLevel {
+			invalid("cacheSnapshot.path", "requires enablePodLevel")
+		}
+		if !filepath.IsAbs(c.CacheSnapshot.Path) {
+			invalid("cacheSnapshot.path", "must be absolute, got %q", c.CacheSnapshot.Path)
+		}
+	}
+	if c.CacheSnapshot.Interval < 0 {
+		invalid("cacheSnapshot.interval", "cannot be negative, got %s", c.CacheSnapshot.Interval)
+	}
+	if c.CacheTombstoneTTL < 0 || c.CacheTombstoneTTL > MaxCacheTombstoneTTL {
+		invalid("cacheTombstoneTTL", "must be between 0s and %s, got %s", MaxCacheTombstoneTTL, c.CacheTombstoneTTL)
+	}
+	if c.CacheTombstoneTTL > 0 && !c.EnablePodLevel {
+		invalid("cacheTombstoneTTL", "requires enablePodLevel")
+	}
+
+	probe := c.NodeConnectivityProbe
+	if probe.Protocol != "" && !slices.Contains(nodeConnectivityProtocols, probe.Protocol) {
+		invalid("nodeConnectivityProbe.protocol", "unknown protocol %q, must be one of %s", probe.Protocol, strings.Join(nodeConnectivityProtocols, ", "))
+	}
+	if probe.Port < 0 || probe.Port > 65535 {
+		invalid("nodeConnectivityProbe.port", "must be between 0 and 65535, got %d", probe.Port)
+	}
+	if probe.SampleSize < 0 {
+		invalid("nodeConnectivityProbe.sampleSize", "cannot be negative, got %d", probe.SampleSize)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidatePlugins checks that the enabled plugins are known to Retina.
+func (c *Config) ValidatePlugins(knownPlugins []string) error {
+	var unknown []string
+	for _, name := range c.EnabledPlugin {
+		if name != "" && !slices.Contains(knownPlugins, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("enabledPlugin: unknown plugins %s, must be among %s", strings.Join(unknown, ", "), strings.Join(knownPlugins, ", "))
+	}
+	return nil
+}
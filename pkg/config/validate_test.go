@@ -0,0 +1,159 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() *Config {
+	return &Config{
+		ApiServer:       Server{Host: "0.0.0.0", Port: 10093},
+		LogLevel:        "info",
+		EnabledPlugin:   []string{"dropreason", "packetforward"},
+		MetricsInterval: 10 * time.Second,
+		EnablePodLevel:  true,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  func(c *Config)
+		wantErr []string
+	}{
+		{
+			name:   "valid",
+			update: func(*Config) {},
+		},
+		{
+			name: "valid with annotations and enabled metrics",
+			update: func(c *Config) {
+				c.EnableAnnotations = true
+				c.EnabledMetrics = []string{"dns_request_count", "drop_count"}
+			},
+		},
+		{
+			name: "invalid log level",
+			update: func(c *Config) {
+				c.LogLevel = "verbose"
+			},
+			wantErr: []string{"logLevel: unknown level \"verbose\""},
+		},
+		{
+			name: "metrics interval out of range",
+			update: func(c *Config) {
+				c.MetricsInterval = 0
+			},
+			wantErr: []string{"metricsInterval: must be between 1s and 1h0m0s, got 0s"},
+		},
+		{
+			name: "invalid port",
+			update: func(c *Config) {
+				c.ApiServer.Port = 70000
+			},
+			wantErr: []string{"apiServer.port: must be between 1 and 65535"},
+		},
+		{
+			name: "duplicated and empty plugins",
+			update: func(c *Config) {
+				c.EnabledPlugin = []string{"dropreason", "dropreason", ""}
+			},
+			wantErr: []string{"enabledPlugin: plugin dropreason is enabled more than once", "enabledPlugin: plugin names cannot be empty"},
+		},
+		{
+			name: "remote context and annotations without pod level",
+			update: func(c *Config) {
+				c.EnablePodLevel = false
+				c.RemoteContext = true
+				c.EnableAnnotations = true
+			},
+			wantErr: []string{"remoteContext: requires enablePodLevel", "enableAnnotations: requires enablePodLevel"},
+		},
+		{
+			name: "enabled metrics without annotations",
+			update: func(c *Config) {
+				c.EnabledMetrics = []string{"dns_request_count"}
+			},
+			wantErr: []string{"enabledMetrics: requires enableAnnotations"},
+		},
+		{
+			name: "unknown enabled metric",
+			update: func(c *Config) {
+				c.EnableAnnotations = true
+				c.EnabledMetrics = []string{"unknown"}
+			},
+			wantErr: []string{"enabledMetrics: unknown advanced metric \"unknown\""},
+		},
+		{
+			name: "valid cache snapshot and tombstones",
+			update: func(c *Config) {
+				c.CacheSnapshot = CacheSnapshot{Path: "/var/run/retina/cache.json", Interval: time.Minute}
+				c.CacheTombstoneTTL = 30 * time.Second
+			},
+		},
+		{
+			name: "invalid cache snapshot and tombstones",
+			update: func(c *Config) {
+				c.CacheSnapshot = CacheSnapshot{Path: "cache.json", Interval: -time.Second}
+				c.CacheTombstoneTTL = time.Hour
+			},
+			wantErr: []string{
+				"cacheSnapshot.path: must be absolute, got \"cache.json\"",
+				"cacheSnapshot.interval: cannot be negative",
+				"cacheTombstoneTTL: must be between 0s and 5m0s, got 1h0m0s",
+			},
+		},
+		{
+			name: "cache snapshot and tombstones without pod level",
+			update: func(c *Config) {
+				c.EnablePodLevel = false
+				c.CacheSnapshot.Path = "/var/run/retina/cache.json"
+				c.CacheTombstoneTTL = 30 * time.Second
+			},
+			wantErr: []string{"cacheSnapshot.path: requires enablePodLevel", "cacheTombstoneTTL: requires enablePodLevel"},
+		},
+		{
+			name: "invalid node connectivity probe",
+			update: func(c *Config) {
+				c.NodeConnectivityProbe = NodeConnectivityProbe{Protocol: "http", Port: -1, SampleSize: -1}
+			},
+			wantErr: []string{
+				"nodeConnectivityProbe.protocol: unknown protocol \"http\"",
+				"nodeConnectivityProbe.port: must be between 0 and 65535",
+				"nodeConnectivityProbe.sampleSize: cannot be negative",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.update(c)
+			err := c.Validate()
+			if len(tt.wantErr) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestValidatePlugins(t *testing.T) {
+	c := validConfig()
+	require.NoError(t, c.ValidatePlugins([]string{"dns", "dropreason", "packetforward"}))
+
+	c.EnabledPlugin = append(c.EnabledPlugin, "unknown")
+	err := c.ValidatePlugins([]string{"dropreason", "packetforward"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown plugins unknown")
+}
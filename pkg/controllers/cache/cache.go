@@ -7,6 +7,7 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/microsoft/retina/pkg/common"
 	"github.com/microsoft/retina/pkg/log"
@@ -41,9 +42,21 @@ type Cache struct {
 	// nsAnnotated is a map of annotated namespaces to watch
 	nsAnnotated map[string]bool
 
+	// restored are the keys of the objects restored from a snapshot, until updated or reconciled.
+	restored map[objectType]map[string]struct{}
+
+	// tombstones is a map of IP to the object deleted from the cache which had it, until the tombstone expires.
+	tombstones   map[string]tombstone
+	tombstoneTTL time.Duration
+
 	pubsub pubsub.PubSubInterface
 }
 
+type tombstone struct {
+	obj     interface{}
+	expires time.Time
+}
+
 // NewCache returns a new instance of Cache.
 func New(p pubsub.PubSubInterface) *Cache {
 	c := &Cache{
@@ -56,20 +69,33 @@ func New(p pubsub.PubSubInterface) *Cache {
 		ipToNodeName: make(map[string]string),
 		nsMap:        make(map[string]int),
 		nsAnnotated:  make(map[string]bool),
+		restored:     make(map[objectType]map[string]struct{}),
+		tombstones:   make(map[string]tombstone),
 		pubsub:       p,
 	}
 
-	cbFunc := pubsub.CallBackFunc(c.SubscribeAPIServerFn)
-	c.pubsub.Subscribe(common.PubSubAPIServer, &cbFunc)
+	APIServerTopic.Subscribe(c.pubsub, c.SubscribeAPIServerFn)
 	return c
 }
 
+// SetTombstoneTTL sets how long the objects deleted from the cache are still returned for their IPs, until another
+// object has them. 0 disables the tombstones.
+func (c *Cache) SetTombstoneTTL(ttl time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.tombstoneTTL = ttl
+}
+
 // GetPodByIP returns the retina endpoint for the given IP.
 func (c *Cache) GetPodByIP(ip string) *common.RetinaEndpoint {
 	c.RLock()
 	defer c.RUnlock()
 
 	obj := c.getObjByIPType(ip, TypeEndpoint)
+	if obj == nil {
+		obj = c.getTombstone(ip)
+	}
 	switch obj := obj.(type) {
 	case *common.RetinaEndpoint:
 		return obj
@@ -84,6 +110,9 @@ func (c *Cache) GetSvcByIP(ip string) *common.RetinaSvc {
 	defer c.RUnlock()
 
 	obj := c.getObjByIPType(ip, TypeSvc)
+	if obj == nil {
+		obj = c.getTombstone(ip)
+	}
 	switch obj := obj.(type) {
 	case *common.RetinaSvc:
 		return obj
@@ -98,6 +127,9 @@ func (c *Cache) GetNodeByIP(ip string) *common.RetinaNode {
 	defer c.RUnlock()
 
 	obj := c.getObjByIPType(ip, TypeNode)
+	if obj == nil {
+		obj = c.getTombstone(ip)
+	}
 	switch obj := obj.(type) {
 	case *common.RetinaNode:
 		return obj
@@ -106,6 +138,18 @@ func (c *Cache) GetNodeByIP(ip string) *common.RetinaNode {
 	}
 }
 
+// GetNodes returns a copy of the nodes of the cache.
+func (c *Cache) GetNodes() []*common.RetinaNode {
+	c.RLock()
+	defer c.RUnlock()
+
+	nodes := make([]*common.RetinaNode, 0, len(c.nodeMap))
+	for _, node := range c.nodeMap {
+		nodes = append(nodes, node.DeepCopy().(*common.RetinaNode))
+	}
+	return nodes
+}
+
 // getObjByIPType returns the retina endpoint for the given IP.
 func (c *Cache) getObjByIPType(ip string, t objectType) interface{} {
 	switch t {
@@ -224,7 +268,9 @@ func (c *Cache) updateEndpoint(ep *common.RetinaEndpoint) error {
 	c.epMap[ep.Key()] = ep
 	for _, ip := range ips {
 		c.ipToEpKey[ip] = ep.Key()
+		delete(c.tombstones, ip)
 	}
+	delete(c.restored[TypeEndpoint], ep.Key())
 
 	// notify pubsub that the endpoint has been updated
 	c.publish(EventTypePodAdded, ep)
@@ -263,6 +309,8 @@ func (c *Cache) updateSvc(svc *common.RetinaSvc) error {
 
 	c.ipToSvcKey[ip] = svc.Key()
 	c.svcMap[svc.Key()] = svc
+	delete(c.tombstones, ip)
+	delete(c.restored[TypeSvc], svc.Key())
 
 	// notify pubsub
 	c.publish(EventTypeSvcAdded, svc)
@@ -296,6 +344,8 @@ func (c *Cache) updateNode(node *common.RetinaNode) error {
 
 	c.nodeMap[node.Name()] = node
 	c.ipToNodeName[node.IPString()] = node.Name()
+	delete(c.tombstones, node.IPString())
+	delete(c.restored[TypeNode], node.Name())
 
 	// notify pubsub
 	c.publish(EventTypeNodeAdded, node)
@@ -329,7 +379,9 @@ func (c *Cache) deleteEndpoint(epKey string) error {
 	delete(c.epMap, epKey)
 	for _, ip := range ips {
 		delete(c.ipToEpKey, ip)
+		c.addTombstone(ip, ep)
 	}
+	delete(c.restored[TypeEndpoint], epKey)
 
 	c.publish(EventTypePodDeleted, ep)
 
@@ -360,6 +412,8 @@ func (c *Cache) deleteSvc(svcKey string) error {
 
 	delete(c.svcMap, svcKey)
 	delete(c.ipToSvcKey, ip)
+	c.addTombstone(ip, svc)
+	delete(c.restored[TypeSvc], svcKey)
 
 	// notify pubsub
 	c.publish(EventTypeSvcDeleted, svc)
@@ -385,12 +439,39 @@ func (c *Cache) deleteNode(nodeName string) error {
 
 	delete(c.nodeMap, nodeName)
 	delete(c.ipToNodeName, node.IPString())
+	c.addTombstone(node.IPString(), node)
+	delete(c.restored[TypeNode], nodeName)
 
 	c.publish(EventTypeNodeDeleted, node)
 
 	return nil
 }
 
+// addTombstone keeps the object deleted from the cache for its IP until the tombstone TTL elapses, and drops the
+// expired tombstones.
+func (c *Cache) addTombstone(ip string, obj interface{}) {
+	if c.tombstoneTTL <= 0 {
+		return
+	}
+	now := time.Now()
+	for tombstoneIP, t := range c.tombstones {
+		if !now.Before(t.expires) {
+			delete(c.tombstones, tombstoneIP)
+		}
+	}
+	c.tombstones[ip] = tombstone{obj: obj, expires: now.Add(c.tombstoneTTL)}
+}
+
+// getTombstone returns the object deleted from the cache which had the given IP, nil if its tombstone expired.
+func (c *Cache) getTombstone(ip string) interface{} {
+	t, ok := c.tombstones[ip]
+	if !ok || !time.Now().Before(t.expires) {
+		return nil
+	}
+	c.l.Debug("tombstone found for IP", zap.String("ip", ip))
+	return t.obj
+}
+
 // deleteByIP deletes the given IP from the cache.
 func (c *Cache) deleteByIP(ip, key string) error {
 	if svcKey, ok := c.ipToSvcKey[ip]; ok {
@@ -425,20 +506,18 @@ func (c *Cache) publish(t EventType, obj common.PublishObj) {
 		return
 	}
 	cev := NewCacheEvent(t, obj)
-	topic := common.PubSubPods
+	topic := PodsTopic
 	if t == EventTypeSvcAdded || t == EventTypeSvcDeleted {
-		topic = common.PubSubSvc
+		topic = SvcTopic
 	} else if t == EventTypeNodeAdded || t == EventTypeNodeDeleted {
-		topic = common.PubSubNode
+		topic = NodeTopic
 	}
 
-	go func() {
-		c.pubsub.Publish(topic, cev)
-	}()
+	// Publish under the lock of the cache, so that the events of an object are published in order.
+	topic.Publish(c.pubsub, cev)
 }
 
-func (c *Cache) SubscribeAPIServerFn(obj interface{}) {
-	event := obj.(*CacheEvent)
+func (c *Cache) SubscribeAPIServerFn(event *CacheEvent) {
 	if event == nil {
 		return
 	}
@@ -493,3 +572,62 @@ func (c *Cache) GetAnnotatedNamespaces() []string {
 	sort.Strings(ns)
 	return ns
 }
+
+// State is a dump of the cache, the pods, services and nodes by key with their IPs.
+type State struct {
+	Pods                map[string][]string `json:"pods"`
+	Services            map[string][]string `json:"services"`
+	Nodes               map[string][]string `json:"nodes"`
+	AnnotatedNamespaces []string            `json:"annotatedNamespaces"`
+	// Tombstones are the objects deleted from the cache which are still returned for their IPs.
+	Tombstones map[string][]string `json:"tombstones,omitempty"`
+}
+
+// State returns a dump of the cache.
+func (c *Cache) State() State {
+	c.RLock()
+	defer c.RUnlock()
+
+	state := State{
+		Pods:                ipsByKey(c.ipToEpKey),
+		Services:            ipsByKey(c.ipToSvcKey),
+		Nodes:               ipsByKey(c.ipToNodeName),
+		AnnotatedNamespaces: make([]string, 0, len(c.nsAnnotated)),
+	}
+	for ns := range c.nsAnnotated {
+		state.AnnotatedNamespaces = append(state.AnnotatedNamespaces, ns)
+	}
+	sort.Strings(state.AnnotatedNamespaces)
+
+	now := time.Now()
+	tombstoneToKey := make(map[string]string, len(c.tombstones))
+	for ip, t := range c.tombstones {
+		if !now.Before(t.expires) {
+			continue
+		}
+		switch obj := t.obj.(type) {
+		case *common.RetinaEndpoint:
+			tombstoneToKey[ip] = obj.Key()
+		case *common.RetinaSvc:
+			tombstoneToKey[ip] = obj.Key()
+		case *common.RetinaNode:
+			tombstoneToKey[ip] = obj.Name()
+		}
+	}
+	if len(tombstoneToKey) > 0 {
+		state.Tombstones = ipsByKey(tombstoneToKey)
+	}
+	return state
+}
+
+// ipsByKey inverts a map of IP to key.
+func ipsByKey(ipToKey map[string]string) map[string][]string {
+	ips := make(map[string][]string, len(ipToKey))
+	for ip, key := range ipToKey {
+		ips[key] = append(ips[key], ip)
+	}
+	for _, keyIPs := range ips {
+		sort.Strings(keyIPs)
+	}
+	return ips
+}
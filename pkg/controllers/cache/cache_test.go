@@ -4,6 +4,7 @@ package cache
 
 import (
 	"net"
+	"os"
 	"testing"
 	"time"
 
@@ -88,6 +89,11 @@ func TestCacheEndpoints(t *testing.T) {
 	assert.Equal(t, ep.Labels()["app"], addEndpoints.Labels()["app"])
 	assert.Equal(t, ep.Annotations()[common.RetinaPodAnnotation], addEndpoints.Annotations()[common.RetinaPodAnnotation])
 
+	// state
+	state := c.State()
+	assert.Equal(t, map[string][]string{addEndpoints.Key(): {"1.2.3.4", "1.2.3.5"}}, state.Pods)
+	assert.Empty(t, state.Services)
+
 	// delete
 	err = c.DeleteRetinaEndpoint(addEndpoints.Key())
 	assert.NoError(t, err)
@@ -165,9 +171,15 @@ func TestCacheNodes(t *testing.T) {
 	assert.Equal(t, addNode.Name(), node.Name())
 	assert.Equal(t, addNode.IPString(), node.IPString())
 
+	// list
+	nodes := c.GetNodes()
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, addNode.Name(), nodes[0].Name())
+
 	// delete
 	err = c.DeleteRetinaNode(addNode.Name())
 	assert.NoError(t, err)
+	assert.Empty(t, c.GetNodes())
 
 	time.Sleep(until)
 }
@@ -365,3 +377,95 @@ func TestCachingNamespace(t *testing.T) {
 	namespaces = c.GetAnnotatedNamespaces()
 	assert.Equal(t, 0, len(namespaces))
 }
+
+func TestCacheTombstones(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	c := New(pubsub.NewSync())
+	c.SetTombstoneTTL(time.Minute)
+
+	ep := common.NewRetinaEndpoint("pod1", "ns1", &common.IPAddresses{IPv4: net.IPv4(1, 2, 3, 4)})
+	assert.NoError(t, c.UpdateRetinaEndpoint(ep))
+	svc := common.NewRetinaSvc("svc1", "ns1", &common.IPAddresses{IPv4: net.IPv4(10, 0, 0, 1)}, nil, nil)
+	assert.NoError(t, c.UpdateRetinaSvc(svc))
+
+	// Deleted objects are still returned for their IPs.
+	assert.NoError(t, c.DeleteRetinaEndpoint(ep.Key()))
+	assert.NoError(t, c.DeleteRetinaSvc(svc.Key()))
+	assert.Equal(t, ep.Key(), c.GetPodByIP("1.2.3.4").Key())
+	assert.Equal(t, svc.Key(), c.GetObjByIP("10.0.0.1").(*common.RetinaSvc).Key())
+	assert.Nil(t, c.GetNodeByIP("1.2.3.4"))
+	assert.Equal(t, map[string][]string{ep.Key(): {"1.2.3.4"}, svc.Key(): {"10.0.0.1"}}, c.State().Tombstones)
+
+	// Until another object has their IP.
+	node := common.NewRetinaNode("node1", net.IPv4(1, 2, 3, 4))
+	assert.NoError(t, c.UpdateRetinaNode(node))
+	assert.Nil(t, c.GetPodByIP("1.2.3.4"))
+	assert.Equal(t, node.Name(), c.GetObjByIP("1.2.3.4").(*common.RetinaNode).Name())
+
+	// Or the tombstone expires.
+	c.tombstones["10.0.0.1"] = tombstone{obj: svc, expires: time.Now()}
+	assert.Nil(t, c.GetSvcByIP("10.0.0.1"))
+	assert.Empty(t, c.State().Tombstones)
+
+	// Deleted objects are not kept without a TTL.
+	c.SetTombstoneTTL(0)
+	assert.NoError(t, c.DeleteRetinaNode(node.Name()))
+	assert.Nil(t, c.GetObjByIP("1.2.3.4"))
+}
+
+func TestCacheSnapshot(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	path := t.TempDir() + "/retina/cache.json"
+
+	c := New(pubsub.NewSync())
+	ep1 := common.NewRetinaEndpoint("pod1", "ns1", &common.IPAddresses{IPv4: net.IPv4(1, 2, 3, 4)})
+	ep1.SetLabels(map[string]string{"app": "app1"})
+	ep1.SetOwnerRefs([]*common.OwnerReference{{Kind: "ReplicaSet", Name: "rs1"}})
+	ep2 := common.NewRetinaEndpoint("pod2", "ns1", &common.IPAddresses{IPv4: net.IPv4(1, 2, 3, 5)})
+	apiServer := common.NewRetinaEndpoint(common.APIServerEndpointName, common.APIServerEndpointName, &common.IPAddresses{IPv4: net.IPv4(1, 2, 3, 6)})
+	for _, ep := range []*common.RetinaEndpoint{ep1, ep2, apiServer} {
+		assert.NoError(t, c.UpdateRetinaEndpoint(ep))
+	}
+	svc := common.NewRetinaSvc("svc1", "ns1", &common.IPAddresses{IPv4: net.IPv4(10, 0, 0, 1)}, net.IPv4(20, 0, 0, 1), map[string]string{"app": "app1"})
+	assert.NoError(t, c.UpdateRetinaSvc(svc))
+	node := common.NewRetinaNode("node1", net.IPv4(192, 168, 0, 1))
+	assert.NoError(t, c.UpdateRetinaNode(node))
+	assert.NoError(t, c.SaveSnapshot(path))
+
+	// The snapshot is restored, but the apiserver endpoint.
+	restoredCache := New(pubsub.NewSync())
+	n, err := restoredCache.LoadSnapshot(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	pod := restoredCache.GetPodByIP("1.2.3.4")
+	assert.Equal(t, ep1.Key(), pod.Key())
+	assert.Equal(t, ep1.Labels(), pod.Labels())
+	assert.Equal(t, "rs1", pod.OwnerRefs()[0].Name)
+	assert.Nil(t, restoredCache.GetPodByIP("1.2.3.6"))
+	restoredSvc := restoredCache.GetSvcByIP("10.0.0.1")
+	assert.Equal(t, svc.Key(), restoredSvc.Key())
+	assert.Equal(t, "20.0.0.1", restoredSvc.LBIP().String())
+	assert.Equal(t, node.Name(), restoredCache.GetNodeByIP("192.168.0.1").Name())
+
+	// Reconcile deletes the restored objects which do not exist anymore, unless updated since.
+	assert.NoError(t, restoredCache.UpdateRetinaEndpoint(ep2))
+	deleted := restoredCache.Reconcile(Keys{
+		Pods:  map[string]struct{}{ep1.Key(): {}},
+		Nodes: map[string]struct{}{node.Name(): {}},
+	})
+	assert.Equal(t, 1, deleted)
+	assert.NotNil(t, restoredCache.GetPodByIP("1.2.3.4"))
+	assert.NotNil(t, restoredCache.GetPodByIP("1.2.3.5"))
+	assert.Nil(t, restoredCache.GetSvcByIP("10.0.0.1"))
+	assert.NotNil(t, restoredCache.GetNodeByIP("192.168.0.1"))
+
+	// No snapshot restores nothing.
+	n, err = New(pubsub.NewSync()).LoadSnapshot(t.TempDir() + "/cache.json")
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	// Nor does a stale snapshot.
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version":1,"time":"2024-01-01T00:00:00Z"}`), 0o600))
+	_, err = New(pubsub.NewSync()).LoadSnapshot(path)
+	assert.ErrorContains(t, err, "older than 10m0s")
+}
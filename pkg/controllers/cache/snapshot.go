@@ -0,0 +1,240 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/microsoft/retina/pkg/common"
+	"go.uber.org/zap"
+)
+
+const (
+	// snapshotVersion is the version of the format of the snapshots, those of other versions are not restored.
+	snapshotVersion = 1
+	// maxSnapshotAge is the age after which a snapshot is too stale to be restored, as the IPs of its pods got reused.
+	maxSnapshotAge = 10 * time.Minute
+)
+
+// snapshot is the content of the cache saved to a file.
+type snapshot struct {
+	Version  int                `json:"version"`
+	Time     time.Time          `json:"time"`
+	Pods     []snapshotEndpoint `json:"pods"`
+	Services []snapshotSvc      `json:"services"`
+	Nodes    []snapshotNode     `json:"nodes"`
+}
+
+type snapshotEndpoint struct {
+	Name        string                    `json:"name"`
+	Namespace   string                    `json:"namespace"`
+	IPs         *common.IPAddresses       `json:"ips"`
+	OwnerRefs   []*common.OwnerReference  `json:"ownerRefs,omitempty"`
+	Containers  []*common.RetinaContainer `json:"containers,omitempty"`
+	Labels      map[string]string         `json:"labels,omitempty"`
+	Annotations map[string]string         `json:"annotations,omitempty"`
+}
+
+type snapshotSvc struct {
+	Name      string              `json:"name"`
+	Namespace string              `json:"namespace"`
+	IPs       *common.IPAddresses `json:"ips"`
+	LBIP      net.IP              `json:"lbIP,omitempty"`
+	Selector  map[string]string   `json:"selector,omitempty"`
+}
+
+type snapshotNode struct {
+	Name string `json:"name"`
+	IP   net.IP `json:"ip"`
+}
+
+// Keys are the keys of the pods, services and nodes which exist in the API server.
+type Keys struct {
+	Pods     map[string]struct{}
+	Services map[string]struct{}
+	Nodes    map[string]struct{}
+}
+
+// SaveSnapshot saves the pods, services and nodes of the cache to the given file, replacing it atomically.
+func (c *Cache) SaveSnapshot(path string) error {
+	data, err := json.Marshal(c.snapshot())
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache snapshot: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory of cache snapshot: %w", err)
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache snapshot: %w", err)
+	}
+	defer os.Remove(f.Name()) //nolint:errcheck // already renamed unless failed
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace cache snapshot: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) snapshot() *snapshot {
+	c.RLock()
+	defer c.RUnlock()
+
+	s := &snapshot{
+		Version:  snapshotVersion,
+		Time:     time.Now(),
+		Pods:     make([]snapshotEndpoint, 0, len(c.epMap)),
+		Services: make([]snapshotSvc, 0, len(c.svcMap)),
+		Nodes:    make([]snapshotNode, 0, len(c.nodeMap)),
+	}
+	for _, ep := range c.epMap {
+		// The apiserver watcher publishes the apiserver endpoint when it starts.
+		if ep.Name() == common.APIServerEndpointName && ep.Namespace() == common.APIServerEndpointName {
+			continue
+		}
+		s.Pods = append(s.Pods, snapshotEndpoint{
+			Name:        ep.Name(),
+			Namespace:   ep.Namespace(),
+			IPs:         ep.NetIPs(),
+			OwnerRefs:   ep.OwnerRefs(),
+			Containers:  ep.Containers(),
+			Labels:      ep.Labels(),
+			Annotations: ep.Annotations(),
+		})
+	}
+	for _, svc := range c.svcMap {
+		s.Services = append(s.Services, snapshotSvc{
+			Name:      svc.Name(),
+			Namespace: svc.Namespace(),
+			IPs:       svc.IPs(),
+			LBIP:      svc.LBIP(),
+			Selector:  svc.Selector(),
+		})
+	}
+	for _, node := range c.nodeMap {
+		s.Nodes = append(s.Nodes, snapshotNode{Name: node.Name(), IP: net.ParseIP(node.IPString())})
+	}
+	return s
+}
+
+// LoadSnapshot restores the pods, services and nodes saved to the given file, and returns how many it restored. It
+// restores nothing when there is no snapshot, or when the snapshot is older than 10 minutes. The restored objects
+// are deleted by Reconcile unless they still exist.
+func (c *Cache) LoadSnapshot(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache snapshot: %w", err)
+	}
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal cache snapshot: %w", err)
+	}
+	if s.Version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported cache snapshot version %d, expected %d", s.Version, snapshotVersion)
+	}
+	if age := time.Since(s.Time); age > maxSnapshotAge {
+		return 0, fmt.Errorf("cache snapshot is %s old, older than %s", age.Round(time.Second), maxSnapshotAge)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	restored := 0
+	restore := func(t objectType, key string, err error) {
+		if err != nil {
+			c.l.Warn("failed to restore object from cache snapshot", zap.String("type", t.String()), zap.String("key", key), zap.Error(err))
+			return
+		}
+		if c.restored[t] == nil {
+			c.restored[t] = make(map[string]struct{})
+		}
+		c.restored[t][key] = struct{}{}
+		restored++
+	}
+	for _, p := range s.Pods {
+		if p.IPs == nil {
+			continue
+		}
+		ep := common.NewRetinaEndpoint(p.Name, p.Namespace, p.IPs)
+		ep.SetOwnerRefs(p.OwnerRefs)
+		ep.SetContainers(p.Containers)
+		ep.SetLabels(p.Labels)
+		ep.SetAnnotations(p.Annotations)
+		restore(TypeEndpoint, ep.Key(), c.updateEndpoint(ep))
+	}
+	for _, svc := range s.Services {
+		if svc.IPs == nil {
+			continue
+		}
+		retinaSvc := common.NewRetinaSvc(svc.Name, svc.Namespace, svc.IPs, svc.LBIP, svc.Selector)
+		restore(TypeSvc, retinaSvc.Key(), c.updateSvc(retinaSvc))
+	}
+	for _, node := range s.Nodes {
+		restore(TypeNode, node.Name, c.updateNode(common.NewRetinaNode(node.Name, node.IP)))
+	}
+	return restored, nil
+}
+
+// Reconcile deletes the objects restored from a snapshot which were not updated since and do not exist anymore, and
+// returns how many it deleted. The restored objects which still exist are kept until their controllers update them.
+func (c *Cache) Reconcile(live Keys) int {
+	c.Lock()
+	defer c.Unlock()
+
+	deleted := 0
+	stale := func(key string, keys map[string]struct{}) bool {
+		_, ok := keys[key]
+		return !ok
+	}
+	for key := range c.restored[TypeEndpoint] {
+		if stale(key, live.Pods) && c.deleteEndpoint(key) == nil {
+			deleted++
+		}
+	}
+	for key := range c.restored[TypeSvc] {
+		if stale(key, live.Services) && c.deleteSvc(key) == nil {
+			deleted++
+		}
+	}
+	for key := range c.restored[TypeNode] {
+		if stale(key, live.Nodes) && c.deleteNode(key) == nil {
+			deleted++
+		}
+	}
+	c.restored = make(map[objectType]map[string]struct{})
+	return deleted
+}
+
+// RunSnapshots saves the cache to the given file every interval until the context is done.
+func (c *Cache) RunSnapshots(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.SaveSnapshot(path); err != nil {
+				c.l.Error("failed to save cache snapshot", zap.Error(err))
+			}
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 
 	"github.com/microsoft/retina/pkg/common"
+	"github.com/microsoft/retina/pkg/pubsub"
 )
 
 //go:generate go run go.uber.org/mock/mockgen@v0.4.0 -destination=mock_cacheinterface.go -copyright_file=../../lib/ignore_headers.txt -package=cache github.com/microsoft/retina/pkg/controllers/cache CacheInterface
@@ -47,6 +48,14 @@ type CacheEvent struct {
 	Obj interface{}
 }
 
+// Topics of the cache events.
+var (
+	PodsTopic      = pubsub.NewTopic[*CacheEvent](common.PubSubPods)
+	SvcTopic       = pubsub.NewTopic[*CacheEvent](common.PubSubSvc)
+	NodeTopic      = pubsub.NewTopic[*CacheEvent](common.PubSubNode)
+	APIServerTopic = pubsub.NewTopic[*CacheEvent](common.PubSubAPIServer)
+)
+
 func NewCacheEvent(t EventType, obj common.PublishObj) *CacheEvent {
 	return &CacheEvent{
 		Type: t,
@@ -15,37 +15,41 @@ package metricsconfigurationcontroller
 
 import (
 	"context"
-	"sync"
+	"fmt"
 
 	"go.uber.org/zap"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
-	"github.com/microsoft/retina/crd/api/v1alpha1/validations"
 	"github.com/microsoft/retina/pkg/log"
 	mm "github.com/microsoft/retina/pkg/module/metrics"
 )
 
-// MetricsConfigurationReconciler reconciles a MetricsConfiguration object
+// MetricsConfigurationReconciler reconciles the MetricsConfigurations applying to the node of the agent.
 type MetricsConfigurationReconciler struct {
-	*sync.Mutex
 	client.Client
 	Scheme        *runtime.Scheme
-	mcCache       map[string]*retinav1alpha1.MetricsConfiguration
-	metricsModule *mm.Module
+	nodeName      string
+	metricsModule mm.IModule
 	l             *log.ZapLogger
 }
 
-func New(client client.Client, scheme *runtime.Scheme, metricsModule *mm.Module) *MetricsConfigurationReconciler {
+func New(client client.Client, scheme *runtime.Scheme, nodeName string, metricsModule mm.IModule) *MetricsConfigurationReconciler {
 	return &MetricsConfigurationReconciler{
-		Mutex:         &sync.Mutex{},
 		l:             log.Logger().Named(string("metricsconfiguration-controller")),
 		Client:        client,
 		Scheme:        scheme,
-		mcCache:       make(map[string]*retinav1alpha1.MetricsConfiguration),
+		nodeName:      nodeName,
 		metricsModule: metricsModule,
 	}
 }
@@ -54,48 +58,72 @@ func New(client client.Client, scheme *runtime.Scheme, metricsModule *mm.Module)
 //+kubebuilder:rbac:groups=operator.retina.sh,resources=metricsconfiguration/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=operator.retina.sh,resources=metricsconfiguration/finalizers,verbs=update
 
+// Reconcile reconciles the metrics module with all the MetricsConfigurations accepted by the operator whose node
+// selector selects the node, as they are merged by the metrics module.
 func (r *MetricsConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	mcc := &retinav1alpha1.MetricsConfiguration{}
-	if err := r.Client.Get(ctx, req.NamespacedName, mcc); err != nil {
-		if apierrors.IsNotFound(err) {
-			// Object not found, it has probably been deleted
-			r.l.Info("deleted", zap.String("name", req.NamespacedName.String()))
-			r.Lock()
-			if _, ok := r.mcCache[req.NamespacedName.String()]; ok {
-				delete(r.mcCache, req.NamespacedName.String())
-				r.l.Info("deleted from cache", zap.String("name", req.NamespacedName.String()))
-			}
-			r.Unlock()
-			return ctrl.Result{}, nil
-		} else {
-			r.l.Info("error getting metricsconfiguration", zap.String("name", req.NamespacedName.String()))
-			return ctrl.Result{}, err
-		}
+	r.l.Info("reconciling", zap.String("name", req.NamespacedName.String()))
+
+	mccList := &retinav1alpha1.MetricsConfigurationList{}
+	if err := r.Client.List(ctx, mccList); err != nil {
+		r.l.Info("error listing metricsconfigurations", zap.Error(err))
+		return ctrl.Result{}, err
 	}
 
-	r.l.Info("reconciled", zap.String("name", req.NamespacedName.String()))
-	r.Lock()
-	defer r.Unlock()
-	if len(r.mcCache) == 0 || r.mcCache[req.NamespacedName.String()] != nil {
-		if mcc.Status.State != retinav1alpha1.StateAccepted {
-			r.l.Info("ignoring this CRD as it is not configured and accepted by operator")
-		} else {
-			r.l.Info("adding to cache", zap.String("name", req.NamespacedName.String()))
-			currentMcc := r.mcCache[req.NamespacedName.String()]
-
-			if validations.CompareMetricsConfig(currentMcc, mcc) {
-				r.l.Info("no change in metrics configuration, skipping reconcile", zap.String("name", req.NamespacedName.String()))
-				return ctrl.Result{}, nil
+	node := &corev1.Node{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: r.nodeName}, node); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get node %s: %w", r.nodeName, err)
+	}
+
+	var namespaces []corev1.Namespace
+	mccs := make([]*retinav1alpha1.MetricsConfiguration, 0, len(mccList.Items))
+	for i := range mccList.Items {
+		mcc := &mccList.Items[i]
+		// Metrics configurations in conflict with others are accepted by the operator with a warning.
+		if mcc.Status.State != retinav1alpha1.StateAccepted && mcc.Status.State != retinav1alpha1.StateWarning {
+			r.l.Info("ignoring this CRD as it is not configured and accepted by operator", zap.String("name", mcc.Name))
+			continue
+		}
+
+		if mcc.Spec.NodeSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(mcc.Spec.NodeSelector)
+			if err != nil {
+				r.l.Error("invalid node selector", zap.String("name", mcc.Name), zap.Error(err))
+				continue
+			}
+			if !selector.Matches(labels.Set(node.Labels)) {
+				r.l.Info("ignoring this CRD as it does not select this node", zap.String("name", mcc.Name))
+				continue
 			}
+		}
 
-			err := r.metricsModule.Reconcile(&mcc.Spec)
+		if mcc.Spec.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(mcc.Spec.NamespaceSelector)
 			if err != nil {
-				r.l.Info("error reconciling metrics configurations", zap.String("name", req.NamespacedName.String()))
+				r.l.Error("invalid namespace selector", zap.String("name", mcc.Name), zap.Error(err))
+				continue
+			}
+			if namespaces == nil {
+				namespaceList := &corev1.NamespaceList{}
+				if err := r.Client.List(ctx, namespaceList); err != nil {
+					return ctrl.Result{}, fmt.Errorf("failed to list namespaces: %w", err)
+				}
+				namespaces = namespaceList.Items
+			}
+			// The metrics module only knows about included namespaces, which the selected namespaces are added to. The
+			// namespace selector is kept, so that no namespace is in the scope when it selects none.
+			mcc = mcc.DeepCopy()
+			for j := range namespaces {
+				if selector.Matches(labels.Set(namespaces[j].Labels)) {
+					mcc.Spec.Namespaces.Include = append(mcc.Spec.Namespaces.Include, namespaces[j].Name)
+				}
 			}
-			r.mcCache[req.NamespacedName.String()] = mcc
 		}
-	} else {
-		r.l.Error("Metrics Configuration is already configured on this cluster, cannot reconcile this new config.", zap.String("name", req.NamespacedName.String()))
+		mccs = append(mccs, mcc)
+	}
+
+	if err := r.metricsModule.ReconcileConfigurations(mccs); err != nil {
+		r.l.Info("error reconciling metrics configurations", zap.Error(err))
+		return ctrl.Result{}, err
 	}
 
 	return ctrl.Result{}, nil
@@ -103,7 +131,18 @@ func (r *MetricsConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MetricsConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The labels of the node and namespaces decide which metrics configurations apply, and for which namespaces.
+	enqueue := handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: r.nodeName}}}
+	})
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&retinav1alpha1.MetricsConfiguration{}).
+		Watches(&corev1.Node{}, enqueue, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(object client.Object) bool {
+				return object.GetName() == r.nodeName
+			}),
+			predicate.LabelChangedPredicate{},
+		)).
+		Watches(&corev1.Namespace{}, enqueue, builder.WithPredicates(predicate.LabelChangedPredicate{})).
 		Complete(r)
 }
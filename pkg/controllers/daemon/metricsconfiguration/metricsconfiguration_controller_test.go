@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -41,89 +42,137 @@ func init() {
 	fakescheme.AddKnownTypes(retinav1alpha1.GroupVersion, &retinav1alpha1.RetinaEndpoint{})
 }
 
+type fakeMetricsModule struct {
+	configs []*retinav1alpha1.MetricsConfiguration
+}
+
+func (f *fakeMetricsModule) Reconcile(*retinav1alpha1.MetricsSpec) error {
+	return nil
+}
+
+func (f *fakeMetricsModule) ReconcileConfigurations(configs []*retinav1alpha1.MetricsConfiguration) error {
+	f.configs = configs
+	return nil
+}
+
 func TestMetricsConfigurationReconciler_Reconcile(t *testing.T) {
 	log.SetupZapLogger(log.GetDefaultLogOpts())
-	type fields struct {
-		existingObjects []client.Object
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{"pool": "app"},
+		},
+	}
+	namespaces := []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app1", Labels: map[string]string{"team": "app"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app2", Labels: map[string]string{"team": "app"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
 	}
-	type args struct {
-		ctx context.Context
-		req ctrl.Request
+	newMetricsConfig := func(name, state string, spec retinav1alpha1.MetricsSpec) *retinav1alpha1.MetricsConfiguration {
+		return &retinav1alpha1.MetricsConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       spec,
+			Status:     retinav1alpha1.MetricsStatus{State: state},
+		}
 	}
+	contextOptions := []retinav1alpha1.MetricsContextOptions{{MetricName: "drop_count", SourceLabels: []string{"ip"}}}
+
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    ctrl.Result
-		wantErr bool
-		wantNil bool
+		name            string
+		existingObjects []client.Object
+		wantConfigs     map[string][]string
 	}{
 		{
-			name: "should create a new metrics configuration",
-			fields: fields{
-				existingObjects: []client.Object{
-					&retinav1alpha1.MetricsConfiguration{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "test",
-							Namespace: "default",
-						},
-					},
-				},
+			name: "should reconcile the metrics configurations accepted by the operator",
+			existingObjects: []client.Object{
+				newMetricsConfig("platform", retinav1alpha1.StateAccepted, retinav1alpha1.MetricsSpec{
+					ContextOptions: contextOptions,
+					Namespaces:     retinav1alpha1.MetricsNamespaces{Include: []string{"kube-system"}},
+				}),
+				newMetricsConfig("conflicting", retinav1alpha1.StateWarning, retinav1alpha1.MetricsSpec{
+					ContextOptions: contextOptions,
+					Namespaces:     retinav1alpha1.MetricsNamespaces{Include: []string{"default"}},
+				}),
+				newMetricsConfig("invalid", retinav1alpha1.StateErrored, retinav1alpha1.MetricsSpec{}),
+				newMetricsConfig("new", "", retinav1alpha1.MetricsSpec{}),
 			},
-			args: args{
-				ctx: context.TODO(),
-				req: ctrl.Request{
-					NamespacedName: types.NamespacedName{
-						Name:      "test",
-						Namespace: "default",
-					},
-				},
+			wantConfigs: map[string][]string{
+				"platform":    {"kube-system"},
+				"conflicting": {"default"},
 			},
-			wantNil: false,
 		},
 		{
-			name: "should send nil to channel when metrics configuration is deleted",
-			fields: fields{
-				existingObjects: []client.Object{},
+			name: "should include the namespaces selected by the namespace selector",
+			existingObjects: []client.Object{
+				newMetricsConfig("app", retinav1alpha1.StateAccepted, retinav1alpha1.MetricsSpec{
+					ContextOptions:    contextOptions,
+					Namespaces:        retinav1alpha1.MetricsNamespaces{Include: []string{"default"}},
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "app"}},
+				}),
 			},
-			args: args{
-				ctx: context.TODO(),
-				req: ctrl.Request{
-					NamespacedName: types.NamespacedName{
-						Name:      "test",
-						Namespace: "default",
-					},
-				},
+			wantConfigs: map[string][]string{
+				"app": {"default", "app1", "app2"},
 			},
-			wantNil: true,
+		},
+		{
+			name: "should include no namespace when the namespace selector selects none",
+			existingObjects: []client.Object{
+				newMetricsConfig("none", retinav1alpha1.StateAccepted, retinav1alpha1.MetricsSpec{
+					ContextOptions:    contextOptions,
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "none"}},
+				}),
+			},
+			wantConfigs: map[string][]string{
+				"none": nil,
+			},
+		},
+		{
+			name: "should ignore the metrics configurations not selecting the node",
+			existingObjects: []client.Object{
+				newMetricsConfig("app", retinav1alpha1.StateAccepted, retinav1alpha1.MetricsSpec{
+					ContextOptions: contextOptions,
+					Namespaces:     retinav1alpha1.MetricsNamespaces{Include: []string{"default"}},
+					NodeSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "app"}},
+				}),
+				newMetricsConfig("system", retinav1alpha1.StateAccepted, retinav1alpha1.MetricsSpec{
+					ContextOptions: contextOptions,
+					Namespaces:     retinav1alpha1.MetricsNamespaces{Include: []string{"kube-system"}},
+					NodeSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "system"}},
+				}),
+			},
+			wantConfigs: map[string][]string{
+				"app": {"default"},
+			},
+		},
+		{
+			name:        "should reconcile no metrics configuration when they are all deleted",
+			wantConfigs: map[string][]string{},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client := fake.NewClientBuilder().WithScheme(fakescheme).WithObjects(tt.fields.existingObjects...).Build()
+			objects := append([]client.Object{node}, namespaces...)
+			objects = append(objects, tt.existingObjects...)
+			client := fake.NewClientBuilder().WithScheme(fakescheme).WithObjects(objects...).Build()
 
-			r := New(client, fakescheme, nil)
+			metricsModule := &fakeMetricsModule{}
+			r := New(client, fakescheme, "node1", metricsModule)
 
-			_, err := r.Reconcile(tt.args.ctx, tt.args.req)
-			if (err != nil) != tt.wantErr {
-				require.Error(t, err)
-			}
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "platform"}})
+			require.NoError(t, err)
 
-			// reactivate when this channel is used again
-			// verify that the pod is deleted
-			/*
-				select {
-				case config := <-metricsconfigchannel:
-					if tt.wantNil {
-						require.Nil(t, config.MetricsConfiguration)
-					} else {
-						require.NotNil(t, config.MetricsConfiguration)
+			gotConfigs := make(map[string][]string, len(metricsModule.configs))
+			for _, mcc := range metricsModule.configs {
+				gotConfigs[mcc.Name] = mcc.Spec.Namespaces.Include
+				// The metrics module tells a namespace selector selecting no namespace from no namespace selector.
+				for _, existing := range tt.existingObjects {
+					if existing.GetName() == mcc.Name {
+						require.Equal(t, existing.(*retinav1alpha1.MetricsConfiguration).Spec.NamespaceSelector, mcc.Spec.NamespaceSelector)
 					}
-
-				case <-time.After(5 * time.Second):
-					require.Errorf(t, nil, "controller didn't write to channel after allotted time")
 				}
-			*/
+			}
+			require.Equal(t, tt.wantConfigs, gotConfigs)
 		})
 	}
 }
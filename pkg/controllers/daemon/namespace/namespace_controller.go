@@ -2,6 +2,7 @@ package namespacecontroller
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	api "github.com/microsoft/retina/crd/api/v1alpha1"
@@ -27,6 +28,10 @@ type NamespaceReconciler struct {
 	cache         cache.CacheInterface
 	metricsModule mm.IModule
 	l             *log.ZapLogger
+
+	// enabledMetrics are the advanced metrics enabled for the annotated namespaces, the default ones when empty.
+	enabledMetricsMu sync.RWMutex
+	enabledMetrics   []string
 }
 
 func New(client client.Client, cache cache.CacheInterface, metricsModule mm.IModule) *NamespaceReconciler {
@@ -63,6 +68,23 @@ func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	return ctrl.Result{}, nil
 }
 
+// SetEnabledMetrics changes the advanced metrics enabled for the annotated namespaces, the default advanced metrics
+// are enabled when empty. The metrics module is reconciled with them at the next tick of Start.
+func (r *NamespaceReconciler) SetEnabledMetrics(metrics []string) {
+	r.enabledMetricsMu.Lock()
+	defer r.enabledMetricsMu.Unlock()
+	r.enabledMetrics = append([]string(nil), metrics...)
+}
+
+func (r *NamespaceReconciler) getEnabledMetrics() []string {
+	r.enabledMetricsMu.RLock()
+	defer r.enabledMetricsMu.RUnlock()
+	if len(r.enabledMetrics) == 0 {
+		return mm.DefaultMetrics()
+	}
+	return r.enabledMetrics
+}
+
 // Reconciles the metrics module with included namespaces from annotation on namespaces
 func (r *NamespaceReconciler) Start(ctx context.Context) {
 	ticker := time.NewTicker(interval)
@@ -74,7 +96,7 @@ func (r *NamespaceReconciler) Start(ctx context.Context) {
 			r.l.Debug("Reconciling metrics module", zap.Any("namespaces", ns))
 			spec := (&api.MetricsSpec{}).
 				WithIncludedNamespaces(ns).
-				WithMetricsContextOptions(mm.DefaultMetrics(), mm.DefaultCtxOptions(), mm.DefaultCtxOptions())
+				WithMetricsContextOptions(r.getEnabledMetrics(), mm.DefaultCtxOptions(), mm.DefaultCtxOptions())
 			// MetricsModule will check the diff between namespaces and spec when reconciling.
 			r.metricsModule.Reconcile(spec)
 		case <-ctx.Done():
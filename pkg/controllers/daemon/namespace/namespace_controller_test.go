@@ -202,3 +202,35 @@ func TestStart(t *testing.T) {
 	time.Sleep(15 * time.Second)
 	cancel()
 }
+
+func TestStartWithEnabledMetrics(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	client := fake.NewClientBuilder().WithScheme(fakescheme).Build()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cache := cache.NewMockCacheInterface(ctrl) //nolint:typecheck
+	cache.EXPECT().GetAnnotatedNamespaces().Return([]string{"test"}).MinTimes(1)
+	mm := metrics.NewMockIModule(ctrl) //nolint:typecheck
+	specs := make(chan *retinav1alpha1.MetricsSpec, 10)
+	mm.EXPECT().Reconcile(gomock.Any()).DoAndReturn(func(spec *retinav1alpha1.MetricsSpec) error {
+		select {
+		case specs <- spec:
+		default:
+		}
+		return nil
+	}).MinTimes(1)
+	r := New(client, cache, mm)
+	r.SetEnabledMetrics([]string{"dns_request_count"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Start(ctx)
+
+	select {
+	case spec := <-specs:
+		assert.Equal(t, []string{"test"}, spec.Namespaces.Include)
+		assert.Len(t, spec.ContextOptions, 1)
+		assert.Equal(t, "dns_request_count", spec.ContextOptions[0].MetricName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("metrics module was not reconciled")
+	}
+}
@@ -53,7 +53,7 @@ func newNodeController(params params) (*NodeReconciler, error) {
 		clusterName: params.Config.ClusterName,
 		l:           params.Logger.WithField("component", "node-controller"),
 		nodes:       make(map[string]types.Node),
-		handlers:    make(map[string]datapath.NodeHandler),
+		handlers:    make(map[datapath.NodeHandler]struct{}),
 		c:           params.IPCache,
 		localNodeIP: os.Getenv("NODE_IP"),
 	}
@@ -34,7 +34,7 @@ type NodeReconciler struct {
 	clusterName string
 
 	l           logrus.FieldLogger
-	handlers    map[string]datapath.NodeHandler
+	handlers    map[datapath.NodeHandler]struct{}
 	nodes       map[string]types.Node
 	c           *ipc.IPCache
 	localNodeIP string
@@ -42,10 +42,13 @@ type NodeReconciler struct {
 }
 
 // isNodeUpdated checks if the node has been updated.
-// This is a simple check for labels and annotations
+// This is a simple check for addresses, labels and annotations
 // being updated. Those are the only fields that are mutable.
 // AKS specific for now.
 func isNodeUpdated(n1, n2 types.Node) bool {
+	if !reflect.DeepEqual(n1.IPAddresses, n2.IPAddresses) {
+		return true
+	}
 	if !reflect.DeepEqual(n1.Labels, n2.Labels) {
 		return true
 	}
@@ -55,6 +58,16 @@ func isNodeUpdated(n1, n2 types.Node) bool {
 	return false
 }
 
+// hasAddress checks if the IP of address is one of addresses.
+func hasAddress(addresses []types.Address, address types.Address) bool {
+	for _, a := range addresses {
+		if a.IP.Equal(address.IP) {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *NodeReconciler) addNode(node *corev1.Node) {
 	r.m.Lock()
 	defer r.m.Unlock()
@@ -87,19 +100,38 @@ func (r *NodeReconciler) addNode(node *corev1.Node) {
 	nd.Cluster = r.clusterName
 
 	// Check if the node already exists.
-	if curNode, ok := r.nodes[node.Name]; ok && !isNodeUpdated(curNode, nd) {
+	// Handlers such as the Hubble peer service turn every call into a notification to Hubble Relay,
+	// so only notify them of nodes which are new or have changed.
+	curNode, exists := r.nodes[node.Name]
+	if exists && !isNodeUpdated(curNode, nd) {
 		r.l.Debug("Node already exists", zap.String("Node", node.Name))
+		return
 	}
 
 	r.nodes[node.Name] = nd
 
-	for _, handler := range r.handlers {
-		err := handler.NodeAdd(nd)
+	for handler := range r.handlers {
+		var err error
+		if exists {
+			err = handler.NodeUpdate(curNode, nd)
+		} else {
+			err = handler.NodeAdd(nd)
+		}
 		if err != nil {
 			r.l.Error("Failed to add Node to datapath handler", zap.Error(err), zap.String("handler", handler.Name()), zap.String("Node", node.Name))
 		}
 	}
 
+	// Delete the addresses the node no longer has, so that they stop resolving to its identity.
+	for _, address := range curNode.IPAddresses {
+		if hasAddress(nd.IPAddresses, address) {
+			continue
+		}
+		//nolint:staticcheck // TODO(timraymond): unhelpful deprecation notice: migration path unclear
+		r.c.Delete(address.ToString(), source.Kubernetes)
+		r.l.Debug("Deleted IP from ipcache", zap.String("IP", address.ToString()))
+	}
+
 	id := identity.ReservedIdentityRemoteNode
 	// Check if the node is the local node.
 	for _, address := range nd.IPAddresses {
@@ -128,7 +160,7 @@ func (r *NodeReconciler) deleteNode(node *corev1.Node) {
 	}
 	delete(r.nodes, node.Name)
 
-	for _, handler := range r.handlers {
+	for handler := range r.handlers {
 		err := handler.NodeDelete(nd)
 		if err != nil {
 			r.l.Error("Failed to delete Node from datapath handler", zap.Error(err), zap.String("handler", handler.Name()), zap.String("Node", node.Name))
@@ -142,12 +174,15 @@ func (r *NodeReconciler) deleteNode(node *corev1.Node) {
 	r.l.Debug("Deleted Node", zap.String("Node", node.Name))
 }
 
+// Subscribe registers nh for node events, and replays all known nodes to it.
+// Handlers are keyed by identity rather than name, as every Hubble Relay
+// connection subscribes its own handler with the same name.
 func (r *NodeReconciler) Subscribe(nh datapath.NodeHandler) {
 	r.l.Debug("Subscribing to datapath handler")
-	r.m.RLock()
-	defer r.m.RUnlock()
+	r.m.Lock()
+	defer r.m.Unlock()
 
-	r.handlers[nh.Name()] = nh
+	r.handlers[nh] = struct{}{}
 	for i := range r.nodes {
 		node := r.nodes[i]
 		if err := nh.NodeAdd(node); err != nil {
@@ -160,7 +195,7 @@ func (r *NodeReconciler) Unsubscribe(nh datapath.NodeHandler) {
 	r.l.Debug("Unsubscribing from datapath handler")
 	r.m.Lock()
 	defer r.m.Unlock()
-	delete(r.handlers, nh.Name())
+	delete(r.handlers, nh)
 }
 
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list
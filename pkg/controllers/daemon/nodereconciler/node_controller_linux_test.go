@@ -0,0 +1,86 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package nodereconciler
+
+import (
+	"context"
+	"testing"
+
+	datapath "github.com/cilium/cilium/pkg/datapath/types"
+	ipc "github.com/cilium/cilium/pkg/ipcache"
+	"github.com/cilium/cilium/pkg/node/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type recordingHandler struct {
+	datapath.NodeHandler
+	events []string
+}
+
+func (h *recordingHandler) Name() string { return "hubble-peer" }
+
+func (h *recordingHandler) NodeAdd(n types.Node) error {
+	h.events = append(h.events, "add:"+n.Name)
+	return nil
+}
+
+func (h *recordingHandler) NodeUpdate(_, n types.Node) error {
+	h.events = append(h.events, "update:"+n.Name)
+	return nil
+}
+
+func (h *recordingHandler) NodeDelete(n types.Node) error {
+	h.events = append(h.events, "delete:"+n.Name)
+	return nil
+}
+
+func testNode(name, ip string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+		},
+	}
+}
+
+func TestNodeReconcilerNotifications(t *testing.T) {
+	r := &NodeReconciler{
+		clusterName: "test",
+		l:           logrus.New(),
+		nodes:       make(map[string]types.Node),
+		handlers:    make(map[datapath.NodeHandler]struct{}),
+		c:           ipc.NewIPCache(&ipc.Configuration{Context: context.Background()}),
+	}
+
+	h1 := &recordingHandler{}
+	h2 := &recordingHandler{}
+	r.Subscribe(h1)
+	r.Subscribe(h2)
+
+	r.addNode(testNode("node1", "10.0.0.1"))
+	// A resync of an unchanged node must not notify again.
+	r.addNode(testNode("node1", "10.0.0.1"))
+	// A changed address is an update, which removes the previous address from the ipcache.
+	r.addNode(testNode("node1", "10.0.0.2"))
+	_, ok := r.c.LookupByIP("10.0.0.1")
+	assert.False(t, ok, "Expected previous address of node1 to be deleted from the ipcache")
+	_, ok = r.c.LookupByIP("10.0.0.2")
+	assert.True(t, ok, "Expected address of node1 to be in the ipcache")
+
+	// Handlers with the same name are tracked separately.
+	r.Unsubscribe(h1)
+	r.deleteNode(testNode("node1", "10.0.0.2"))
+
+	assert.Equal(t, []string{"add:node1", "update:node1"}, h1.events)
+	assert.Equal(t, []string{"add:node1", "update:node1", "delete:node1"}, h2.events)
+
+	// New subscribers get the known nodes replayed.
+	r.addNode(testNode("node2", "10.0.0.3"))
+	h3 := &recordingHandler{}
+	r.Subscribe(h3)
+	assert.Equal(t, []string{"add:node2"}, h3.events)
+}
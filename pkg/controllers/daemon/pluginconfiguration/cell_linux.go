@@ -0,0 +1,40 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package pluginconfiguration
+
+import (
+	"os"
+
+	"github.com/cilium/cilium/pkg/hive/cell"
+	"github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/managers/pluginmanager"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const nodeNameEnvKey = "NODE_NAME"
+
+var Cell = cell.Module(
+	"pluginconfiguration-controller",
+	"PluginConfiguration Controller enables plugins at runtime",
+	// Setting up the PluginConfiguration controller with the controller manager
+	cell.Invoke(func(l logrus.FieldLogger, cfg config.Config, pluginManager *pluginmanager.PluginManager, ctrlManager ctrl.Manager) error {
+		if !cfg.EnablePluginConfiguration {
+			return nil
+		}
+		nodeName := os.Getenv(nodeNameEnvKey)
+		if nodeName == "" {
+			return errors.Errorf("failed to get node name from environment variable %s", nodeNameEnvKey)
+		}
+
+		l.Info("Setting up PluginConfiguration controller with manager")
+		r := New(ctrlManager.GetClient(), nodeName, cfg.EnabledPlugin, pluginManager)
+		if err := r.SetupWithManager(ctrlManager); err != nil {
+			l.Errorf("failed to setup PluginConfiguration controller with manager: %v", err)
+			return errors.Wrap(err, "failed to setup PluginConfiguration controller with manager")
+		}
+		return nil
+	}),
+)
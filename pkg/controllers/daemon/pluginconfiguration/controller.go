@@ -0,0 +1,119 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package pluginconfiguration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	"github.com/microsoft/retina/pkg/log"
+	pm "github.com/microsoft/retina/pkg/managers/pluginmanager"
+	"github.com/microsoft/retina/pkg/plugin/api"
+)
+
+// statusRefreshInterval is the interval the status of the plugins is refreshed in the PluginConfiguration.
+const statusRefreshInterval = 30 * time.Second
+
+// pluginManager enables the plugins at runtime and reports their status.
+type pluginManager interface {
+	SetEnabledPlugins(pluginNames ...api.PluginName) error
+	PluginStatuses() []pm.PluginStatus
+}
+
+// PluginConfigurationReconciler reconciles the PluginConfiguration named after the node of the agent.
+type PluginConfigurationReconciler struct {
+	client.Client
+
+	l        *log.ZapLogger
+	nodeName string
+	// defaultPlugins are the plugins enabled by the configuration of the agent, which are enabled again when the
+	// PluginConfiguration is deleted.
+	defaultPlugins []api.PluginName
+	pluginManager  pluginManager
+}
+
+func New(client client.Client, nodeName string, defaultPlugins []string, pluginManager pluginManager) *PluginConfigurationReconciler {
+	pluginNames := make([]api.PluginName, 0, len(defaultPlugins))
+	for _, name := range defaultPlugins {
+		pluginNames = append(pluginNames, api.PluginName(name))
+	}
+	return &PluginConfigurationReconciler{
+		Client:         client,
+		l:              log.Logger().Named("pluginconfiguration-controller"),
+		nodeName:       nodeName,
+		defaultPlugins: pluginNames,
+		pluginManager:  pluginManager,
+	}
+}
+
+// +kubebuilder:rbac:groups=retina.sh,resources=pluginconfigurations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=retina.sh,resources=pluginconfigurations/status,verbs=get;update;patch
+
+func (r *PluginConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	pluginConfiguration := &retinav1alpha1.PluginConfiguration{}
+	if err := r.Client.Get(ctx, req.NamespacedName, pluginConfiguration); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("failed to get PluginConfiguration %s: %w", req.Name, err)
+		}
+		r.l.Info("PluginConfiguration deleted, enabling the plugins of the agent configuration",
+			zap.String("name", req.Name), zap.String("plugins", fmt.Sprint(r.defaultPlugins)))
+		if err := r.pluginManager.SetEnabledPlugins(r.defaultPlugins...); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to enable the plugins of the agent configuration: %w", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	pluginNames := make([]api.PluginName, 0, len(pluginConfiguration.Spec.EnabledPlugins))
+	for _, name := range pluginConfiguration.Spec.EnabledPlugins {
+		pluginNames = append(pluginNames, api.PluginName(name))
+	}
+	status := retinav1alpha1.PluginConfigurationStatus{
+		State:              retinav1alpha1.StateAccepted,
+		ObservedGeneration: pluginConfiguration.Generation,
+	}
+	if err := r.pluginManager.SetEnabledPlugins(pluginNames...); err != nil {
+		r.l.Error("failed to enable plugins", zap.String("name", req.Name), zap.Error(err))
+		status.State = retinav1alpha1.StateErrored
+		status.Reason = err.Error()
+	}
+	for _, pluginStatus := range r.pluginManager.PluginStatuses() {
+		status.Plugins = append(status.Plugins, retinav1alpha1.PluginStatus{
+			Name:      pluginStatus.Name,
+			State:     string(pluginStatus.State),
+			Restarts:  pluginStatus.Restarts,
+			LastError: pluginStatus.LastError,
+		})
+	}
+
+	if !equality.Semantic.DeepEqual(pluginConfiguration.Status, status) {
+		pluginConfiguration.Status = status
+		if err := r.Client.Status().Update(ctx, pluginConfiguration); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status of PluginConfiguration %s: %w", req.Name, err)
+		}
+	}
+	// Requeue to keep the status of the plugins up to date.
+	return ctrl.Result{RequeueAfter: statusRefreshInterval}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PluginConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&retinav1alpha1.PluginConfiguration{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(object client.Object) bool {
+				return object.GetName() == r.nodeName
+			}),
+			predicate.GenerationChangedPredicate{},
+		)).
+		Complete(r)
+}
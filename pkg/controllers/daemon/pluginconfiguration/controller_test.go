@@ -0,0 +1,115 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package pluginconfiguration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	"github.com/microsoft/retina/pkg/log"
+	pm "github.com/microsoft/retina/pkg/managers/pluginmanager"
+	"github.com/microsoft/retina/pkg/plugin/api"
+)
+
+var fakescheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(fakescheme))
+	utilruntime.Must(retinav1alpha1.AddToScheme(fakescheme))
+}
+
+type fakePluginManager struct {
+	enabled []api.PluginName
+}
+
+func (f *fakePluginManager) SetEnabledPlugins(pluginNames ...api.PluginName) error {
+	for _, name := range pluginNames {
+		if name == "unknown" {
+			return fmt.Errorf("plugin %s not found in registry", name)
+		}
+	}
+	f.enabled = pluginNames
+	return nil
+}
+
+func (f *fakePluginManager) PluginStatuses() []pm.PluginStatus {
+	statuses := make([]pm.PluginStatus, 0, len(f.enabled))
+	for _, name := range f.enabled {
+		statuses = append(statuses, pm.PluginStatus{Name: string(name), State: pm.PluginStateRunning})
+	}
+	return statuses
+}
+
+func TestPluginConfigurationReconciler_Reconcile(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+
+	tests := []struct {
+		name             string
+		enabledPlugins   []string
+		deleted          bool
+		wantEnabled      []api.PluginName
+		wantState        string
+		wantPluginStatus int
+	}{
+		{
+			name:             "enables the plugins of the PluginConfiguration",
+			enabledPlugins:   []string{"dropreason", "packetforward"},
+			wantEnabled:      []api.PluginName{"dropreason", "packetforward"},
+			wantState:        retinav1alpha1.StateAccepted,
+			wantPluginStatus: 2,
+		},
+		{
+			name:             "keeps the enabled plugins when a plugin is unknown",
+			enabledPlugins:   []string{"dropreason", "unknown"},
+			wantEnabled:      []api.PluginName{"linuxutil"},
+			wantState:        retinav1alpha1.StateErrored,
+			wantPluginStatus: 1,
+		},
+		{
+			name:        "enables the plugins of the agent configuration when the PluginConfiguration is deleted",
+			deleted:     true,
+			wantEnabled: []api.PluginName{"linuxutil"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pluginConfiguration := &retinav1alpha1.PluginConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "node1", Generation: 2},
+				Spec:       retinav1alpha1.PluginConfigurationSpec{EnabledPlugins: tt.enabledPlugins},
+			}
+			builder := fake.NewClientBuilder().WithScheme(fakescheme).WithStatusSubresource(pluginConfiguration)
+			if !tt.deleted {
+				builder = builder.WithObjects(pluginConfiguration)
+			}
+			client := builder.Build()
+
+			pluginManager := &fakePluginManager{enabled: []api.PluginName{"linuxutil"}}
+			r := New(client, "node1", []string{"linuxutil"}, pluginManager)
+			_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "node1"}})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantEnabled, pluginManager.enabled)
+			if tt.deleted {
+				return
+			}
+
+			got := &retinav1alpha1.PluginConfiguration{}
+			require.NoError(t, client.Get(context.Background(), types.NamespacedName{Name: "node1"}, got))
+			require.Equal(t, tt.wantState, got.Status.State)
+			require.Equal(t, int64(2), got.Status.ObservedGeneration)
+			require.Len(t, got.Status.Plugins, tt.wantPluginStatus)
+		})
+	}
+}
@@ -13,15 +13,18 @@ import (
 	"go.uber.org/zap"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
 	pkgcapture "github.com/microsoft/retina/pkg/capture"
@@ -36,15 +39,25 @@ const (
 
 	captureErrorReasonExceedJobNumLimit = "ExceedJobNumLimit"
 	captureErrorReasonFindSecretFailed  = "FindSecretFailed"
+	captureErrorReasonInvalidFilter     = "InvalidFilter"
+	captureErrorReasonPolicyDenied      = "PolicyDenied"
 	captureErrorReasonOthers            = "OtherError"
 	captureErrorReasonCreateJobFailed   = "CreateSecretFailed"
 	captureErrorReasonRunJobFailed      = "RunJobFailed"
 
 	captureInPogressReason        = "JobsInProgress"
+	captureStoppingReason         = "JobsStopping"
 	captureCompleteReason         = "JobsCompleted"
+	captureStoppedReason          = "Stopped"
 	captureInPogressMessage       = "%d/%d Capture jobs are in progress, waiting for completion"
+	captureStoppingMessage        = "%d/%d Capture jobs are stopping, waiting for completion"
+	captureStoppedMessage         = "Capture is stopped before Capture jobs are created"
 	captureFailedJobFailedMessage = "%d Capture jobs are in failed state"
 	captureCompleteMessage        = "All %d Capture jobs are completed"
+
+	// captureProgressInterval is the interval the progress of running Captures is updated at, as the Capture is not
+	// reconciled when the Pods of its jobs change.
+	captureProgressInterval = 30 * time.Second
 )
 
 // CaptureReconciler reconciles a Capture object
@@ -54,14 +67,21 @@ type CaptureReconciler struct {
 
 	logger *log.ZapLogger
 
+	kubeClient             kubernetes.Interface
 	captureToPodTranslator *pkgcapture.CaptureToPodTranslator
+	recorder               record.EventRecorder
+
+	// defaultTTL is the time to keep the Captures not setting their TTL after they finish.
+	defaultTTL time.Duration
 }
 
 func NewCaptureReconciler(client client.Client, scheme *runtime.Scheme, kubeClient kubernetes.Interface, config config.CaptureConfig) *CaptureReconciler {
 	cr := &CaptureReconciler{
-		Client: client,
-		scheme: scheme,
-		logger: log.Logger().Named("Capture"),
+		Client:     client,
+		scheme:     scheme,
+		logger:     log.Logger().Named("Capture"),
+		kubeClient: kubeClient,
+		defaultTTL: config.CaptureTTL,
 	}
 
 	cr.captureToPodTranslator = pkgcapture.NewCaptureToPodTranslator(kubeClient, cr.logger, config)
@@ -76,8 +96,12 @@ func NewCaptureReconciler(client client.Client, scheme *runtime.Scheme, kubeClie
 //+kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list
 //+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list
-//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;patch
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list
+//+kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+//+kubebuilder:rbac:groups=retina.sh,resources=capturepolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -130,14 +154,10 @@ func (cr *CaptureReconciler) updateCaptureStatusFromJobs(ctx context.Context, ca
 		Name:      capture.Name,
 	}
 
-	isJobFinished := func(job *batchv1.Job) (bool, batchv1.JobConditionType) {
-		for _, c := range job.Status.Conditions {
-			if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
-				return true, c.Type
-			}
-		}
-
-		return false, ""
+	pods, err := cr.listCapturePods(ctx, capture)
+	if err != nil {
+		cr.logger.Error("Failed to list Capture Pods", zap.Error(err), zap.String("Capture", captureRef.String()))
+		return ctrl.Result{}, err
 	}
 
 	// update status of the capture depending on the status of the jobs
@@ -146,11 +166,18 @@ func (cr *CaptureReconciler) updateCaptureStatusFromJobs(ctx context.Context, ca
 	var activeJobs []*batchv1.Job
 	var successfulJobs []*batchv1.Job
 	var failedJobs []*batchv1.Job
-	for i, job := range captureJobs {
-		_, finishedType := isJobFinished(&job)
-		switch finishedType {
+	for i := range captureJobs {
+		job := &captureJobs[i]
+		switch jobFinishedType(job) {
 		case "": // ongoing
 			activeJobs = append(activeJobs, &captureJobs[i])
+			// Running capture jobs are stopped or extended through the annotations of their Pods.
+			if pod, ok := pods[job.UID]; ok {
+				if err := cr.controlCapturePod(ctx, pod, captureJobControl(capture, job)); err != nil {
+					cr.logger.Error("Failed to control Capture job", zap.Error(err), zap.String("Capture", captureRef.String()), zap.String("Capture job", job.Name))
+					return ctrl.Result{}, err
+				}
+			}
 		case batchv1.JobFailed:
 			failedJobs = append(failedJobs, &captureJobs[i])
 		case batchv1.JobComplete:
@@ -161,6 +188,7 @@ func (cr *CaptureReconciler) updateCaptureStatusFromJobs(ctx context.Context, ca
 	capture.Status.Active = int32(len(activeJobs))
 	capture.Status.Failed = int32(len(failedJobs))
 	capture.Status.Succeeded = int32(len(successfulJobs))
+	capture.Status.Nodes = captureNodeStatuses(captureJobs, pods, capture.Spec.Stop)
 	// Once we detect jobs are in failed state, we'll update the status of the Capture to error, meanwhile we keep
 	// updating the status of the Capture to inProgress if there are still active jobs.
 	if len(failedJobs) != 0 {
@@ -175,14 +203,22 @@ func (cr *CaptureReconciler) updateCaptureStatusFromJobs(ctx context.Context, ca
 	}
 	// Update Capture inProgress status if there are still active jobs.
 	if len(successfulJobs) != len(captureJobs) {
+		reason, message := captureInPogressReason, captureInPogressMessage
+		if capture.Spec.Stop && len(activeJobs) != 0 {
+			reason, message = captureStoppingReason, captureStoppingMessage
+		}
 		meta.SetStatusCondition(&capture.Status.Conditions, metav1.Condition{
 			Type:    string(retinav1alpha1.CaptureComplete),
 			Status:  metav1.ConditionFalse,
-			Reason:  captureInPogressReason,
-			Message: fmt.Sprintf(captureInPogressMessage, len(activeJobs), len(captureJobs)),
+			Reason:  reason,
+			Message: fmt.Sprintf(message, len(activeJobs), len(captureJobs)),
 		})
 
-		return cr.updateStatus(ctx, capture)
+		result, err := cr.updateStatus(ctx, capture)
+		if err == nil && len(activeJobs) != 0 {
+			result.RequeueAfter = captureProgressInterval
+		}
+		return result, err
 	}
 
 	// Update status of Capture to complete when all jobs are completed.
@@ -209,7 +245,7 @@ func (cr *CaptureReconciler) createJobsFromCapture(ctx context.Context, capture
 		Name:      capture.Name,
 	}
 
-	jobs, err := cr.captureToPodTranslator.TranslateCaptureToJobs(capture)
+	jobs, err := cr.translateCaptureToJobs(ctx, capture)
 	if err != nil {
 		cr.logger.Error("Failed to translate Capture to jobs", zap.Error(err), zap.String("Capture", captureRef.String()))
 		var errorReason string
@@ -220,6 +256,13 @@ func (cr *CaptureReconciler) createJobsFromCapture(ctx context.Context, capture
 		case pkgcapture.SecretNotFoundError:
 			errorReason = captureErrorReasonFindSecretFailed
 			cr.logger.Error("Failed to find Capture secret", zap.Error(err), zap.String("Capture", captureRef.String()))
+		case pkgcapture.CaptureFilterInvalidError:
+			errorReason = captureErrorReasonInvalidFilter
+			cr.logger.Error("Invalid Capture filter", zap.Error(err), zap.String("Capture", captureRef.String()))
+		case pkgcapture.CapturePolicyDeniedError:
+			errorReason = captureErrorReasonPolicyDenied
+			cr.logger.Error("Capture is denied by CapturePolicies", zap.Error(err), zap.String("Capture", captureRef.String()))
+			cr.recorder.Event(capture, corev1.EventTypeWarning, captureErrorReasonPolicyDenied, err.Error())
 		default:
 			errorReason = captureErrorReasonOthers
 			cr.logger.Error("Failed to translate Capture to jobs", zap.Error(err), zap.String("Capture", captureRef.String()))
@@ -287,9 +330,43 @@ func (cr *CaptureReconciler) handleUpdate(ctx context.Context, capture *retinav1
 		return ctrl.Result{}, err
 	}
 
-	// Once the jobs are created, we'll update the status of the Capture according to the status of the jobs.
-	if len(captureJobList.Items) != 0 {
-		return cr.updateCaptureStatusFromJobs(ctx, capture, captureJobList.Items)
+	// An expired Capture is deleted after its artifacts, otherwise it is reconciled again once it expires.
+	expiry := cr.captureExpiry(capture, captureJobList.Items)
+	if expiry != nil && !time.Now().Before(*expiry) {
+		return cr.expireCapture(ctx, capture, captureJobList.Items)
+	}
+	result, err := cr.reconcileCaptureJobs(ctx, capture, captureJobList.Items)
+	if err == nil && expiry != nil {
+		if untilExpiry := time.Until(*expiry); result.RequeueAfter == 0 || untilExpiry < result.RequeueAfter {
+			result.RequeueAfter = untilExpiry
+		}
+	}
+	return result, err
+}
+
+// reconcileCaptureJobs creates the capture jobs of the Capture, or updates its status from the existing jobs.
+func (cr *CaptureReconciler) reconcileCaptureJobs(ctx context.Context, capture *retinav1alpha1.Capture, captureJobs []batchv1.Job) (ctrl.Result, error) {
+	// Once the jobs are created, we'll update the status of the Capture according to the status of the jobs, after
+	// capturing the Pods added to the selected Services.
+	if len(captureJobs) != 0 {
+		captureJobs = cr.captureServiceEndpoints(ctx, capture, captureJobs)
+		return cr.updateCaptureStatusFromJobs(ctx, capture, captureJobs)
+	}
+
+	// A Capture stopped before its jobs are created completes without capturing network packets.
+	if capture.Spec.Stop {
+		if meta.IsStatusConditionTrue(capture.Status.Conditions, string(retinav1alpha1.CaptureComplete)) {
+			return ctrl.Result{}, nil
+		}
+		meta.SetStatusCondition(&capture.Status.Conditions, metav1.Condition{
+			Type:    string(retinav1alpha1.CaptureComplete),
+			Status:  metav1.ConditionTrue,
+			Reason:  captureStoppedReason,
+			Message: captureStoppedMessage,
+		})
+		now := metav1.Now()
+		capture.Status.CompletionTime = &now
+		return cr.updateStatus(ctx, capture)
 	}
 
 	return cr.createJobsFromCapture(ctx, capture)
@@ -335,9 +412,12 @@ func (cr *CaptureReconciler) handleDelete(ctx context.Context, capture *retinav1
 
 // SetupWithManager sets up the controller with the Manager.
 func (cr *CaptureReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	cr.recorder = mgr.GetEventRecorderFor("retina-capture-controller")
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&retinav1alpha1.Capture{}).
 		Owns(&batchv1.Job{}). // Once the job owned by capture is created /deleted/updated, the capture will be reconciled.
+		// Once the endpoints of Services change, the running Captures selecting Services will be reconciled.
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(cr.capturesOfEndpointSlice)).
 		Complete(cr)
 }
 
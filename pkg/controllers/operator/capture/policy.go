@@ -0,0 +1,35 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+)
+
+// translateCaptureToJobs translates the Capture to its capture jobs once it is allowed by the CapturePolicies.
+func (cr *CaptureReconciler) translateCaptureToJobs(ctx context.Context, capture *retinav1alpha1.Capture) ([]*batchv1.Job, error) {
+	if err := cr.enforceCapturePolicies(ctx, capture); err != nil {
+		return nil, err
+	}
+	return cr.captureToPodTranslator.TranslateCaptureToJobs(capture)
+}
+
+// enforceCapturePolicies checks the Capture is allowed by the CapturePolicies selecting its namespace.
+func (cr *CaptureReconciler) enforceCapturePolicies(ctx context.Context, capture *retinav1alpha1.Capture) error {
+	policyList := &retinav1alpha1.CapturePolicyList{}
+	if err := cr.Client.List(ctx, policyList); err != nil {
+		// Captures are not restricted when the CapturePolicy CRD is not installed.
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list CapturePolicies: %w", err)
+	}
+	return cr.captureToPodTranslator.EnforceCapturePolicies(capture, policyList.Items)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+	captureUtils "github.com/microsoft/retina/pkg/capture/utils"
+	"github.com/microsoft/retina/pkg/common/apiretry"
+)
+
+// captureExpiry returns the time the Capture expires, or nil if it is kept forever or is still running.
+func (cr *CaptureReconciler) captureExpiry(capture *retinav1alpha1.Capture, captureJobs []batchv1.Job) *time.Time {
+	ttl := cr.defaultTTL
+	if capture.Spec.TTL != nil {
+		ttl = capture.Spec.TTL.Duration
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	finishedTime := captureFinishedTime(capture, captureJobs)
+	if finishedTime == nil {
+		return nil
+	}
+	expiry := finishedTime.Add(ttl)
+	return &expiry
+}
+
+// captureFinishedTime returns the time the last capture job of the Capture finished, or the time the Capture
+// completed or failed without jobs. It returns nil if the Capture is still running.
+func captureFinishedTime(capture *retinav1alpha1.Capture, captureJobs []batchv1.Job) *metav1.Time {
+	if len(captureJobs) == 0 {
+		if c := meta.FindStatusCondition(capture.Status.Conditions, string(retinav1alpha1.CaptureComplete)); c != nil && c.Status == metav1.ConditionTrue {
+			if capture.Status.CompletionTime != nil {
+				return capture.Status.CompletionTime
+			}
+			return &c.LastTransitionTime
+		}
+		if c := meta.FindStatusCondition(capture.Status.Conditions, string(retinav1alpha1.CaptureError)); c != nil && c.Status == metav1.ConditionTrue {
+			return &c.LastTransitionTime
+		}
+		return nil
+	}
+
+	var finishedTime *metav1.Time
+	for i := range captureJobs {
+		jobFinishedTime := captureJobFinishedTime(&captureJobs[i])
+		if jobFinishedTime == nil {
+			return nil
+		}
+		if finishedTime == nil || jobFinishedTime.After(finishedTime.Time) {
+			finishedTime = jobFinishedTime
+		}
+	}
+	return finishedTime
+}
+
+// captureJobFinishedTime returns the time the job completed or failed, or nil if it is still active.
+func captureJobFinishedTime(job *batchv1.Job) *metav1.Time {
+	finishedType := jobFinishedType(job)
+	if finishedType == "" {
+		return nil
+	}
+	if finishedType == batchv1.JobComplete && job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime
+	}
+	for i := range job.Status.Conditions {
+		if job.Status.Conditions[i].Type == finishedType {
+			return &job.Status.Conditions[i].LastTransitionTime
+		}
+	}
+	return nil
+}
+
+// expireCapture deletes the capture artifacts of the expired Capture through a cleanup job on the node of each
+// capture job, then deletes the Capture once the cleanup jobs finish. The capture jobs are deleted with the Capture,
+// and the cleanup jobs are garbage collected as the Capture owns them.
+func (cr *CaptureReconciler) expireCapture(ctx context.Context, capture *retinav1alpha1.Capture, captureJobs []batchv1.Job) (ctrl.Result, error) {
+	captureRef := types.NamespacedName{
+		Namespace: capture.Namespace,
+		Name:      capture.Name,
+	}
+
+	cleanupJobList := &batchv1.JobList{}
+	if err := apiretry.Do(
+		func() error {
+			return cr.Client.List(ctx, cleanupJobList, client.InNamespace(capture.Namespace), client.MatchingLabels(captureUtils.GetCleanupJobLabelsFromCaptureName(capture.Name)))
+		},
+	); err != nil {
+		cr.logger.Error("Failed to list Capture cleanup jobs", zap.Error(err), zap.String("Capture", captureRef.String()))
+		return ctrl.Result{}, err
+	}
+
+	// The capture artifacts of each node are deleted by a cleanup job on the node, as they may be stored on its host.
+	cleanedNodes := map[string]bool{}
+	for i := range cleanupJobList.Items {
+		cleanedNodes[captureJobNodeName(&cleanupJobList.Items[i], nil)] = true
+	}
+	created := false
+	for i := range captureJobs {
+		nodeName := captureJobNodeName(&captureJobs[i], nil)
+		if cleanedNodes[nodeName] {
+			continue
+		}
+		cleanedNodes[nodeName] = true
+
+		job := pkgcapture.CleanupJobFromCaptureJob(capture.Name, &captureJobs[i])
+		if err := controllerutil.SetControllerReference(capture, job, cr.scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := cr.Client.Create(ctx, job); err != nil {
+			cr.logger.Error("Failed to create Capture cleanup job", zap.Error(err), zap.String("Capture", captureRef.String()), zap.String("node", nodeName))
+			return ctrl.Result{}, err
+		}
+		cr.logger.Info("Capture cleanup job is created", zap.String("Capture", captureRef.String()), zap.String("Capture cleanup job", job.Name))
+		created = true
+	}
+	// The Capture is reconciled again when the cleanup jobs change.
+	if created {
+		return ctrl.Result{}, nil
+	}
+
+	for i := range cleanupJobList.Items {
+		job := &cleanupJobList.Items[i]
+		switch jobFinishedType(job) {
+		case "":
+			return ctrl.Result{}, nil
+		case batchv1.JobFailed:
+			cr.logger.Warn("Failed to delete capture artifacts", zap.String("Capture", captureRef.String()), zap.String("Capture cleanup job", job.Name))
+		}
+	}
+
+	cr.logger.Info("Deleting expired Capture", zap.String("Capture", captureRef.String()))
+	if err := cr.Client.Delete(ctx, capture); client.IgnoreNotFound(err) != nil {
+		cr.logger.Error("Failed to delete expired Capture", zap.Error(err), zap.String("Capture", captureRef.String()))
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+)
+
+func TestCaptureExpiry(t *testing.T) {
+	finished := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) metav1.Time {
+		return metav1.NewTime(finished.Add(time.Duration(minutes) * time.Minute))
+	}
+	completeJob := func(name string, minutes int) batchv1.Job {
+		job := captureTestJob(name, name, batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue, LastTransitionTime: at(minutes + 1)})
+		completionTime := at(minutes)
+		job.Status.CompletionTime = &completionTime
+		return job
+	}
+	failedJob := func(name string, minutes int) batchv1.Job {
+		return captureTestJob(name, name, batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: at(minutes)})
+	}
+	hour := &metav1.Duration{Duration: time.Hour}
+
+	cases := []struct {
+		name       string
+		defaultTTL time.Duration
+		ttl        *metav1.Duration
+		conditions []metav1.Condition
+		jobs       []batchv1.Job
+		want       *metav1.Time
+	}{
+		{
+			name: "no TTL",
+			jobs: []batchv1.Job{completeJob("job-a", 0)},
+		},
+		{
+			name: "running jobs",
+			ttl:  hour,
+			jobs: []batchv1.Job{completeJob("job-a", 0), captureTestJob("job-b", "node-b")},
+		},
+		{
+			name: "finished jobs",
+			ttl:  hour,
+			jobs: []batchv1.Job{completeJob("job-a", 0), failedJob("job-b", 5)},
+			want: ptrTime(at(65)),
+		},
+		{
+			name:       "default TTL",
+			defaultTTL: 2 * time.Hour,
+			jobs:       []batchv1.Job{completeJob("job-a", 0)},
+			want:       ptrTime(at(120)),
+		},
+		{
+			name:       "TTL overrides default TTL",
+			defaultTTL: 2 * time.Hour,
+			ttl:        hour,
+			jobs:       []batchv1.Job{completeJob("job-a", 0)},
+			want:       ptrTime(at(60)),
+		},
+		{
+			name: "stopped without jobs",
+			ttl:  hour,
+			conditions: []metav1.Condition{
+				{Type: string(retinav1alpha1.CaptureComplete), Status: metav1.ConditionTrue, LastTransitionTime: at(10)},
+			},
+			want: ptrTime(at(70)),
+		},
+		{
+			name: "failed without jobs",
+			ttl:  hour,
+			conditions: []metav1.Condition{
+				{Type: string(retinav1alpha1.CaptureError), Status: metav1.ConditionTrue, LastTransitionTime: at(20)},
+			},
+			want: ptrTime(at(80)),
+		},
+		{
+			name: "pending without jobs",
+			ttl:  hour,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &CaptureReconciler{defaultTTL: tt.defaultTTL}
+			capture := &retinav1alpha1.Capture{
+				Spec:   retinav1alpha1.CaptureSpec{TTL: tt.ttl},
+				Status: retinav1alpha1.CaptureStatus{Conditions: tt.conditions},
+			}
+			got := cr.captureExpiry(capture, tt.jobs)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("captureExpiry() want nil, got %s", got)
+			case tt.want != nil && (got == nil || !got.Equal(tt.want.Time)):
+				t.Errorf("captureExpiry() want %s, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func ptrTime(t metav1.Time) *metav1.Time {
+	return &t
+}
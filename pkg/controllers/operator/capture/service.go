@@ -0,0 +1,107 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+)
+
+// captureServiceEndpoints creates the capture jobs of the Pods added to the Services selected by the running Capture
+// since it started, and returns the capture jobs including the created ones. Failing to capture the new Pods does not
+// fail the Capture, which keeps capturing the Pods it started with, and is retried when the Capture is reconciled again.
+func (cr *CaptureReconciler) captureServiceEndpoints(ctx context.Context, capture *retinav1alpha1.Capture, captureJobs []batchv1.Job) []batchv1.Job {
+	if capture.Spec.CaptureConfiguration.CaptureTarget.ServiceSelector == nil || capture.Spec.Stop {
+		return captureJobs
+	}
+	running := false
+	for i := range captureJobs {
+		if jobFinishedType(&captureJobs[i]) == "" {
+			running = true
+			break
+		}
+	}
+	if !running {
+		return captureJobs
+	}
+
+	captureRef := types.NamespacedName{
+		Namespace: capture.Namespace,
+		Name:      capture.Name,
+	}
+	// The Pods added to the Services may not be allowed by the CapturePolicies the Capture started with.
+	if err := cr.enforceCapturePolicies(ctx, capture); err != nil {
+		cr.logger.Warn("New Service endpoints of Capture are not captured", zap.Error(err), zap.String("Capture", captureRef.String()))
+		if _, ok := err.(pkgcapture.CapturePolicyDeniedError); ok {
+			cr.recorder.Event(capture, corev1.EventTypeWarning, captureErrorReasonPolicyDenied, err.Error())
+		}
+		return captureJobs
+	}
+	durationOffset := time.Since(captureStartTime(captureJobs)).Round(time.Second)
+	jobs, err := cr.captureToPodTranslator.TranslateCaptureToJobsOfNewEndpoints(capture, captureJobs, durationOffset)
+	if err != nil {
+		cr.logger.Warn("Failed to translate new Service endpoints of Capture to jobs", zap.Error(err), zap.String("Capture", captureRef.String()))
+		return captureJobs
+	}
+	for _, job := range jobs {
+		if err := controllerutil.SetControllerReference(capture, job, cr.scheme); err != nil {
+			cr.logger.Warn("Failed to set owner of Capture job", zap.Error(err), zap.String("Capture", captureRef.String()))
+			return captureJobs
+		}
+		if err := cr.Client.Create(ctx, job); err != nil {
+			cr.logger.Warn("Failed to create Capture job of new Service endpoints", zap.Error(err), zap.String("Capture", captureRef.String()))
+			return captureJobs
+		}
+		cr.logger.Info("Capture job of new Service endpoints is created", zap.String("Capture", captureRef.String()), zap.String("Capture job", job.Name), zap.Duration("duration offset", durationOffset))
+		captureJobs = append(captureJobs, *job)
+	}
+	return captureJobs
+}
+
+// captureStartTime returns the time the first capture job of the Capture was created.
+func captureStartTime(captureJobs []batchv1.Job) time.Time {
+	var startTime time.Time
+	for i := range captureJobs {
+		if created := captureJobs[i].CreationTimestamp.Time; startTime.IsZero() || created.Before(startTime) {
+			startTime = created
+		}
+	}
+	return startTime
+}
+
+// capturesOfEndpointSlice maps the change of an EndpointSlice to the running Captures selecting Services, which may be
+// backed by the Pods of the EndpointSlice.
+func (cr *CaptureReconciler) capturesOfEndpointSlice(ctx context.Context, _ client.Object) []reconcile.Request {
+	captureList := &retinav1alpha1.CaptureList{}
+	if err := cr.Client.List(ctx, captureList); err != nil {
+		cr.logger.Error("Failed to list Captures", zap.Error(err))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range captureList.Items {
+		capture := &captureList.Items[i]
+		if capture.Spec.CaptureConfiguration.CaptureTarget.ServiceSelector == nil || capture.Spec.Stop {
+			continue
+		}
+		if meta.IsStatusConditionTrue(capture.Status.Conditions, string(retinav1alpha1.CaptureComplete)) ||
+			meta.IsStatusConditionTrue(capture.Status.Conditions, string(retinav1alpha1.CaptureError)) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: capture.Namespace, Name: capture.Name}})
+	}
+	return requests
+}
@@ -0,0 +1,176 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+	captureUtils "github.com/microsoft/retina/pkg/capture/utils"
+)
+
+// jobFinishedType returns the type of the condition finishing the job, or empty if the job is still active.
+func jobFinishedType(job *batchv1.Job) batchv1.JobConditionType {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return c.Type
+		}
+	}
+	return ""
+}
+
+// captureJobControl returns the control of the running capture job of the Capture. The duration of a job created after
+// the Capture started, for the Pods added to the selected Services, is the rest of the Capture duration.
+func captureJobControl(capture *retinav1alpha1.Capture, job *batchv1.Job) pkgcapture.CaptureJobControl {
+	control := pkgcapture.CaptureJobControl{Stop: capture.Spec.Stop}
+	if duration := capture.Spec.CaptureConfiguration.CaptureOption.Duration; duration != nil {
+		control.Duration = duration.Duration
+		// An invalid offset is ignored, which captures for the Capture duration from the start of the job.
+		if offset, err := pkgcapture.CaptureJobDurationOffset(job); err == nil && offset != 0 {
+			// A Capture shortened to end before the job is created stops the job right away.
+			control.Duration = max(duration.Duration-offset, time.Nanosecond)
+		}
+	}
+	return control
+}
+
+// listCapturePods returns the Pods of the capture jobs by the UID of their job.
+func (cr *CaptureReconciler) listCapturePods(ctx context.Context, capture *retinav1alpha1.Capture) (map[types.UID]*corev1.Pod, error) {
+	podList, err := cr.kubeClient.CoreV1().Pods(capture.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(captureUtils.GetContainerLabelsFromCaptureName(capture.Name)).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list capture Pods: %w", err)
+	}
+
+	// Capture jobs don't retry, so there is usually one Pod per job, otherwise the latest one is used.
+	pods := map[types.UID]*corev1.Pod{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil || owner.Kind != "Job" {
+			continue
+		}
+		if latest, ok := pods[owner.UID]; ok && pod.CreationTimestamp.Before(&latest.CreationTimestamp) {
+			continue
+		}
+		pods[owner.UID] = pod
+	}
+	return pods, nil
+}
+
+// controlCapturePod sets the control of the running capture job in the annotations of its Pod, if it changed. A stop
+// request cannot be undone, so it is never removed from the annotations.
+func (cr *CaptureReconciler) controlCapturePod(ctx context.Context, pod *corev1.Pod, control pkgcapture.CaptureJobControl) error {
+	if pod.Status.Phase != corev1.PodPending && pod.Status.Phase != corev1.PodRunning {
+		return nil
+	}
+	current, err := pkgcapture.CaptureJobControlFromAnnotations(pod.Annotations)
+	if err == nil && (current.Stop || !control.Stop) && (control.Duration == 0 || current.Duration == control.Duration) {
+		return nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": control.Annotations(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal capture control: %w", err)
+	}
+	if _, err := cr.kubeClient.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, data, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch capture Pod %s: %w", pod.Name, err)
+	}
+	cr.logger.Info("Capture Pod control is updated", zap.String("Pod", pod.Name), zap.Bool("stop", control.Stop), zap.Duration("duration", control.Duration))
+	return nil
+}
+
+// captureNodeStatuses returns the progress of the capture on each node, from the capture jobs and their Pods.
+func captureNodeStatuses(jobs []batchv1.Job, pods map[types.UID]*corev1.Pod, stop bool) []retinav1alpha1.CaptureNodeStatus {
+	statuses := make([]retinav1alpha1.CaptureNodeStatus, 0, len(jobs))
+	for i := range jobs {
+		statuses = append(statuses, captureNodeStatus(&jobs[i], pods[jobs[i].UID], stop))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NodeName < statuses[j].NodeName
+	})
+	return statuses
+}
+
+func captureNodeStatus(job *batchv1.Job, pod *corev1.Pod, stop bool) retinav1alpha1.CaptureNodeStatus {
+	status := retinav1alpha1.CaptureNodeStatus{
+		NodeName: captureJobNodeName(job, pod),
+		JobName:  job.Name,
+	}
+
+	switch jobFinishedType(job) {
+	case batchv1.JobComplete:
+		status.Phase = retinav1alpha1.CaptureNodeSucceeded
+	case batchv1.JobFailed:
+		status.Phase = retinav1alpha1.CaptureNodeFailed
+		for _, c := range job.Status.Conditions {
+			if c.Type == batchv1.JobFailed {
+				status.Message = c.Message
+			}
+		}
+	default:
+		switch {
+		case pod == nil || pod.Status.Phase == corev1.PodPending:
+			status.Phase = retinav1alpha1.CaptureNodePending
+		case stop:
+			status.Phase = retinav1alpha1.CaptureNodeStopping
+		default:
+			status.Phase = retinav1alpha1.CaptureNodeRunning
+		}
+	}
+
+	if pod == nil {
+		return status
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.Name != captureConstants.CaptureContainername || containerStatus.State.Terminated == nil {
+			continue
+		}
+		terminated := containerStatus.State.Terminated
+		if len(terminated.Message) == 0 {
+			continue
+		}
+		result, err := pkgcapture.ParseCaptureJobResult(terminated.Message)
+		if err != nil {
+			continue
+		}
+		status.PacketsCaptured = result.PacketsCaptured
+		status.BytesCaptured = &result.BytesCaptured
+		status.Uploads = result.Uploads
+	}
+	return status
+}
+
+// captureJobNodeName returns the name of the node the capture job runs on.
+func captureJobNodeName(job *batchv1.Job, pod *corev1.Pod) string {
+	for _, container := range job.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == captureConstants.NodeHostNameEnvKey {
+				return env.Value
+			}
+		}
+	}
+	if pod != nil {
+		return pod.Spec.NodeName
+	}
+	return ""
+}
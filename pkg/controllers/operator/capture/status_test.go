@@ -0,0 +1,151 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+	captureConstants "github.com/microsoft/retina/pkg/capture/constants"
+)
+
+func captureTestJob(name, nodeName string, conditions ...batchv1.JobCondition) batchv1.Job {
+	return batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: captureConstants.CaptureContainername,
+						Env:  []corev1.EnvVar{{Name: captureConstants.NodeHostNameEnvKey, Value: nodeName}},
+					}},
+				},
+			},
+		},
+		Status: batchv1.JobStatus{Conditions: conditions},
+	}
+}
+
+func TestCaptureNodeStatuses(t *testing.T) {
+	packets, bytes := int64(10), int64(2048)
+	jobs := []batchv1.Job{
+		captureTestJob("job-c", "node-c", batchv1.JobCondition{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}),
+		captureTestJob("job-b", "node-b"),
+		captureTestJob("job-a", "node-a"),
+		captureTestJob("job-d", "node-d", batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}),
+	}
+	pods := map[types.UID]*corev1.Pod{
+		"job-b": {Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		"job-c": {Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: captureConstants.CaptureContainername,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Message: `{"packetsCaptured":10,"bytesCaptured":2048,"uploads":[{"location":"HostPath","succeeded":true}]}`,
+				}},
+			}},
+		}},
+	}
+
+	cases := []struct {
+		name string
+		stop bool
+		want []retinav1alpha1.CaptureNodeStatus
+	}{
+		{
+			name: "running",
+			want: []retinav1alpha1.CaptureNodeStatus{
+				{NodeName: "node-a", JobName: "job-a", Phase: retinav1alpha1.CaptureNodePending},
+				{NodeName: "node-b", JobName: "job-b", Phase: retinav1alpha1.CaptureNodeRunning},
+				{
+					NodeName:        "node-c",
+					JobName:         "job-c",
+					Phase:           retinav1alpha1.CaptureNodeSucceeded,
+					PacketsCaptured: &packets,
+					BytesCaptured:   &bytes,
+					Uploads:         []retinav1alpha1.CaptureUploadStatus{{Location: "HostPath", Succeeded: true}},
+				},
+				{NodeName: "node-d", JobName: "job-d", Phase: retinav1alpha1.CaptureNodeFailed, Message: "BackoffLimitExceeded"},
+			},
+		},
+		{
+			name: "stopping",
+			stop: true,
+			want: []retinav1alpha1.CaptureNodeStatus{
+				{NodeName: "node-a", JobName: "job-a", Phase: retinav1alpha1.CaptureNodePending},
+				{NodeName: "node-b", JobName: "job-b", Phase: retinav1alpha1.CaptureNodeStopping},
+				{
+					NodeName:        "node-c",
+					JobName:         "job-c",
+					Phase:           retinav1alpha1.CaptureNodeSucceeded,
+					PacketsCaptured: &packets,
+					BytesCaptured:   &bytes,
+					Uploads:         []retinav1alpha1.CaptureUploadStatus{{Location: "HostPath", Succeeded: true}},
+				},
+				{NodeName: "node-d", JobName: "job-d", Phase: retinav1alpha1.CaptureNodeFailed, Message: "BackoffLimitExceeded"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := captureNodeStatuses(jobs, pods, tc.stop)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("captureNodeStatuses() mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCaptureJobControl(t *testing.T) {
+	capture := &retinav1alpha1.Capture{
+		Spec: retinav1alpha1.CaptureSpec{
+			CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+				CaptureOption: retinav1alpha1.CaptureOption{Duration: &metav1.Duration{Duration: time.Minute}},
+			},
+		},
+	}
+	lateJob := captureTestJob("job-late", "node-b")
+	lateJob.Annotations = map[string]string{captureConstants.CaptureDurationOffsetAnnotation: "20s"}
+	expiredJob := captureTestJob("job-expired", "node-c")
+	expiredJob.Annotations = map[string]string{captureConstants.CaptureDurationOffsetAnnotation: "2m"}
+
+	cases := []struct {
+		name string
+		job  batchv1.Job
+		want pkgcapture.CaptureJobControl
+	}{
+		{
+			name: "job created when the Capture started",
+			job:  captureTestJob("job", "node-a"),
+			want: pkgcapture.CaptureJobControl{Duration: time.Minute},
+		},
+		{
+			name: "job created during the Capture",
+			job:  lateJob,
+			want: pkgcapture.CaptureJobControl{Duration: 40 * time.Second},
+		},
+		{
+			name: "job created after the shortened Capture ended",
+			job:  expiredJob,
+			want: pkgcapture.CaptureJobControl{Duration: time.Nanosecond},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := captureJobControl(capture, &tt.job); got != tt.want {
+				t.Errorf("captureJobControl() want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	pkgcapture "github.com/microsoft/retina/pkg/capture"
+	"github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/log"
+)
+
+// CaptureValidator validates Captures on admission, so bad Captures are rejected when they are created instead of
+// failing when they are reconciled.
+type CaptureValidator struct {
+	logger                 *log.ZapLogger
+	captureToPodTranslator *pkgcapture.CaptureToPodTranslator
+}
+
+var _ admission.CustomValidator = &CaptureValidator{}
+
+var (
+	errCaptureSpecImmutable       = errors.New("spec of Capture is immutable, only stop, ttl and duration can be changed")
+	errCaptureResumed             = errors.New("stopped Capture cannot be resumed")
+	errCaptureDurationNotPositive = errors.New("duration of Capture must be positive")
+)
+
+func NewCaptureValidator(kubeClient kubernetes.Interface, config config.CaptureConfig) *CaptureValidator {
+	logger := log.Logger().Named("CaptureWebhook")
+	return &CaptureValidator{
+		logger:                 logger,
+		captureToPodTranslator: pkgcapture.NewCaptureToPodTranslator(kubeClient, logger, config),
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-retina-sh-v1alpha1-capture,mutating=false,failurePolicy=fail,sideEffects=None,groups=retina.sh,resources=captures,verbs=create;update,versions=v1alpha1,name=vcapture.retina.sh,admissionReviewVersions=v1
+
+// SetupWebhookWithManager registers the webhook with the webhook server of the manager.
+func (cv *CaptureValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&retinav1alpha1.Capture{}).
+		WithValidator(cv).
+		Complete()
+}
+
+// ValidateCreate validates the Capture along with the targets it selects and the objects it references.
+func (cv *CaptureValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	capture, ok := obj.(*retinav1alpha1.Capture)
+	if !ok {
+		return nil, fmt.Errorf("expected a Capture but got %T", obj)
+	}
+	if err := cv.captureToPodTranslator.ValidateCapture(capture); err != nil {
+		cv.logger.Info("Capture is rejected", zap.String("Capture", capture.Namespace+"/"+capture.Name), zap.Error(err))
+		return nil, err
+	}
+	if err := cv.captureToPodTranslator.ValidateCaptureTargets(capture); err != nil {
+		cv.logger.Info("Capture is rejected", zap.String("Capture", capture.Namespace+"/"+capture.Name), zap.Error(err))
+		return nil, err
+	}
+	return nil, nil
+}
+
+// ValidateUpdate only allows stopping the Capture and changing its TTL, the rest of its spec is immutable as the capture
+// jobs are already created from it. The spec is not validated again, as the Capture policies and the targets may have
+// changed since the Capture started, which must not block stopping it.
+func (cv *CaptureValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCapture, ok := oldObj.(*retinav1alpha1.Capture)
+	if !ok {
+		return nil, fmt.Errorf("expected a Capture but got %T", oldObj)
+	}
+	capture, ok := newObj.(*retinav1alpha1.Capture)
+	if !ok {
+		return nil, fmt.Errorf("expected a Capture but got %T", newObj)
+	}
+	// Removing the finalizer of the deleted Capture, or changing its status and metadata, is always allowed.
+	if capture.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldCapture.Spec, capture.Spec) {
+		return nil, nil
+	}
+	if err := validateCaptureSpecUpdate(&oldCapture.Spec, &capture.Spec); err != nil {
+		cv.logger.Info("Capture update is rejected", zap.String("Capture", capture.Namespace+"/"+capture.Name), zap.Error(err))
+		return nil, err
+	}
+	return nil, nil
+}
+
+// validateCaptureSpecUpdate rejects changing the spec of a Capture other than stopping it, changing its TTL, and
+// extending or shortening its duration, which the controller gives to the running capture jobs.
+func validateCaptureSpecUpdate(oldSpec, spec *retinav1alpha1.CaptureSpec) error {
+	if oldSpec.Stop && !spec.Stop {
+		return errCaptureResumed
+	}
+	oldDuration, duration := oldSpec.CaptureConfiguration.CaptureOption.Duration, spec.CaptureConfiguration.CaptureOption.Duration
+	if !equality.Semantic.DeepEqual(oldDuration, duration) && (duration == nil || duration.Duration <= 0) {
+		return errCaptureDurationNotPositive
+	}
+	mutable := func(spec retinav1alpha1.CaptureSpec) retinav1alpha1.CaptureSpec {
+		spec.Stop = false
+		spec.TTL = nil
+		spec.CaptureConfiguration.CaptureOption.Duration = nil
+		return spec
+	}
+	if !equality.Semantic.DeepEqual(mutable(*oldSpec), mutable(*spec)) {
+		return errCaptureSpecImmutable
+	}
+	return nil
+}
+
+// ValidateDelete allows deleting any Capture.
+func (cv *CaptureValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
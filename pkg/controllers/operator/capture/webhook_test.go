@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+package capture
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
+	"github.com/microsoft/retina/pkg/log"
+)
+
+func TestValidateUpdate(t *testing.T) {
+	_, _ = log.SetupZapLogger(log.GetDefaultLogOpts())
+	newCapture := func(update func(*retinav1alpha1.Capture)) *retinav1alpha1.Capture {
+		capture := &retinav1alpha1.Capture{
+			ObjectMeta: metav1.ObjectMeta{Name: "capture", Namespace: "default"},
+			Spec: retinav1alpha1.CaptureSpec{
+				CaptureConfiguration: retinav1alpha1.CaptureConfiguration{
+					CaptureTarget: retinav1alpha1.CaptureTarget{
+						PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+					},
+					TcpdumpFilter: ptr.To("tcp"),
+				},
+			},
+		}
+		if update != nil {
+			update(capture)
+		}
+		return capture
+	}
+
+	cases := []struct {
+		name    string
+		old     *retinav1alpha1.Capture
+		new     *retinav1alpha1.Capture
+		wantErr error
+	}{
+		{
+			name: "metadata changed",
+			old:  newCapture(nil),
+			new:  newCapture(func(c *retinav1alpha1.Capture) { c.Finalizers = []string{captureFinalizer} }),
+		},
+		{
+			name: "stopped",
+			old:  newCapture(nil),
+			new:  newCapture(func(c *retinav1alpha1.Capture) { c.Spec.Stop = true }),
+		},
+		{
+			name: "ttl changed",
+			old:  newCapture(nil),
+			new:  newCapture(func(c *retinav1alpha1.Capture) { c.Spec.TTL = &metav1.Duration{Duration: time.Hour} }),
+		},
+		{
+			name: "duration extended",
+			old:  newCapture(func(c *retinav1alpha1.Capture) { c.Spec.CaptureConfiguration.CaptureOption.Duration = &metav1.Duration{Duration: time.Minute} }),
+			new:  newCapture(func(c *retinav1alpha1.Capture) { c.Spec.CaptureConfiguration.CaptureOption.Duration = &metav1.Duration{Duration: time.Hour} }),
+		},
+		{
+			name:    "duration removed",
+			old:     newCapture(func(c *retinav1alpha1.Capture) { c.Spec.CaptureConfiguration.CaptureOption.Duration = &metav1.Duration{Duration: time.Minute} }),
+			new:     newCapture(nil),
+			wantErr: errCaptureDurationNotPositive,
+		},
+		{
+			name:    "duration not positive",
+			old:     newCapture(func(c *retinav1alpha1.Capture) { c.Spec.CaptureConfiguration.CaptureOption.Duration = &metav1.Duration{Duration: time.Minute} }),
+			new:     newCapture(func(c *retinav1alpha1.Capture) { c.Spec.CaptureConfiguration.CaptureOption.Duration = &metav1.Duration{} }),
+			wantErr: errCaptureDurationNotPositive,
+		},
+		{
+			name:    "resumed",
+			old:     newCapture(func(c *retinav1alpha1.Capture) { c.Spec.Stop = true }),
+			new:     newCapture(nil),
+			wantErr: errCaptureResumed,
+		},
+		{
+			name:    "filter changed",
+			old:     newCapture(nil),
+			new:     newCapture(func(c *retinav1alpha1.Capture) { c.Spec.CaptureConfiguration.TcpdumpFilter = ptr.To("udp") }),
+			wantErr: errCaptureSpecImmutable,
+		},
+		{
+			name: "target changed while stopped",
+			old:  newCapture(nil),
+			new: newCapture(func(c *retinav1alpha1.Capture) {
+				c.Spec.Stop = true
+				c.Spec.CaptureConfiguration.CaptureTarget.NamespaceSelector = &metav1.LabelSelector{}
+			}),
+			wantErr: errCaptureSpecImmutable,
+		},
+		{
+			name: "spec changed while deleted",
+			old:  newCapture(nil),
+			new: newCapture(func(c *retinav1alpha1.Capture) {
+				c.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				c.Spec.CaptureConfiguration.TcpdumpFilter = nil
+			}),
+		},
+	}
+
+	// The spec is not validated again on update, so the validator needs no translator.
+	cv := &CaptureValidator{logger: log.Logger().Named("test")}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cv.ValidateUpdate(context.Background(), tc.old, tc.new)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("ValidateUpdate() want error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -16,13 +16,14 @@ package metricsconfigurationcontroller
 import (
 	"context"
 	"fmt"
-	"sync"
 
 	"go.uber.org/zap"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	retinav1alpha1 "github.com/microsoft/retina/crd/api/v1alpha1"
 	validate "github.com/microsoft/retina/crd/api/v1alpha1/validations"
@@ -31,11 +32,9 @@ import (
 
 // MetricsConfigurationReconciler reconciles a MetricsConfiguration object
 type MetricsConfigurationReconciler struct {
-	*sync.Mutex
 	client.Client
-	Scheme  *runtime.Scheme
-	mcCache map[string]*retinav1alpha1.MetricsConfiguration
-	l       *log.ZapLogger
+	Scheme *runtime.Scheme
+	l      *log.ZapLogger
 }
 
 func init() {
@@ -46,11 +45,9 @@ func init() {
 
 func New(client client.Client, scheme *runtime.Scheme) *MetricsConfigurationReconciler {
 	return &MetricsConfigurationReconciler{
-		Mutex:   &sync.Mutex{},
-		l:       log.Logger().Named(string("metricsconfiguration-controller")),
-		Client:  client,
-		Scheme:  scheme,
-		mcCache: make(map[string]*retinav1alpha1.MetricsConfiguration),
+		l:      log.Logger().Named(string("metricsconfiguration-controller")),
+		Client: client,
+		Scheme: scheme,
 	}
 }
 
@@ -58,58 +55,62 @@ func New(client client.Client, scheme *runtime.Scheme) *MetricsConfigurationReco
 //+kubebuilder:rbac:groups=operator.retina.sh,resources=metricsconfigurations/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=operator.retina.sh,resources=metricsconfigurations/finalizers,verbs=update
 
+// Reconcile validates all the MetricsConfigurations of the cluster, as creating, updating or deleting one of them
+// may resolve or cause conflicts with the others, and reports the conflicts in their status.
 func (r *MetricsConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	mcc := &retinav1alpha1.MetricsConfiguration{}
-	if err := r.Client.Get(ctx, req.NamespacedName, mcc); err != nil {
-		if apierrors.IsNotFound(err) {
-			// Object not found, it has probably been deleted
-			r.l.Info("deleted", zap.String("name", req.NamespacedName.String()))
-			r.Lock()
-			_, ok := r.mcCache[req.NamespacedName.String()]
-			if ok {
-				delete(r.mcCache, req.NamespacedName.String())
-				r.l.Info("deleted from cache", zap.String("name", req.NamespacedName.String()))
-			}
-			r.Unlock()
-			return ctrl.Result{}, nil
-		} else {
-			r.l.Info("error getting metricsconfiguration", zap.String("name", req.NamespacedName.String()))
-			return ctrl.Result{}, err
-		}
-	}
+	r.l.Info("reconciling", zap.String("name", req.NamespacedName.String()))
 
-	r.l.Info("reconciled", zap.String("name", req.NamespacedName.String()))
-	r.Lock()
-	defer r.Unlock()
-	if len(r.mcCache) == 0 || r.mcCache[req.NamespacedName.String()] != nil {
-		err := validate.MetricsCRD(mcc)
-		if err != nil {
-			r.l.Error("Error validating metrics configuration", zap.Error(err))
+	mccList := &retinav1alpha1.MetricsConfigurationList{}
+	if err := r.Client.List(ctx, mccList); err != nil {
+		r.l.Error("error listing metricsconfigurations", zap.Error(err))
+		return ctrl.Result{}, err
+	}
 
-			mcc.Status = retinav1alpha1.MetricsStatus{
+	statuses := make(map[string]retinav1alpha1.MetricsStatus, len(mccList.Items))
+	validMccs := make([]*retinav1alpha1.MetricsConfiguration, 0, len(mccList.Items))
+	for i := range mccList.Items {
+		mcc := &mccList.Items[i]
+		if err := validate.MetricsCRD(mcc); err != nil {
+			r.l.Error("Error validating metrics configuration", zap.String("crd Name", mcc.Name), zap.Error(err))
+			statuses[mcc.Name] = retinav1alpha1.MetricsStatus{
 				State:  retinav1alpha1.StateErrored,
 				Reason: fmt.Sprintf("Validation of CRD failed with: %s", err.Error()),
 			}
-		} else {
-			r.l.Info("metrics configuration is valid", zap.String("crd Name", mcc.Name))
-			mcc.Status = retinav1alpha1.MetricsStatus{
-				State:  retinav1alpha1.StateAccepted,
-				Reason: "CRD is Accepted",
-			}
-			r.mcCache[req.NamespacedName.String()] = mcc
+			continue
 		}
-	} else {
-		r.l.Info("Metrics Conguration is already configured on this cluster, cannot reconcile this new config.")
+		validMccs = append(validMccs, mcc)
+	}
 
-		mcc.Status = retinav1alpha1.MetricsStatus{
-			State:  retinav1alpha1.StateErrored,
-			Reason: "Metrics Configuration is already configured on this cluster, cannot reconcile this new config.",
+	conflicts := validate.MetricsConflicts(validMccs)
+	for _, mcc := range validMccs {
+		if len(conflicts[mcc.Name]) > 0 {
+			r.l.Warn("metrics configuration is in conflict with other metrics configurations", zap.String("crd Name", mcc.Name))
+			statuses[mcc.Name] = retinav1alpha1.MetricsStatus{
+				State:     retinav1alpha1.StateWarning,
+				Reason:    "CRD is Accepted, but some of its metrics are collected as configured by other CRDs",
+				Conflicts: conflicts[mcc.Name],
+			}
+			continue
+		}
+		r.l.Info("metrics configuration is valid", zap.String("crd Name", mcc.Name))
+		statuses[mcc.Name] = retinav1alpha1.MetricsStatus{
+			State:  retinav1alpha1.StateAccepted,
+			Reason: "CRD is Accepted",
 		}
 	}
 
-	if err := r.Client.Status().Update(ctx, mcc); err != nil {
-		r.l.Error("Error updating metrics configuration", zap.Error(err))
-		return ctrl.Result{}, err
+	for i := range mccList.Items {
+		mcc := &mccList.Items[i]
+		status := statuses[mcc.Name]
+		status.LastKnownSpec = mcc.Status.LastKnownSpec
+		if equality.Semantic.DeepEqual(mcc.Status, status) {
+			continue
+		}
+		mcc.Status = status
+		if err := r.Client.Status().Update(ctx, mcc); err != nil {
+			r.l.Error("Error updating metrics configuration", zap.String("crd Name", mcc.Name), zap.Error(err))
+			return ctrl.Result{}, err
+		}
 	}
 
 	return ctrl.Result{}, nil
@@ -118,6 +119,6 @@ func (r *MetricsConfigurationReconciler) Reconcile(ctx context.Context, req ctrl
 // SetupWithManager sets up the controller with the Manager.
 func (r *MetricsConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&retinav1alpha1.MetricsConfiguration{}).
+		For(&retinav1alpha1.MetricsConfiguration{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
@@ -16,6 +16,7 @@ package metricsconfigurationcontroller
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -125,3 +126,69 @@ func TestMetricsConfigurationReconciler_Reconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsConfigurationReconciler_ReconcileMultiple(t *testing.T) {
+	now := time.Now()
+	platform := &retinav1alpha1.MetricsConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "platform",
+			CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+		},
+		Spec: retinav1alpha1.MetricsSpec{
+			ContextOptions: []retinav1alpha1.MetricsContextOptions{
+				{MetricName: "drop_count", SourceLabels: []string{"ip"}},
+			},
+			Namespaces: retinav1alpha1.MetricsNamespaces{Include: []string{"kube-system"}},
+		},
+	}
+	app := &retinav1alpha1.MetricsConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "app",
+			CreationTimestamp: metav1.NewTime(now),
+		},
+		Spec: retinav1alpha1.MetricsSpec{
+			ContextOptions: []retinav1alpha1.MetricsContextOptions{
+				{MetricName: "drop_count", SourceLabels: []string{"podname"}},
+				{MetricName: "forward_count", SourceLabels: []string{"podname"}},
+			},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "app"}},
+		},
+	}
+	invalid := &retinav1alpha1.MetricsConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "invalid",
+			CreationTimestamp: metav1.NewTime(now),
+		},
+	}
+	client := fake.NewClientBuilder().
+		WithScheme(fakescheme).
+		WithObjects(platform, app, invalid).
+		WithStatusSubresource(platform, app, invalid).
+		Build()
+
+	r := New(client, fakescheme)
+	_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "app"}})
+	require.NoError(t, err)
+
+	got := &retinav1alpha1.MetricsConfiguration{}
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "platform"}, got))
+	require.Equal(t, retinav1alpha1.StateAccepted, got.Status.State)
+	require.Empty(t, got.Status.Conflicts)
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "app"}, got))
+	require.Equal(t, retinav1alpha1.StateWarning, got.Status.State)
+	require.Len(t, got.Status.Conflicts, 1)
+	require.Equal(t, "drop_count", got.Status.Conflicts[0].MetricName)
+	require.Equal(t, "platform", got.Status.Conflicts[0].ConfigurationName)
+
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "invalid"}, got))
+	require.Equal(t, retinav1alpha1.StateErrored, got.Status.State)
+
+	// Deleting the older metrics configuration resolves the conflict of the newer one.
+	require.NoError(t, client.Delete(context.TODO(), platform))
+	_, err = r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "platform"}})
+	require.NoError(t, err)
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "app"}, got))
+	require.Equal(t, retinav1alpha1.StateAccepted, got.Status.State)
+	require.Empty(t, got.Status.Conflicts)
+}
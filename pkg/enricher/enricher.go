@@ -5,8 +5,10 @@ package enricher
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 
 	"github.com/cilium/cilium/api/v1/flow"
 	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
@@ -38,6 +40,11 @@ type Enricher struct {
 	Reader *container.RingReader
 
 	outputRing *container.Ring
+
+	// written and read count the events written to and read from the input ring, their difference is how far the
+	// enricher is behind the plugins.
+	written atomic.Uint64
+	read    atomic.Uint64
 }
 
 func New(ctx context.Context, cache cache.CacheInterface) *Enricher {
@@ -82,6 +89,7 @@ func (e *Enricher) Run() {
 					e.l.Debug("received nil from input channel for enricher")
 					continue
 				}
+				e.read.Add(1)
 				// todo
 				switch ev.Event.(type) {
 				case *flow.Flow:
@@ -184,6 +192,25 @@ func (e *Enricher) getWorkloads(ownerRefs []*common.OwnerReference) []*flow.Work
 
 func (e *Enricher) Write(ev *v1.Event) {
 	e.inputRing.Write(ev)
+	e.written.Add(1)
+}
+
+// Lag returns the number of events written to the enricher and not enriched yet.
+func (e *Enricher) Lag() uint64 {
+	read, written := e.read.Load(), e.written.Load()
+	if read >= written {
+		return 0
+	}
+	return written - read
+}
+
+// ReadyzCheck fails when the enricher is so far behind that the events written to it overwrite the ones it did
+// not enrich yet.
+func (e *Enricher) ReadyzCheck() error {
+	if lag, capacity := e.Lag(), e.inputRing.Cap(); lag >= capacity {
+		return fmt.Errorf("enricher is %d events behind, events are dropped past %d", lag, capacity)
+	}
+	return nil
 }
 
 func (e *Enricher) ExportReader() *container.RingReader {
@@ -76,6 +76,17 @@ func CreatePrometheusCounterVecForControlPlaneMetric(r prometheus.Registerer, na
 	)
 }
 
+func CreatePrometheusGaugeVecForControlPlaneMetric(r prometheus.Registerer, name, desc string, labels ...string) *prometheus.GaugeVec {
+	return promauto.With(r).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: retinaControlPlaneNamespace,
+			Name:      name,
+			Help:      desc,
+		},
+		labels,
+	)
+}
+
 func CreatePrometheusHistogramWithLinearBucketsForMetric(r prometheus.Registerer, name, desc string, start, width float64, count int) prometheus.Histogram {
 	opts := prometheus.HistogramOpts{
 		Namespace: RetinaNamespace,
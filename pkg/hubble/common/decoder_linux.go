@@ -20,12 +20,16 @@ type EpDecoder interface {
 type epDecoder struct {
 	localHostIP string
 	ipcache     *ipc.IPCache
+	workloads   WorkloadResolver
 }
 
-func NewEpDecoder(c *ipc.IPCache) *epDecoder { //nolint:revive // This is a factory function.
+// NewEpDecoder returns an EpDecoder backed by the ipcache.
+// w is optional; when set, endpoints of pods are also decorated with their workloads.
+func NewEpDecoder(c *ipc.IPCache, w WorkloadResolver) *epDecoder { //nolint:revive // This is a factory function.
 	return &epDecoder{
 		localHostIP: os.Getenv("NODE_IP"),
 		ipcache:     c,
+		workloads:   w,
 	}
 }
 
@@ -34,6 +38,9 @@ func (e *epDecoder) Decode(ip netip.Addr) *flow.Endpoint {
 	if metadata := e.ipcache.GetK8sMetadata(ip); metadata != nil {
 		ep.PodName = metadata.PodName
 		ep.Namespace = metadata.Namespace
+		if e.workloads != nil {
+			ep.Workloads = e.workloads.Workloads(metadata.Namespace, metadata.PodName)
+		}
 	}
 	id, ok := e.ipcache.LookupByIP(ip.String())
 	if !ok {
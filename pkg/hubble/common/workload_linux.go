@@ -0,0 +1,152 @@
+package common
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	slim_corev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/api/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	k8sUtils "github.com/cilium/cilium/pkg/k8s/utils"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// WorkloadResolver resolves the workloads (Deployment, DaemonSet, CronJob, ...) owning a pod.
+type WorkloadResolver interface {
+	Workloads(namespace, podName string) []*flow.Workload
+}
+
+// podWorkloadResolver keeps the workloads of the pods running on the node, from an informer watching only them.
+// It is called for every decoded flow, so it only reads memory and never waits for the API server.
+// Pods of other nodes, and pods not synced yet, have no workloads.
+type podWorkloadResolver struct {
+	sync.RWMutex
+	l         *logrus.Entry
+	workloads map[types.NamespacedName][]*flow.Workload
+}
+
+// NewWorkloadResolver returns a WorkloadResolver for the pods of the given node, whose informer runs until the
+// context is done.
+func NewWorkloadResolver(ctx context.Context, l *logrus.Entry, cs kubernetes.Interface, nodeName string) WorkloadResolver {
+	r := newPodWorkloadResolver(l)
+
+	factory := informers.NewSharedInformerFactoryWithOptions(cs, 0, informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+		o.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+	}))
+	informer := factory.Core().V1().Pods().Informer()
+	// Only the metadata is needed to resolve the workloads.
+	if err := informer.SetTransform(stripPod); err != nil {
+		r.l.WithError(err).Warn("Failed to set the transform of the pod informer")
+	}
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    r.onPod,
+		UpdateFunc: func(_, obj interface{}) { r.onPod(obj) },
+		DeleteFunc: r.onPodDelete,
+	}); err != nil {
+		r.l.WithError(err).Error("Failed to watch the pods of the node, workloads are not resolved")
+		return r
+	}
+	factory.Start(ctx.Done())
+	return r
+}
+
+func newPodWorkloadResolver(l *logrus.Entry) *podWorkloadResolver {
+	return &podWorkloadResolver{
+		l:         l.WithField("subsys", "workload-resolver"),
+		workloads: make(map[types.NamespacedName][]*flow.Workload),
+	}
+}
+
+func (r *podWorkloadResolver) Workloads(namespace, podName string) []*flow.Workload {
+	if namespace == "" || podName == "" {
+		return nil
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+	return r.workloads[types.NamespacedName{Namespace: namespace, Name: podName}]
+}
+
+func (r *podWorkloadResolver) onPod(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	workloads := workloadsFromPod(pod)
+
+	r.Lock()
+	defer r.Unlock()
+	if workloads == nil {
+		delete(r.workloads, key)
+		return
+	}
+	r.workloads[key] = workloads
+}
+
+func (r *podWorkloadResolver) onPodDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	delete(r.workloads, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name})
+}
+
+// stripPod keeps the metadata used to resolve the workloads of a pod, so the informer holds as little as possible.
+func stripPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			GenerateName:    pod.GenerateName,
+			Labels:          pod.Labels,
+			OwnerReferences: pod.OwnerReferences,
+			ResourceVersion: pod.ResourceVersion,
+		},
+	}, nil
+}
+
+// workloadsFromPod derives the owning workload from the pod's controller reference,
+// using the same heuristics as Cilium so metric labels match between the two.
+func workloadsFromPod(pod *corev1.Pod) []*flow.Workload {
+	slimPod := &slim_corev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:         pod.Name,
+			Namespace:    pod.Namespace,
+			GenerateName: pod.GenerateName,
+			Labels:       pod.Labels,
+		},
+	}
+	for _, ref := range pod.OwnerReferences {
+		slimPod.OwnerReferences = append(slimPod.OwnerReferences, slim_metav1.OwnerReference{
+			APIVersion: ref.APIVersion,
+			Kind:       ref.Kind,
+			Name:       ref.Name,
+			UID:        ref.UID,
+			Controller: ref.Controller,
+		})
+	}
+
+	meta, typeMeta, ok := k8sUtils.GetWorkloadMetaFromPod(slimPod)
+	if !ok {
+		return nil
+	}
+	return []*flow.Workload{{Name: meta.Name, Kind: typeMeta.Kind}}
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+func TestWorkloadsFromPod(t *testing.T) {
+	controller := true
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want []*flow.Workload
+	}{
+		{
+			name: "deployment",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-5d8f7c9b4-abcde",
+				GenerateName:    "web-5d8f7c9b4-",
+				Labels:          map[string]string{"pod-template-hash": "5d8f7c9b4"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d8f7c9b4", Controller: &controller}},
+			}},
+			want: []*flow.Workload{{Name: "web", Kind: "Deployment"}},
+		},
+		{
+			name: "daemonset",
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:            "agent-xyz12",
+				GenerateName:    "agent-",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent", Controller: &controller}},
+			}},
+			want: []*flow.Workload{{Name: "agent", Kind: "DaemonSet"}},
+		},
+		{
+			name: "bare pod",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, workloadsFromPod(tt.pod))
+		})
+	}
+}
+
+func TestPodWorkloadResolver(t *testing.T) {
+	controller := true
+	r := newPodWorkloadResolver(logrus.NewEntry(logrus.New()))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "agent-xyz12",
+			Namespace:       "kube-system",
+			GenerateName:    "agent-",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent", Controller: &controller}},
+		},
+		Spec: corev1.PodSpec{NodeName: "node1"},
+	}
+
+	// Unknown pods are not looked up.
+	assert.Nil(t, r.Workloads("kube-system", "agent-xyz12"))
+
+	stripped, err := stripPod(pod)
+	assert.NoError(t, err)
+	assert.Empty(t, stripped.(*corev1.Pod).Spec.NodeName)
+	r.onPod(stripped)
+	assert.Equal(t, []*flow.Workload{{Name: "agent", Kind: "DaemonSet"}}, r.Workloads("kube-system", "agent-xyz12"))
+	assert.Nil(t, r.Workloads("default", "agent-xyz12"))
+
+	// A pod whose owner is removed has no workload anymore.
+	orphan := pod.DeepCopy()
+	orphan.OwnerReferences = nil
+	r.onPod(orphan)
+	assert.Nil(t, r.Workloads("kube-system", "agent-xyz12"))
+
+	r.onPod(pod)
+	r.onPodDelete(toolscache.DeletedFinalStateUnknown{Key: "kube-system/agent-xyz12", Obj: pod})
+	assert.Nil(t, r.Workloads("kube-system", "agent-xyz12"))
+}
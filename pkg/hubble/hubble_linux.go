@@ -3,6 +3,7 @@ package hubble
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/cilium/cilium/api/v1/flow"
 	"github.com/cilium/cilium/pkg/crypto/certloader"
@@ -17,11 +18,13 @@ import (
 	"github.com/cilium/cilium/pkg/hubble/server"
 	"github.com/cilium/cilium/pkg/hubble/server/serveroption"
 	"github.com/cilium/cilium/pkg/ipcache"
+	k8sClient "github.com/cilium/cilium/pkg/k8s/client"
 	"github.com/cilium/cilium/pkg/logging/logfields"
 	monitoragent "github.com/cilium/cilium/pkg/monitor/agent"
 	"github.com/cilium/cilium/pkg/option"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	rnode "github.com/microsoft/retina/pkg/controllers/daemon/nodereconciler"
+	"github.com/microsoft/retina/pkg/hubble/common"
 	"github.com/microsoft/retina/pkg/hubble/parser"
 	"github.com/pkg/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -30,9 +33,12 @@ import (
 	"go.uber.org/zap"
 )
 
+const nodeNameEnvKey = "NODE_NAME"
+
 type RetinaHubble struct {
 	log            *logrus.Entry
 	client         client.Client
+	clientset      k8sClient.Clientset
 	monitorAgent   monitoragent.Agent
 	ipc            *ipcache.IPCache
 	nodeReconciler *rnode.NodeReconciler
@@ -42,6 +48,7 @@ type hubbleParams struct {
 	cell.In
 
 	Client         client.Client
+	Clientset      k8sClient.Clientset
 	MonitorAgent   monitoragent.Agent
 	IPCache        *ipcache.IPCache
 	NodeReconciler *rnode.NodeReconciler
@@ -52,6 +59,7 @@ func newRetinaHubble(params hubbleParams) *RetinaHubble {
 	rh := &RetinaHubble{
 		log:            params.Log.WithField(logfields.LogSubsys, "retina-hubble"),
 		client:         params.Client,
+		clientset:      params.Clientset,
 		monitorAgent:   params.MonitorAgent,
 		ipc:            params.IPCache,
 		nodeReconciler: params.NodeReconciler,
@@ -63,9 +71,7 @@ func newRetinaHubble(params hubbleParams) *RetinaHubble {
 
 func (rh *RetinaHubble) defaultOptions() {
 	// Not final, will be updated later.
-	option.Config.HubblePreferIpv6 = false
 	option.Config.EnableHighScaleIPcache = false
-	option.Config.EnableHubbleOpenMetrics = false
 
 	rh.log.Info("Starting Hubble with configuration", zap.Any("config", option.Config))
 }
@@ -88,8 +94,9 @@ func (rh *RetinaHubble) start(ctx context.Context) error {
 
 	// ---------------------------------------------------------------------------------------------------------------------------------------------------- //
 	// Setup metrics.
+	metricsCfg := parseHubbleMetrics(rh.log, option.Config.HubbleMetrics, option.Config.EnableHubbleOpenMetrics)
 	grpcMetrics := grpc_prometheus.NewServerMetrics()
-	if err := metrics.EnableMetrics(rh.log, option.Config.HubbleMetricsServer, option.Config.HubbleMetrics, grpcMetrics, option.Config.EnableHubbleOpenMetrics); err != nil {
+	if err := metrics.EnableMetrics(rh.log, option.Config.HubbleMetricsServer, metricsCfg.metrics, grpcMetrics, option.Config.EnableHubbleOpenMetrics); err != nil {
 		rh.log.Error("Failed to enable metrics", zap.Error(err))
 		return fmt.Errorf("enabling metrics: %w", err)
 	}
@@ -114,8 +121,12 @@ func (rh *RetinaHubble) start(ctx context.Context) error {
 		}),
 	)
 
-	// TODO: Replace with our custom parser.
-	payloadParser := parser.New(rh.log, rh.ipc)
+	// Workloads are only resolved when a metric needs them, since it requires watching the pods of the node.
+	var workloads common.WorkloadResolver
+	if metricsCfg.needsWorkloads {
+		workloads = common.NewWorkloadResolver(ctx, rh.log, rh.clientset, os.Getenv(nodeNameEnvKey))
+	}
+	payloadParser := parser.New(rh.log, rh.ipc, workloads)
 
 	namespaceManager := observer.NewNamespaceManager()
 	go namespaceManager.Run(ctx)
@@ -153,6 +164,9 @@ func (rh *RetinaHubble) start(ctx context.Context) error {
 		tlsSrvOpt = serveroption.WithServerTLS(tlsCfg)
 	}
 	peerServiceOptions = append(peerServiceOptions, tlsPeerOpt...)
+	if option.Config.HubblePreferIpv6 {
+		peerServiceOptions = append(peerServiceOptions, serviceoption.WithAddressFamilyPreference(serviceoption.AddressPreferIPv6))
+	}
 
 	peerSvc := peer.NewService(rh.nodeReconciler, peerServiceOptions...)
 	localSrvOpts = append(localSrvOpts,
@@ -0,0 +1,92 @@
+package hubble
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cilium/cilium/pkg/hubble/metrics/api"
+	"github.com/sirupsen/logrus"
+)
+
+// supportedHubbleMetrics are the Hubble metrics handlers which Retina's flows carry enough information for.
+// Other handlers (e.g. policy, kafka) rely on Cilium datapath events and would never be populated.
+var supportedHubbleMetrics = map[string]struct{}{
+	"dns":               {},
+	"drop":              {},
+	"tcp":               {},
+	"flow":              {},
+	"port-distribution": {},
+	"httpV2":            {},
+}
+
+// hubbleMetricsConfig is the result of validating the configured Hubble metrics.
+type hubbleMetricsConfig struct {
+	// metrics is the list of metrics, in the same format as option.Config.HubbleMetrics,
+	// with unsupported or invalid handlers dropped.
+	metrics []string
+	// needsWorkloads is true if any metric labels flows with workload contexts,
+	// in which case the parser has to resolve the workloads of pods.
+	needsWorkloads bool
+}
+
+// parseHubbleMetrics validates the configured Hubble metrics against the handlers supported by Retina.
+// Unsupported handlers and handlers with invalid context options are logged and skipped,
+// so a single bad entry doesn't prevent the metrics server from starting.
+func parseHubbleMetrics(l logrus.FieldLogger, configured []string, openMetrics bool) hubbleMetricsConfig {
+	cfg := hubbleMetricsConfig{}
+	for _, metric := range configured {
+		metric = strings.TrimSpace(metric)
+		if metric == "" {
+			continue
+		}
+		parsed := api.ParseMetricList([]string{metric})
+		for name, opts := range parsed {
+			log := l.WithField("metric", name)
+			if _, ok := supportedHubbleMetrics[name]; !ok {
+				log.WithField("supported", supportedHubbleMetricNames()).Warn("Hubble metric is not supported by Retina, skipping")
+				continue
+			}
+			ctxOpts, err := api.ParseContextOptions(opts)
+			if err != nil {
+				log.WithError(err).Warn("Invalid context options for Hubble metric, skipping")
+				continue
+			}
+			if usesWorkloads(ctxOpts) {
+				cfg.needsWorkloads = true
+			}
+			if strings.EqualFold(opts["exemplars"], "true") && !openMetrics {
+				log.Warn("Exemplars are only exported in OpenMetrics format, set enable-hubble-open-metrics to use them")
+			}
+			cfg.metrics = append(cfg.metrics, metric)
+		}
+	}
+	return cfg
+}
+
+func usesWorkloads(o *api.ContextOptions) bool {
+	for _, list := range []api.ContextIdentifierList{
+		o.Source, o.SourceEgress, o.SourceIngress,
+		o.Destination, o.DestinationEgress, o.DestinationIngress,
+	} {
+		for _, c := range list {
+			if c == api.ContextWorkload || c == api.ContextWorkloadName {
+				return true
+			}
+		}
+	}
+	for _, label := range []string{"source_workload", "source_workload_kind", "destination_workload", "destination_workload_kind"} {
+		if o.Labels.HasLabel(label) {
+			return true
+		}
+	}
+	return false
+}
+
+func supportedHubbleMetricNames() []string {
+	names := make([]string, 0, len(supportedHubbleMetrics))
+	for name := range supportedHubbleMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
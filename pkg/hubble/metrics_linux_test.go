@@ -0,0 +1,58 @@
+package hubble
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHubbleMetrics(t *testing.T) {
+	l := logrus.NewEntry(logrus.New())
+
+	tests := []struct {
+		name               string
+		configured         []string
+		wantMetrics        []string
+		wantNeedsWorkloads bool
+	}{
+		{
+			name:        "supported metrics with pod contexts",
+			configured:  []string{"flow:sourceEgressContext=pod;destinationIngressContext=pod", "dns:query", "drop", "tcp", "port-distribution", "httpV2:exemplars=true"},
+			wantMetrics: []string{"flow:sourceEgressContext=pod;destinationIngressContext=pod", "dns:query", "drop", "tcp", "port-distribution", "httpV2:exemplars=true"},
+		},
+		{
+			name:        "unsupported metrics are skipped",
+			configured:  []string{"policy", "kafka", "drop"},
+			wantMetrics: []string{"drop"},
+		},
+		{
+			name:        "invalid context is skipped",
+			configured:  []string{"flow:sourceContext=invalid", "tcp"},
+			wantMetrics: []string{"tcp"},
+		},
+		{
+			name:               "workload context",
+			configured:         []string{"flow:sourceContext=workload-name;destinationContext=pod"},
+			wantMetrics:        []string{"flow:sourceContext=workload-name;destinationContext=pod"},
+			wantNeedsWorkloads: true,
+		},
+		{
+			name:               "workload labels context",
+			configured:         []string{"drop:labelsContext=source_namespace,destination_workload"},
+			wantMetrics:        []string{"drop:labelsContext=source_namespace,destination_workload"},
+			wantNeedsWorkloads: true,
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parseHubbleMetrics(l, tt.configured, false)
+			assert.Equal(t, tt.wantMetrics, cfg.metrics)
+			assert.Equal(t, tt.wantNeedsWorkloads, cfg.needsWorkloads)
+		})
+	}
+}
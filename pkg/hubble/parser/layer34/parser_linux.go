@@ -3,9 +3,12 @@ package layer34
 import (
 	"fmt"
 	"net/netip"
+	"strings"
+	"time"
 
 	"github.com/cilium/cilium/api/v1/flow"
 	"github.com/cilium/cilium/pkg/ipcache"
+	ttlcache "github.com/jellydator/ttlcache/v3"
 	"github.com/microsoft/retina/pkg/hubble/common"
 	"github.com/microsoft/retina/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -13,15 +16,34 @@ import (
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
+const (
+	// latencyTTL is how long a TCP packet leaving the node waits for the packet echoing its timestamp.
+	latencyTTL = 500 * time.Millisecond
+	// latencyLimit is the number of TCP packets waiting for their echo.
+	latencyLimit uint64 = 100000
+)
+
+// latencyKey identifies a TCP packet by its connection and its TSval, which the reply echoes as TSecr.
+type latencyKey struct {
+	srcIP string
+	dstIP string
+	srcP  uint32
+	dstP  uint32
+	id    uint64
+}
+
 type Parser struct {
 	l  *logrus.Entry
 	ep common.EpDecoder
+	// latency holds the time of the TCP packets leaving the node, until the packet echoing their timestamp arrives.
+	latency *ttlcache.Cache[latencyKey, time.Time]
 }
 
-func New(l *logrus.Entry, c *ipcache.IPCache) *Parser {
+func New(l *logrus.Entry, c *ipcache.IPCache, w common.WorkloadResolver) *Parser {
 	p := &Parser{
-		l:  l.WithField("subsys", "layer34"),
-		ep: common.NewEpDecoder(c),
+		l:       l.WithField("subsys", "layer34"),
+		ep:      common.NewEpDecoder(c, w),
+		latency: newLatencyCache(),
 	}
 	// Log the localHostIP for debugging purposes.
 	return p
@@ -51,11 +73,14 @@ func (p *Parser) Decode(f *flow.Flow) *flow.Flow {
 	f.Source = p.ep.Decode(sourceIP)
 	f.Destination = p.ep.Decode(destIP)
 
+	// Map Retina metadata to the corresponding Hubble fields.
+	meta := p.decodeRetinaMetadata(f)
+
 	// Add IsReply to flow.
 	p.decodeIsReply(f)
 
 	// Add L34 Summary to flow.
-	p.decodeSummary(f)
+	p.decodeSummary(f, meta)
 
 	// Add TrafficDirection to flow.
 	p.decodeTrafficDirection(f)
@@ -63,13 +88,94 @@ func (p *Parser) Decode(f *flow.Flow) *flow.Flow {
 	return f
 }
 
-func (p *Parser) decodeSummary(f *flow.Flow) {
+// decodeRetinaMetadata unmarshals the RetinaMetadata extension of the flow, and returns nil when the flow has none.
+// The extension is left as is, so consumers can still read the raw metadata.
+// The drop reason is only mapped to the flow's DropReasonDesc when the producer did not set it already. As the
+// zero drop reason is the default of the metadata, it cannot be told apart from a missing one and is not mapped.
+func (p *Parser) decodeRetinaMetadata(f *flow.Flow) *utils.RetinaMetadata {
+	if f.GetExtensions() == nil {
+		return nil
+	}
+	meta := &utils.RetinaMetadata{}
+	if err := f.GetExtensions().UnmarshalTo(meta); err != nil {
+		p.l.WithError(err).Debug("Failed to unmarshal Retina metadata")
+		return nil
+	}
+
+	if f.GetVerdict() == flow.Verdict_DROPPED {
+		if f.GetDropReasonDesc() == flow.DropReason_DROP_REASON_UNKNOWN && meta.GetDropReason() != utils.DropReason_IPTABLE_RULE_DROP {
+			f.DropReasonDesc = utils.GetDropReasonDesc(meta.GetDropReason())
+		}
+		f.DropReason = uint32(f.GetDropReasonDesc()) // nolint:staticcheck // Older Hubble clients still read DropReason.
+	}
+	return meta
+}
+
+func newLatencyCache() *ttlcache.Cache[latencyKey, time.Time] {
+	// Expired packets are never returned, and the capacity bounds those never echoed, so the cache is not started.
+	return ttlcache.New(
+		ttlcache.WithTTL[latencyKey, time.Time](latencyTTL),
+		ttlcache.WithCapacity[latencyKey, time.Time](latencyLimit),
+		ttlcache.WithDisableTouchOnHit[latencyKey, time.Time](),
+	)
+}
+
+// decodeLatency returns the round trip time of the TCP packet the flow replies to, from the TCP timestamps.
+// The packets leaving the node through the network are remembered with their TSval, and the first packet entering
+// the node with the same TSecr on the same connection is their reply. It returns 0 when the flow is not such a reply.
+func (p *Parser) decodeLatency(f *flow.Flow, meta *utils.RetinaMetadata) time.Duration {
+	tcp := f.GetL4().GetTCP()
+	if p.latency == nil || tcp == nil || meta.GetTcpId() == 0 || f.GetTime() == nil {
+		return 0
+	}
+
+	switch f.GetTraceObservationPoint() { // nolint:exhaustive // Only packets observed at the network carry the RTT.
+	case flow.TraceObservationPoint_TO_NETWORK:
+		k := latencyKey{
+			srcIP: f.GetIP().GetSource(),
+			dstIP: f.GetIP().GetDestination(),
+			srcP:  tcp.GetSourcePort(),
+			dstP:  tcp.GetDestinationPort(),
+			id:    meta.GetTcpId(),
+		}
+		// Retransmissions have the same TSval, keep the first one.
+		if p.latency.Get(k) == nil {
+			p.latency.Set(k, f.GetTime().AsTime(), ttlcache.DefaultTTL)
+		}
+	case flow.TraceObservationPoint_FROM_NETWORK:
+		k := latencyKey{
+			srcIP: f.GetIP().GetDestination(),
+			dstIP: f.GetIP().GetSource(),
+			srcP:  tcp.GetDestinationPort(),
+			dstP:  tcp.GetSourcePort(),
+			id:    meta.GetTcpId(),
+		}
+		if item := p.latency.Get(k); item != nil {
+			p.latency.Delete(k)
+			if rtt := f.GetTime().AsTime().Sub(item.Value()); rtt > 0 {
+				return rtt
+			}
+		}
+	}
+	return 0
+}
+
+func (p *Parser) decodeSummary(f *flow.Flow, meta *utils.RetinaMetadata) {
 	if f.GetVerdict() == flow.Verdict_DROPPED {
 		// Setting subtype to DROPPED for huuble cli.
 		if f.GetEventType() != nil {
 			f.GetEventType().SubType = int32(f.GetDropReasonDesc())
+			// Without metadata, the Retina drop reason is unknown.
+			reason := f.GetDropReasonDesc().String()
+			if meta != nil {
+				reason = meta.GetDropReason().String()
+			}
+			summary := "Drop Reason: " + reason
+			if meta.GetBytes() > 0 {
+				summary += fmt.Sprintf("; Bytes: %d", meta.GetBytes())
+			}
 			//nolint:lll // long line is long
-			f.Summary = fmt.Sprintf("Drop Reason: %s\nNote: This reason is most accurate. Prefer over others while using Hubble CLI.", utils.DropReasonDescription(f)) // nolint:staticcheck // We need summary for now.
+			f.Summary = summary + "\nNote: This reason is most accurate. Prefer over others while using Hubble CLI." // nolint:staticcheck // We need summary for now.
 		}
 		return
 
@@ -82,12 +188,50 @@ func (p *Parser) decodeSummary(f *flow.Flow) {
 		case *flow.Layer4_TCP:
 			tcpFlags := f.GetL4().GetTCP().GetFlags()
 			if tcpFlags != nil {
-				f.Summary = "TCP Flags: " + tcpFlags.String() // nolint:staticcheck // We need summary for now.
+				summary := "TCP Flags: " + tcpFlagsSummary(tcpFlags)
+				if meta.GetBytes() > 0 {
+					summary += fmt.Sprintf("; Bytes: %d", meta.GetBytes())
+				}
+				if meta.GetTcpId() > 0 {
+					summary += fmt.Sprintf("; TCP Timestamp: %d", meta.GetTcpId())
+				}
+				if rtt := p.decodeLatency(f, meta); rtt > 0 {
+					summary += "; Latency: " + rtt.String()
+				}
+				f.Summary = summary // nolint:staticcheck // We need summary for now.
 			}
 		case *flow.Layer4_UDP:
-			f.Summary = "UDP" // nolint:staticcheck // We need summary for now.
+			summary := "UDP"
+			if meta.GetBytes() > 0 {
+				summary += fmt.Sprintf("; Bytes: %d", meta.GetBytes())
+			}
+			f.Summary = summary // nolint:staticcheck // We need summary for now.
+		}
+	}
+}
+
+// tcpFlagsSummary returns the set TCP flags in the same format as Hubble, e.g. "SYN, ACK".
+func tcpFlagsSummary(flags *flow.TCPFlags) string {
+	set := []string{}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"SYN", flags.GetSYN()},
+		{"FIN", flags.GetFIN()},
+		{"RST", flags.GetRST()},
+		{"PSH", flags.GetPSH()},
+		{"ACK", flags.GetACK()},
+		{"URG", flags.GetURG()},
+		{"ECE", flags.GetECE()},
+		{"CWR", flags.GetCWR()},
+		{"NS", flags.GetNS()},
+	} {
+		if flag.set {
+			set = append(set, flag.name)
 		}
 	}
+	return strings.Join(set, ", ")
 }
 
 // decodeIsReply sets the flow's IsReply field.
@@ -0,0 +1,168 @@
+package layer34
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestDecodeRetinaMetadata(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	p := &Parser{l: logrus.NewEntry(logrus.New())}
+
+	tests := []struct {
+		name            string
+		flow            func() *flow.Flow
+		wantDropReason  flow.DropReason
+		wantSummary     string
+		wantDropReasonN uint32
+	}{
+		{
+			name: "dropped flow",
+			flow: func() *flow.Flow {
+				// As built by the dropreason plugin.
+				f := utils.ToFlow(0, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 80, 443, 6, 2, flow.Verdict_DROPPED)
+				meta := &utils.RetinaMetadata{}
+				utils.AddDropReason(f, meta, uint32(utils.DropReason_IPTABLE_NAT_DROP))
+				utils.AddPacketSize(meta, 100)
+				utils.AddRetinaMetadata(f, meta)
+				return f
+			},
+			wantDropReason:  flow.DropReason_SNAT_NO_MAP_FOUND,
+			wantDropReasonN: uint32(flow.DropReason_SNAT_NO_MAP_FOUND),
+			wantSummary:     "Drop Reason: IPTABLE_NAT_DROP; Bytes: 100\nNote: This reason is most accurate. Prefer over others while using Hubble CLI.",
+		},
+		{
+			name: "dropped flow with iptables rule drop",
+			flow: func() *flow.Flow {
+				f := utils.ToFlow(0, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 80, 443, 6, 2, flow.Verdict_DROPPED)
+				meta := &utils.RetinaMetadata{}
+				utils.AddDropReason(f, meta, uint32(utils.DropReason_IPTABLE_RULE_DROP))
+				utils.AddRetinaMetadata(f, meta)
+				return f
+			},
+			wantDropReason:  flow.DropReason_POLICY_DENIED,
+			wantDropReasonN: uint32(flow.DropReason_POLICY_DENIED),
+			wantSummary:     "Drop Reason: IPTABLE_RULE_DROP\nNote: This reason is most accurate. Prefer over others while using Hubble CLI.",
+		},
+		{
+			name: "dropped flow without drop reason in the flow",
+			flow: func() *flow.Flow {
+				f := utils.ToFlow(0, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 80, 443, 6, 2, flow.Verdict_DROPPED)
+				f.EventType = &flow.CiliumEventType{}
+				utils.AddRetinaMetadata(f, &utils.RetinaMetadata{DropReason: utils.DropReason_CONNTRACK_ADD_DROP})
+				return f
+			},
+			wantDropReason:  flow.DropReason_UNKNOWN_CONNECTION_TRACKING_STATE,
+			wantDropReasonN: uint32(flow.DropReason_UNKNOWN_CONNECTION_TRACKING_STATE),
+			wantSummary:     "Drop Reason: CONNTRACK_ADD_DROP\nNote: This reason is most accurate. Prefer over others while using Hubble CLI.",
+		},
+		{
+			name: "dropped flow with default metadata",
+			flow: func() *flow.Flow {
+				// The zero drop reason cannot be told apart from a missing one.
+				f := utils.ToFlow(0, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 80, 443, 6, 2, flow.Verdict_DROPPED)
+				f.EventType = &flow.CiliumEventType{}
+				return f
+			},
+			wantDropReason: flow.DropReason_DROP_REASON_UNKNOWN,
+			wantSummary:    "Drop Reason: IPTABLE_RULE_DROP\nNote: This reason is most accurate. Prefer over others while using Hubble CLI.",
+		},
+		{
+			name: "dropped flow with other extensions",
+			flow: func() *flow.Flow {
+				f := utils.ToFlow(0, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 80, 443, 6, 2, flow.Verdict_DROPPED)
+				f.EventType = &flow.CiliumEventType{}
+				f.Extensions, _ = anypb.New(wrapperspb.String("not retina metadata"))
+				return f
+			},
+			wantDropReason: flow.DropReason_DROP_REASON_UNKNOWN,
+			wantSummary:    "Drop Reason: DROP_REASON_UNKNOWN\nNote: This reason is most accurate. Prefer over others while using Hubble CLI.",
+		},
+		{
+			name: "tcp flow",
+			flow: func() *flow.Flow {
+				f := utils.ToFlow(0, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 80, 443, 6, 3, flow.Verdict_FORWARDED)
+				utils.AddTCPFlags(f, 1, 1, 0, 0, 0, 0)
+				meta := &utils.RetinaMetadata{}
+				utils.AddPacketSize(meta, 60)
+				utils.AddTCPID(meta, 1234)
+				utils.AddRetinaMetadata(f, meta)
+				return f
+			},
+			wantDropReason: flow.DropReason_DROP_REASON_UNKNOWN,
+			wantSummary:    "TCP Flags: SYN, ACK; Bytes: 60; TCP Timestamp: 1234",
+		},
+		{
+			name: "udp flow",
+			flow: func() *flow.Flow {
+				f := utils.ToFlow(0, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 53, 53, 17, 3, flow.Verdict_FORWARDED)
+				meta := &utils.RetinaMetadata{}
+				utils.AddPacketSize(meta, 80)
+				utils.AddRetinaMetadata(f, meta)
+				return f
+			},
+			wantDropReason: flow.DropReason_DROP_REASON_UNKNOWN,
+			wantSummary:    "UDP; Bytes: 80",
+		},
+		{
+			name: "flow without extensions",
+			flow: func() *flow.Flow {
+				f := utils.ToFlow(0, net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), 53, 53, 17, 3, flow.Verdict_FORWARDED)
+				f.Extensions = nil
+				return f
+			},
+			wantDropReason: flow.DropReason_DROP_REASON_UNKNOWN,
+			wantSummary:    "UDP",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.flow()
+			meta := p.decodeRetinaMetadata(f)
+			p.decodeSummary(f, meta)
+
+			assert.Equal(t, tt.wantDropReason, f.GetDropReasonDesc())
+			assert.Equal(t, tt.wantDropReasonN, f.GetDropReason()) //nolint:staticcheck // Testing the deprecated field is set.
+			assert.Equal(t, tt.wantSummary, f.GetSummary())        //nolint:staticcheck // We need summary for now.
+		})
+	}
+}
+
+func TestDecodeLatency(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	p := &Parser{l: logrus.NewEntry(logrus.New()), latency: newLatencyCache()}
+
+	// As built by the packetparser plugin, with the TSval leaving the node and the TSecr entering it.
+	tcpFlow := func(ts time.Time, src, dst string, srcP, dstP uint32, observationPoint uint32, id uint64) *flow.Flow {
+		f := utils.ToFlow(ts.UnixNano(), net.ParseIP(src), net.ParseIP(dst), srcP, dstP, 6, observationPoint, flow.Verdict_FORWARDED)
+		utils.AddTCPFlags(f, 0, 1, 0, 0, 0, 0)
+		meta := &utils.RetinaMetadata{}
+		utils.AddTCPID(meta, id)
+		utils.AddRetinaMetadata(f, meta)
+		return f
+	}
+	decode := func(f *flow.Flow) string {
+		p.decodeSummary(f, p.decodeRetinaMetadata(f))
+		return f.GetSummary() //nolint:staticcheck // We need summary for now.
+	}
+
+	now := time.Now()
+	assert.Equal(t, "TCP Flags: ACK; TCP Timestamp: 100", decode(tcpFlow(now, "10.0.0.1", "10.0.0.2", 40000, 443, 3, 100)))
+	// A retransmission does not restart the measure.
+	assert.Equal(t, "TCP Flags: ACK; TCP Timestamp: 100", decode(tcpFlow(now.Add(time.Millisecond), "10.0.0.1", "10.0.0.2", 40000, 443, 3, 100)))
+	// The reply of another connection does not match.
+	assert.Equal(t, "TCP Flags: ACK; TCP Timestamp: 100", decode(tcpFlow(now.Add(2*time.Millisecond), "10.0.0.2", "10.0.0.1", 443, 40001, 2, 100)))
+	assert.Equal(t, "TCP Flags: ACK; TCP Timestamp: 100; Latency: 3ms", decode(tcpFlow(now.Add(3*time.Millisecond), "10.0.0.2", "10.0.0.1", 443, 40000, 2, 100)))
+	// Only the first reply is measured.
+	assert.Equal(t, "TCP Flags: ACK; TCP Timestamp: 100", decode(tcpFlow(now.Add(4*time.Millisecond), "10.0.0.2", "10.0.0.1", 443, 40000, 2, 100)))
+}
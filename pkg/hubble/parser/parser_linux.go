@@ -7,6 +7,7 @@ import (
 	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
 	observer "github.com/cilium/cilium/pkg/hubble/observer/types"
 	ipc "github.com/cilium/cilium/pkg/ipcache"
+	"github.com/microsoft/retina/pkg/hubble/common"
 	"github.com/microsoft/retina/pkg/hubble/parser/layer34"
 	"github.com/microsoft/retina/pkg/hubble/parser/seven"
 	"github.com/sirupsen/logrus"
@@ -29,13 +30,15 @@ type Parser struct {
 	l7  *seven.Parser
 }
 
-func New(l *logrus.Entry, c *ipc.IPCache) *Parser {
+// New creates a Parser enriching Retina flows with the ipcache.
+// w is optional, and only needed when Hubble metrics use workload contexts.
+func New(l *logrus.Entry, c *ipc.IPCache, w common.WorkloadResolver) *Parser {
 	return &Parser{
 		l:       l,
 		ipcache: c,
 
-		l34: layer34.New(l, c),
-		l7:  seven.New(l, c),
+		l34: layer34.New(l, c, w),
+		l7:  seven.New(l, c, w),
 	}
 }
 
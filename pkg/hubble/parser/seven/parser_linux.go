@@ -18,10 +18,10 @@ type Parser struct {
 	ep common.EpDecoder
 }
 
-func New(l *logrus.Entry, c *ipcache.IPCache) *Parser {
+func New(l *logrus.Entry, c *ipcache.IPCache, w common.WorkloadResolver) *Parser {
 	return &Parser{
 		l:  l.WithField("subsys", "seven"),
-		ep: common.NewEpDecoder(c),
+		ep: common.NewEpDecoder(c, w),
 	}
 }
 
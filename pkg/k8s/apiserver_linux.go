@@ -31,10 +31,8 @@ type APIServerEventHandler struct {
 	l logrus.FieldLogger
 }
 
-func (a *APIServerEventHandler) handleAPIServerEvent(event interface{}) {
-	cacheEvent, ok := event.(*cc.CacheEvent)
-	if !ok {
-		a.l.WithField("Event", event).Warn("Received unknown event type")
+func (a *APIServerEventHandler) handleAPIServerEvent(cacheEvent *cc.CacheEvent) {
+	if cacheEvent == nil {
 		return
 	}
 	switch cacheEvent.Type { //nolint:exhaustive // the default case adequately handles these
@@ -19,7 +19,7 @@ import (
 	"github.com/cilium/cilium/pkg/k8s/watchers"
 	"github.com/cilium/cilium/pkg/node"
 	"github.com/cilium/cilium/pkg/option"
-	"github.com/microsoft/retina/pkg/common"
+	cc "github.com/microsoft/retina/pkg/controllers/cache"
 	"github.com/microsoft/retina/pkg/pubsub"
 	"github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -129,8 +129,7 @@ var Cell = cell.Module(
 	cell.Provide(newAPIServerEventHandler),
 	cell.Invoke(func(a *APIServerEventHandler) {
 		ps := pubsub.New()
-		fn := pubsub.CallBackFunc(a.handleAPIServerEvent)
-		uuid := ps.Subscribe(common.PubSubAPIServer, &fn)
+		uuid := cc.APIServerTopic.Subscribe(ps, a.handleAPIServerEvent)
 		a.l.WithFields(logrus.Fields{
 			"uuid": uuid,
 		}).Info("Subscribed to PubSub APIServer")
@@ -51,7 +51,7 @@ func Logger() *ZapLogger {
 
 type ZapLogger struct {
 	*zap.Logger
-	lvl    zapcore.Level
+	level  zap.AtomicLevel
 	closeF []func()
 }
 
@@ -73,6 +73,7 @@ func SetupZapLogger(lOpts *LogOpts, persistentFields ...zap.Field) (*ZapLogger,
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse log level")
 	}
+	logger.level = lev
 	encoderCfg := EncoderConfig()
 
 	// Setup a default stdout logger
@@ -144,10 +145,26 @@ func (l *ZapLogger) Close() {
 func (l *ZapLogger) Named(name string) *ZapLogger {
 	return &ZapLogger{
 		Logger: l.Logger.Named(name),
-		lvl:    l.lvl,
+		level:  l.level,
 	}
 }
 
+// SetLevel changes the level of the logger and of every logger derived from
+// it with Named. It is safe to call while the logger is in use.
+func (l *ZapLogger) SetLevel(level string) error {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse log level")
+	}
+	l.level.SetLevel(lvl)
+	return nil
+}
+
+// Level returns the current level of the logger.
+func (l *ZapLogger) Level() string {
+	return l.level.Level().String()
+}
+
 func (l *ZapLogger) GetZappedMiddleware() func(next http.Handler) http.Handler {
 	return l.zappedMiddleware
 }
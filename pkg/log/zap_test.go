@@ -51,3 +51,21 @@ func TestLogFileRotation(t *testing.T) {
 	assert.NoError(t, err, "Test log file walk through failed with err")
 	assert.Equal(t, expectedReplicas, curReplicas, "Test log file replicas are not as expected on 2nd try")
 }
+
+func TestSetLevel(t *testing.T) {
+	l, err := SetupZapLogger(GetDefaultLogOpts())
+	assert.NoError(t, err)
+	level := l.Level()
+	defer l.SetLevel(level) //nolint:errcheck // restoring a valid level
+
+	named := l.Named("test")
+	assert.NoError(t, l.SetLevel("debug"))
+	assert.Equal(t, "debug", named.Level())
+	assert.True(t, named.Core().Enabled(zap.DebugLevel))
+
+	assert.NoError(t, named.SetLevel("error"))
+	assert.False(t, l.Core().Enabled(zap.InfoLevel))
+
+	assert.Error(t, l.SetLevel("verbose"))
+	assert.Equal(t, "error", l.Level())
+}
@@ -4,8 +4,10 @@ package controllermanager
 
 import (
 	"context"
+	"net/http"
 	"time"
 
+	"github.com/microsoft/retina/pkg/bpf"
 	kcfg "github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/controllers/cache"
 	"github.com/microsoft/retina/pkg/enricher"
@@ -14,6 +16,7 @@ import (
 	sm "github.com/microsoft/retina/pkg/managers/servermanager"
 	"github.com/microsoft/retina/pkg/plugin/api"
 	"github.com/microsoft/retina/pkg/pubsub"
+	"github.com/microsoft/retina/pkg/server"
 	"github.com/microsoft/retina/pkg/telemetry"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -81,6 +84,15 @@ func (m *Controller) Init(ctx context.Context) error {
 	if err := m.httpServer.Init(); err != nil {
 		return err
 	}
+	m.httpServer.RegisterHandler(pm.PluginStatusPath, m.pluginManager.PluginStatusHandler())
+	m.httpServer.AddHealthzCheck("plugins", m.pluginManager.HealthzCheck)
+	m.httpServer.AddReadyzCheck("plugins", m.pluginManager.ReadyzCheck)
+	m.httpServer.AddDebugState("plugins", func() interface{} {
+		return m.pluginManager.DebugState()
+	})
+	m.httpServer.AddDebugState("bpf", func() interface{} {
+		return bpf.GetState()
+	})
 
 	if m.conf.EnablePodLevel {
 		// create pubsub instance
@@ -96,6 +108,31 @@ func (m *Controller) Init(ctx context.Context) error {
 	return nil
 }
 
+// PluginManager returns the plugin manager running the plugins.
+func (m *Controller) PluginManager() *pm.PluginManager {
+	return m.pluginManager
+}
+
+// RegisterHandler registers a handler on the HTTP server of the agent API, and must be called after Init.
+func (m *Controller) RegisterHandler(pattern string, handler http.Handler) {
+	m.httpServer.RegisterHandler(pattern, handler)
+}
+
+// AddHealthzCheck adds a liveness check of the agent, and must be called after Init.
+func (m *Controller) AddHealthzCheck(name string, check server.Check) {
+	m.httpServer.AddHealthzCheck(name, check)
+}
+
+// AddReadyzCheck adds a readiness check of the agent, and must be called after Init.
+func (m *Controller) AddReadyzCheck(name string, check server.Check) {
+	m.httpServer.AddReadyzCheck(name, check)
+}
+
+// AddDebugState adds the internal state of a component to the debug state of the agent, and must be called after Init.
+func (m *Controller) AddDebugState(name string, state server.StateFunc) {
+	m.httpServer.AddDebugState(name, state)
+}
+
 func (m *Controller) Start(ctx context.Context) {
 	// Only track panics if telemetry is enabled
 	defer telemetry.TrackPanic()
@@ -11,6 +11,7 @@ import (
 	kcfg "github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/log"
 	pm "github.com/microsoft/retina/pkg/managers/pluginmanager"
+	"github.com/microsoft/retina/pkg/metrics"
 	"github.com/microsoft/retina/pkg/plugin/api"
 	"github.com/microsoft/retina/pkg/plugin/api/mock"
 	"github.com/microsoft/retina/pkg/telemetry"
@@ -76,6 +77,7 @@ func TestControllerPluginManagerStartFail(t *testing.T) {
 	cm, err := NewControllerManager(c, kubeclient, telemetry.NewNoopTelemetry())
 	assert.NoError(t, err, "Expected no error, instead got %+v", err)
 	assert.NotNil(t, cm)
+	metrics.InitializeMetrics()
 
 	ctl := gomock.NewController(t)
 	defer ctl.Finish()
@@ -104,5 +106,26 @@ func TestControllerPluginManagerStartFail(t *testing.T) {
 	err = cm.Init(context.Background())
 	require.NoError(t, err, "Expected no error, instead got %+v", err)
 
-	require.Panics(t, func() { cm.Start(context.Background()) })
+	// A failing plugin is restarted by the plugin manager instead of stopping the controller manager.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cm.Start(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		statuses := mgr.PluginStatuses()
+		return len(statuses) == 1 && statuses[0].State == pm.PluginStateDegraded
+	}, 5*time.Second, 10*time.Millisecond, "Expected plugin to be degraded, got %v", mgr.PluginStatuses())
+
+	cancel()
+	require.Eventually(t, func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, 2*pm.MAX_STARTUP_TIME, 10*time.Millisecond, "Expected controller manager to stop")
 }
@@ -5,6 +5,7 @@ package filtermanager
 
 import (
 	"net"
+	"sort"
 	"sync"
 )
 
@@ -98,3 +99,22 @@ func (f *filterCache) deleteIP(ip net.IP, r Requestor, m RequestMetadata) bool {
 	}
 	return ipDeleted
 }
+
+func (f *filterCache) state() State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := make(State, len(f.data))
+	for ip, reqs := range f.data {
+		s[ip] = make(map[Requestor][]RequestMetadata, len(reqs))
+		for r, metadata := range reqs {
+			for m := range metadata {
+				s[ip][r] = append(s[ip][r], m)
+			}
+			sort.Slice(s[ip][r], func(i, j int) bool {
+				return s[ip][r][i].RuleID < s[ip][r][j].RuleID
+			})
+		}
+	}
+	return s
+}
@@ -74,6 +74,23 @@ func addIPsHelper() {
 	time.Sleep(1 * time.Second)
 }
 
+func Test_state(t *testing.T) {
+	f := newCache()
+	f.reset()
+	assert.Empty(t, f.state())
+
+	f.addIP(net.ParseIP("1.1.1.1"), "trace1", RequestMetadata{RuleID: "rule2"})
+	f.addIP(net.ParseIP("1.1.1.1"), "trace1", RequestMetadata{RuleID: "rule1"})
+	f.addIP(net.ParseIP("1.1.1.1"), "trace2", RequestMetadata{RuleID: "rule3"})
+	assert.Equal(t, State{
+		"1.1.1.1": {
+			"trace1": {{RuleID: "rule1"}, {RuleID: "rule2"}},
+			"trace2": {{RuleID: "rule3"}},
+		},
+	}, f.state())
+	f.reset()
+}
+
 func Test_addIP(t *testing.T) {
 	addIPsHelper()
 
@@ -130,6 +130,17 @@ func (f *FilterManager) HasIP(ip net.IP) bool {
 	return f.c.hasKey(ip)
 }
 
+// State returns the IPs in the filter map with their requestors.
+func (f *FilterManager) State() State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.c == nil {
+		return State{}
+	}
+	return f.c.state()
+}
+
 func (f *FilterManager) Reset() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
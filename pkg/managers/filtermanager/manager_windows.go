@@ -27,6 +27,10 @@ func (f *FilterManager) HasIP(_ net.IP) bool {
 	return false
 }
 
+func (f *FilterManager) State() State {
+	return nil
+}
+
 func (f *FilterManager) Reset() error {
 	return nil
 }
@@ -105,6 +105,20 @@ func (mr *MockICacheMockRecorder) reset() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "reset", reflect.TypeOf((*MockICache)(nil).reset))
 }
 
+// state mocks base method.
+func (m *MockICache) state() State {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "state")
+	ret0, _ := ret[0].(State)
+	return ret0
+}
+
+// state indicates an expected call of state.
+func (mr *MockICacheMockRecorder) state() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "state", reflect.TypeOf((*MockICache)(nil).state))
+}
+
 // MockIFilterManager is a mock of IFilterManager interface.
 type MockIFilterManager struct {
 	ctrl     *gomock.Controller
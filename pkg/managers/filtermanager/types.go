@@ -14,6 +14,7 @@ type ICache interface {
 	hasKey(net.IP) bool
 	addIP(net.IP, Requestor, RequestMetadata)
 	deleteIP(net.IP, Requestor, RequestMetadata) bool
+	state() State
 }
 
 type IFilterManager interface {
@@ -47,5 +48,8 @@ type Requestor string
 
 type RequestMetadata struct {
 	// Each trace can have multiple rules.
-	RuleID string
+	RuleID string `json:"ruleID"`
 }
+
+// State maps the IPs in the filter map to their requestors and the metadata of their requests.
+type State map[string]map[Requestor][]RequestMetadata
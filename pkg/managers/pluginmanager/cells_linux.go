@@ -6,6 +6,7 @@ import (
 
 	"github.com/cilium/cilium/pkg/hive/cell"
 	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
+	k8sClient "github.com/cilium/cilium/pkg/k8s/client"
 	"github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/metrics"
 	"github.com/microsoft/retina/pkg/plugin/api"
@@ -34,8 +35,10 @@ type pluginManagerParams struct {
 	Log       logrus.FieldLogger
 	Lifecycle cell.Lifecycle
 	Config    config.Config
+	Reloader  *config.Reloader
 	Telemetry telemetry.Telemetry
 	EventChan chan *v1.Event
+	Clientset k8sClient.Clientset
 }
 
 func newPluginManager(params pluginManagerParams) (*PluginManager, error) {
@@ -53,6 +56,18 @@ func newPluginManager(params pluginManagerParams) (*PluginManager, error) {
 		return &PluginManager{}, err
 	}
 
+	// Retina's cache is not used with Hubble, the plugins only get the Kubernetes API.
+	if params.Clientset != nil && params.Clientset.IsEnabled() {
+		pluginMgr.SetCluster(api.Cluster{Client: params.Clientset})
+	}
+
+	params.Reloader.AddValidator(ValidatePlugins)
+	params.Reloader.OnReload(func(oldCfg, newCfg *config.Config) {
+		if oldCfg.MetricsInterval != newCfg.MetricsInterval {
+			pluginMgr.SetMetricsInterval(newCfg.MetricsInterval)
+		}
+	})
+
 	pmCtx, cancelCtx := context.WithCancel(context.Background())
 	// Setup the event channel to be used by hubble
 	pluginMgr.SetupChannel(params.EventChan)
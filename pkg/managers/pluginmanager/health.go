@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package pluginmanager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/microsoft/retina/pkg/managers/watchermanager"
+)
+
+// watcherStatusReporter is implemented by the watcher managers reporting the refreshes of their watchers.
+type watcherStatusReporter interface {
+	Statuses() []watchermanager.WatcherStatus
+	ReadyzCheck() error
+}
+
+// DebugState is the state of the plugins and of the watchers feeding them.
+type DebugState struct {
+	Plugins  []PluginStatus                 `json:"plugins"`
+	Watchers []watchermanager.WatcherStatus `json:"watchers,omitempty"`
+}
+
+// HealthzCheck fails when plugins failed too many times in a row to be restarted, which restarting the agent does.
+func (p *PluginManager) HealthzCheck() error {
+	var failed []string
+	for _, status := range p.PluginStatuses() {
+		if status.State == PluginStateFailed {
+			failed = append(failed, status.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("plugins %s failed and are no longer restarted", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// ReadyzCheck fails until all the enabled plugins run with their eBPF programs attached, and when a watcher stopped
+// refreshing.
+func (p *PluginManager) ReadyzCheck() error {
+	p.mu.Lock()
+	enabled := make([]string, 0, len(p.plugins))
+	for name := range p.plugins {
+		enabled = append(enabled, string(name))
+	}
+	p.mu.Unlock()
+	sort.Strings(enabled)
+
+	statuses := make(map[string]PluginStatus, len(enabled))
+	for _, status := range p.PluginStatuses() {
+		statuses[status.Name] = status
+	}
+
+	var problems []string
+	for _, name := range enabled {
+		status, ok := statuses[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("plugin %s is not started", name))
+			continue
+		}
+		if status.State != PluginStateRunning {
+			problems = append(problems, fmt.Sprintf("plugin %s is %s", name, status.State))
+			continue
+		}
+		for _, attachment := range status.Attachments {
+			if attachment.Error != "" {
+				problems = append(problems, fmt.Sprintf("plugin %s is not attached to %s: %s", name, attachment.Target, attachment.Error))
+			}
+		}
+	}
+	if reporter, ok := p.watcherManager.(watcherStatusReporter); ok {
+		if err := reporter.ReadyzCheck(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// DebugState returns the state of the plugins and of the watchers feeding them.
+func (p *PluginManager) DebugState() DebugState {
+	state := DebugState{Plugins: p.PluginStatuses()}
+	if reporter, ok := p.watcherManager.(watcherStatusReporter); ok {
+		state.Watchers = reporter.Statuses()
+	}
+	return state
+}
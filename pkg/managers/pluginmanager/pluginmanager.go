@@ -12,13 +12,11 @@ import (
 	kcfg "github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/managers/watchermanager"
-	"github.com/microsoft/retina/pkg/metrics"
 	"github.com/microsoft/retina/pkg/plugin/api"
 	"github.com/microsoft/retina/pkg/plugin/registry"
 	"github.com/microsoft/retina/pkg/telemetry"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -44,6 +42,20 @@ type PluginManager struct {
 	tel     telemetry.Telemetry
 
 	watcherManager watchermanager.IWatcherManager
+
+	// mu guards plugins, the supervisors of the running plugins, and the event channel.
+	mu sync.Mutex
+	// ctx is the context the plugins run in, set once the plugin manager is started.
+	ctx         context.Context //nolint:containedctx // plugins enabled at runtime run in the context of Start
+	supervisors map[api.PluginName]*pluginSupervisor
+	wg          sync.WaitGroup
+	eventChan   chan *v1.Event
+	cluster     api.Cluster
+
+	// statusMu guards the statuses of the plugins, and the plugins reporting their attachments.
+	statusMu  sync.RWMutex
+	statuses  map[api.PluginName]*PluginStatus
+	reporters map[api.PluginName]api.AttachmentReporter
 }
 
 func init() {
@@ -82,8 +94,14 @@ func NewPluginManager(
 }
 
 func (p *PluginManager) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	var wg sync.WaitGroup
-	for _, plugin := range p.plugins {
+	for name, plugin := range p.plugins {
+		if p.stoppedBySupervisorLocked(name) {
+			continue
+		}
 		wg.Add(1)
 		go func(plugin api.Plugin) {
 			defer wg.Done()
@@ -127,26 +145,21 @@ func (p *PluginManager) Reconcile(ctx context.Context, plugin api.Plugin) error
 // Note: This will block as long as main thread is running.
 func (p *PluginManager) Start(ctx context.Context) error {
 	counter := p.tel.StartPerf("start-plugin-manager")
-	// start plugins evenly throughout the interval,
-	// if 2 plugins enabled, and 10 second interval
-	// 10 / 2 = 5, then after every start sleep 5s
-	// plugin 1 = 0s
-	// plugin 2 = 5s
-	// then the plugins won't awake at the same time and they'll have even execution time
-
-	delay := float32(MAX_STARTUP_TIME) / float32(len(p.plugins))
 	p.l.Info("Starting plugin manager ...")
-	var err error
 
-	if p.cfg == nil {
+	// The config is replaced, not changed, when the metrics interval changes.
+	p.mu.Lock()
+	cfg := p.cfg
+	p.mu.Unlock()
+	if cfg == nil {
 		return ErrNilCfg
 	}
 
-	if p.cfg.MetricsInterval == 0 {
+	if cfg.MetricsInterval == 0 {
 		return ErrZeroInterval
 	}
 
-	if p.cfg.EnablePodLevel {
+	if cfg.EnablePodLevel {
 		p.l.Info("starting watchers")
 
 		// Start watcher manager
@@ -155,38 +168,42 @@ func (p *PluginManager) Start(ctx context.Context) error {
 		}
 	}
 
-	// start all plugins
-	g, ctx := errgroup.WithContext(ctx)
-	for _, plugin := range p.plugins {
-		plug := plugin
-
-		reconcilectx, cancel := context.WithTimeout(ctx, time.Duration(MAX_RECONCILE_TIME))
-		defer cancel()
-		err = p.Reconcile(reconcilectx, plug)
-		if err != nil {
-			// Update control plane metrics counter
-			metrics.PluginManagerFailedToReconcileCounter.WithLabelValues(plugin.Name()).Inc()
-			return errors.Wrapf(err, "failed to reconcile plugin %s", plugin.Name())
+	// Start all plugins, each under its own supervisor so that a failing plugin
+	// is restarted on its own and does not stop the other plugins.
+	p.mu.Lock()
+	p.ctx = ctx
+	plugins := make(map[api.PluginName]api.Plugin, len(p.plugins))
+	for name, plugin := range p.plugins {
+		plugins[name] = plugin
+	}
+	p.mu.Unlock()
+	// start plugins evenly throughout the interval,
+	// if 2 plugins enabled, and 10 second interval
+	// 10 / 2 = 5, then after every start sleep 5s
+	// plugin 1 = 0s
+	// plugin 2 = 5s
+	// then the plugins won't awake at the same time and they'll have even execution time
+	delay := float32(MAX_STARTUP_TIME) / float32(len(plugins))
+	for name, plugin := range plugins {
+		p.mu.Lock()
+		// The plugin may have been disabled, or started when enabled again, in the meantime.
+		if p.plugins[name] == plugin && p.supervisors[name] == nil {
+			p.startPluginLocked(name, plugin)
 		}
-
-		g.Go(func() error {
-			p.l.Info(fmt.Sprintf("starting plugin %s", plug.Name()))
-			return errors.Wrapf(plug.Start(ctx), "failed to start plugin %s", plug.Name())
-		})
+		p.mu.Unlock()
 
 		time.Sleep(time.Duration(delay))
 	}
 
 	p.tel.StopPerf(counter)
 	p.l.Info("successfully started pluginmanager")
-	// on cancel context wait for all plugins to exit
-	err = g.Wait()
-	if err != nil {
-		p.l.Error("plugin manager exited with error", zap.Error(err))
-		return errors.Wrapf(err, "failed to start plugin manager, plugin exited")
-	}
+	// on cancel context wait for all plugin supervisors to exit
+	<-ctx.Done()
+	p.mu.Lock()
+	p.wg.Wait()
+	p.mu.Unlock()
 
-	if p.cfg.EnablePodLevel {
+	if cfg.EnablePodLevel {
 		p.l.Info("stopping watcher manager")
 
 		// Stop watcher manager.
@@ -204,13 +221,112 @@ func (p *PluginManager) SetPlugin(name api.PluginName, plugin api.Plugin) {
 		return
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if p.plugins == nil {
 		p.plugins = map[api.PluginName]api.Plugin{}
 	}
 	p.plugins[name] = plugin
 }
 
+// SetEnabledPlugins enables the plugins and disables the other ones. The plugins enabled once the plugin manager is
+// started are started right away, and the disabled plugins are stopped. Nothing is changed when one of the plugins is
+// not found in the registry.
+func (p *PluginManager) SetEnabledPlugins(pluginNames ...api.PluginName) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	enabled := make(map[api.PluginName]struct{}, len(pluginNames))
+	for _, name := range pluginNames {
+		if _, ok := p.plugins[name]; !ok {
+			if _, ok := registry.PluginHandler[name]; !ok {
+				return fmt.Errorf("plugin %s not found in registry", name)
+			}
+		}
+		enabled[name] = struct{}{}
+	}
+
+	for name, plugin := range p.plugins {
+		if _, ok := enabled[name]; ok {
+			continue
+		}
+		p.l.Info("disabling plugin", zap.String("name", string(name)))
+		p.stopPluginLocked(name, plugin)
+		delete(p.plugins, name)
+	}
+
+	if p.plugins == nil {
+		p.plugins = map[api.PluginName]api.Plugin{}
+	}
+	for name := range enabled {
+		if _, ok := p.plugins[name]; ok {
+			continue
+		}
+		p.l.Info("enabling plugin", zap.String("name", string(name)))
+		plugin := registry.PluginHandler[name](p.cfg)
+		if user, ok := plugin.(api.ClusterUser); ok {
+			user.SetCluster(p.cluster)
+		}
+		if p.eventChan != nil {
+			if err := plugin.SetupChannel(p.eventChan); err != nil {
+				p.l.Error("failed to setup channel for plugin", zap.String("plugin name", string(name)), zap.Error(err))
+			}
+		}
+		p.plugins[name] = plugin
+		if p.ctx != nil && p.ctx.Err() == nil {
+			p.startPluginLocked(name, plugin)
+		}
+	}
+	return nil
+}
+
+// SetMetricsInterval changes the interval the plugins publish their metrics at. The plugins keep running, the new
+// interval is given to those implementing api.MetricsIntervalUpdater.
+func (p *PluginManager) SetMetricsInterval(interval time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cfg.MetricsInterval == interval {
+		return
+	}
+	p.l.Info("changing metrics interval", zap.Duration("from", p.cfg.MetricsInterval), zap.Duration("to", interval))
+	// The plugins share the config, which is replaced for the plugins enabled from now on instead of being changed
+	// under the running ones.
+	cfg := *p.cfg
+	cfg.MetricsInterval = interval
+	p.cfg = &cfg
+	for _, plugin := range p.plugins {
+		if updater, ok := plugin.(api.MetricsIntervalUpdater); ok {
+			updater.SetMetricsInterval(interval)
+		}
+	}
+}
+
+// SetCluster gives the Kubernetes cluster to the plugins using it, including the plugins enabled later. It must be
+// called before the plugin manager is started.
+func (p *PluginManager) SetCluster(cluster api.Cluster) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cluster = cluster
+	for _, plugin := range p.plugins {
+		if user, ok := plugin.(api.ClusterUser); ok {
+			user.SetCluster(cluster)
+		}
+	}
+}
+
+// ValidatePlugins checks that the plugins enabled by the config are in the registry.
+func ValidatePlugins(cfg *kcfg.Config) error {
+	return cfg.ValidatePlugins(registry.PluginNames())
+}
+
 func (p *PluginManager) SetupChannel(c chan *v1.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Keep the channel for the plugins enabled at runtime.
+	p.eventChan = c
 	for name, plugin := range p.plugins {
 		err := plugin.SetupChannel(c)
 		if err != nil {
@@ -4,7 +4,11 @@ package pluginmanager
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -37,6 +41,15 @@ var (
 	}
 )
 
+// setupSupervisorForTest shortens the restart backoff of the plugin supervisors for the test.
+func setupSupervisorForTest(t *testing.T) {
+	initial, maxBackoff, failures := restartBackoffInitial, restartBackoffMax, maxConsecutiveFailures
+	restartBackoffInitial, restartBackoffMax, maxConsecutiveFailures = time.Millisecond, 10*time.Millisecond, 3
+	t.Cleanup(func() {
+		restartBackoffInitial, restartBackoffMax, maxConsecutiveFailures = initial, maxBackoff, failures
+	})
+}
+
 func setupWatcherManagerMock(ctl *gomock.Controller) (m *watchermock.MockIWatcherManager) {
 	m = watchermock.NewMockIWatcherManager(ctl)
 	m.EXPECT().Start(gomock.Any()).Return(nil).AnyTimes()
@@ -97,6 +110,7 @@ func TestNewManager(t *testing.T) {
 
 func TestNewManagerStart(t *testing.T) {
 	log.SetupZapLogger(log.GetDefaultLogOpts())
+	metrics.InitializeMetrics()
 	tel := telemetry.NewNoopTelemetry()
 	tests := []struct {
 		name       string
@@ -143,8 +157,8 @@ func TestNewManagerWithPluginStartFailure(t *testing.T) {
 	ctl := gomock.NewController(t)
 	defer ctl.Finish()
 	log.SetupZapLogger(log.GetDefaultLogOpts())
-
-	pluginName := "mockplugin"
+	metrics.InitializeMetrics()
+	setupSupervisorForTest(t)
 
 	mgr := &PluginManager{
 		cfg:            cfgPodLevelEnabled,
@@ -154,26 +168,175 @@ func TestNewManagerWithPluginStartFailure(t *testing.T) {
 		watcherManager: setupWatcherManagerMock(ctl),
 	}
 
-	mockPlugin := pluginmock.NewMockPlugin(ctl)
-	mockPlugin.EXPECT().Generate(gomock.Any()).Return(nil).AnyTimes()
-	mockPlugin.EXPECT().Compile(gomock.Any()).Return(nil).AnyTimes()
-	mockPlugin.EXPECT().Stop().Return(nil).AnyTimes()
-	mockPlugin.EXPECT().Init().Return(nil).AnyTimes()
-	mockPlugin.EXPECT().Start(gomock.Any()).Return(errors.New("Plugin failed to start")).AnyTimes()
-	mockPlugin.EXPECT().Name().Return(pluginName).AnyTimes()
+	failingPlugin := pluginmock.NewMockPlugin(ctl)
+	failingPlugin.EXPECT().Generate(gomock.Any()).Return(nil).AnyTimes()
+	failingPlugin.EXPECT().Compile(gomock.Any()).Return(nil).AnyTimes()
+	failingPlugin.EXPECT().Stop().Return(nil).AnyTimes()
+	failingPlugin.EXPECT().Init().Return(nil).AnyTimes()
+	failingPlugin.EXPECT().Start(gomock.Any()).Return(errors.New("Plugin failed to start")).Times(maxConsecutiveFailures)
+	failingPlugin.EXPECT().Name().Return("failingplugin").AnyTimes()
+	mgr.plugins["failingplugin"] = failingPlugin
+
+	healthyPlugin := pluginmock.NewMockPlugin(ctl)
+	healthyPlugin.EXPECT().Generate(gomock.Any()).Return(nil).AnyTimes()
+	healthyPlugin.EXPECT().Compile(gomock.Any()).Return(nil).AnyTimes()
+	healthyPlugin.EXPECT().Stop().Return(nil).AnyTimes()
+	healthyPlugin.EXPECT().Init().Return(nil).AnyTimes()
+	healthyPlugin.EXPECT().Start(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}).Times(1)
+	healthyPlugin.EXPECT().Name().Return("healthyplugin").AnyTimes()
+	mgr.plugins["healthyplugin"] = healthyPlugin
 
-	mgr.plugins[api.PluginName(pluginName)] = mockPlugin
+	ctx, cancel := context.WithCancel(context.Background())
+	g, errctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return mgr.Start(errctx)
+	})
+
+	// The failing plugin is restarted until the circuit breaker opens, while the healthy plugin keeps running.
+	require.Eventually(t, func() bool {
+		statuses := mgr.PluginStatuses()
+		return len(statuses) == 2 &&
+			statuses[0].State == PluginStateFailed &&
+			statuses[1].State == PluginStateRunning
+	}, 15*time.Second, 10*time.Millisecond, "Expected failing plugin to fail and healthy plugin to run, got %v", mgr.PluginStatuses())
+
+	status := mgr.PluginStatuses()[0]
+	require.Equal(t, maxConsecutiveFailures-1, status.Restarts)
+	require.Equal(t, maxConsecutiveFailures, status.ConsecutiveFailures)
+	require.Contains(t, status.LastError, "Plugin failed to start")
+
+	rec := httptest.NewRecorder()
+	mgr.PluginStatusHandler()(rec, httptest.NewRequest(http.MethodGet, PluginStatusPath, http.NoBody))
+	var served []PluginStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Len(t, served, 2)
+	require.Equal(t, PluginStateFailed, served[0].State)
+
+	require.ErrorContains(t, mgr.HealthzCheck(), "failingplugin")
+	readyErr := mgr.ReadyzCheck()
+	require.ErrorContains(t, readyErr, "plugin failingplugin is failed")
+	require.NotContains(t, readyErr.Error(), "healthyplugin")
+	require.Len(t, mgr.DebugState().Plugins, 2)
+
+	gauge, err := metrics.PluginManagerPluginStateGauge.GetMetricWithLabelValues("failingplugin", string(PluginStateFailed))
+	require.NoError(t, err)
+	out := &dto.Metric{}
+	require.NoError(t, gauge.Write(out))
+	require.Equal(t, float64(1), *out.Gauge.Value)
+
+	cancel()
+	require.NoError(t, g.Wait(), "Expected plugin manager to stop gracefully")
+}
+
+func TestNewManagerWithPluginPanic(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	metrics.InitializeMetrics()
+	setupSupervisorForTest(t)
+
+	mgr := &PluginManager{
+		cfg:            cfgPodLevelEnabled,
+		l:              log.Logger().Named("plugin-manager"),
+		plugins:        make(map[api.PluginName]api.Plugin),
+		tel:            telemetry.NewNoopTelemetry(),
+		watcherManager: setupWatcherManagerMock(ctl),
+	}
+
+	// The plugin starts a goroutine in each attempt before panicking, which must be cancelled before it is restarted.
+	attemptsDone := make(chan (<-chan struct{}), maxConsecutiveFailures)
+	panickingPlugin := pluginmock.NewMockPlugin(ctl)
+	panickingPlugin.EXPECT().Generate(gomock.Any()).Return(nil).AnyTimes()
+	panickingPlugin.EXPECT().Compile(gomock.Any()).Return(nil).AnyTimes()
+	panickingPlugin.EXPECT().Stop().Return(nil).AnyTimes()
+	panickingPlugin.EXPECT().Init().Return(nil).AnyTimes()
+	panickingPlugin.EXPECT().Start(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		attemptsDone <- ctx.Done()
+		panic("plugin panicked")
+	}).Times(maxConsecutiveFailures)
+	panickingPlugin.EXPECT().Name().Return("panickingplugin").AnyTimes()
+	mgr.plugins["panickingplugin"] = panickingPlugin
 
 	ctx, cancel := context.WithCancel(context.Background())
+	g, errctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return mgr.Start(errctx)
+	})
 
-	go func() {
-		err := mgr.Start(ctx)
-		require.NotNil(t, err, "Expected Error but got nil:%w", err)
-		require.ErrorContains(t, err, "Plugin failed to start", err, "Expected error to contain 'Plugin failed to start' but got %s", err.Error())
-	}()
+	require.Eventually(t, func() bool {
+		statuses := mgr.PluginStatuses()
+		return len(statuses) == 1 && statuses[0].State == PluginStateFailed
+	}, 15*time.Second, 10*time.Millisecond, "Expected panicking plugin to fail, got %v", mgr.PluginStatuses())
+	status := mgr.PluginStatuses()[0]
+	require.Equal(t, maxConsecutiveFailures, status.ConsecutiveFailures)
+	require.Contains(t, status.LastError, "plugin panickingplugin panicked: plugin panicked")
+
+	require.Len(t, attemptsDone, maxConsecutiveFailures)
+	for range maxConsecutiveFailures {
+		select {
+		case <-<-attemptsDone:
+		default:
+			t.Fatal("Expected the context of each failed attempt to be cancelled")
+		}
+	}
 
-	time.Sleep(1 * time.Second)
 	cancel()
+	require.NoError(t, g.Wait(), "Expected plugin manager to stop gracefully")
+}
+
+func TestFailedPluginIsStoppedOnce(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	metrics.InitializeMetrics()
+	setupSupervisorForTest(t)
+
+	for _, disable := range []bool{true, false} {
+		mgr := &PluginManager{
+			cfg:            cfgPodLevelEnabled,
+			l:              log.Logger().Named("plugin-manager"),
+			plugins:        make(map[api.PluginName]api.Plugin),
+			tel:            telemetry.NewNoopTelemetry(),
+			watcherManager: setupWatcherManagerMock(ctl),
+		}
+
+		var stops atomic.Int32
+		failingPlugin := pluginmock.NewMockPlugin(ctl)
+		failingPlugin.EXPECT().Generate(gomock.Any()).Return(nil).AnyTimes()
+		failingPlugin.EXPECT().Compile(gomock.Any()).Return(nil).AnyTimes()
+		failingPlugin.EXPECT().Stop().DoAndReturn(func() error {
+			stops.Add(1)
+			return nil
+		}).AnyTimes()
+		failingPlugin.EXPECT().Init().Return(nil).AnyTimes()
+		failingPlugin.EXPECT().Start(gomock.Any()).Return(errors.New("Plugin failed to start")).Times(maxConsecutiveFailures)
+		failingPlugin.EXPECT().Name().Return("failingplugin").AnyTimes()
+		mgr.plugins["failingplugin"] = failingPlugin
+
+		ctx, cancel := context.WithCancel(context.Background())
+		g, errctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return mgr.Start(errctx)
+		})
+		require.Eventually(t, func() bool {
+			statuses := mgr.PluginStatuses()
+			return len(statuses) == 1 && statuses[0].State == PluginStateFailed
+		}, 15*time.Second, 10*time.Millisecond, "Expected failing plugin to fail, got %v", mgr.PluginStatuses())
+		// The plugin is stopped before each restart, and once by the supervisor when it fails too many times in a row.
+		require.Equal(t, int32(maxConsecutiveFailures+1), stops.Load())
+
+		if disable {
+			require.NoError(t, mgr.SetEnabledPlugins())
+			require.Empty(t, mgr.PluginStatuses())
+		}
+		cancel()
+		require.NoError(t, g.Wait())
+		mgr.Stop()
+		require.Equal(t, int32(maxConsecutiveFailures+1), stops.Load(), "Expected failed plugin not to be stopped again")
+	}
 }
 
 func TestNewManagerWithPluginReconcileFailure(t *testing.T) {
@@ -181,8 +344,9 @@ func TestNewManagerWithPluginReconcileFailure(t *testing.T) {
 	defer ctl.Finish()
 	log.SetupZapLogger(log.GetDefaultLogOpts())
 	metrics.InitializeMetrics()
+	setupSupervisorForTest(t)
 
-	pluginName := "mockplugin"
+	pluginName := "reconcilefailureplugin"
 
 	mgr := &PluginManager{
 		cfg:            cfgPodLevelEnabled,
@@ -197,26 +361,29 @@ func TestNewManagerWithPluginReconcileFailure(t *testing.T) {
 	mockPlugin.EXPECT().Compile(gomock.Any()).Return(nil).AnyTimes()
 	mockPlugin.EXPECT().Stop().Return(errors.New("Plugin failed to stop")).AnyTimes()
 	mockPlugin.EXPECT().Init().Return(nil).AnyTimes()
-	mockPlugin.EXPECT().Start(gomock.Any()).Return(nil).AnyTimes()
+	mockPlugin.EXPECT().Start(gomock.Any()).Return(nil).Times(0)
 	mockPlugin.EXPECT().Name().Return(pluginName).AnyTimes()
 
 	mgr.plugins[api.PluginName(pluginName)] = mockPlugin
 
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	go func() {
-		err := mgr.Start(ctx)
-		require.NotNil(t, err, "Expected Error but got nil:%w", err)
-		require.ErrorContains(t, err, "Plugin failed to stop", err, "Expected error to contain 'Plugin failed to start' but got %s", err.Error())
-		count, error := metrics.PluginManagerFailedToReconcileCounter.GetMetricWithLabelValues(pluginName)
-		out := &dto.Metric{}
-		count.Write(out)
-		require.Nil(t, error, "Expected nil but got error:%w", error)
-		require.Equal(t, float64(1), *out.Counter.Value, "Expected 1 but got %f", *out.Counter.Value)
+		_ = mgr.Start(ctx)
 	}()
 
-	time.Sleep(1 * time.Second)
-	cancel()
+	require.Eventually(t, func() bool {
+		statuses := mgr.PluginStatuses()
+		return len(statuses) == 1 && statuses[0].State == PluginStateFailed
+	}, 5*time.Second, 10*time.Millisecond, "Expected plugin to fail, got %v", mgr.PluginStatuses())
+	require.Contains(t, mgr.PluginStatuses()[0].LastError, "Plugin failed to stop")
+
+	count, err := metrics.PluginManagerFailedToReconcileCounter.GetMetricWithLabelValues(pluginName)
+	require.Nil(t, err, "Expected nil but got error:%w", err)
+	out := &dto.Metric{}
+	require.NoError(t, count.Write(out))
+	require.Equal(t, float64(maxConsecutiveFailures), *out.Counter.Value, "Expected %d but got %f", maxConsecutiveFailures, *out.Counter.Value)
 }
 
 func TestPluginInit(t *testing.T) {
@@ -399,6 +566,7 @@ func TestStopPluginManagerGracefully(t *testing.T) {
 	ctl := gomock.NewController(t)
 	defer ctl.Finish()
 	log.SetupZapLogger(log.GetDefaultLogOpts())
+	metrics.InitializeMetrics()
 
 	pluginName := "mockplugin"
 
@@ -453,3 +621,146 @@ func TestWatcherManagerFailure(t *testing.T) {
 	require.NotNil(t, err, "Expected Start err but got nil")
 	require.ErrorContains(t, err, "failed to start watcher manager", "Expected watcher manager , but got:%w", err)
 }
+
+func TestSetEnabledPlugins(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	metrics.InitializeMetrics()
+
+	mgr := &PluginManager{
+		cfg:            cfgPodLevelEnabled,
+		l:              log.Logger().Named("plugin-manager"),
+		plugins:        make(map[api.PluginName]api.Plugin),
+		tel:            telemetry.NewNoopTelemetry(),
+		watcherManager: setupWatcherManagerMock(ctl),
+	}
+
+	stopped := make(chan struct{})
+	disabledPlugin := pluginmock.NewMockPlugin(ctl)
+	disabledPlugin.EXPECT().Generate(gomock.Any()).Return(nil).AnyTimes()
+	disabledPlugin.EXPECT().Compile(gomock.Any()).Return(nil).AnyTimes()
+	disabledPlugin.EXPECT().Stop().Return(nil).AnyTimes()
+	disabledPlugin.EXPECT().Init().Return(nil).AnyTimes()
+	disabledPlugin.EXPECT().Start(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	}).Times(1)
+	disabledPlugin.EXPECT().Name().Return("disabledplugin").AnyTimes()
+	mgr.plugins["disabledplugin"] = disabledPlugin
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g, errctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return mgr.Start(errctx)
+	})
+
+	require.Eventually(t, func() bool {
+		statuses := mgr.PluginStatuses()
+		return len(statuses) == 1 && statuses[0].State == PluginStateRunning
+	}, 5*time.Second, 10*time.Millisecond, "Expected plugin to run, got %v", mgr.PluginStatuses())
+
+	// Plugins not found in the registry are rejected without changing the enabled plugins.
+	require.Error(t, mgr.SetEnabledPlugins("mockplugin", "unknownplugin"))
+	require.Len(t, mgr.PluginStatuses(), 1)
+
+	require.NoError(t, mgr.SetEnabledPlugins("mockplugin"))
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Expected disabled plugin to be stopped")
+	}
+	require.Eventually(t, func() bool {
+		statuses := mgr.PluginStatuses()
+		return len(statuses) == 1 && statuses[0].Name == "mockplugin" && statuses[0].State == PluginStateRunning
+	}, 5*time.Second, 10*time.Millisecond, "Expected enabled plugin to run, got %v", mgr.PluginStatuses())
+
+	cancel()
+	require.NoError(t, g.Wait())
+}
+
+type intervalPlugin struct {
+	*pluginmock.MockPlugin
+	intervals chan time.Duration
+}
+
+func (i *intervalPlugin) SetMetricsInterval(interval time.Duration) {
+	i.intervals <- interval
+}
+
+func TestSetMetricsInterval(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	metrics.InitializeMetrics()
+
+	cfg := *cfgPodLevelDisabled
+	mgr := &PluginManager{
+		cfg:     &cfg,
+		l:       log.Logger().Named("plugin-manager"),
+		plugins: make(map[api.PluginName]api.Plugin),
+		tel:     telemetry.NewNoopTelemetry(),
+	}
+
+	started := make(chan struct{})
+	plugin := &intervalPlugin{MockPlugin: pluginmock.NewMockPlugin(ctl), intervals: make(chan time.Duration, 1)}
+	plugin.EXPECT().Generate(gomock.Any()).Return(nil).AnyTimes()
+	plugin.EXPECT().Compile(gomock.Any()).Return(nil).AnyTimes()
+	plugin.EXPECT().Init().Return(nil).Times(1)
+	plugin.EXPECT().Stop().Return(nil).AnyTimes()
+	plugin.EXPECT().Start(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	}).Times(1)
+	plugin.EXPECT().Name().Return("mockplugin").AnyTimes()
+	mgr.plugins["mockplugin"] = plugin
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g, errctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return mgr.Start(errctx)
+	})
+
+	<-started
+	mgr.SetMetricsInterval(time.Minute)
+	require.Equal(t, time.Minute, <-plugin.intervals, "Expected running plugin to get the new interval")
+	require.Equal(t, timeInter, cfg.MetricsInterval, "Expected config shared with the plugins to be left unchanged")
+	require.Equal(t, time.Minute, mgr.cfg.MetricsInterval)
+
+	// Setting the same interval again is a no-op.
+	mgr.SetMetricsInterval(time.Minute)
+	require.Empty(t, plugin.intervals)
+
+	cancel()
+	require.NoError(t, g.Wait())
+}
+
+type clusterPlugin struct {
+	*pluginmock.MockPlugin
+	cluster *api.Cluster
+}
+
+func (c *clusterPlugin) SetCluster(cluster api.Cluster) {
+	c.cluster = &cluster
+}
+
+func TestSetCluster(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+
+	mgr := &PluginManager{
+		cfg:     cfgPodLevelDisabled,
+		l:       log.Logger().Named("plugin-manager"),
+		plugins: make(map[api.PluginName]api.Plugin),
+		tel:     telemetry.NewNoopTelemetry(),
+	}
+	user := &clusterPlugin{MockPlugin: pluginmock.NewMockPlugin(ctl)}
+	mgr.plugins["clusterplugin"] = user
+	mgr.plugins["mockplugin"] = pluginmock.NewMockPlugin(ctl)
+
+	mgr.SetCluster(api.Cluster{})
+	require.NotNil(t, user.cluster, "Expected plugin using the cluster to get it")
+}
@@ -0,0 +1,288 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package pluginmanager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/microsoft/retina/pkg/metrics"
+	"github.com/microsoft/retina/pkg/plugin/api"
+	"github.com/microsoft/retina/pkg/utils"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// PluginState is the state of a plugin supervised by the plugin manager.
+type PluginState string
+
+const (
+	// PluginStateStarting is the state of a plugin being reconciled and started.
+	PluginStateStarting PluginState = "starting"
+	// PluginStateRunning is the state of a plugin started successfully.
+	PluginStateRunning PluginState = "running"
+	// PluginStateDegraded is the state of a plugin which failed and waits to be restarted.
+	PluginStateDegraded PluginState = "degraded"
+	// PluginStateFailed is the state of a plugin which failed too many times in a row and is no longer restarted.
+	PluginStateFailed PluginState = "failed"
+)
+
+// PluginStatusPath is the path of the agent API serving the status of the plugins.
+const PluginStatusPath = "/plugins"
+
+var pluginStates = []PluginState{PluginStateStarting, PluginStateRunning, PluginStateDegraded, PluginStateFailed}
+
+var (
+	// restartBackoffInitial is the delay before restarting a plugin after its first failure,
+	// doubled after each consecutive failure up to restartBackoffMax.
+	restartBackoffInitial = time.Second
+	restartBackoffMax     = 2 * time.Minute
+	// maxConsecutiveFailures is the number of consecutive failures after which a plugin is no longer restarted.
+	maxConsecutiveFailures = 5
+	// stableRunTime is how long a plugin must run before failing for its previous failures to be forgotten.
+	stableRunTime = 5 * time.Minute
+)
+
+// PluginStatus is the status of a plugin supervised by the plugin manager.
+type PluginStatus struct {
+	Name                string      `json:"name"`
+	State               PluginState `json:"state"`
+	Restarts            int         `json:"restarts"`
+	ConsecutiveFailures int         `json:"consecutiveFailures"`
+	LastError           string      `json:"lastError,omitempty"`
+	LastTransitionTime  time.Time   `json:"lastTransitionTime"`
+	// Attachments are the eBPF programs of the running plugin attached to the kernel, for the plugins reporting them.
+	Attachments []api.Attachment `json:"attachments,omitempty"`
+}
+
+// pluginSupervisor is the supervisor of a running plugin.
+type pluginSupervisor struct {
+	// cancel cancels the context of the plugin, which stops the supervisor.
+	cancel context.CancelFunc
+	// done is closed once the supervisor exits.
+	done chan struct{}
+	// stopped tells the supervisor stopped the plugin when it failed too many times in a row. It is written by the
+	// supervisor before done is closed.
+	stopped bool
+}
+
+// startPluginLocked starts the supervisor of the plugin in the context of the plugin manager, and must be called
+// with p.mu held.
+func (p *PluginManager) startPluginLocked(name api.PluginName, plugin api.Plugin) {
+	if p.supervisors == nil {
+		p.supervisors = map[api.PluginName]*pluginSupervisor{}
+	}
+	ctx, cancel := context.WithCancel(p.ctx)
+	supervisor := &pluginSupervisor{cancel: cancel, done: make(chan struct{})}
+	p.supervisors[name] = supervisor
+	p.updatePluginStatus(name, PluginStateStarting, nil)
+	if reporter, ok := plugin.(api.AttachmentReporter); ok {
+		p.statusMu.Lock()
+		if p.reporters == nil {
+			p.reporters = map[api.PluginName]api.AttachmentReporter{}
+		}
+		p.reporters[name] = reporter
+		p.statusMu.Unlock()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(supervisor.done)
+		supervisor.stopped = p.supervise(ctx, name, plugin)
+	}()
+}
+
+// stopPluginLocked stops the supervisor of the plugin and waits for it to exit, then stops the plugin, which
+// detaches its eBPF programs if it has any, unless the supervisor already stopped it. It must be called with p.mu
+// held.
+func (p *PluginManager) stopPluginLocked(name api.PluginName, plugin api.Plugin) {
+	stopped := false
+	if supervisor, ok := p.supervisors[name]; ok {
+		supervisor.cancel()
+		<-supervisor.done
+		stopped = p.stoppedBySupervisorLocked(name)
+		delete(p.supervisors, name)
+	}
+	// The status is deleted first, so that the attachments of the plugin are not read while it stops.
+	p.deletePluginStatus(name)
+	if stopped {
+		return
+	}
+	if err := plugin.Stop(); err != nil {
+		p.l.Error("failed to stop plugin", zap.String("name", string(name)), zap.Error(err))
+	}
+}
+
+// stoppedBySupervisorLocked tells the supervisor of the plugin exited after stopping the plugin, which must not be
+// stopped again. It must be called with p.mu held.
+func (p *PluginManager) stoppedBySupervisorLocked(name api.PluginName) bool {
+	supervisor, ok := p.supervisors[name]
+	if !ok {
+		return false
+	}
+	select {
+	case <-supervisor.done:
+		return supervisor.stopped
+	default:
+		return false
+	}
+}
+
+// supervise reconciles and starts the plugin, and restarts it through Reconcile with an exponential backoff
+// whenever it fails, until the context is done or the plugin fails maxConsecutiveFailures times in a row, in which
+// case it stops the plugin and returns true. A failing plugin never affects the other plugins.
+func (p *PluginManager) supervise(ctx context.Context, name api.PluginName, plugin api.Plugin) bool {
+	backoff := restartBackoffInitial
+	failures := 0
+	for {
+		p.updatePluginStatus(name, PluginStateStarting, nil)
+		startTime := time.Now()
+		// Each attempt runs in its own context, so the goroutines a failed attempt started are cancelled before the
+		// plugin is restarted.
+		attemptCtx, cancelAttempt := context.WithCancel(ctx)
+		err := p.reconcileAndStart(attemptCtx, name, plugin)
+		if err == nil && ctx.Err() == nil {
+			// The plugin returned without an error, it keeps running in the background until it is stopped.
+			<-ctx.Done()
+		}
+		cancelAttempt()
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if time.Since(startTime) >= stableRunTime {
+			failures = 0
+			backoff = restartBackoffInitial
+		}
+		failures++
+		if failures >= maxConsecutiveFailures {
+			p.l.Error("plugin failed too many times in a row, it will not be restarted",
+				zap.String("name", string(name)), zap.Int("failures", failures), zap.Error(err))
+			p.updatePluginStatus(name, PluginStateFailed, err, func(status *PluginStatus) {
+				status.ConsecutiveFailures = failures
+			})
+			if err := plugin.Stop(); err != nil {
+				p.l.Error("failed to stop plugin", zap.String("name", string(name)), zap.Error(err))
+			}
+			return true
+		}
+
+		p.l.Warn("plugin failed, restarting it",
+			zap.String("name", string(name)), zap.Int("failures", failures), zap.Duration("backoff", backoff), zap.Error(err))
+		p.updatePluginStatus(name, PluginStateDegraded, err, func(status *PluginStatus) {
+			status.ConsecutiveFailures = failures
+		})
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, restartBackoffMax)
+
+		metrics.PluginManagerPluginRestartsCounter.WithLabelValues(string(name)).Inc()
+		p.updatePluginStatus(name, PluginStateDegraded, nil, func(status *PluginStatus) {
+			status.Restarts++
+		})
+	}
+}
+
+// reconcileAndStart reconciles the plugin and runs it until it returns. A panic of the plugin is returned as an error,
+// so it is restarted like any other failure instead of crashing the agent.
+func (p *PluginManager) reconcileAndStart(ctx context.Context, name api.PluginName, plugin api.Plugin) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.l.Error("plugin panicked", zap.String("name", string(name)), zap.Any("panic", r), zap.Stack("stack"))
+			err = errors.Errorf("plugin %s panicked: %v", name, r)
+		}
+	}()
+
+	reconcilectx, cancel := context.WithTimeout(ctx, MAX_RECONCILE_TIME)
+	defer cancel()
+	if err := p.Reconcile(reconcilectx, plugin); err != nil {
+		// Update control plane metrics counter
+		metrics.PluginManagerFailedToReconcileCounter.WithLabelValues(plugin.Name()).Inc()
+		return errors.Wrapf(err, "failed to reconcile plugin %s", plugin.Name())
+	}
+
+	p.updatePluginStatus(name, PluginStateRunning, nil)
+	p.l.Info(fmt.Sprintf("starting plugin %s", plugin.Name()))
+	return errors.Wrapf(plugin.Start(ctx), "failed to start plugin %s", plugin.Name())
+}
+
+// updatePluginStatus moves the plugin to the state, records the error when it is not nil, and applies the updates.
+func (p *PluginManager) updatePluginStatus(name api.PluginName, state PluginState, err error, updates ...func(status *PluginStatus)) {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+
+	if p.statuses == nil {
+		p.statuses = map[api.PluginName]*PluginStatus{}
+	}
+	status, ok := p.statuses[name]
+	if !ok {
+		status = &PluginStatus{Name: string(name)}
+		p.statuses[name] = status
+	}
+	if status.State != state {
+		status.State = state
+		status.LastTransitionTime = time.Now()
+	}
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	for _, update := range updates {
+		update(status)
+	}
+
+	for _, s := range pluginStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		metrics.PluginManagerPluginStateGauge.WithLabelValues(string(name), string(s)).Set(value)
+	}
+}
+
+// deletePluginStatus deletes the status of the plugin, and its state from the metrics.
+func (p *PluginManager) deletePluginStatus(name api.PluginName) {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+
+	delete(p.statuses, name)
+	delete(p.reporters, name)
+	for _, s := range pluginStates {
+		metrics.PluginManagerPluginStateGauge.DeleteLabelValues(string(name), string(s))
+	}
+}
+
+// PluginStatuses returns the status of the plugins sorted by name.
+func (p *PluginManager) PluginStatuses() []PluginStatus {
+	p.statusMu.RLock()
+	defer p.statusMu.RUnlock()
+
+	statuses := make([]PluginStatus, 0, len(p.statuses))
+	for name, status := range p.statuses {
+		status := *status
+		// The plugin is not reconciled while it is running, which cannot change while the status is locked.
+		if reporter, ok := p.reporters[name]; ok && status.State == PluginStateRunning {
+			status.Attachments = reporter.Attachments()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// PluginStatusHandler serves the status of the plugins as JSON.
+func (p *PluginManager) PluginStatusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if err := utils.EncodeResponseBody(w, p.PluginStatuses()); err != nil {
+			p.l.Error("failed to encode plugin statuses", zap.Error(err))
+		}
+	}
+}
@@ -5,6 +5,7 @@ package servermanager
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/server"
@@ -38,6 +39,26 @@ func (s *HTTPServer) Init() error {
 	return nil
 }
 
+// RegisterHandler registers the handler for the pattern, and must be called after Init.
+func (s *HTTPServer) RegisterHandler(pattern string, handler http.Handler) {
+	s.router.Handle(pattern, handler)
+}
+
+// AddHealthzCheck adds a liveness check of the agent, and must be called after Init.
+func (s *HTTPServer) AddHealthzCheck(name string, check server.Check) {
+	s.router.AddHealthzCheck(name, check)
+}
+
+// AddReadyzCheck adds a readiness check of the agent, and must be called after Init.
+func (s *HTTPServer) AddReadyzCheck(name string, check server.Check) {
+	s.router.AddReadyzCheck(name, check)
+}
+
+// AddDebugState adds the internal state of a component to the debug state of the agent, and must be called after Init.
+func (s *HTTPServer) AddDebugState(name string, state server.StateFunc) {
+	s.router.AddDebugState(name, state)
+}
+
 func (s *HTTPServer) Start(ctx context.Context) error {
 	s.l.Info("Starting HTTP server ...", zap.String("host", s.host), zap.Int("port", s.port))
 	return s.router.Start(ctx, fmt.Sprintf("%s:%d", s.host, s.port))
@@ -30,4 +30,16 @@ type WatcherManager struct {
 	refreshRate time.Duration
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
+
+	statusMu sync.RWMutex
+	statuses map[string]*WatcherStatus
+}
+
+// WatcherStatus is the state of the refreshes of a watcher.
+type WatcherStatus struct {
+	Name string `json:"name"`
+	// Running is false once the watcher stopped refreshing, after it failed to refresh.
+	Running     bool      `json:"running"`
+	LastRefresh time.Time `json:"lastRefresh"`
+	LastError   string    `json:"lastError,omitempty"`
 }
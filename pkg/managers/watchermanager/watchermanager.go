@@ -6,6 +6,7 @@ package watchermanager
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/microsoft/retina/pkg/log"
@@ -17,6 +18,8 @@ import (
 const (
 	// DefaultRefreshRate is the default refresh rate for watchers.
 	DefaultRefreshRate = 30 * time.Second
+	// staleRefreshes is the number of refresh intervals after which a watcher which did not refresh is stale.
+	staleRefreshes = 3
 )
 
 func NewWatcherManager() *WatcherManager {
@@ -39,6 +42,7 @@ func (wm *WatcherManager) Start(ctx context.Context) error {
 			wm.l.Error("init failed", zap.String("watcher_type", fmt.Sprintf("%T", w)))
 			return err
 		}
+		wm.updateStatus(w, true, nil)
 		wm.wg.Add(1)
 		go wm.runWatcher(newCtx, w)
 		wm.l.Info("watcher started", zap.String("watcher_type", fmt.Sprintf("%T", w)))
@@ -72,6 +76,7 @@ func (wm *WatcherManager) runWatcher(ctx context.Context, w IWatcher) error {
 			return nil
 		case <-ticker.C:
 			err := w.Refresh(ctx)
+			wm.updateStatus(w, err == nil, err)
 			if err != nil {
 				wm.l.Error("refresh failed", zap.Error(err))
 				return err
@@ -79,3 +84,52 @@ func (wm *WatcherManager) runWatcher(ctx context.Context, w IWatcher) error {
 		}
 	}
 }
+
+func (wm *WatcherManager) updateStatus(w IWatcher, running bool, err error) {
+	wm.statusMu.Lock()
+	defer wm.statusMu.Unlock()
+
+	if wm.statuses == nil {
+		wm.statuses = map[string]*WatcherStatus{}
+	}
+	name := fmt.Sprintf("%T", w)
+	status, ok := wm.statuses[name]
+	if !ok {
+		status = &WatcherStatus{Name: name}
+		wm.statuses[name] = status
+	}
+	status.Running = running
+	if err != nil {
+		status.LastError = err.Error()
+		return
+	}
+	status.LastRefresh = time.Now()
+}
+
+// Statuses returns the state of the refreshes of the watchers sorted by name.
+func (wm *WatcherManager) Statuses() []WatcherStatus {
+	wm.statusMu.RLock()
+	defer wm.statusMu.RUnlock()
+
+	statuses := make([]WatcherStatus, 0, len(wm.statuses))
+	for _, status := range wm.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// ReadyzCheck fails when a watcher stopped refreshing, or is stale.
+func (wm *WatcherManager) ReadyzCheck() error {
+	for _, status := range wm.Statuses() {
+		if !status.Running {
+			return fmt.Errorf("watcher %s stopped refreshing: %s", status.Name, status.LastError)
+		}
+		if time.Since(status.LastRefresh) > staleRefreshes*wm.refreshRate {
+			return fmt.Errorf("watcher %s did not refresh since %s", status.Name, status.LastRefresh.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/microsoft/retina/pkg/log"
 	mock "github.com/microsoft/retina/pkg/managers/watchermanager/mocks"
@@ -63,3 +64,34 @@ func TestWatcherStopWithoutStart(t *testing.T) {
 	err := mgr.Stop(context.Background())
 	require.Nil(t, err, "Expected no error when stopping watcher manager without starting it")
 }
+
+func TestWatcherStatuses(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+
+	mockWatcher := mock.NewMockIWatcher(ctl)
+	mockWatcher.EXPECT().Init(gomock.Any()).Return(nil).Times(1)
+	mockWatcher.EXPECT().Refresh(gomock.Any()).Return(errors.New("refresh failed")).Times(1)
+	mockWatcher.EXPECT().Stop(gomock.Any()).Return(nil).Times(1)
+
+	mgr := NewWatcherManager()
+	mgr.Watchers = []IWatcher{mockWatcher}
+	mgr.refreshRate = 10 * time.Millisecond
+
+	require.NoError(t, mgr.Start(context.Background()))
+	statuses := mgr.Statuses()
+	require.Len(t, statuses, 1)
+	require.True(t, statuses[0].Running)
+	require.False(t, statuses[0].LastRefresh.IsZero())
+	require.NoError(t, mgr.ReadyzCheck())
+
+	// The watcher stops refreshing once a refresh fails.
+	require.Eventually(t, func() bool {
+		return !mgr.Statuses()[0].Running
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "refresh failed", mgr.Statuses()[0].LastError)
+	require.ErrorContains(t, mgr.ReadyzCheck(), "stopped refreshing")
+
+	require.NoError(t, mgr.Stop(context.Background()))
+}
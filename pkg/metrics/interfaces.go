@@ -17,6 +17,7 @@ type ICounterVec interface {
 type IGaugeVec interface {
 	WithLabelValues(lvs ...string) prometheus.Gauge
 	GetMetricWithLabelValues(lvs ...string) (prometheus.Gauge, error)
+	DeleteLabelValues(lvs ...string) bool
 }
 
 type IHistogramVec interface {
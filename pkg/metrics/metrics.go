@@ -123,6 +123,19 @@ func InitializeMetrics() {
 		pluginManagerFailedToReconcileCounterDescription,
 		utils.Reason,
 	)
+	PluginManagerPluginStateGauge = exporter.CreatePrometheusGaugeVecForControlPlaneMetric(
+		exporter.DefaultRegistry,
+		pluginManagerPluginStateGaugeName,
+		pluginManagerPluginStateGaugeDescription,
+		utils.Plugin,
+		utils.State,
+	)
+	PluginManagerPluginRestartsCounter = exporter.CreatePrometheusCounterVecForControlPlaneMetric(
+		exporter.DefaultRegistry,
+		pluginManagerPluginRestartsCounterName,
+		pluginManagerPluginRestartsCounterDescription,
+		utils.Plugin,
+	)
 
 	// Lost Events defines the number of packets lost from reading eBPF maps
 	LostEventsCounter = exporter.CreatePrometheusCounterVecForControlPlaneMetric(
@@ -100,6 +100,24 @@ func (m *MockIGaugeVec) EXPECT() *MockIGaugeVecMockRecorder {
 	return m.recorder
 }
 
+// DeleteLabelValues mocks base method.
+func (m *MockIGaugeVec) DeleteLabelValues(lvs ...string) bool {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range lvs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DeleteLabelValues", varargs...)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// DeleteLabelValues indicates an expected call of DeleteLabelValues.
+func (mr *MockIGaugeVecMockRecorder) DeleteLabelValues(lvs ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLabelValues", reflect.TypeOf((*MockIGaugeVec)(nil).DeleteLabelValues), lvs...)
+}
+
 // GetMetricWithLabelValues mocks base method.
 func (m *MockIGaugeVec) GetMetricWithLabelValues(lvs ...string) (prometheus.Gauge, error) {
 	m.ctrl.T.Helper()
@@ -11,6 +11,8 @@ import (
 const (
 	// Control plane metrics
 	pluginManagerFailedToReconcileCounterName = "plugin_manager_failed_to_reconcile"
+	pluginManagerPluginStateGaugeName         = "plugin_manager_plugin_state"
+	pluginManagerPluginRestartsCounterName    = "plugin_manager_plugin_restarts"
 	lostEventsCounterName                     = "lost_events_counter"
 
 	// Windows
@@ -43,6 +45,8 @@ const (
 
 	// Control plane metrics
 	pluginManagerFailedToReconcileCounterDescription = "Number of times the plugin manager failed to reconcile the plugins"
+	pluginManagerPluginStateGaugeDescription         = "State of the plugins supervised by the plugin manager, 1 for the current state of each plugin"
+	pluginManagerPluginRestartsCounterDescription    = "Number of times the plugin manager restarted the plugins after they failed"
 	lostEventsCounterDescription                     = "Number of events lost in control plane"
 )
 
@@ -83,6 +87,8 @@ var (
 
 	// Control Plane Metrics
 	PluginManagerFailedToReconcileCounter ICounterVec
+	PluginManagerPluginStateGauge         IGaugeVec
+	PluginManagerPluginRestartsCounter    ICounterVec
 	LostEventsCounter                     ICounterVec
 
 	// DNS Metrics.
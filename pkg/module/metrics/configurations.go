@@ -0,0 +1,138 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package metrics
+
+import (
+	"github.com/cilium/cilium/api/v1/flow"
+	api "github.com/microsoft/retina/crd/api/v1alpha1"
+	"github.com/microsoft/retina/crd/api/v1alpha1/validations"
+)
+
+// metricsScope is the scope of a MetricsConfiguration, the namespaces its metrics are collected for.
+type metricsScope struct {
+	included map[string]struct{}
+	excluded map[string]struct{}
+	// selected tells the included namespaces are selected by the namespace selector of the MetricsConfiguration, in
+	// which case only they are in the scope, even when the selector selects no namespace.
+	selected bool
+}
+
+// newMetricsScope returns the scope of the MetricsConfiguration spec, whose namespace selector is resolved to the
+// included namespaces by the MetricsConfiguration controller of the agent.
+func newMetricsScope(spec api.MetricsSpec) metricsScope {
+	s := metricsScope{
+		included: make(map[string]struct{}, len(spec.Namespaces.Include)),
+		excluded: make(map[string]struct{}, len(spec.Namespaces.Exclude)),
+		selected: spec.NamespaceSelector != nil,
+	}
+	for _, ns := range spec.Namespaces.Include {
+		s.included[ns] = struct{}{}
+	}
+	for _, ns := range spec.Namespaces.Exclude {
+		s.excluded[ns] = struct{}{}
+	}
+	return s
+}
+
+// hasNamespace checks if the namespace is in the scope. Included namespaces take precedence over excluded ones,
+// as in nsOfInterest.
+func (s metricsScope) hasNamespace(ns string) bool {
+	if len(s.included) > 0 || s.selected {
+		_, ok := s.included[ns]
+		return ok
+	}
+	if len(s.excluded) > 0 {
+		_, ok := s.excluded[ns]
+		return !ok
+	}
+	return true
+}
+
+// scopedMetrics processes the flows of a metric only if their source or destination is in the scope of one of the
+// MetricsConfigurations configuring the metric.
+type scopedMetrics struct {
+	AdvMetricsInterface
+	scopes []metricsScope
+}
+
+func (s *scopedMetrics) ProcessFlow(f *flow.Flow) {
+	for _, scope := range s.scopes {
+		if scope.hasNamespace(f.GetSource().GetNamespace()) || scope.hasNamespace(f.GetDestination().GetNamespace()) {
+			s.AdvMetricsInterface.ProcessFlow(f)
+			return
+		}
+	}
+}
+
+// mergeConfigurations merges the MetricsConfigurations into the spec of the metrics module, with the union of their
+// metrics and namespaces, and returns the scopes of each metric.
+// The spec includes the namespaces included by the MetricsConfigurations when all of them include namespaces.
+// Otherwise, it excludes the namespaces excluded by all the other MetricsConfigurations and included by none, and the
+// scopes of each metric narrow it down to the namespaces of the MetricsConfigurations configuring the metric.
+// A metric configured by several MetricsConfigurations with different labels is collected as configured by the
+// oldest one, as reported in the status of the others by the operator.
+func mergeConfigurations(configs []*api.MetricsConfiguration) (*api.MetricsSpec, map[string][]metricsScope) {
+	sorted := make([]*api.MetricsConfiguration, len(configs))
+	copy(sorted, configs)
+	validations.SortMetricsConfigurations(sorted)
+
+	spec := &api.MetricsSpec{}
+	scopes := make(map[string][]metricsScope)
+	contextOptions := make(map[string]api.MetricsContextOptions)
+	included := make(map[string]struct{})
+	// excluded is nil until a MetricsConfiguration collects the metrics of all the namespaces it does not exclude.
+	var excluded map[string]struct{}
+	for _, config := range sorted {
+		scope := newMetricsScope(config.Spec)
+		for _, ctxOption := range config.Spec.ContextOptions {
+			current, ok := contextOptions[ctxOption.MetricName]
+			if !ok {
+				contextOptions[ctxOption.MetricName] = ctxOption
+				spec.ContextOptions = append(spec.ContextOptions, ctxOption)
+			} else if !validations.MetricsContextOptionsCompare([]api.MetricsContextOptions{current}, []api.MetricsContextOptions{ctxOption}) {
+				continue
+			}
+			scopes[ctxOption.MetricName] = append(scopes[ctxOption.MetricName], scope)
+		}
+
+		if len(scope.included) > 0 || scope.selected {
+			for _, ns := range config.Spec.Namespaces.Include {
+				if _, ok := included[ns]; !ok {
+					included[ns] = struct{}{}
+					spec.Namespaces.Include = append(spec.Namespaces.Include, ns)
+				}
+			}
+			continue
+		}
+		// A namespace is excluded only if it is excluded by all the MetricsConfigurations excluding namespaces.
+		if excluded == nil {
+			excluded = make(map[string]struct{}, len(scope.excluded))
+			for ns := range scope.excluded {
+				excluded[ns] = struct{}{}
+			}
+		} else {
+			for ns := range excluded {
+				if _, ok := scope.excluded[ns]; !ok {
+					delete(excluded, ns)
+				}
+			}
+		}
+	}
+
+	if excluded != nil {
+		spec.Namespaces.Include = nil
+		for _, config := range sorted {
+			for _, ns := range config.Spec.Namespaces.Exclude {
+				if _, ok := included[ns]; ok {
+					continue
+				}
+				if _, ok := excluded[ns]; ok {
+					spec.Namespaces.Exclude = append(spec.Namespaces.Exclude, ns)
+					delete(excluded, ns)
+				}
+			}
+		}
+	}
+
+	return spec, scopes
+}
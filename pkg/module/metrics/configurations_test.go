@@ -0,0 +1,164 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	api "github.com/microsoft/retina/crd/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestMetricsConfiguration(name string, created time.Time, namespaces api.MetricsNamespaces, ctxOptions ...api.MetricsContextOptions) *api.MetricsConfiguration {
+	return &api.MetricsConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: api.MetricsSpec{
+			ContextOptions: ctxOptions,
+			Namespaces:     namespaces,
+		},
+	}
+}
+
+func TestMergeConfigurations(t *testing.T) {
+	now := time.Now()
+	platform := newTestMetricsConfiguration("platform", now.Add(-time.Hour),
+		api.MetricsNamespaces{Include: []string{"kube-system", "default"}},
+		api.MetricsContextOptions{MetricName: "drop_count", SourceLabels: []string{"ip"}},
+		api.MetricsContextOptions{MetricName: "forward_count", SourceLabels: []string{"ip"}},
+	)
+	app := newTestMetricsConfiguration("app", now,
+		api.MetricsNamespaces{Include: []string{"default", "app"}},
+		api.MetricsContextOptions{MetricName: "drop_count", SourceLabels: []string{"podname"}},
+		api.MetricsContextOptions{MetricName: "forward_count", SourceLabels: []string{"ip"}},
+		api.MetricsContextOptions{MetricName: "dns", SourceLabels: []string{"podname"}},
+	)
+
+	spec, scopes := mergeConfigurations([]*api.MetricsConfiguration{app, platform})
+
+	// The union of the metrics, as configured by the oldest metrics configuration when they are in conflict.
+	assert.Equal(t, []api.MetricsContextOptions{
+		{MetricName: "drop_count", SourceLabels: []string{"ip"}},
+		{MetricName: "forward_count", SourceLabels: []string{"ip"}},
+		{MetricName: "dns", SourceLabels: []string{"podname"}},
+	}, spec.ContextOptions)
+	assert.Equal(t, []string{"kube-system", "default", "app"}, spec.Namespaces.Include)
+	assert.Empty(t, spec.Namespaces.Exclude)
+
+	platformScope := newMetricsScope(platform.Spec)
+	appScope := newMetricsScope(app.Spec)
+	assert.Equal(t, []metricsScope{platformScope}, scopes["drop_count"])
+	assert.Equal(t, []metricsScope{platformScope, appScope}, scopes["forward_count"])
+	assert.Equal(t, []metricsScope{appScope}, scopes["dns"])
+}
+
+func TestMergeConfigurationsExcludedNamespaces(t *testing.T) {
+	now := time.Now()
+	first := newTestMetricsConfiguration("first", now.Add(-time.Hour),
+		api.MetricsNamespaces{Exclude: []string{"kube-system", "default"}},
+		api.MetricsContextOptions{MetricName: "drop_count"},
+	)
+	second := newTestMetricsConfiguration("second", now,
+		api.MetricsNamespaces{Exclude: []string{"kube-system"}},
+		api.MetricsContextOptions{MetricName: "drop_count"},
+	)
+
+	spec, scopes := mergeConfigurations([]*api.MetricsConfiguration{first, second})
+
+	// A namespace is excluded only when all the metrics configurations exclude it.
+	assert.Empty(t, spec.Namespaces.Include)
+	assert.Equal(t, []string{"kube-system"}, spec.Namespaces.Exclude)
+	assert.Len(t, scopes["drop_count"], 2)
+	assert.Len(t, scopes["drop_count"][0].excluded, 2, "merging must not modify the scopes")
+}
+
+func TestMergeConfigurationsIncludedAndExcludedNamespaces(t *testing.T) {
+	now := time.Now()
+	teamA := newTestMetricsConfiguration("team-a", now.Add(-time.Hour),
+		api.MetricsNamespaces{Include: []string{"team-a", "kube-system"}},
+		api.MetricsContextOptions{MetricName: "drop_count"},
+	)
+	platform := newTestMetricsConfiguration("platform", now,
+		api.MetricsNamespaces{Exclude: []string{"kube-system", "default"}},
+		api.MetricsContextOptions{MetricName: "forward_count"},
+	)
+
+	spec, scopes := mergeConfigurations([]*api.MetricsConfiguration{teamA, platform})
+
+	// The namespaces not excluded by platform are collected too, except the ones no metrics configuration includes.
+	assert.Empty(t, spec.Namespaces.Include)
+	assert.Equal(t, []string{"default"}, spec.Namespaces.Exclude)
+	// The scopes of the metrics narrow them down to their metrics configurations.
+	assert.True(t, scopes["drop_count"][0].hasNamespace("team-a"))
+	assert.False(t, scopes["drop_count"][0].hasNamespace("team-b"))
+	assert.True(t, scopes["forward_count"][0].hasNamespace("team-b"))
+	assert.False(t, scopes["forward_count"][0].hasNamespace("kube-system"))
+
+	// A metrics configuration excluding no namespace collects the metrics of all namespaces.
+	all := newTestMetricsConfiguration("all", now.Add(time.Hour),
+		api.MetricsNamespaces{Exclude: []string{}},
+		api.MetricsContextOptions{MetricName: "dns"},
+	)
+	spec, _ = mergeConfigurations([]*api.MetricsConfiguration{teamA, platform, all})
+	assert.Empty(t, spec.Namespaces.Include)
+	assert.Empty(t, spec.Namespaces.Exclude)
+}
+
+func TestScopedMetricsProcessFlow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	metricObj := NewMockAdvMetricsInterface(ctrl)
+	scoped := &scopedMetrics{
+		AdvMetricsInterface: metricObj,
+		scopes: []metricsScope{
+			newMetricsScope(api.MetricsSpec{Namespaces: api.MetricsNamespaces{Include: []string{"app"}}}),
+			newMetricsScope(api.MetricsSpec{Namespaces: api.MetricsNamespaces{Exclude: []string{"kube-system", "default"}}}),
+		},
+	}
+
+	newFlow := func(srcNamespace, dstNamespace string) *flow.Flow {
+		return &flow.Flow{
+			Source:      &flow.Endpoint{Namespace: srcNamespace},
+			Destination: &flow.Endpoint{Namespace: dstNamespace},
+		}
+	}
+	inScope := []*flow.Flow{
+		newFlow("app", "kube-system"),
+		newFlow("default", "app"),
+		newFlow("other", "default"),
+	}
+	for _, f := range inScope {
+		metricObj.EXPECT().ProcessFlow(f).Times(1)
+		scoped.ProcessFlow(f)
+	}
+
+	// Neither the source nor the destination is in the scope of the metric.
+	metricObj.EXPECT().ProcessFlow(gomock.Any()).Times(0)
+	scoped.ProcessFlow(newFlow("kube-system", "default"))
+}
+
+func TestMetricsScopeNamespaceSelector(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "app"}}
+
+	// The namespaces selected by the namespace selector are added to the included namespaces.
+	selected := newMetricsScope(api.MetricsSpec{NamespaceSelector: selector, Namespaces: api.MetricsNamespaces{Include: []string{"app"}}})
+	assert.True(t, selected.hasNamespace("app"))
+	assert.False(t, selected.hasNamespace("default"))
+
+	// A namespace selector selecting no namespace selects no namespace, instead of all of them.
+	none := newMetricsScope(api.MetricsSpec{NamespaceSelector: selector})
+	assert.False(t, none.hasNamespace("app"))
+	assert.False(t, none.hasNamespace("default"))
+	assert.False(t, none.hasNamespace(""))
+
+	// Without namespace selector nor namespaces, all the namespaces are in the scope.
+	all := newMetricsScope(api.MetricsSpec{})
+	assert.True(t, all.hasNamespace("default"))
+}
@@ -139,10 +139,9 @@ func (lm *LatencyMetrics) Init(metricName string) {
 
 	// Register callback.
 	// Everytime the apiserver object is updated, the callback function is called and it updates the ip variable.
-	fn := pubsub.CallBackFunc(lm.apiserverWatcherCallbackFn)
 	// Check if callback is already registered.
 	if lm.callbackId == "" {
-		lm.callbackId = ps.Subscribe(common.PubSubAPIServer, &fn)
+		lm.callbackId = cc.APIServerTopic.Subscribe(ps, lm.apiserverWatcherCallbackFn)
 	}
 
 	go lm.cache.Start()
@@ -167,7 +166,7 @@ func (lm *LatencyMetrics) Clean() {
 
 	// Unsubscribe callback.
 	if lm.callbackId != "" {
-		if err := ps.Unsubscribe(common.PubSubAPIServer, lm.callbackId); err != nil {
+		if err := cc.APIServerTopic.Unsubscribe(ps, lm.callbackId); err != nil {
 			lm.l.Error("failed to unsubscribe callback", zap.Error(err))
 			return
 		}
@@ -303,8 +302,7 @@ func (lm *LatencyMetrics) calculateLatency(f *flow.Flow) {
 	}
 }
 
-func (lm *LatencyMetrics) apiserverWatcherCallbackFn(obj interface{}) {
-	event := obj.(*cc.CacheEvent)
+func (lm *LatencyMetrics) apiserverWatcherCallbackFn(event *cc.CacheEvent) {
 	if event == nil {
 		return
 	}
@@ -5,6 +5,7 @@ package metrics
 import (
 	"context"
 	"net"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -73,6 +74,10 @@ type Module struct {
 	// current metrics spec for metrics module
 	currentSpec *api.MetricsSpec
 
+	// currentScopes are the scopes of the metrics of the current spec, by metric name, when the spec is merged
+	// from MetricsConfigurations. The metrics are not scoped when it is nil.
+	currentScopes map[string][]metricsScope
+
 	// pubsub is the pubsub client
 	pubsub pubsub.PubSubInterface
 
@@ -139,8 +144,25 @@ func InitModule(ctx context.Context,
 }
 
 func (m *Module) Reconcile(spec *api.MetricsSpec) error {
+	return m.reconcile(spec, nil)
+}
+
+// ReconcileConfigurations reconciles the metric module with the MetricsConfigurations applying to the node.
+// Their metrics are merged, and each metric is collected for the namespaces of the MetricsConfigurations
+// configuring it.
+func (m *Module) ReconcileConfigurations(configs []*api.MetricsConfiguration) error {
+	if len(configs) == 0 && m.currentSpec == nil {
+		m.l.Debug("No metrics configuration. Not reconciling.")
+		return nil
+	}
+
+	spec, scopes := mergeConfigurations(configs)
+	return m.reconcile(spec, scopes)
+}
+
+func (m *Module) reconcile(spec *api.MetricsSpec, scopes map[string][]metricsScope) error {
 	// If the new spec has not changed, then do nothing.
-	if m.currentSpec != nil && m.currentSpec.Equals(spec) {
+	if m.currentSpec != nil && m.currentSpec.Equals(spec) && reflect.DeepEqual(m.currentScopes, scopes) {
 		m.l.Debug("Spec has not changed. Not reconciling.")
 		return nil
 	}
@@ -160,11 +182,14 @@ func (m *Module) Reconcile(spec *api.MetricsSpec) error {
 
 	m.updateNamespaceLists(spec)
 
-	if m.currentSpec == nil || !validations.MetricsContextOptionsCompare(m.currentSpec.ContextOptions, spec.ContextOptions) {
-		m.updateMetricsContexts(spec)
+	if m.currentSpec == nil ||
+		!validations.MetricsContextOptionsCompare(m.currentSpec.ContextOptions, spec.ContextOptions) ||
+		!reflect.DeepEqual(m.currentScopes, scopes) {
+		m.updateMetricsContexts(spec, scopes)
 	}
 
 	m.currentSpec = spec
+	m.currentScopes = scopes
 
 	newCtx, cancel := context.WithCancel(m.moduleCtx)
 	m.ctxCancel = cancel
@@ -189,9 +214,15 @@ func (m *Module) updateNamespaceLists(spec *api.MetricsSpec) {
 		m.appendExcludeList(spec.Namespaces.Exclude)
 		m.appendIncludeList([]string{})
 	}
+
+	// No namespace is included anymore, e.g. when the last MetricsConfiguration is deleted.
+	if len(spec.Namespaces.Include) == 0 && len(spec.Namespaces.Exclude) == 0 && len(m.includedNamespaces) > 0 {
+		m.l.Info("No namespaces to include or exclude")
+		m.appendIncludeList([]string{})
+	}
 }
 
-func (m *Module) updateMetricsContexts(spec *api.MetricsSpec) {
+func (m *Module) updateMetricsContexts(spec *api.MetricsSpec, scopes map[string][]metricsScope) {
 	// clean old metrics from registry (remove prometheus collectors and remove map entry)
 	// reset the advanced metrics registry
 	for key, metricObj := range m.registry {
@@ -249,6 +280,9 @@ func (m *Module) updateMetricsContexts(spec *api.MetricsSpec) {
 
 	for metricName, metricObj := range m.registry {
 		metricObj.Init(metricName)
+		if metricScopes, ok := scopes[metricName]; ok {
+			m.registry[metricName] = &scopedMetrics{AdvMetricsInterface: metricObj, scopes: metricScopes}
+		}
 	}
 }
 
@@ -258,8 +292,7 @@ func (m *Module) run(newCtx context.Context) {
 		return
 	}
 
-	cbFunc := pubsub.CallBackFunc(m.PodCallBackFn)
-	m.pubsubPodSub = m.pubsub.Subscribe(common.PubSubPods, &cbFunc)
+	m.pubsubPodSub = cache.PodsTopic.Subscribe(m.pubsub, m.PodCallBackFn)
 
 	m.wg.Add(1)
 	go func() {
@@ -316,7 +349,7 @@ func (m *Module) run(newCtx context.Context) {
 				m.applyDirtyPods()
 			case <-newCtx.Done():
 				m.l.Info("Context cancelled. Exiting.")
-				err := m.pubsub.Unsubscribe(common.PubSubPods, m.pubsubPodSub)
+				err := cache.PodsTopic.Unsubscribe(m.pubsub, m.pubsubPodSub)
 				if err != nil {
 					m.l.Error("Error unsubscribing from pubsub", zap.Error(err))
 				}
@@ -401,8 +434,7 @@ func (m *Module) appendExcludeList(ns []string) {
 	// needs to be added to filter manager and which needs to be removed
 }
 
-func (m *Module) PodCallBackFn(obj interface{}) {
-	event := obj.(*cache.CacheEvent)
+func (m *Module) PodCallBackFn(event *cache.CacheEvent) {
 	if event == nil {
 		return
 	}
@@ -54,6 +54,20 @@ func (mr *MockIModuleMockRecorder) Reconcile(spec any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconcile", reflect.TypeOf((*MockIModule)(nil).Reconcile), spec)
 }
 
+// ReconcileConfigurations mocks base method.
+func (m *MockIModule) ReconcileConfigurations(configs []*v1alpha1.MetricsConfiguration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileConfigurations", configs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcileConfigurations indicates an expected call of ReconcileConfigurations.
+func (mr *MockIModuleMockRecorder) ReconcileConfigurations(configs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileConfigurations", reflect.TypeOf((*MockIModule)(nil).ReconcileConfigurations), configs)
+}
+
 // MockAdvMetricsInterface is a mock of AdvMetricsInterface interface.
 type MockAdvMetricsInterface struct {
 	ctrl     *gomock.Controller
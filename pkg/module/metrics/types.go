@@ -62,6 +62,7 @@ const (
 //go:generate go run go.uber.org/mock/mockgen@v0.4.0 -source=types.go -destination=mock_types.go -package=metrics
 type IModule interface {
 	Reconcile(spec *api.MetricsSpec) error
+	ReconcileConfigurations(configs []*api.MetricsConfiguration) error
 }
 
 type enrichmentContext string
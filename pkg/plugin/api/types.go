@@ -6,8 +6,11 @@ package api
 
 import (
 	"context"
+	"time"
 
 	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
+	"github.com/microsoft/retina/pkg/common"
+	"k8s.io/client-go/kubernetes"
 )
 
 //go:generate go run go.uber.org/mock/mockgen@v0.4.0 -destination=mock/mock_plugin.go -copyright_file=../../lib/ignore_headers.txt -package=mock github.com/microsoft/retina/pkg/plugin/api Plugin
@@ -40,3 +43,45 @@ type Plugin interface {
 	// This can be useful for plugins that need to send data to other components for post-processing.
 	SetupChannel(chan *v1.Event) error
 }
+
+// Attachment is an eBPF program of a plugin attached to a hook of the kernel.
+type Attachment struct {
+	// Target is what the program is attached to, e.g. a network interface or a kernel function.
+	Target string `json:"target"`
+	// Error is why attaching the program failed, empty once it is attached.
+	Error string `json:"error,omitempty"`
+}
+
+// AttachmentReporter is implemented by the plugins attaching eBPF programs, to report the state of their attachments.
+type AttachmentReporter interface {
+	// Attachments returns the attachments of the running plugin.
+	Attachments() []Attachment
+}
+
+// NodeLister lists the nodes of the cluster known to the agent.
+type NodeLister interface {
+	// GetNodes returns the nodes of the cluster.
+	GetNodes() []*common.RetinaNode
+}
+
+// Cluster is the Kubernetes cluster the agent runs in.
+type Cluster struct {
+	// Client is the client of the Kubernetes API.
+	Client kubernetes.Interface
+	// Nodes lists the nodes of the cache of the agent, it is nil when pod level is disabled.
+	Nodes NodeLister
+}
+
+// ClusterUser is implemented by the plugins using the Kubernetes cluster, which the plugin manager gives them before
+// they are initialized.
+type ClusterUser interface {
+	// SetCluster sets the cluster the plugin runs in.
+	SetCluster(cluster Cluster)
+}
+
+// MetricsIntervalUpdater is implemented by the plugins publishing their metrics periodically, to change the interval of
+// the running plugin without stopping it.
+type MetricsIntervalUpdater interface {
+	// SetMetricsInterval sets the interval the plugin publishes its metrics at.
+	SetMetricsInterval(interval time.Duration)
+}
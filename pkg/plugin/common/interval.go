@@ -0,0 +1,40 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// MetricsInterval is the interval a plugin publishes its metrics at, which can be changed while the plugin runs.
+// The zero value is ready to use.
+type MetricsInterval struct {
+	mu       sync.Mutex
+	interval time.Duration
+	changed  chan struct{}
+}
+
+// Get returns the interval, or fallback until the interval is set, and a channel closed once the interval is changed.
+func (m *MetricsInterval) Get(fallback time.Duration) (time.Duration, <-chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.changed == nil {
+		m.changed = make(chan struct{})
+	}
+	if m.interval == 0 {
+		return fallback, m.changed
+	}
+	return m.interval, m.changed
+}
+
+// Set changes the interval, notifying the callers of Get.
+func (m *MetricsInterval) Set(interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interval = interval
+	if m.changed != nil {
+		close(m.changed)
+	}
+	m.changed = make(chan struct{})
+}
@@ -0,0 +1,36 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsInterval(t *testing.T) {
+	var m MetricsInterval
+
+	interval, changed := m.Get(time.Second)
+	require.Equal(t, time.Second, interval, "Expected fallback until the interval is set")
+	select {
+	case <-changed:
+		t.Fatal("Expected interval not to be changed")
+	default:
+	}
+
+	m.Set(time.Minute)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("Expected interval to be changed")
+	}
+	interval, changed = m.Get(time.Second)
+	require.Equal(t, time.Minute, interval)
+	select {
+	case <-changed:
+		t.Fatal("Expected new channel not to be closed")
+	default:
+	}
+}
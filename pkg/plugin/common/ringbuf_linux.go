@@ -0,0 +1,101 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package common
+
+import (
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+	"github.com/cilium/ebpf/perf"
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/microsoft/retina/pkg/log"
+	"go.uber.org/zap"
+)
+
+// lostEventsPollInterval is how often RingBufReader checks the kernel's lost events counter.
+const lostEventsPollInterval = time.Second
+
+// RingBufferSupported reports whether the running kernel supports BPF_MAP_TYPE_RINGBUF (5.8+).
+func RingBufferSupported() bool {
+	return features.HaveMapType(ebpf.RingBuf) == nil
+}
+
+// RingBufReader reads events from a BPF ring buffer and exposes them as perf records,
+// so plugins can switch between perf and ring buffers without changing their record pipeline.
+//
+// Ring buffers have no notion of lost samples on the reader side. Instead, eBPF programs
+// increment a per-CPU counter (lostMap) when bpf_ringbuf_output fails, and the reader
+// surfaces the delta as a record with LostSamples set, the same way perf.Reader does.
+type RingBufReader struct {
+	l         *log.ZapLogger
+	reader    *ringbuf.Reader
+	lostMap   *ebpf.Map
+	lastLost  uint64
+	lastCheck time.Time
+}
+
+// NewRingBufReader creates a reader for the given ring buffer map.
+// lostMap is optional, and must be a single entry BPF_MAP_TYPE_PERCPU_ARRAY of uint64 counters.
+func NewRingBufReader(l *log.ZapLogger, m, lostMap *ebpf.Map) (*RingBufReader, error) {
+	r, err := ringbuf.NewReader(m)
+	if err != nil {
+		return nil, err
+	}
+	l.Info("ring buffer reader created", zap.Any("Map", m.String()), zap.Int("BufferSize", r.BufferSize()))
+	return &RingBufReader{
+		l:       l,
+		reader:  r,
+		lostMap: lostMap,
+	}, nil
+}
+
+// Read blocks until a record is available in the ring buffer.
+// It returns perf.ErrClosed (os.ErrClosed) once the reader is closed.
+func (r *RingBufReader) Read() (perf.Record, error) {
+	if lost := r.lostSinceLastCheck(); lost > 0 {
+		return perf.Record{LostSamples: lost}, nil
+	}
+
+	record, err := r.reader.Read()
+	if err != nil {
+		return perf.Record{}, err
+	}
+	return perf.Record{
+		RawSample: record.RawSample,
+		Remaining: record.Remaining,
+	}, nil
+}
+
+// Close closes the underlying ring buffer reader, unblocking any pending Read.
+func (r *RingBufReader) Close() error {
+	return r.reader.Close()
+}
+
+// lostSinceLastCheck returns the number of events the kernel failed to write
+// to the ring buffer since the previous check. It polls at most once per lostEventsPollInterval.
+func (r *RingBufReader) lostSinceLastCheck() uint64 {
+	if r.lostMap == nil || time.Since(r.lastCheck) < lostEventsPollInterval {
+		return 0
+	}
+	r.lastCheck = time.Now()
+
+	var (
+		key    uint32
+		perCPU []uint64
+	)
+	if err := r.lostMap.Lookup(&key, &perCPU); err != nil {
+		r.l.Debug("failed to read ring buffer lost events counter", zap.Error(err))
+		return 0
+	}
+	var total uint64
+	for _, v := range perCPU {
+		total += v
+	}
+	if total <= r.lastLost {
+		return 0
+	}
+	lost := total - r.lastLost
+	r.lastLost = total
+	return lost
+}
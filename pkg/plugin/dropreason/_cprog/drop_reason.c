@@ -17,6 +17,16 @@ char __license[] SEC("license") = "Dual MIT/GPL";
 #define ETH_P_8021Q 0x8100
 #define ETH_P_ARP 0x0806
 #define TASK_COMM_LEN 16
+
+// USE_RING_BUFFER and RING_BUFFER_SIZE are set in dynamic.h at runtime
+// when the kernel supports BPF_MAP_TYPE_RINGBUF (5.8+).
+#ifndef USE_RING_BUFFER
+#define USE_RING_BUFFER 0
+#endif
+
+#ifndef RING_BUFFER_SIZE
+#define RING_BUFFER_SIZE (4 * 1024 * 1024)
+#endif
 // TODO (Vamsi): Add top 100 dropped connections with LRU map
 
 // Ref: https://elixir.bootlin.com/linux/latest/source/include/uapi/linux/if_packet.h#L26
@@ -57,11 +67,29 @@ struct packet
     // will send a perf event along with the usual aggregation in metricsmap
     bool in_filtermap;
 };
+#if USE_RING_BUFFER
+struct
+{
+    __uint(type, BPF_MAP_TYPE_RINGBUF);
+    __uint(max_entries, RING_BUFFER_SIZE);
+} dropreason_events SEC(".maps");
+#else
 struct
 {
     __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
     __uint(max_entries, 16384);
 } dropreason_events SEC(".maps");
+#endif
+
+// Number of events that could not be written to the ring buffer.
+// Perf buffers report lost samples to userspace on their own.
+struct
+{
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, __u32);
+    __type(value, __u64);
+} dropreason_lost_events SEC(".maps");
 
 // Define const variables to avoid warnings.
 const struct packet *unused __attribute__((unused));
@@ -141,7 +169,17 @@ void update_metrics_map(void *ctx, drop_reason_t drop_type, int ret_val, struct
             .drop_type = drop_type,
             .return_val = ret_val};
         p->key = key2;
+#if USE_RING_BUFFER
+        if (bpf_ringbuf_output(&dropreason_events, p, sizeof(struct packet), 0) != 0)
+        {
+            __u32 lost_key = 0;
+            __u64 *lost = bpf_map_lookup_elem(&dropreason_lost_events, &lost_key);
+            if (lost)
+                *lost += 1;
+        }
+#else
         bpf_perf_event_output(ctx, &dropreason_events, BPF_F_CURRENT_CPU, p, sizeof(struct packet));
+#endif
     };
 #endif
 #endif
@@ -78,6 +78,10 @@ func (dr *dropReason) Generate(ctx context.Context) error {
 		j = 1
 	}
 	st := fmt.Sprintf("#define ADVANCED_METRICS %d \n#define BYPASS_LOOKUP_IP_OF_INTEREST %d \n", i, j)
+	if !dr.cfg.DisableRingBuffer && ringBufferSupported() {
+		dr.l.Info("Kernel supports BPF ring buffer, using it for dropreason events")
+		st += fmt.Sprintf("#define USE_RING_BUFFER 1 \n#define RING_BUFFER_SIZE %d \n", ringBufferSize)
+	}
 	err := loader.WriteFile(ctx, dynamicHeaderPath, st)
 	if err != nil {
 		dr.l.Error("Error writing dynamic header", zap.Error(err))
@@ -133,13 +137,21 @@ func (dr *dropReason) Init() error {
 	bpfOutputFile := fmt.Sprintf("%s/%s", dir, bpfObjectFileName)
 
 	objs := &kprobeObjects{} //nolint:typecheck
+	lost := &lostEventsObjects{}
 	spec, err := ebpf.LoadCollectionSpec(bpfOutputFile)
 	if err != nil {
 		return err
 	}
+	// The events map type is decided at compile time by Generate.
+	if eventsSpec, ok := spec.Maps[eventsMapName]; ok {
+		dr.useRingBuffer = eventsSpec.Type == ebpf.RingBuf
+	}
 
 	// TODO remove the opts
-	if err := spec.LoadAndAssign(objs, &ebpf.CollectionOptions{
+	if err := spec.LoadAndAssign(&struct {
+		*kprobeObjects
+		*lostEventsObjects
+	}{objs, lost}, &ebpf.CollectionOptions{
 		Programs: ebpf.ProgramOptions{
 			LogLevel: 2,
 		},
@@ -151,11 +163,23 @@ func (dr *dropReason) Init() error {
 		return err
 	}
 
-	// read perf map
-	dr.reader, err = plugincommon.NewPerfReader(dr.l, objs.DropreasonEvents, perCPUBuffer, 1)
-	if err != nil {
-		dr.l.Error("Error NewReader: %w", zap.Error(err))
-		return err
+	dr.lostEventsMap = lost.DropreasonLostEvents
+
+	// read perf map or ring buffer
+	if dr.useRingBuffer {
+		reader, err := plugincommon.NewRingBufReader(dr.l, objs.DropreasonEvents, dr.lostEventsMap)
+		if err != nil {
+			dr.l.Error("Error NewRingBufReader: %w", zap.Error(err))
+			return err
+		}
+		dr.reader = reader
+	} else {
+		reader, err := plugincommon.NewPerfReader(dr.l, objs.DropreasonEvents, perCPUBuffer, 1)
+		if err != nil {
+			dr.l.Error("Error NewReader: %w", zap.Error(err))
+			return err
+		}
+		dr.reader = reader
 	}
 
 	dr.KNfHook, err = link.Kprobe(nfHookSlowFn, objs.NfHookSlow, nil)
@@ -236,6 +260,36 @@ func (dr *dropReason) Init() error {
 	return nil
 }
 
+// Attachments returns the kernel functions the probes of dropreason are attached to. The probes of the functions
+// missing from some kernels are left out when they are not attached.
+func (dr *dropReason) Attachments() []api.Attachment {
+	probes := []struct {
+		target   string
+		link     link.Link
+		optional bool
+	}{
+		{target: "kprobe/" + nfHookSlowFn, link: dr.KNfHook},
+		{target: "kretprobe/" + nfHookSlowFn, link: dr.KRetnfhook},
+		{target: "kretprobe/" + tcpConnectFn, link: dr.KRetTCPConnect},
+		{target: "kretprobe/" + intCskAcceptFn, link: dr.KTCPAccept},
+		{target: "kretprobe/" + intCskAcceptFn, link: dr.KRetTCPAccept},
+		{target: "kretprobe/" + nfNatInetFn, link: dr.KNfNatInet, optional: true},
+		{target: "kretprobe/" + nfNatInetFn, link: dr.KRetNfNatInet, optional: true},
+		{target: "kprobe/" + nfConntrackConfirmFn, link: dr.KNfConntrackConfirm, optional: true},
+		{target: "kretprobe/" + nfConntrackConfirmFn, link: dr.KRetNfConntrackConfirm, optional: true},
+	}
+	attachments := make([]api.Attachment, 0, len(probes))
+	for _, probe := range probes {
+		switch {
+		case probe.link != nil:
+			attachments = append(attachments, api.Attachment{Target: probe.target})
+		case !probe.optional:
+			attachments = append(attachments, api.Attachment{Target: probe.target, Error: "not attached"})
+		}
+	}
+	return attachments
+}
+
 func (dr *dropReason) Start(ctx context.Context) error {
 	dr.isRunning = true
 	dr.l.Info("Start listening for drop reason events...")
@@ -276,7 +330,12 @@ func (dr *dropReason) run(ctx context.Context) error {
 	go dr.readBasicMetricsData(ctx)
 
 	if dr.cfg.EnablePodLevel {
-		for i := 0; i < workers; i++ {
+		// A single worker keeps the ordering guaranteed by the ring buffer.
+		n := workers
+		if dr.useRingBuffer {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
 			dr.wg.Add(1)
 			go dr.processRecord(ctx, i)
 		}
@@ -296,16 +355,25 @@ func (dr *dropReason) run(ctx context.Context) error {
 	return nil
 }
 
+// SetMetricsInterval changes the interval the drop metrics are read from the metrics map at.
+func (dr *dropReason) SetMetricsInterval(interval time.Duration) {
+	dr.interval.Set(interval)
+}
+
 func (dr *dropReason) readBasicMetricsData(ctx context.Context) {
 	var dataKey dropMetricKey
 	var dataValue dropMetricValues
-	ticker := time.NewTicker(dr.cfg.MetricsInterval)
+	interval, changed := dr.interval.Get(dr.cfg.MetricsInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			dr.l.Info("Context is done, dropreason basic metrics loop will stop running")
 			return
+		case <-changed:
+			interval, changed = dr.interval.Get(dr.cfg.MetricsInterval)
+			ticker.Reset(interval)
 		case <-ticker.C:
 			var iter IMapIterator
 			iter = iMapIterator(dr.metricsMapData)
@@ -474,6 +542,9 @@ func (dr *dropReason) Stop() error {
 	if dr.metricsMapData != nil {
 		dr.metricsMapData.Close()
 	}
+	if dr.lostEventsMap != nil {
+		dr.lostEventsMap.Close()
+	}
 
 	if dr.reader != nil {
 		if err := dr.reader.Close(); err != nil {
@@ -486,6 +557,7 @@ func (dr *dropReason) Stop() error {
 	// as well as the producer of the records channel.
 	if dr.recordsChannel != nil {
 		close(dr.recordsChannel)
+		dr.recordsChannel = nil
 		dr.l.Debug("Closed records channel")
 	}
 
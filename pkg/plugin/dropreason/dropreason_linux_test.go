@@ -18,6 +18,7 @@ import (
 	"github.com/microsoft/retina/pkg/enricher"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/metrics"
+	plugincommon "github.com/microsoft/retina/pkg/plugin/common"
 	mocks "github.com/microsoft/retina/pkg/plugin/dropreason/mocks"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
@@ -84,6 +85,25 @@ func TestShutdown(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestAttachments(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	p := &dropReason{
+		cfg: cfgPodLevelEnabled,
+		l:   log.Logger().Named(string(Name)),
+	}
+
+	// Only the probes of the functions every kernel has are reported when not attached.
+	attachments := p.Attachments()
+	if len(attachments) != 5 {
+		t.Fatalf("Expected 5 attachments, got %d", len(attachments))
+	}
+	for _, attachment := range attachments {
+		if attachment.Error == "" {
+			t.Fatalf("Expected %s to be reported as not attached", attachment.Target)
+		}
+	}
+}
+
 func TestCompile(t *testing.T) {
 	takeBackup()
 	defer restoreBackup()
@@ -429,6 +449,9 @@ func TestDropReasonGenerate(t *testing.T) {
 	takeBackup()
 	defer restoreBackup()
 
+	ringBufferSupported = func() bool { return false }
+	defer func() { ringBufferSupported = plugincommon.RingBufferSupported }()
+
 	log.SetupZapLogger(log.GetDefaultLogOpts())
 	// Get the directory of the current test file.
 	_, filename, _, ok := runtime.Caller(0)
@@ -465,6 +488,49 @@ func TestDropReasonGenerate(t *testing.T) {
 	}
 }
 
+func TestDropReasonGenerateRingBuffer(t *testing.T) {
+	takeBackup()
+	defer restoreBackup()
+
+	ringBufferSupported = func() bool { return true }
+	defer func() { ringBufferSupported = plugincommon.RingBufferSupported }()
+
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	// Get the directory of the current test file.
+	_, filename, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("failed to determine test file path")
+	}
+	currDir := path.Dir(filename)
+	dynamicHeaderPath := fmt.Sprintf("%s/%s/%s", currDir, bpfSourceDir, dynamicHeaderFileName)
+
+	// Instantiate the dropReason struct with a mocked logger and context.
+	dr := &dropReason{
+		cfg: cfgPodLevelEnabled,
+		l:   log.Logger().Named(string(Name)),
+	}
+	ctx := context.Background()
+
+	// Call the Generate function and check if it returns an error.
+	if err := dr.Generate(ctx); err != nil {
+		t.Fatalf("failed to generate DropReason header: %v", err)
+	}
+
+	// Verify that the dynamic header file was created in the expected location and contains the expected contents.
+	if _, err := os.Stat(dynamicHeaderPath); os.IsNotExist(err) {
+		t.Fatalf("dynamic header file does not exist: %v", err)
+	}
+
+	expectedContents := "#define ADVANCED_METRICS 1 \n#define BYPASS_LOOKUP_IP_OF_INTEREST 1 \n#define USE_RING_BUFFER 1 \n#define RING_BUFFER_SIZE 4194304 \n"
+	actualContents, err := os.ReadFile(dynamicHeaderPath)
+	if err != nil {
+		t.Fatalf("failed to read dynamic header file: %v", err)
+	}
+	if string(actualContents) != expectedContents {
+		t.Errorf("unexpected dynamic header file contents: got %q, want %q", string(actualContents), expectedContents)
+	}
+}
+
 // Helpers.
 func takeBackup() {
 	// Get the directory of the current test file.
@@ -13,6 +13,7 @@ import (
 	"github.com/microsoft/retina/pkg/enricher"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/plugin/api"
+	plugincommon "github.com/microsoft/retina/pkg/plugin/common"
 	"github.com/microsoft/retina/pkg/utils"
 )
 
@@ -24,12 +25,20 @@ const (
 	dynamicHeaderFileName string         = "dynamic.h"
 	buffer                int            = 10000
 	workers               int            = 2
+	eventsMapName         string         = "dropreason_events"
 )
 
 // Determined via testing on a large cluster.
 // Actual buffer size will be 16 * pagesize.
 var perCPUBuffer = 16
 
+// Size in bytes of the ring buffer shared by all CPUs.
+// Must be a power of 2 and a multiple of pagesize.
+var ringBufferSize = 4 * 1024 * 1024
+
+// Assigned to a variable to mock the kernel feature probe in unit tests.
+var ringBufferSupported = plugincommon.RingBufferSupported
+
 type dropReason struct {
 	cfg                    *kcfg.Config
 	l                      *log.ZapLogger
@@ -49,6 +58,14 @@ type dropReason struct {
 	recordsChannel         chan perf.Record
 	wg                     sync.WaitGroup
 	externalChannel        chan *hubblev1.Event
+	useRingBuffer          bool
+	lostEventsMap          *ebpf.Map
+	interval               plugincommon.MetricsInterval
+}
+
+// lostEventsObjects holds the maps that are not part of the bpf2go generated objects.
+type lostEventsObjects struct {
+	DropreasonLostEvents *ebpf.Map `ebpf:"dropreason_lost_events"`
 }
 
 type (
@@ -7,6 +7,7 @@ package filter
 import (
 	"errors"
 	"net"
+	"path"
 	"strings"
 	"sync"
 
@@ -175,6 +176,26 @@ func (f *FilterMap) Close() {
 	f.obj.Close()
 }
 
+// PinnedIPs returns the IPs in the filter map pinned to the BPF filesystem, which the plugins filter on.
+func PinnedIPs() ([]net.IP, error) {
+	m, err := ebpf.LoadPinnedMap(path.Join(plugincommon.FilterMapPath, plugincommon.FilterMapName), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	var (
+		key   filterMapKey //nolint:typecheck
+		value uint8
+		ips   []net.IP
+	)
+	iter := m.Iterate()
+	for iter.Next(&key, &value) {
+		ips = append(ips, utils.Int2ip(key.Data))
+	}
+	return ips, iter.Err()
+}
+
 // Helper functions.
 func mapKey(ip net.IP) (filterMapKey, error) { //nolint:typecheck
 	// Convert to 4 byte representation.
@@ -50,9 +50,15 @@ func (ib *infiniband) SetupChannel(ch chan *hubblev1.Event) error { // nolint //
 	return nil
 }
 
+// SetMetricsInterval changes the interval the infiniband metrics are published at.
+func (ib *infiniband) SetMetricsInterval(interval time.Duration) {
+	ib.interval.Set(interval)
+}
+
 func (ib *infiniband) run(ctx context.Context) error {
 	ib.l.Info("Running infiniband plugin...")
-	ticker := time.NewTicker(ib.cfg.MetricsInterval)
+	interval, changed := ib.interval.Get(ib.cfg.MetricsInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -60,6 +66,9 @@ func (ib *infiniband) run(ctx context.Context) error {
 		case <-ctx.Done():
 			ib.l.Info("Context is done, infiniband will stop running")
 			return nil
+		case <-changed:
+			interval, changed = ib.interval.Get(ib.cfg.MetricsInterval)
+			ticker.Reset(interval)
 		case <-ticker.C:
 			infinibandReader := NewInfinibandReader()
 			err := infinibandReader.readAndUpdate()
@@ -6,6 +6,7 @@ import (
 	kcfg "github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/plugin/api"
+	"github.com/microsoft/retina/pkg/plugin/common"
 )
 
 const (
@@ -17,6 +18,7 @@ type infiniband struct {
 	cfg       *kcfg.Config
 	l         *log.ZapLogger
 	isRunning bool
+	interval  common.MetricsInterval
 }
 
 type CounterStat struct {
@@ -53,9 +53,15 @@ func (lu *linuxUtil) SetupChannel(ch chan *hubblev1.Event) error {
 	return nil
 }
 
+// SetMetricsInterval changes the interval the linuxutil metrics are published at.
+func (lu *linuxUtil) SetMetricsInterval(interval time.Duration) {
+	lu.interval.Set(interval)
+}
+
 func (lu *linuxUtil) run(ctx context.Context) error {
 	lu.l.Info("Running linuxutil plugin...")
-	ticker := time.NewTicker(lu.cfg.MetricsInterval)
+	interval, changed := lu.interval.Get(lu.cfg.MetricsInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -63,6 +69,9 @@ func (lu *linuxUtil) run(ctx context.Context) error {
 		case <-ctx.Done():
 			lu.l.Info("Context is done, linuxutil will stop running")
 			return nil
+		case <-changed:
+			interval, changed = lu.interval.Get(lu.cfg.MetricsInterval)
+			ticker.Reset(interval)
 		case <-ticker.C:
 			opts := &NetstatOpts{
 				CuratedKeys:      false,
@@ -7,6 +7,7 @@ import (
 	kcfg "github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/plugin/api"
+	"github.com/microsoft/retina/pkg/plugin/common"
 )
 
 const (
@@ -19,6 +20,7 @@ type linuxUtil struct {
 	l                *log.ZapLogger
 	isRunning        bool
 	prevTCPSockStats *SocketStats
+	interval         common.MetricsInterval
 }
 
 var netstatCuratedKeys = map[string]struct{}{
@@ -0,0 +1,292 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+// Package nodeconnectivity contains the Retina nodeconnectivity plugin.
+// It actively probes other nodes of the cluster and reports their reachability and latency.
+package nodeconnectivity
+
+import (
+	"context"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
+	"github.com/microsoft/retina/pkg/common"
+	kcfg "github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/controllers/cache"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/metrics"
+	"github.com/microsoft/retina/pkg/plugin/api"
+	"github.com/microsoft/retina/pkg/pubsub"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func New(cfg *kcfg.Config) api.Plugin {
+	n := &nodeConnectivity{
+		cfg:       cfg,
+		l:         log.Logger().Named(string(Name)),
+		ps:        pubsub.New(),
+		localNode: os.Getenv("NODE_NAME"),
+		localIP:   os.Getenv("NODE_IP"),
+		nodes:     make(map[string]string),
+		reachable: make(map[string]bool),
+	}
+	return n
+}
+
+// SetCluster sets the cluster, whose cache seeds the nodes to probe and whose API gets the events.
+func (n *nodeConnectivity) SetCluster(cluster api.Cluster) {
+	n.cluster = cluster
+}
+
+// SetMetricsInterval changes the interval the nodes are probed at.
+func (n *nodeConnectivity) SetMetricsInterval(interval time.Duration) {
+	n.interval.Set(interval)
+}
+
+func (n *nodeConnectivity) Name() string {
+	return string(Name)
+}
+
+func (n *nodeConnectivity) Generate(ctx context.Context) error {
+	return nil
+}
+
+func (n *nodeConnectivity) Compile(ctx context.Context) error {
+	return nil
+}
+
+func (n *nodeConnectivity) Init() error {
+	probeCfg := n.cfg.NodeConnectivityProbe
+	p, err := newProber(probeCfg.Protocol, probeCfg.Port)
+	if err != nil {
+		n.l.Error("Failed to create prober", zap.Error(err))
+		return err
+	}
+	n.prober = p
+
+	if n.callbackID == "" {
+		n.callbackID = cache.NodeTopic.Subscribe(n.ps, n.nodeCallbackFn)
+	}
+	if n.cluster.Client != nil && n.broadcaster == nil {
+		n.broadcaster = record.NewBroadcaster()
+		n.broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: n.cluster.Client.CoreV1().Events("")})
+		n.recorder = n.broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent, Host: n.localNode})
+	}
+	n.l.Info("Initialized nodeconnectivity plugin",
+		zap.String("protocol", probeCfg.Protocol),
+		zap.Int("port", probeCfg.Port),
+		zap.Int("sampleSize", probeCfg.SampleSize),
+	)
+	return nil
+}
+
+func (n *nodeConnectivity) Start(ctx context.Context) error {
+	n.l.Info("Starting nodeconnectivity plugin")
+	// The nodes published before the plugin subscribed, e.g. when it is enabled at runtime, are only in the cache.
+	n.seedNodes()
+	interval, changed := n.interval.Get(n.cfg.MetricsInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			n.l.Info("Context is done, nodeconnectivity will stop running")
+			return nil
+		case <-changed:
+			interval, changed = n.interval.Get(n.cfg.MetricsInterval)
+			ticker.Reset(interval)
+		case <-ticker.C:
+			n.probeNodes(ctx)
+		}
+	}
+}
+
+func (n *nodeConnectivity) Stop() error {
+	if n.callbackID != "" {
+		if err := cache.NodeTopic.Unsubscribe(n.ps, n.callbackID); err != nil {
+			n.l.Error("Failed to unsubscribe from node events", zap.Error(err))
+		}
+		n.callbackID = ""
+	}
+	if n.broadcaster != nil {
+		n.broadcaster.Shutdown()
+		n.broadcaster = nil
+		n.recorder = nil
+	}
+	n.l.Info("Stopped nodeconnectivity plugin")
+	return nil
+}
+
+func (n *nodeConnectivity) SetupChannel(ch chan *v1.Event) error {
+	n.l.Warn("SetupChannel is not supported by plugin", zap.String("plugin", string(Name)))
+	return nil
+}
+
+func (n *nodeConnectivity) nodeCallbackFn(event *cache.CacheEvent) {
+	if event == nil {
+		return
+	}
+	node, ok := event.Obj.(*common.RetinaNode)
+	if !ok || node == nil {
+		return
+	}
+
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	switch event.Type { //nolint:exhaustive // Only node events are published on this topic.
+	case cache.EventTypeNodeAdded:
+		n.addNodeLocked(node)
+	case cache.EventTypeNodeDeleted:
+		n.deleteNodeLocked(node.Name())
+	}
+}
+
+// seedNodes replaces the nodes to probe with the nodes of the cache, when the plugin has one.
+func (n *nodeConnectivity) seedNodes() {
+	if n.cluster.Nodes == nil {
+		return
+	}
+	nodes := n.cluster.Nodes.GetNodes()
+
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	seeded := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		seeded[node.Name()] = struct{}{}
+		n.addNodeLocked(node)
+	}
+	for name := range n.nodes {
+		if _, ok := seeded[name]; !ok {
+			n.deleteNodeLocked(name)
+		}
+	}
+	n.l.Info("Seeded nodes from the cache", zap.Int("nodes", len(n.nodes)))
+}
+
+func (n *nodeConnectivity) addNodeLocked(node *common.RetinaNode) {
+	if node.Name() == n.localNode || node.IPString() == n.localIP {
+		return
+	}
+	n.nodes[node.Name()] = node.IPString()
+}
+
+func (n *nodeConnectivity) deleteNodeLocked(name string) {
+	delete(n.nodes, name)
+	delete(n.reachable, name)
+	metrics.NodeConnectivityStatusGauge.DeleteLabelValues(n.localNode, name)
+	metrics.NodeConnectivityLatencyGauge.DeleteLabelValues(n.localNode, name)
+}
+
+// sample returns the nodes to probe this interval.
+// With a sample size, consecutive intervals rotate over the sorted node list, so every node is eventually probed.
+func (n *nodeConnectivity) sample() map[string]string {
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	size := n.cfg.NodeConnectivityProbe.SampleSize
+	if size <= 0 || size >= len(n.nodes) {
+		targets := make(map[string]string, len(n.nodes))
+		for name, ip := range n.nodes {
+			targets[name] = ip
+		}
+		return targets
+	}
+
+	names := make([]string, 0, len(n.nodes))
+	for name := range n.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	targets := make(map[string]string, size)
+	for i := 0; i < size; i++ {
+		name := names[(n.next+i)%len(names)]
+		targets[name] = n.nodes[name]
+	}
+	n.next = (n.next + size) % len(names)
+	return targets
+}
+
+func (n *nodeConnectivity) probeNodes(ctx context.Context) {
+	targets := n.sample()
+	if len(targets) == 0 {
+		n.l.Debug("No nodes to probe")
+		return
+	}
+
+	timeout, _ := n.interval.Get(n.cfg.MetricsInterval)
+	if timeout <= 0 || timeout > maxProbeTimeout {
+		timeout = maxProbeTimeout
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentProbes)
+	for name, ip := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name, ip string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			rtt, err := n.prober.probe(probeCtx, net.ParseIP(ip))
+			n.record(name, ip, rtt, err)
+		}(name, ip)
+	}
+	wg.Wait()
+}
+
+// record updates the metrics for target, and raises an event when its reachability changes.
+func (n *nodeConnectivity) record(target, ip string, rtt time.Duration, err error) {
+	reachable := err == nil
+
+	n.m.Lock()
+	if _, ok := n.nodes[target]; !ok {
+		// The node was deleted while being probed, its metrics are deleted already.
+		n.m.Unlock()
+		return
+	}
+	if reachable {
+		metrics.NodeConnectivityStatusGauge.WithLabelValues(n.localNode, target).Set(1)
+		metrics.NodeConnectivityLatencyGauge.WithLabelValues(n.localNode, target).Set(rtt.Seconds())
+	} else {
+		// There is no latency to an unreachable node.
+		metrics.NodeConnectivityStatusGauge.WithLabelValues(n.localNode, target).Set(0)
+		metrics.NodeConnectivityLatencyGauge.DeleteLabelValues(n.localNode, target)
+	}
+	previous, known := n.reachable[target]
+	n.reachable[target] = reachable
+	n.m.Unlock()
+
+	// Nodes are assumed reachable until proven otherwise, so only failures are raised on the first probe.
+	if (!known && reachable) || (known && previous == reachable) {
+		return
+	}
+	if reachable {
+		n.l.Info("Node is reachable again", zap.String("node", target), zap.String("ip", ip))
+	} else {
+		n.l.Warn("Node is unreachable", zap.String("node", target), zap.String("ip", ip), zap.Error(err))
+	}
+	if n.recorder == nil {
+		return
+	}
+	// Events about nodes are raised on the node, whose UID is its name for the Kubernetes API.
+	ref := &corev1.ObjectReference{Kind: "Node", Name: target, UID: types.UID(target)}
+	if reachable {
+		n.recorder.Eventf(ref, corev1.EventTypeNormal, reasonNodeReachable, "Node %s (%s) is reachable again from node %s", target, ip, n.localNode)
+	} else {
+		n.recorder.Eventf(ref, corev1.EventTypeWarning, reasonNodeUnreachable, "Node %s (%s) is unreachable from node %s: %v", target, ip, n.localNode, err)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package nodeconnectivity
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/microsoft/retina/pkg/common"
+	kcfg "github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/controllers/cache"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/metrics"
+	"github.com/microsoft/retina/pkg/pubsub"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"k8s.io/client-go/tools/record"
+)
+
+var errUnreachable = errors.New("unreachable")
+
+type fakeProber struct {
+	m           sync.Mutex
+	unreachable map[string]bool
+}
+
+func (f *fakeProber) probe(_ context.Context, ip net.IP) (time.Duration, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.unreachable[ip.String()] {
+		return 0, errUnreachable
+	}
+	return 5 * time.Millisecond, nil
+}
+
+type fakeNodeLister []*common.RetinaNode
+
+func (f fakeNodeLister) GetNodes() []*common.RetinaNode {
+	return f
+}
+
+func newTestPlugin(t *testing.T, sampleSize int) (*nodeConnectivity, *pubsub.MockPubSubInterface) {
+	t.Helper()
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	metrics.InitializeMetrics()
+	metrics.NodeConnectivityStatusGauge.(*prometheus.GaugeVec).Reset()
+	metrics.NodeConnectivityLatencyGauge.(*prometheus.GaugeVec).Reset()
+
+	ctrl := gomock.NewController(t)
+	ps := pubsub.NewMockPubSubInterface(ctrl)
+	n := &nodeConnectivity{
+		cfg: &kcfg.Config{
+			MetricsInterval:       time.Second,
+			NodeConnectivityProbe: kcfg.NodeConnectivityProbe{SampleSize: sampleSize},
+		},
+		l:         log.Logger().Named(string(Name)),
+		ps:        ps,
+		localNode: "node0",
+		localIP:   "10.0.0.10",
+		nodes:     make(map[string]string),
+		reachable: make(map[string]bool),
+		recorder:  record.NewFakeRecorder(10),
+	}
+	return n, ps
+}
+
+// series returns the number of series of the gauge.
+func series(g metrics.IGaugeVec) int {
+	return testutil.CollectAndCount(g.(prometheus.Collector))
+}
+
+func addNode(n *nodeConnectivity, name, ip string) {
+	n.nodeCallbackFn(cache.NewCacheEvent(cache.EventTypeNodeAdded, common.NewRetinaNode(name, net.ParseIP(ip))))
+}
+
+func TestNodeCallbackFn(t *testing.T) {
+	n, _ := newTestPlugin(t, 0)
+
+	addNode(n, "node0", "10.0.0.10")
+	addNode(n, "node1", "10.0.0.1")
+	addNode(n, "node2", "10.0.0.2")
+	assert.Equal(t, map[string]string{"node1": "10.0.0.1", "node2": "10.0.0.2"}, n.nodes, "local node must not be probed")
+
+	n.nodeCallbackFn(cache.NewCacheEvent(cache.EventTypeNodeDeleted, common.NewRetinaNode("node1", net.ParseIP("10.0.0.1"))))
+	assert.Equal(t, map[string]string{"node2": "10.0.0.2"}, n.nodes)
+}
+
+func TestSampleRotates(t *testing.T) {
+	n, _ := newTestPlugin(t, 2)
+	addNode(n, "node1", "10.0.0.1")
+	addNode(n, "node2", "10.0.0.2")
+	addNode(n, "node3", "10.0.0.3")
+
+	seen := map[string]int{}
+	for i := 0; i < 3; i++ {
+		targets := n.sample()
+		assert.Len(t, targets, 2)
+		for name := range targets {
+			seen[name]++
+		}
+	}
+	assert.Equal(t, map[string]int{"node1": 2, "node2": 2, "node3": 2}, seen)
+}
+
+func TestSeedNodes(t *testing.T) {
+	n, _ := newTestPlugin(t, 0)
+	addNode(n, "node1", "10.0.0.1")
+	addNode(n, "node3", "10.0.0.3")
+	metrics.NodeConnectivityStatusGauge.WithLabelValues("node0", "node3").Set(1)
+
+	// Without a cache, the nodes are only fed by the events.
+	n.seedNodes()
+	assert.Equal(t, map[string]string{"node1": "10.0.0.1", "node3": "10.0.0.3"}, n.nodes)
+
+	n.cluster.Nodes = fakeNodeLister{
+		common.NewRetinaNode("node0", net.ParseIP("10.0.0.10")),
+		common.NewRetinaNode("node1", net.ParseIP("10.0.0.1")),
+		common.NewRetinaNode("node2", net.ParseIP("10.0.0.2")),
+	}
+	n.seedNodes()
+	assert.Equal(t, map[string]string{"node1": "10.0.0.1", "node2": "10.0.0.2"}, n.nodes, "nodes must be replaced by the cache")
+	assert.Zero(t, series(metrics.NodeConnectivityStatusGauge))
+}
+
+func TestProbeNodes(t *testing.T) {
+	n, _ := newTestPlugin(t, 0)
+	recorder := n.recorder.(*record.FakeRecorder)
+	prober := &fakeProber{unreachable: map[string]bool{}}
+	n.prober = prober
+	addNode(n, "node1", "10.0.0.1")
+	addNode(n, "node2", "10.0.0.2")
+
+	// All nodes reachable, no events.
+	n.probeNodes(context.Background())
+	assert.InDelta(t, 1, testutil.ToFloat64(metrics.NodeConnectivityStatusGauge.WithLabelValues("node0", "node1")), 0)
+	assert.InDelta(t, 0.005, testutil.ToFloat64(metrics.NodeConnectivityLatencyGauge.WithLabelValues("node0", "node2")), 0.0001)
+	assert.Empty(t, recorder.Events)
+
+	// node2 becomes unreachable, which raises an event once and removes its latency.
+	prober.unreachable["10.0.0.2"] = true
+	n.probeNodes(context.Background())
+	n.probeNodes(context.Background())
+	assert.InDelta(t, 0, testutil.ToFloat64(metrics.NodeConnectivityStatusGauge.WithLabelValues("node0", "node2")), 0)
+	assert.Equal(t, 1, series(metrics.NodeConnectivityLatencyGauge), "only node1 has a latency")
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Warning NodeUnreachable Node node2 (10.0.0.2) is unreachable from node node0: unreachable", <-recorder.Events)
+
+	// node2 recovers.
+	prober.unreachable["10.0.0.2"] = false
+	n.probeNodes(context.Background())
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal NodeReachable Node node2 (10.0.0.2) is reachable again from node node0", <-recorder.Events)
+}
+
+func TestRecordDeletedNode(t *testing.T) {
+	n, _ := newTestPlugin(t, 0)
+	addNode(n, "node1", "10.0.0.1")
+	n.nodeCallbackFn(cache.NewCacheEvent(cache.EventTypeNodeDeleted, common.NewRetinaNode("node1", net.ParseIP("10.0.0.1"))))
+
+	// A probe finishing after the node is deleted does not bring its metrics back.
+	n.record("node1", "10.0.0.1", time.Millisecond, nil)
+	n.record("node1", "10.0.0.1", 0, errUnreachable)
+	assert.Zero(t, series(metrics.NodeConnectivityStatusGauge))
+	assert.Zero(t, series(metrics.NodeConnectivityLatencyGauge))
+	assert.Empty(t, n.recorder.(*record.FakeRecorder).Events)
+}
+
+func TestNewProber(t *testing.T) {
+	for _, protocol := range []string{"", protocolICMP, protocolTCP, protocolUDP} {
+		p, err := newProber(protocol, 0)
+		require.NoError(t, err)
+		assert.NotNil(t, p)
+	}
+	_, err := newProber("sctp", 0)
+	assert.Error(t, err)
+}
+
+func TestTCPProber(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	p := &tcpProber{port: lis.Addr().(*net.TCPAddr).Port}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = p.probe(ctx, net.ParseIP("127.0.0.1"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package nodeconnectivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// ianaProtocolICMP is the IANA protocol number of ICMP for IPv4, used to parse replies.
+const ianaProtocolICMP = 1
+
+var errIPv6NotSupported = errors.New("icmp probes only support IPv4 nodes")
+
+func newProber(protocol string, port int) (prober, error) {
+	switch protocol {
+	case "", protocolICMP:
+		return &icmpProber{id: os.Getpid() & 0xffff}, nil
+	case protocolTCP:
+		if port == 0 {
+			port = defaultTCPPort
+		}
+		return &tcpProber{port: port}, nil
+	case protocolUDP:
+		if port == 0 {
+			port = defaultUDPPort
+		}
+		return &udpProber{port: port}, nil
+	default:
+		return nil, fmt.Errorf("unknown probe protocol %q", protocol) //nolint:goerr113 // user input
+	}
+}
+
+// icmpProber sends ICMP echo requests over a raw socket.
+type icmpProber struct {
+	id  int
+	seq atomic.Uint32
+}
+
+func (p *icmpProber) probe(ctx context.Context, ip net.IP) (time.Duration, error) {
+	if ip.To4() == nil {
+		return 0, errIPv6NotSupported
+	}
+
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return 0, fmt.Errorf("failed to set ICMP socket deadline: %w", err)
+		}
+	}
+
+	seq := int(p.seq.Add(1) & 0xffff)
+	req := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: p.id, Seq: seq, Data: []byte("retina")},
+	}
+	b, err := req.Marshal(nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal ICMP echo: %w", err)
+	}
+
+	start := time.Now()
+	if _, err = conn.WriteTo(b, &net.IPAddr{IP: ip}); err != nil {
+		return 0, fmt.Errorf("failed to send ICMP echo: %w", err)
+	}
+
+	// The raw socket receives every ICMP packet on the host, so skip replies to other probes.
+	buf := make([]byte, 1500) //nolint:gomnd // MTU
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read ICMP echo reply: %w", err)
+		}
+		if addr, ok := peer.(*net.IPAddr); !ok || !addr.IP.Equal(ip) {
+			continue
+		}
+		reply, err := icmp.ParseMessage(ianaProtocolICMP, buf[:n])
+		if err != nil || reply.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.ID == p.id && echo.Seq == seq {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// tcpProber measures the TCP handshake time.
+// A refused connection still proves the node is reachable.
+type tcpProber struct {
+	port int
+}
+
+func (p *tcpProber) probe(ctx context.Context, ip net.IP) (time.Duration, error) {
+	var d net.Dialer
+	start := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(p.port)))
+	rtt := time.Since(start)
+	if err != nil {
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return rtt, nil
+		}
+		return 0, fmt.Errorf("failed to connect: %w", err)
+	}
+	conn.Close()
+	return rtt, nil
+}
+
+// udpProber sends a datagram and waits for either a reply or ICMP port unreachable,
+// which the kernel surfaces as ECONNREFUSED on the connected socket.
+type udpProber struct {
+	port int
+}
+
+func (p *udpProber) probe(ctx context.Context, ip net.IP) (time.Duration, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", net.JoinHostPort(ip.String(), strconv.Itoa(p.port)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open UDP socket: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return 0, fmt.Errorf("failed to set UDP socket deadline: %w", err)
+		}
+	}
+
+	start := time.Now()
+	if _, err = conn.Write([]byte("retina")); err != nil {
+		return 0, fmt.Errorf("failed to send UDP probe: %w", err)
+	}
+	buf := make([]byte, 64) //nolint:gomnd // replies are ignored
+	_, err = conn.Read(buf)
+	rtt := time.Since(start)
+	if err != nil && !errors.Is(err, syscall.ECONNREFUSED) {
+		return 0, fmt.Errorf("no UDP probe response: %w", err)
+	}
+	return rtt, nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package nodeconnectivity
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	kcfg "github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/plugin/api"
+	"github.com/microsoft/retina/pkg/plugin/common"
+	"github.com/microsoft/retina/pkg/pubsub"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	Name api.PluginName = "nodeconnectivity"
+
+	protocolICMP = "icmp"
+	protocolTCP  = "tcp"
+	protocolUDP  = "udp"
+
+	// defaultTCPPort is the kubelet port, which is open on every node.
+	defaultTCPPort = 10250
+	// defaultUDPPort is the traceroute base port, which is unlikely to be bound,
+	// so a reachable node answers with ICMP port unreachable.
+	defaultUDPPort = 33434
+
+	// maxProbeTimeout bounds a single probe, so a partitioned node doesn't delay the others.
+	maxProbeTimeout = 2 * time.Second
+	// maxConcurrentProbes bounds the number of probes in flight in large clusters.
+	maxConcurrentProbes = 32
+
+	// eventComponent is the source of the Kubernetes events raised on partitions.
+	eventComponent = "retina-agent"
+	// reasonNodeUnreachable and reasonNodeReachable are the reasons of the events raised on the target nodes.
+	reasonNodeUnreachable = "NodeUnreachable"
+	reasonNodeReachable   = "NodeReachable"
+)
+
+// prober sends a single probe to ip and returns the round trip time.
+type prober interface {
+	probe(ctx context.Context, ip net.IP) (time.Duration, error)
+}
+
+type nodeConnectivity struct {
+	cfg        *kcfg.Config
+	l          *log.ZapLogger
+	ps         pubsub.PubSubInterface
+	callbackID string
+	prober     prober
+	localNode  string
+	localIP    string
+	cluster    api.Cluster
+	// interval is the interval the nodes are probed at, which also bounds the timeout of a probe.
+	interval common.MetricsInterval
+	// broadcaster sends the events of the recorder to the Kubernetes API, when the plugin has a client.
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+
+	m sync.Mutex
+	// nodes is a map of node name to node IP, seeded from the cache and fed by its node events.
+	nodes map[string]string
+	// reachable is the last observed status of each probed node.
+	reachable map[string]bool
+	// next is the index of the first node to probe in the next sample, so sampling rotates over all nodes.
+	next int
+}
@@ -198,6 +198,11 @@ func (p *packetForward) Stop() error {
 	return nil
 }
 
+// SetMetricsInterval changes the interval the packet forward metrics are published at.
+func (p *packetForward) SetMetricsInterval(interval time.Duration) {
+	p.interval.Set(interval)
+}
+
 func (p *packetForward) SetupChannel(ch chan *hubblev1.Event) error {
 	p.l.Warn("SetupChannel is not supported by plugin", zap.String("plugin", string(Name)))
 	return nil
@@ -205,7 +210,8 @@ func (p *packetForward) SetupChannel(ch chan *hubblev1.Event) error {
 
 func (p *packetForward) run(ctx context.Context) error {
 	var err error
-	ticker := time.NewTicker(p.cfg.MetricsInterval)
+	interval, changed := p.interval.Get(p.cfg.MetricsInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -213,6 +219,9 @@ func (p *packetForward) run(ctx context.Context) error {
 		case <-ctx.Done():
 			p.l.Info("Context is done, packetforward will stop running")
 			return nil
+		case <-changed:
+			interval, changed = p.interval.Get(p.cfg.MetricsInterval)
+			ticker.Reset(interval)
 		case <-ticker.C:
 			data := &PacketForwardData{}
 			data.ingressCountTotal, data.ingressBytesTotal, err = processMapValue(p.hashmapData, ingressKey)
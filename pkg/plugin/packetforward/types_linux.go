@@ -9,6 +9,7 @@ import (
 
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/plugin/api"
+	"github.com/microsoft/retina/pkg/plugin/common"
 )
 
 const (
@@ -40,6 +41,7 @@ type packetForward struct {
 	hashmapData IMap
 	sock        int
 	isRunning   bool
+	interval    common.MetricsInterval
 }
 
 type PacketForwardData struct {
@@ -10,6 +10,15 @@
 
 char __license[] SEC("license") = "Dual MIT/GPL";
 
+// USE_RING_BUFFER and RING_BUFFER_SIZE are set in dynamic.h at runtime
+// when the kernel supports BPF_MAP_TYPE_RINGBUF (5.8+).
+#ifndef USE_RING_BUFFER
+#define USE_RING_BUFFER 0
+#endif
+
+#ifndef RING_BUFFER_SIZE
+#define RING_BUFFER_SIZE (8 * 1024 * 1024)
+#endif
 
 
 struct tcpmetadata {
@@ -40,11 +49,29 @@ struct packet
 	__u64 bytes; // packet size in bytes
 };
 
+#if USE_RING_BUFFER
+struct
+{
+	__uint(type, BPF_MAP_TYPE_RINGBUF);
+	__uint(max_entries, RING_BUFFER_SIZE);
+} packetparser_events SEC(".maps");
+#else
 struct
 {
 	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
 	__uint(max_entries, 16384);
 } packetparser_events SEC(".maps");
+#endif
+
+// Number of events that could not be written to the ring buffer.
+// Perf buffers report lost samples to userspace on their own.
+struct
+{
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, __u64);
+} packetparser_lost_events SEC(".maps");
 
 // Define const variables to avoid warnings.
 const struct packet *unused __attribute__((unused));
@@ -227,7 +254,17 @@ static void parse(struct __sk_buff *skb, direction d)
 		return;
 	}
 
+#if USE_RING_BUFFER
+	if (bpf_ringbuf_output(&packetparser_events, &p, sizeof(p), 0) != 0)
+	{
+		__u32 key = 0;
+		__u64 *lost = bpf_map_lookup_elem(&packetparser_lost_events, &key);
+		if (lost)
+			*lost += 1;
+	}
+#else
 	bpf_perf_event_output(skb, &packetparser_events, BPF_F_CURRENT_CPU, &p, sizeof(p));
+#endif
 }
 
 SEC("classifier_endpoint_ingress")
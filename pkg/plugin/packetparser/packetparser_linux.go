@@ -13,6 +13,7 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"sort"
 	"sync"
 
 	"github.com/cilium/cilium/api/v1/flow"
@@ -23,7 +24,6 @@ import (
 	"github.com/florianl/go-tc"
 	helper "github.com/florianl/go-tc/core"
 	"github.com/microsoft/retina/internal/ktime"
-	"github.com/microsoft/retina/pkg/common"
 	kcfg "github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/enricher"
 	"github.com/microsoft/retina/pkg/loader"
@@ -73,6 +73,10 @@ func (p *packetParser) Generate(ctx context.Context) error {
 		i = 1
 	}
 	st := fmt.Sprintf("#define BYPASS_LOOKUP_IP_OF_INTEREST %d \n", i)
+	if !p.cfg.DisableRingBuffer && ringBufferSupported() {
+		p.l.Info("Kernel supports BPF ring buffer, using it for packetparser events")
+		st += fmt.Sprintf("#define USE_RING_BUFFER 1 \n#define RING_BUFFER_SIZE %d \n", ringBufferSize)
+	}
 	err := loader.WriteFile(ctx, dynamicHeaderPath, st)
 	if err != nil {
 		p.l.Error("Error writing dynamic header", zap.Error(err))
@@ -129,12 +133,20 @@ func (p *packetParser) Init() error {
 	bpfOutputFile := fmt.Sprintf("%s/%s", dir, bpfObjectFileName)
 
 	objs := &packetparserObjects{}
+	lost := &lostEventsObjects{}
 	spec, err := ebpf.LoadCollectionSpec(bpfOutputFile)
 	if err != nil {
 		return err
 	}
+	// The events map type is decided at compile time by Generate.
+	if eventsSpec, ok := spec.Maps[eventsMapName]; ok {
+		p.useRingBuffer = eventsSpec.Type == ebpf.RingBuf
+	}
 	//nolint:typecheck
-	if err := spec.LoadAndAssign(objs, &ebpf.CollectionOptions{ //nolint:typecheck
+	if err := spec.LoadAndAssign(&struct {
+		*packetparserObjects
+		*lostEventsObjects
+	}{objs, lost}, &ebpf.CollectionOptions{ //nolint:typecheck
 		Maps: ebpf.MapOptions{
 			PinPath: plugincommon.FilterMapPath,
 		},
@@ -143,6 +155,7 @@ func (p *packetParser) Init() error {
 		return err
 	}
 	p.objs = objs
+	p.lostEventsMap = lost.PacketparserLostEvents
 
 	// Endpoint bpf programs.
 	p.endpointIngressInfo, err = p.objs.EndpointIngressFilter.Info()
@@ -168,10 +181,20 @@ func (p *packetParser) Init() error {
 		return err
 	}
 
-	p.reader, err = plugincommon.NewPerfReader(p.l, objs.PacketparserEvents, perCPUBuffer, 1)
-	if err != nil {
-		p.l.Error("Error NewReader", zap.Error(err))
-		return err
+	if p.useRingBuffer {
+		reader, err := plugincommon.NewRingBufReader(p.l, objs.PacketparserEvents, p.lostEventsMap)
+		if err != nil {
+			p.l.Error("Error NewRingBufReader", zap.Error(err))
+			return err
+		}
+		p.reader = reader
+	} else {
+		reader, err := plugincommon.NewPerfReader(p.l, objs.PacketparserEvents, perCPUBuffer, 1)
+		if err != nil {
+			p.l.Error("Error NewReader", zap.Error(err))
+			return err
+		}
+		p.reader = reader
 	}
 
 	p.tcMap = &sync.Map{}
@@ -201,10 +224,9 @@ func (p *packetParser) Start(ctx context.Context) error {
 
 	// Register callback.
 	// Every time a new endpoint is created, we will create a qdisc and attach it to the endpoint.
-	fn := pubsub.CallBackFunc(p.endpointWatcherCallbackFn)
 	// Check if callback is already registered.
 	if p.callbackID == "" {
-		p.callbackID = ps.Subscribe(common.PubSubEndpoints, &fn)
+		p.callbackID = endpoint.EndpointsTopic.Subscribe(ps, p.endpointWatcherCallbackFn)
 	}
 
 	outgoingLinks, err := utils.GetDefaultOutgoingLinks()
@@ -247,8 +269,10 @@ func (p *packetParser) Stop() error {
 
 	// Close the channel. The producer should have stopped by now.
 	// All consumers should have stopped by now.
+	// Reset it so that stopping the plugin again does not close it twice.
 	if p.recordsChannel != nil {
 		close(p.recordsChannel)
+		p.recordsChannel = nil
 		p.l.Debug("Closed records channel")
 	}
 
@@ -258,11 +282,16 @@ func (p *packetParser) Stop() error {
 			p.l.Error("Error closing objects", zap.Error(err))
 		}
 	}
+	if p.lostEventsMap != nil {
+		if err := p.lostEventsMap.Close(); err != nil {
+			p.l.Error("Error closing lost events map", zap.Error(err))
+		}
+	}
 	p.l.Debug("Stopped map/progs")
 
 	// Unregister callback.
 	if p.callbackID != "" {
-		if err := ps.Unsubscribe(common.PubSubEndpoints, p.callbackID); err != nil {
+		if err := endpoint.EndpointsTopic.Unsubscribe(ps, p.callbackID); err != nil {
 			p.l.Error("Error unregistering callback for packetParser", zap.Error(err))
 		}
 		// Reset callback ID.
@@ -302,6 +331,10 @@ func (p *packetParser) cleanAll() error {
 	// cleanAll is only invoked from Stop(), and Stop can
 	// only be called from PluginManager (which is single threaded).
 	p.tcMap = &sync.Map{}
+	p.attachFailures.Range(func(key, _ interface{}) bool {
+		p.attachFailures.Delete(key)
+		return true
+	})
 	return nil
 }
 
@@ -320,9 +353,7 @@ func (p *packetParser) clean(tcnl ITc, tcIngressObj *tc.Object, tcEgressObj *tc.
 	}
 }
 
-func (p *packetParser) endpointWatcherCallbackFn(obj interface{}) {
-	// Contract is that we will receive an endpoint event pointer.
-	event := obj.(*endpoint.EndpointEvent)
+func (p *packetParser) endpointWatcherCallbackFn(event *endpoint.EndpointEvent) {
 	if event == nil {
 		return
 	}
@@ -348,6 +379,7 @@ func (p *packetParser) endpointWatcherCallbackFn(obj interface{}) {
 			// Delete from map.
 			p.tcMap.Delete(ifaceKey)
 		}
+		p.attachFailures.Delete(ifaceKey)
 	default:
 		// Unknown.
 		p.l.Debug("Unknown event", zap.String("type", event.Type.String()))
@@ -373,6 +405,15 @@ func (p *packetParser) createQdiscAndAttach(iface netlink.LinkAttrs, ifaceType s
 		ingressInfo, egressInfo       *ebpf.ProgramInfo
 	)
 
+	// Record whether the programs are attached to the interface, reported by Attachments.
+	defer func() {
+		if err != nil {
+			p.attachFailures.Store(ifaceToKey(iface), err.Error())
+		} else {
+			p.attachFailures.Delete(ifaceToKey(iface))
+		}
+	}()
+
 	if ifaceType == "device" {
 		ingressProgram = p.objs.HostIngressFilter
 		egressProgram = p.objs.HostEgressFilter
@@ -387,6 +428,7 @@ func (p *packetParser) createQdiscAndAttach(iface netlink.LinkAttrs, ifaceType s
 		egressInfo = p.endpointEgressInfo
 	} else {
 		p.l.Error("Unknown ifaceType", zap.String("ifaceType", ifaceType))
+		err = fmt.Errorf("unknown interface type %s", ifaceType)
 		return
 	}
 
@@ -412,7 +454,7 @@ func (p *packetParser) createQdiscAndAttach(iface netlink.LinkAttrs, ifaceType s
 		},
 	}
 	// Install Qdisc on interface.
-	if err := getQdisc(tcnl).Add(qdiscIngress); err != nil && !errors.Is(err, os.ErrExist) {
+	if err = getQdisc(tcnl).Add(qdiscIngress); err != nil && !errors.Is(err, os.ErrExist) {
 		p.l.Error("could not assign clsact ingress to ", zap.String("interface", iface.Name), zap.Error(err))
 		p.clean(tcnl, qdiscIngress, qdiscEgress)
 		return
@@ -435,7 +477,7 @@ func (p *packetParser) createQdiscAndAttach(iface netlink.LinkAttrs, ifaceType s
 			},
 		},
 	}
-	if err := getFilter(tcnl).Add(&filterIngress); err != nil && !errors.Is(err, os.ErrExist) {
+	if err = getFilter(tcnl).Add(&filterIngress); err != nil && !errors.Is(err, os.ErrExist) {
 		p.l.Error("could not add bpf ingress to ", zap.String("interface", iface.Name), zap.Error(err))
 		p.clean(tcnl, qdiscIngress, qdiscEgress)
 		return
@@ -455,7 +497,7 @@ func (p *packetParser) createQdiscAndAttach(iface netlink.LinkAttrs, ifaceType s
 	}
 
 	// Install Qdisc on interface.
-	if err := getQdisc(tcnl).Add(qdiscEgress); err != nil && !errors.Is(err, os.ErrExist) {
+	if err = getQdisc(tcnl).Add(qdiscEgress); err != nil && !errors.Is(err, os.ErrExist) {
 		p.l.Error("could not assign clsact egress to ", zap.String("interface", iface.Name), zap.Error(err))
 		p.clean(tcnl, qdiscIngress, qdiscEgress)
 		return
@@ -478,13 +520,14 @@ func (p *packetParser) createQdiscAndAttach(iface netlink.LinkAttrs, ifaceType s
 			},
 		},
 	}
-	if err := getFilter(tcnl).Add(&filterEgress); err != nil && !errors.Is(err, os.ErrExist) {
+	if err = getFilter(tcnl).Add(&filterEgress); err != nil && !errors.Is(err, os.ErrExist) {
 		p.l.Error("could not add bpf egress to ", zap.String("interface", iface.Name), zap.Error(err))
 		p.clean(tcnl, qdiscIngress, qdiscEgress)
 		return
 	}
 
 	// Cache.
+	err = nil
 	ifaceKey := ifaceToKey(iface)
 	ifaceVal := &val{tcnl: tcnl, tcIngressObj: qdiscIngress, tcEgressObj: qdiscEgress}
 	p.tcMap.Store(ifaceKey, ifaceVal)
@@ -492,9 +535,34 @@ func (p *packetParser) createQdiscAndAttach(iface netlink.LinkAttrs, ifaceType s
 	p.l.Debug("Successfully added bpf", zap.String("interface", iface.Name))
 }
 
+// Attachments returns the interfaces the programs of packetparser are attached to, or failed to be attached to.
+func (p *packetParser) Attachments() []api.Attachment {
+	var attachments []api.Attachment
+	if p.tcMap != nil {
+		p.tcMap.Range(func(k, _ interface{}) bool {
+			attachments = append(attachments, api.Attachment{Target: "tc/" + k.(key).name})
+			return true
+		})
+	}
+	p.attachFailures.Range(func(k, v interface{}) bool {
+		attachments = append(attachments, api.Attachment{Target: "tc/" + k.(key).name, Error: v.(string)})
+		return true
+	})
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].Target < attachments[j].Target
+	})
+	return attachments
+}
+
 func (p *packetParser) run(ctx context.Context) error {
 	// Start perf record handlers (consumers).
-	for i := 0; i < workers; i++ {
+	// The ring buffer preserves event order across CPUs,
+	// so a single consumer is used to keep that order.
+	n := workers
+	if p.useRingBuffer {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
 		p.wg.Add(1)
 		go p.processRecord(ctx, i)
 	}
@@ -23,6 +23,8 @@ import (
 	"github.com/microsoft/retina/pkg/enricher"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/metrics"
+	"github.com/microsoft/retina/pkg/plugin/api"
+	plugincommon "github.com/microsoft/retina/pkg/plugin/common"
 	"github.com/microsoft/retina/pkg/plugin/packetparser/mocks"
 	"github.com/microsoft/retina/pkg/watchers/endpoint"
 	"github.com/prometheus/client_golang/prometheus"
@@ -267,6 +269,21 @@ func TestCreateQdiscAndAttach(t *testing.T) {
 	key = ifaceToKey(linkAttr2)
 	_, ok = p.tcMap.Load(key)
 	assert.True(t, ok)
+
+	// Test unknown interface type.
+	linkAttr3 := netlink.LinkAttrs{
+		Name:         "test3",
+		HardwareAddr: []byte("test3"),
+		NetNsID:      3,
+	}
+	p.createQdiscAndAttach(linkAttr3, "unknown")
+
+	attachments := p.Attachments()
+	assert.Len(t, attachments, 3)
+	assert.Equal(t, api.Attachment{Target: "tc/test"}, attachments[0])
+	assert.Equal(t, api.Attachment{Target: "tc/test2"}, attachments[1])
+	assert.Equal(t, "tc/test3", attachments[2].Target)
+	assert.NotEmpty(t, attachments[2].Error)
 }
 
 func TestReadData_Error(t *testing.T) {
@@ -372,6 +389,9 @@ func TestPacketParseGenerate(t *testing.T) {
 	takeBackup()
 	defer restoreBackup()
 
+	ringBufferSupported = func() bool { return false }
+	defer func() { ringBufferSupported = plugincommon.RingBufferSupported }()
+
 	log.SetupZapLogger(log.GetDefaultLogOpts())
 	// Get the directory of the current test file.
 	_, filename, _, ok := runtime.Caller(0)
@@ -408,6 +428,49 @@ func TestPacketParseGenerate(t *testing.T) {
 	}
 }
 
+func TestPacketParseGenerateRingBuffer(t *testing.T) {
+	takeBackup()
+	defer restoreBackup()
+
+	ringBufferSupported = func() bool { return true }
+	defer func() { ringBufferSupported = plugincommon.RingBufferSupported }()
+
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	// Get the directory of the current test file.
+	_, filename, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("failed to determine test file path")
+	}
+	currDir := path.Dir(filename)
+	dynamicHeaderPath := fmt.Sprintf("%s/%s/%s", currDir, bpfSourceDir, dynamicHeaderFileName)
+
+	// Instantiate the packetParser struct with a mocked logger and context.
+	p := &packetParser{
+		cfg: cfgPodLevelEnabled,
+		l:   log.Logger().Named(string(Name)),
+	}
+	ctx := context.Background()
+
+	// Call the Generate function and check if it returns an error.
+	if err := p.Generate(ctx); err != nil {
+		t.Fatalf("failed to generate PacketParser header: %v", err)
+	}
+
+	// Verify that the dynamic header file was created in the expected location and contains the expected contents.
+	if _, err := os.Stat(dynamicHeaderPath); os.IsNotExist(err) {
+		t.Fatalf("dynamic header file does not exist: %v", err)
+	}
+
+	expectedContents := "#define BYPASS_LOOKUP_IP_OF_INTEREST 1 \n#define USE_RING_BUFFER 1 \n#define RING_BUFFER_SIZE 8388608 \n"
+	actualContents, err := os.ReadFile(dynamicHeaderPath)
+	if err != nil {
+		t.Fatalf("failed to read dynamic header file: %v", err)
+	}
+	if string(actualContents) != expectedContents {
+		t.Errorf("unexpected dynamic header file contents: got %q, want %q", string(actualContents), expectedContents)
+	}
+}
+
 func TestCompile(t *testing.T) {
 	takeBackup()
 	defer restoreBackup()
@@ -17,6 +17,7 @@ import (
 	"github.com/microsoft/retina/pkg/enricher"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/plugin/api"
+	plugincommon "github.com/microsoft/retina/pkg/plugin/common"
 )
 
 const (
@@ -31,6 +32,7 @@ const (
 	bpfSourceFileName     string         = "packetparser.c"
 	bpfObjectFileName     string         = "packetparser_bpf.o"
 	dynamicHeaderFileName string         = "dynamic.h"
+	eventsMapName         string         = "packetparser_events"
 )
 
 var (
@@ -46,9 +48,13 @@ var (
 	getFD = func(e *ebpf.Program) int {
 		return e.FD()
 	}
+	ringBufferSupported = plugincommon.RingBufferSupported
 	// Determined via testing on a large cluster.
 	// Actual buffer size will be 32 * pagesize.
 	perCPUBuffer = 32
+	// Size in bytes of the ring buffer shared by all CPUs.
+	// Must be a power of 2 and a multiple of pagesize.
+	ringBufferSize = 8 * 1024 * 1024
 )
 
 type key struct {
@@ -80,6 +86,11 @@ type IPerf interface {
 	Close() error
 }
 
+// lostEventsObjects holds the maps that are not part of the bpf2go generated objects.
+type lostEventsObjects struct {
+	PacketparserLostEvents *ebpf.Map `ebpf:"packetparser_lost_events"`
+}
+
 type val struct {
 	tcnl         ITc
 	tcIngressObj *tc.Object
@@ -92,9 +103,11 @@ type packetParser struct {
 	callbackID string
 	objs       *packetparserObjects //nolint:typecheck
 	// tcMap is a map of key to *val.
-	tcMap    *sync.Map
-	reader   IPerf
-	enricher enricher.EnricherInterface
+	tcMap *sync.Map
+	// attachFailures is a map of key to the error attaching the programs to the interface.
+	attachFailures sync.Map
+	reader         IPerf
+	enricher       enricher.EnricherInterface
 	// interfaceLockMap is a map of key to *sync.Mutex.
 	interfaceLockMap    *sync.Map
 	endpointIngressInfo *ebpf.ProgramInfo
@@ -104,6 +117,9 @@ type packetParser struct {
 	wg                  sync.WaitGroup
 	recordsChannel      chan perf.Record
 	externalChannel     chan *v1.Event
+	// useRingBuffer is true when packetparser_events is a BPF ring buffer instead of a perf buffer.
+	useRingBuffer bool
+	lostEventsMap *ebpf.Map
 }
 
 func ifaceToKey(iface netlink.LinkAttrs) key {
@@ -0,0 +1,70 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/microsoft/retina/pkg/plugin/api"
+)
+
+var (
+	externalPluginsMu sync.Mutex
+	// externalPlugins are the plugins registered with Add, by plugins living outside of this repository.
+	externalPlugins = map[api.PluginName]NewPluginFn{}
+)
+
+// Add registers a plugin which is not part of Retina, so it can be enabled like the plugins of Retina.
+// It is meant to be called from the init function of the plugin package, and panics if a plugin with the
+// same name is already registered.
+func Add(name api.PluginName, newPluginFn NewPluginFn) {
+	externalPluginsMu.Lock()
+	defer externalPluginsMu.Unlock()
+
+	if _, ok := externalPlugins[name]; ok {
+		panic(fmt.Sprintf("plugin %s is already registered", name))
+	}
+	externalPlugins[name] = newPluginFn
+	// The plugins of Retina may already be registered, depending on the order packages are initialized in.
+	if PluginHandler != nil {
+		addPlugin(name, newPluginFn)
+	}
+}
+
+// IsAdded tells if a plugin was registered with Add.
+func IsAdded(name api.PluginName) bool {
+	externalPluginsMu.Lock()
+	defer externalPluginsMu.Unlock()
+
+	_, ok := externalPlugins[name]
+	return ok
+}
+
+// PluginNames returns the sorted names of the registered plugins.
+func PluginNames() []string {
+	names := make([]string, 0, len(PluginHandler))
+	for name := range PluginHandler {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registerExternalPlugins adds the plugins registered with Add to PluginHandler.
+func registerExternalPlugins() {
+	externalPluginsMu.Lock()
+	defer externalPluginsMu.Unlock()
+
+	for name, newPluginFn := range externalPlugins {
+		addPlugin(name, newPluginFn)
+	}
+}
+
+func addPlugin(name api.PluginName, newPluginFn NewPluginFn) {
+	if _, ok := PluginHandler[name]; ok {
+		panic(fmt.Sprintf("plugin %s is already registered", name))
+	}
+	PluginHandler[name] = newPluginFn
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/microsoft/retina/pkg/plugin/infiniband"
 	"github.com/microsoft/retina/pkg/plugin/linuxutil"
 	"github.com/microsoft/retina/pkg/plugin/mockplugin"
+	"github.com/microsoft/retina/pkg/plugin/nodeconnectivity"
 	"github.com/microsoft/retina/pkg/plugin/packetforward"
 	"github.com/microsoft/retina/pkg/plugin/packetparser"
 	"github.com/microsoft/retina/pkg/plugin/tcpretrans"
@@ -31,5 +32,7 @@ func RegisterPlugins() {
 	PluginHandler[packetparser.Name] = packetparser.New
 	PluginHandler[dns.Name] = dns.New
 	PluginHandler[tcpretrans.Name] = tcpretrans.New
+	PluginHandler[nodeconnectivity.Name] = nodeconnectivity.New
 	PluginHandler[mockplugin.Name] = mockplugin.New
+	registerExternalPlugins()
 }
@@ -16,4 +16,5 @@ var PluginHandler map[api.PluginName]NewPluginFn
 func RegisterPlugins() {
 	PluginHandler = make(map[api.PluginName]NewPluginFn, 500)
 	PluginHandler[hnsstats.Name] = hnsstats.New
+	registerExternalPlugins()
 }
@@ -0,0 +1,110 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+// Package conformance is the conformance test suite of the plugin SDK. It checks that a plugin behaves the way
+// the plugin manager of Retina expects it to, and is run from a test of the plugin:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, myplugin.Name, myplugin.New, &sdk.Config{})
+//	}
+//
+// Plugins using eBPF must run it with the privileges they need to load their programs.
+package conformance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/plugin/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// runTime is how long the plugin runs before its context is canceled.
+	runTime = time.Second
+	// stopTimeout is how long Start may take to return once its context is canceled.
+	stopTimeout = 10 * time.Second
+)
+
+// Run runs the conformance tests of the plugin registered as name and created by newPluginFn with cfg.
+func Run(t *testing.T, name sdk.PluginName, newPluginFn sdk.NewPluginFn, cfg *sdk.Config) {
+	t.Helper()
+	if _, err := log.SetupZapLogger(log.GetDefaultLogOpts()); err != nil {
+		t.Fatalf("failed to setup logger: %v", err)
+	}
+
+	t.Run("Registered", func(t *testing.T) {
+		require.True(t, sdk.IsRegistered(name), "plugin must be registered with sdk.Register from the init function of its package")
+	})
+
+	t.Run("Name", func(t *testing.T) {
+		plugin := newPluginFn(cfg)
+		require.NotNil(t, plugin)
+		require.Equal(t, string(name), plugin.Name(), "Name must return the name the plugin is registered with")
+	})
+
+	t.Run("StopBeforeStart", func(t *testing.T) {
+		plugin := newPluginFn(cfg)
+		require.NoError(t, plugin.Stop(), "Stop must succeed when the plugin is not started")
+	})
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		plugin := newPluginFn(cfg)
+		require.NoError(t, plugin.SetupChannel(make(chan *v1.Event, 100)))
+		reconcile(t, plugin)
+		start(t, plugin)
+		require.NoError(t, plugin.Stop())
+		require.NoError(t, plugin.Stop(), "Stop must succeed when the plugin is already stopped")
+	})
+
+	t.Run("Restart", func(t *testing.T) {
+		// The plugin manager restarts a failed plugin by reconciling it again, which stops and initializes it.
+		plugin := newPluginFn(cfg)
+		reconcile(t, plugin)
+		start(t, plugin)
+		reconcile(t, plugin)
+		start(t, plugin)
+		require.NoError(t, plugin.Stop())
+	})
+}
+
+// reconcile runs the sequence of calls the plugin manager reconciles the plugin with.
+func reconcile(t *testing.T, plugin sdk.Plugin) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, plugin.Generate(ctx))
+	require.NoError(t, plugin.Compile(ctx))
+	require.NoError(t, plugin.Stop())
+	require.NoError(t, plugin.Init())
+}
+
+// start starts the plugin, and checks that Start succeeds and returns once its context is canceled.
+func start(t *testing.T, plugin sdk.Plugin) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- plugin.Start(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		// The plugin started its main loop in the background.
+		require.NoError(t, err)
+		return
+	case <-time.After(runTime):
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(stopTimeout):
+		t.Fatalf("Start must return within %s once its context is canceled", stopTimeout)
+	}
+}
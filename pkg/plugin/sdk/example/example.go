@@ -0,0 +1,128 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+// Package example is an example of a plugin living outside of the Retina repository, built with the plugin SDK.
+// It emits a synthetic flow at a regular interval, which goes through the enricher and metrics pipeline of Retina
+// like the flows of the plugins of Retina.
+package example
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/plugin/sdk"
+	"go.uber.org/zap"
+)
+
+// Name is the name the plugin is enabled with in the configuration of the agent.
+const Name sdk.PluginName = "example"
+
+// interval is the interval the plugin emits a flow at.
+var interval = 10 * time.Second
+
+func init() {
+	sdk.Register(Name, New)
+}
+
+type example struct {
+	cfg             *sdk.Config
+	l               *log.ZapLogger
+	enricher        *sdk.Enricher
+	mu              sync.Mutex
+	externalChannel chan *v1.Event
+}
+
+// New creates the example plugin.
+func New(cfg *sdk.Config) sdk.Plugin {
+	return &example{
+		cfg: cfg,
+		l:   log.Logger().Named(string(Name)),
+	}
+}
+
+func (e *example) Name() string {
+	return string(Name)
+}
+
+func (e *example) Generate(context.Context) error {
+	return nil
+}
+
+func (e *example) Compile(context.Context) error {
+	return nil
+}
+
+func (e *example) Init() error {
+	if e.cfg != nil && e.cfg.EnablePodLevel {
+		e.enricher = sdk.GetEnricher()
+	}
+	e.l.Info("Initialized example plugin", zap.String("sdkVersion", sdk.Version))
+	return nil
+}
+
+// Start emits a flow at a regular interval until the context is done.
+func (e *example) Start(ctx context.Context) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			e.emit()
+		}
+	}
+}
+
+func (e *example) Stop() error {
+	return nil
+}
+
+func (e *example) SetupChannel(ch chan *v1.Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.externalChannel = ch
+	return nil
+}
+
+func (e *example) emit() {
+	fl := sdk.ToFlow(
+		time.Now().UnixNano(),
+		net.ParseIP("10.0.0.1").To4(),
+		net.ParseIP("10.0.0.2").To4(),
+		443,
+		8080,
+		6, // TCP.
+		1, // From the container to the network stack.
+		flow.Verdict_FORWARDED,
+	)
+	if fl == nil {
+		return
+	}
+	meta := &sdk.RetinaMetadata{}
+	sdk.AddPacketSize(meta, 64)
+	sdk.AddRetinaMetadata(fl, meta)
+
+	ev := &v1.Event{
+		Event:     fl,
+		Timestamp: fl.GetTime(),
+	}
+	if e.enricher != nil {
+		e.enricher.Write(ev)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.externalChannel != nil {
+		select {
+		case e.externalChannel <- ev:
+		default:
+			e.l.Debug("external channel is full, dropping event")
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package example
+
+import (
+	"testing"
+	"time"
+
+	"github.com/microsoft/retina/pkg/plugin/sdk"
+	"github.com/microsoft/retina/pkg/plugin/sdk/conformance"
+)
+
+func TestConformance(t *testing.T) {
+	interval = 100 * time.Millisecond
+	conformance.Run(t, Name, New, &sdk.Config{})
+}
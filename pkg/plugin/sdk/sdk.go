@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+
+// Package sdk is the SDK for plugins living outside of the Retina repository. A plugin implements Plugin,
+// registers itself with Register from the init function of its package, and is linked into the Retina agent
+// by blank importing its package from a file of the agent guarded by a build tag.
+// Its events, sent on the channel given to SetupChannel, go through the enricher and metrics pipeline of Retina
+// like the events of the plugins of Retina.
+//
+// The SDK is versioned by Version. Breaking changes of the SDK bump the major version.
+package sdk
+
+import (
+	"net"
+
+	"github.com/cilium/cilium/api/v1/flow"
+	kcfg "github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/enricher"
+	"github.com/microsoft/retina/pkg/plugin/api"
+	"github.com/microsoft/retina/pkg/plugin/registry"
+	"github.com/microsoft/retina/pkg/utils"
+)
+
+// Version is the version of the plugin SDK.
+const Version = "v1.0.0"
+
+type (
+	// Plugin is the interface a plugin implements, see api.Plugin.
+	Plugin = api.Plugin
+	// PluginName is the name a plugin is enabled with in the configuration of the agent.
+	PluginName = api.PluginName
+	// Config is the configuration of the agent, given to the plugin when it is created.
+	Config = kcfg.Config
+	// NewPluginFn creates the plugin from the configuration of the agent.
+	NewPluginFn = registry.NewPluginFn
+	// Enricher enriches the flows written to it with the Kubernetes metadata of their endpoints.
+	Enricher = enricher.Enricher
+	// RetinaMetadata is the metadata Retina adds to the flows, see utils.RetinaMetadata.
+	RetinaMetadata = utils.RetinaMetadata
+)
+
+// Register registers the plugin, so it can be enabled in the configuration of the agent like the plugins of
+// Retina. It must be called from the init function of the plugin package, and panics if a plugin with the same
+// name is already registered.
+func Register(name PluginName, newPluginFn NewPluginFn) {
+	registry.Add(name, newPluginFn)
+}
+
+// IsRegistered tells if the plugin was registered with Register.
+func IsRegistered(name PluginName) bool {
+	return registry.IsAdded(name)
+}
+
+// GetEnricher returns the enricher of the agent, which is only running when pod level metrics are enabled,
+// or nil when it is not running.
+func GetEnricher() *Enricher {
+	if !enricher.IsInitialized() {
+		return nil
+	}
+	return enricher.Instance()
+}
+
+// ToFlow returns the L3/L4 flow of a packet, see utils.ToFlow.
+func ToFlow(
+	ts int64,
+	sourceIP, destIP net.IP,
+	sourcePort, destPort uint32,
+	proto uint8,
+	observationPoint uint32,
+	verdict flow.Verdict,
+) *flow.Flow {
+	return utils.ToFlow(ts, sourceIP, destIP, sourcePort, destPort, proto, observationPoint, verdict)
+}
+
+// AddRetinaMetadata adds the metadata to the flow.
+func AddRetinaMetadata(f *flow.Flow, meta *RetinaMetadata) {
+	utils.AddRetinaMetadata(f, meta)
+}
+
+// AddPacketSize adds the size of the packet to the metadata.
+func AddPacketSize(meta *RetinaMetadata, packetSize uint64) {
+	utils.AddPacketSize(meta, packetSize)
+}
+
+// AddTCPFlags adds the TCP flags of the packet to the flow.
+func AddTCPFlags(f *flow.Flow, syn, ack, fin, rst, psh, urg uint16) {
+	utils.AddTCPFlags(f, syn, ack, fin, rst, psh, urg)
+}
+
+// AddDropReason adds the reason the packet was dropped for to the flow.
+func AddDropReason(f *flow.Flow, meta *RetinaMetadata, dropReason uint32) {
+	utils.AddDropReason(f, meta, dropReason)
+}
@@ -0,0 +1,29 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package sdk
+
+import (
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/perf"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/plugin/common"
+)
+
+// RingBufReader reads the records of a ring buffer, see common.RingBufReader.
+type RingBufReader = common.RingBufReader
+
+// NewPerfReader creates a reader of the perf event array, with a buffer of max pages, or fewer pages down to min
+// when the memory is short. See common.NewPerfReader.
+func NewPerfReader(l *log.ZapLogger, m *ebpf.Map, max, min int) (*perf.Reader, error) {
+	return common.NewPerfReader(l, m, max, min)
+}
+
+// RingBufferSupported tells if the kernel supports eBPF ring buffers.
+func RingBufferSupported() bool {
+	return common.RingBufferSupported()
+}
+
+// NewRingBufReader creates a reader of the ring buffer m, which counts the records lost in lostMap.
+func NewRingBufReader(l *log.ZapLogger, m, lostMap *ebpf.Map) (*RingBufReader, error) {
+	return common.NewRingBufReader(l, m, lostMap)
+}
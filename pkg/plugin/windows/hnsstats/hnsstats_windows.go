@@ -83,8 +83,14 @@ func (h *hnsstats) SetupChannel(ch chan *v1.Event) error {
 	return nil
 }
 
+// SetMetricsInterval changes the interval the hnsstats metrics are pulled at.
+func (h *hnsstats) SetMetricsInterval(interval time.Duration) {
+	h.interval.Set(interval)
+}
+
 func pullHnsStats(ctx context.Context, h *hnsstats) error {
-	ticker := time.NewTicker(h.cfg.MetricsInterval)
+	interval, changed := h.interval.Get(h.cfg.MetricsInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -92,6 +98,9 @@ func pullHnsStats(ctx context.Context, h *hnsstats) error {
 		case <-ctx.Done():
 			h.l.Error("hnsstats plugin canceling", zap.Error(ctx.Err()))
 			return h.Stop()
+		case <-changed:
+			interval, changed = h.interval.Get(h.cfg.MetricsInterval)
+			ticker.Reset(interval)
 		case <-ticker.C:
 			// Pull data from node
 			// Get local endpoints that are healthy
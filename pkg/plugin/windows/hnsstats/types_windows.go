@@ -5,13 +5,13 @@ package hnsstats
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/Microsoft/hcsshim"
 	"github.com/Microsoft/hcsshim/hcn"
 	kcfg "github.com/microsoft/retina/pkg/config"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/plugin/api"
+	"github.com/microsoft/retina/pkg/plugin/common"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 
@@ -70,7 +70,7 @@ const (
 
 type hnsstats struct {
 	cfg           *kcfg.Config
-	interval      time.Duration
+	interval      common.MetricsInterval
 	state         int
 	l             *log.ZapLogger
 	endpointQuery hcn.HostComputeQuery
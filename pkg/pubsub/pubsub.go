@@ -5,12 +5,19 @@ package pubsub
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/metrics"
+	"github.com/microsoft/retina/pkg/utils"
 	"go.uber.org/zap"
 )
 
+// defaultQueueSize is the number of messages queued for a subscriber before the next ones are dropped.
+const defaultQueueSize = 10000
+
 var (
 	p    *PubSub
 	once sync.Once
@@ -20,41 +27,78 @@ type PubSub struct {
 	sync.RWMutex
 	// l is the logger.
 	l *log.ZapLogger
-	// topicToCallback is a map of topic to a map of callback function
-	topicToCallback map[PubSubTopic]map[string]*CallBackFunc
+	// topicToSubscriber is a map of topic to a map of subscribers by uuid.
+	topicToSubscriber map[PubSubTopic]map[string]*subscriber
+	// queueSize is the size of the queue of each subscriber.
+	queueSize int
+	// synchronous calls the callbacks in Publish instead of queueing the messages.
+	synchronous bool
+}
+
+// subscriber calls its callback with the messages of its queue, in the order they were published.
+type subscriber struct {
+	callback *CallBackFunc
+	queue    chan interface{}
+	// pending is the number of messages queued or being handled by the callback.
+	pending atomic.Int64
+	done    chan struct{}
 }
 
 // New returns a new instance of PubSub.
 func New() *PubSub {
 	once.Do(func() {
-		p = &PubSub{
-			l:               log.Logger().Named(string("PubSub")),
-			topicToCallback: make(map[PubSubTopic]map[string]*CallBackFunc),
-		}
+		p = newPubSub(false)
 	})
 
 	return p
 }
 
+// NewSync returns a PubSub which calls the callbacks before Publish returns, for tests.
+func NewSync() *PubSub {
+	return newPubSub(true)
+}
+
+func newPubSub(synchronous bool) *PubSub {
+	return &PubSub{
+		l:                 log.Logger().Named(string("PubSub")),
+		topicToSubscriber: make(map[PubSubTopic]map[string]*subscriber),
+		queueSize:         defaultQueueSize,
+		synchronous:       synchronous,
+	}
+}
+
 // Publish publishes the given message to the given topic,
-// and calls all the callback functions subscribed to the topic.
+// and queues it for all the subscribers of the topic.
+// A subscriber gets the messages in the order they were published. When its queue is full, the message is dropped.
 func (p *PubSub) Publish(topic PubSubTopic, msg interface{}) {
+	if p.synchronous {
+		// Call the callbacks without the lock, so that they can subscribe or publish.
+		for _, s := range p.subscribers(topic) {
+			(*s.callback)(msg)
+		}
+		return
+	}
+
 	p.RLock()
 	defer p.RUnlock()
 
-	// If there are no callbacks for the given topic, return nil.
-	if _, ok := p.topicToCallback[topic]; !ok {
+	// If there are no subscribers for the given topic, return.
+	if _, ok := p.topicToSubscriber[topic]; !ok {
 		p.l.Debug("no callbacks for topic", zap.String("topic", string(topic)))
 		return
 	}
 
-	// Run all callbacks in parallel using a wait group.
-	for uuid, callback := range p.topicToCallback[topic] {
-		callback := callback
-		p.l.Debug("running callback", zap.String("topic", string(topic)), zap.String("uuid", uuid))
-		go func() {
-			(*callback)(msg)
-		}()
+	for uuid, s := range p.topicToSubscriber[topic] {
+		s.pending.Add(1)
+		select {
+		case s.queue <- msg:
+		default:
+			s.pending.Add(-1)
+			p.l.Warn("subscriber queue is full, dropping message", zap.String("topic", string(topic)), zap.String("uuid", uuid))
+			if metrics.LostEventsCounter != nil {
+				metrics.LostEventsCounter.WithLabelValues(utils.PubSubQueue, string(topic)).Inc()
+			}
+		}
 	}
 }
 
@@ -65,20 +109,30 @@ func (p *PubSub) Subscribe(topic PubSubTopic, callback *CallBackFunc) string {
 	defer p.Unlock()
 
 	// If the topic does not exist, create it.
-	if _, ok := p.topicToCallback[topic]; !ok {
-		p.topicToCallback[topic] = make(map[string]*CallBackFunc)
+	if _, ok := p.topicToSubscriber[topic]; !ok {
+		p.topicToSubscriber[topic] = make(map[string]*subscriber)
+	}
+
+	s := &subscriber{
+		callback: callback,
+		done:     make(chan struct{}),
+	}
+	if !p.synchronous {
+		s.queue = make(chan interface{}, p.queueSize)
+		go s.run()
 	}
 
 	// Generate a new uuid for the callback.
 	uuid := uuid.New().String()
-	// Add the callback to the topic.
-	p.topicToCallback[topic][uuid] = callback
+	// Add the subscriber to the topic.
+	p.topicToSubscriber[topic][uuid] = s
 	p.l.Debug("subscribed to topic", zap.String("topic", string(topic)), zap.String("uuid", uuid))
 
 	return uuid
 }
 
 // Unsubscribe unsubscribes from the given topic.
+// The messages still queued for the subscriber are dropped.
 func (p *PubSub) Unsubscribe(topic PubSubTopic, uuid string) error {
 	p.Lock()
 	defer p.Unlock()
@@ -88,26 +142,82 @@ func (p *PubSub) Unsubscribe(topic PubSubTopic, uuid string) error {
 	}
 
 	// If the topic does not exist, return nil.
-	if _, ok := p.topicToCallback[topic]; !ok {
+	if _, ok := p.topicToSubscriber[topic]; !ok {
 		p.l.Debug("no callbacks for topic", zap.String("topic", string(topic)))
 		return nil
 	}
 
 	// If the callback does not exist, return nil.
-	if _, ok := p.topicToCallback[topic][uuid]; !ok {
+	s, ok := p.topicToSubscriber[topic][uuid]
+	if !ok {
 		p.l.Debug("callback does not exist", zap.String("topic", string(topic)), zap.String("uuid", uuid))
 		return nil
 	}
 
-	// Delete the callback from the topic.
-	delete(p.topicToCallback[topic], uuid)
+	// Delete the subscriber from the topic and stop it.
+	delete(p.topicToSubscriber[topic], uuid)
+	close(s.done)
 	p.l.Debug("unsubscribed from topic", zap.String("topic", string(topic)), zap.String("uuid", uuid))
 
 	// Delete the topic if there are no callbacks left.
-	if len(p.topicToCallback[topic]) == 0 {
-		delete(p.topicToCallback, topic)
+	if len(p.topicToSubscriber[topic]) == 0 {
+		delete(p.topicToSubscriber, topic)
 		p.l.Debug("deleted topic", zap.String("topic", string(topic)))
 	}
 
 	return nil
 }
+
+// Flush waits until the callbacks handled all the messages published so far, for tests.
+func (p *PubSub) Flush() {
+	p.RLock()
+	var subscribers []*subscriber
+	for topic := range p.topicToSubscriber {
+		for _, s := range p.topicToSubscriber[topic] {
+			subscribers = append(subscribers, s)
+		}
+	}
+	p.RUnlock()
+
+	for _, s := range subscribers {
+		for s.pending.Load() > 0 && !s.stopped() {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func (p *PubSub) subscribers(topic PubSubTopic) []*subscriber {
+	p.RLock()
+	defer p.RUnlock()
+
+	subscribers := make([]*subscriber, 0, len(p.topicToSubscriber[topic]))
+	for _, s := range p.topicToSubscriber[topic] {
+		subscribers = append(subscribers, s)
+	}
+	return subscribers
+}
+
+func (s *subscriber) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *subscriber) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.queue:
+			// Both cases are ready when unsubscribed with messages left.
+			if s.stopped() {
+				return
+			}
+			(*s.callback)(msg)
+			s.pending.Add(-1)
+		}
+	}
+}
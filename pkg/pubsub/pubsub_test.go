@@ -112,3 +112,117 @@ func TestPubSub(t *testing.T) {
 
 	time.Sleep(until)
 }
+
+func TestPublishOrder(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	ps := New()
+
+	var got []int
+	cb := CallBackFunc(func(msg interface{}) {
+		got = append(got, msg.(int))
+	})
+	uuid := ps.Subscribe("order", &cb)
+	defer ps.Unsubscribe("order", uuid) //nolint:errcheck // Test cleanup.
+
+	want := make([]int, 100)
+	for i := range want {
+		want[i] = i
+		ps.Publish("order", i)
+	}
+	ps.Flush()
+	assert.Equal(t, want, got, "Expected the messages in the order they were published")
+}
+
+func TestPublishQueueFull(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	ps := newPubSub(false)
+	ps.queueSize = 1
+
+	block := make(chan struct{})
+	var got []string
+	cb := CallBackFunc(func(msg interface{}) {
+		<-block
+		got = append(got, msg.(string))
+	})
+	uuid := ps.Subscribe("full", &cb)
+	defer ps.Unsubscribe("full", uuid) //nolint:errcheck // Test cleanup.
+
+	// The first message is handled, the second queued and the third dropped.
+	ps.Publish("full", "first")
+	assert.Eventually(t, func() bool {
+		return len(ps.topicToSubscriber["full"][uuid].queue) == 0
+	}, time.Second, until)
+	ps.Publish("full", "second")
+	ps.Publish("full", "third")
+	close(block)
+	ps.Flush()
+	assert.Equal(t, []string{"first", "second"}, got, "Expected the message to be dropped when the queue is full")
+}
+
+func TestUnsubscribeStopsDelivery(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	ps := newPubSub(false)
+
+	block := make(chan struct{})
+	got := make(chan string, 3)
+	cb := CallBackFunc(func(msg interface{}) {
+		<-block
+		got <- msg.(string)
+	})
+	uuid := ps.Subscribe("stop", &cb)
+	ps.Publish("stop", "first")
+	ps.Publish("stop", "second")
+	assert.Eventually(t, func() bool {
+		return len(ps.topicToSubscriber["stop"][uuid].queue) == 1
+	}, time.Second, until)
+
+	// The message being handled completes, the queued one is dropped.
+	assert.NoError(t, ps.Unsubscribe("stop", uuid))
+	close(block)
+	ps.Publish("stop", "third")
+	assert.Equal(t, "first", <-got)
+	time.Sleep(10 * until)
+	assert.Empty(t, got, "Expected no message after unsubscribing")
+}
+
+func TestNewSync(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	ps := NewSync()
+	assert.NotSame(t, New(), ps, "Expected a new PubSub")
+
+	var got []string
+	cb := CallBackFunc(func(msg interface{}) {
+		got = append(got, msg.(string))
+	})
+	uuid := ps.Subscribe("sync", &cb)
+	ps.Publish("sync", "msg")
+	assert.Equal(t, []string{"msg"}, got, "Expected the callback to be called before Publish returns")
+
+	assert.NoError(t, ps.Unsubscribe("sync", uuid))
+	ps.Publish("sync", "msg")
+	assert.Len(t, got, 1, "Expected no message after unsubscribing")
+}
+
+type event struct {
+	name string
+}
+
+func TestTopic(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	ps := NewSync()
+	topic := NewTopic[*event]("events")
+	assert.Equal(t, PubSubTopic("events"), topic.Name())
+
+	var got []string
+	uuid := topic.Subscribe(ps, func(e *event) {
+		got = append(got, e.name)
+	})
+	topic.Publish(ps, &event{name: "created"})
+	// Messages of another type published on the topic are ignored.
+	ps.Publish(topic.Name(), "deleted")
+	assert.Equal(t, []string{"created"}, got)
+
+	assert.NoError(t, topic.Unsubscribe(ps, uuid))
+	topic.Publish(ps, &event{name: "deleted"})
+	assert.Equal(t, []string{"created"}, got, "Expected no message after unsubscribing")
+}
@@ -0,0 +1,42 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package pubsub
+
+// Topic is a topic whose messages are of type T.
+// It is declared next to T, so that its publishers and subscribers agree on the type of its messages.
+type Topic[T any] struct {
+	name PubSubTopic
+}
+
+// NewTopic returns the topic of the given name with messages of type T.
+func NewTopic[T any](name PubSubTopic) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name returns the name of the topic.
+func (t Topic[T]) Name() PubSubTopic {
+	return t.name
+}
+
+// Publish publishes the given message to the topic.
+func (t Topic[T]) Publish(ps PubSubInterface, msg T) {
+	ps.Publish(t.name, msg)
+}
+
+// Subscribe subscribes to the topic and calls the given function with its messages, in the order they were
+// published. Messages which are not of type T are ignored. It returns the uuid of the subscription.
+func (t Topic[T]) Subscribe(ps PubSubInterface, fn func(T)) string {
+	cb := CallBackFunc(func(obj interface{}) {
+		msg, ok := obj.(T)
+		if !ok {
+			return
+		}
+		fn(msg)
+	})
+	return ps.Subscribe(t.name, &cb)
+}
+
+// Unsubscribe unsubscribes the given uuid from the topic.
+func (t Topic[T]) Unsubscribe(ps PubSubInterface, uuid string) error {
+	return ps.Unsubscribe(t.name, uuid)
+}
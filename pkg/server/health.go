@@ -0,0 +1,139 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const (
+	// HealthzPath is the path of the liveness checks of the agent.
+	HealthzPath = "/healthz"
+	// ReadyzPath is the path of the readiness checks of the agent.
+	ReadyzPath = "/readyz"
+	// DebugStatePath is the path of the dump of the internal state of the agent.
+	DebugStatePath = "/debug/state"
+)
+
+const (
+	checkStatusOK     = "ok"
+	checkStatusFailed = "failed"
+)
+
+// Check tells whether a component of the agent is healthy, and returns why it is not otherwise.
+type Check func() error
+
+// StateFunc returns the internal state of a component of the agent, to be encoded as JSON.
+type StateFunc func() interface{}
+
+// CheckResult is the result of a Check.
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ChecksResponse is the response of the health and readiness endpoints.
+type ChecksResponse struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// checks are the named checks served on an endpoint.
+type checks struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+func (c *checks) add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checks == nil {
+		c.checks = map[string]Check{}
+	}
+	c.checks[name] = check
+}
+
+// run runs the checks, and tells whether all of them passed.
+func (c *checks) run() (ChecksResponse, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	resp := ChecksResponse{Status: checkStatusOK, Checks: make([]CheckResult, 0, len(c.checks))}
+	for name, check := range c.checks {
+		result := CheckResult{Name: name, Status: checkStatusOK}
+		if err := check(); err != nil {
+			result.Status = checkStatusFailed
+			result.Error = err.Error()
+			resp.Status = checkStatusFailed
+		}
+		resp.Checks = append(resp.Checks, result)
+	}
+	sort.Slice(resp.Checks, func(i, j int) bool {
+		return resp.Checks[i].Name < resp.Checks[j].Name
+	})
+	return resp, resp.Status == checkStatusOK
+}
+
+func (c *checks) handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		resp, ok := c.run()
+		code := http.StatusOK
+		if !ok {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, resp)
+	}
+}
+
+// states are the named internal states served on DebugStatePath.
+type states struct {
+	mu     sync.RWMutex
+	states map[string]StateFunc
+}
+
+func (s *states) add(name string, state StateFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = map[string]StateFunc{}
+	}
+	s.states[name] = state
+}
+
+func (s *states) handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		s.mu.RLock()
+		resp := make(map[string]interface{}, len(s.states))
+		for name, state := range s.states {
+			resp[name] = state()
+		}
+		s.mu.RUnlock()
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// AddHealthzCheck adds a check served on HealthzPath. The agent is live as long as all of these checks pass, they
+// should only fail when restarting the agent is the way to recover.
+func (rt *Server) AddHealthzCheck(name string, check Check) {
+	rt.healthz.add(name, check)
+}
+
+// AddReadyzCheck adds a check served on ReadyzPath. The agent is ready once all of these checks pass.
+func (rt *Server) AddReadyzCheck(name string, check Check) {
+	rt.readyz.add(name, check)
+}
+
+// AddDebugState adds the internal state of a component served on DebugStatePath.
+func (rt *Server) AddDebugState(name string, state StateFunc) {
+	rt.states.add(name, state)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
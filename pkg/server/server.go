@@ -21,6 +21,10 @@ import (
 type Server struct {
 	l   *log.ZapLogger
 	mux *chi.Mux
+
+	healthz checks
+	readyz  checks
+	states  states
 }
 
 func New(logger *log.ZapLogger) *Server {
@@ -54,9 +58,17 @@ func (rt *Server) SetupHandlers() {
 	rt.mux.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
 	rt.mux.Handle("/debug/pprof/allocs", pprof.Handler("allocs"))
 	rt.mux.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
+	rt.mux.Get(HealthzPath, rt.healthz.handler())
+	rt.mux.Get(ReadyzPath, rt.readyz.handler())
+	rt.mux.Get(DebugStatePath, rt.states.handler())
 	rt.l.Info("Completed handler setup")
 }
 
+// Handle registers the handler for the pattern, for the handlers served by the other components of the agent.
+func (rt *Server) Handle(pattern string, handler http.Handler) {
+	rt.mux.Handle(pattern, handler)
+}
+
 func (rt *Server) servePrometheusMetrics() {
 	rt.mux.Get("/metrics", promhttp.HandlerFor(exporter.CombinedGatherer, promhttp.HandlerOpts{}).ServeHTTP)
 }
@@ -2,6 +2,10 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -54,3 +58,45 @@ func TestServerStartOnUsedPort(t *testing.T) {
 
 	require.Error(t, err)
 }
+
+func TestHealthEndpoints(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	s := New(log.Logger().Named("http-server"))
+	s.SetupHandlers()
+
+	get := func(path string, v interface{}) int {
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(v))
+		return rec.Code
+	}
+
+	// No checks pass.
+	var resp ChecksResponse
+	require.Equal(t, http.StatusOK, get(HealthzPath, &resp))
+	require.Equal(t, checkStatusOK, resp.Status)
+
+	s.AddHealthzCheck("live", func() error { return nil })
+	s.AddReadyzCheck("live", func() error { return nil })
+	s.AddReadyzCheck("plugins", func() error { return errors.New("plugin packetparser is starting") })
+
+	resp = ChecksResponse{}
+	require.Equal(t, http.StatusOK, get(HealthzPath, &resp))
+	require.Equal(t, []CheckResult{{Name: "live", Status: checkStatusOK}}, resp.Checks)
+
+	resp = ChecksResponse{}
+	require.Equal(t, http.StatusServiceUnavailable, get(ReadyzPath, &resp))
+	require.Equal(t, checkStatusFailed, resp.Status)
+	require.Equal(t, []CheckResult{
+		{Name: "live", Status: checkStatusOK},
+		{Name: "plugins", Status: checkStatusFailed, Error: "plugin packetparser is starting"},
+	}, resp.Checks)
+
+	s.AddDebugState("filters", func() interface{} {
+		return map[string][]string{"10.0.0.1": {"trace1"}}
+	})
+	var state map[string]map[string][]string
+	require.Equal(t, http.StatusOK, get(DebugStatePath, &state))
+	require.Equal(t, map[string]map[string][]string{"filters": {"10.0.0.1": {"trace1"}}}, state)
+}
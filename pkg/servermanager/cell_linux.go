@@ -6,7 +6,9 @@ import (
 	"sync"
 
 	"github.com/cilium/cilium/pkg/hive/cell"
+	"github.com/microsoft/retina/pkg/bpf"
 	"github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/managers/pluginmanager"
 	sm "github.com/microsoft/retina/pkg/managers/servermanager"
 	"github.com/sirupsen/logrus"
 )
@@ -23,6 +25,9 @@ type serverParams struct {
 	Log       logrus.FieldLogger
 	Lifecycle cell.Lifecycle
 	Config    config.Config
+	Reloader  *config.Reloader
+
+	PluginManager *pluginmanager.PluginManager
 }
 
 func newServerManager(params serverParams) (*sm.HTTPServer, error) {
@@ -35,6 +40,16 @@ func newServerManager(params serverParams) (*sm.HTTPServer, error) {
 		cancelCtx()
 		return nil, fmt.Errorf("unable to initialize Http server: %w", err)
 	}
+	serverManager.RegisterHandler(pluginmanager.PluginStatusPath, params.PluginManager.PluginStatusHandler())
+	serverManager.RegisterHandler(config.ConfigPath, params.Reloader.Handler())
+	serverManager.AddHealthzCheck("plugins", params.PluginManager.HealthzCheck)
+	serverManager.AddReadyzCheck("plugins", params.PluginManager.ReadyzCheck)
+	serverManager.AddDebugState("plugins", func() interface{} {
+		return params.PluginManager.DebugState()
+	})
+	serverManager.AddDebugState("bpf", func() interface{} {
+		return bpf.GetState()
+	})
 
 	wg := sync.WaitGroup{}
 	params.Lifecycle.Append(cell.Hook{
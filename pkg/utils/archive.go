@@ -0,0 +1,69 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+package utils
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CompressFolderToTarGz writes the files of the src folder to the dst gzipped tarball, by their path relative to src.
+func CompressFolderToTarGz(src string, dst string) error {
+	// Create the output file
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// Create the gzip writer
+	gz := gzip.NewWriter(out)
+	defer gz.Close()
+
+	// Create the tar writer
+	tarWriter := tar.NewWriter(gz)
+	defer tarWriter.Close()
+
+	// Walk the source directory and add files to the tar archive
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Create a new tar header
+		header, err := tar.FileInfoHeader(info, info.Name())
+		if err != nil {
+			return err
+		}
+
+		// Set the header name to the relative path
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		header.Name = relPath
+
+		// Write the header and file contents to the tar archive
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if _, err = io.Copy(tarWriter, file); err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	return err
+}
@@ -47,6 +47,7 @@ var (
 	Flag           = "flag"
 	Endpoint       = "endpoint"
 	AclRule        = "aclrule"
+	Plugin         = "plugin"
 	Active         = "ACTIVE"
 	Device         = "device"
 
@@ -64,6 +65,7 @@ var (
 	EnricherRing    = "enricher_ring"
 	BufferedChannel = "buffered_channel"
 	ExternalChannel = "external_channel"
+	PubSubQueue     = "pubsub_queue"
 
 	// TCP Flags
 	SYN    = "SYN"
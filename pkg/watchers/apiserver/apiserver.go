@@ -212,7 +212,7 @@ func (a *ApiServerWatcher) publish(netIPs []net.IP, eventType cc.EventType) {
 		ipsToPublish = append(ipsToPublish, ip.String())
 	}
 	ps := pubsub.New()
-	ps.Publish(common.PubSubAPIServer,
+	cc.APIServerTopic.Publish(ps,
 		cc.NewCacheEvent(
 			eventType,
 			common.NewAPIServerObject(ipsToPublish),
@@ -5,8 +5,8 @@ package endpoint
 
 import (
 	"context"
+	"sync"
 
-	"github.com/microsoft/retina/pkg/common"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/pubsub"
 	"go.uber.org/zap"
@@ -15,9 +15,13 @@ import (
 type EndpointWatcher struct {
 	isRunning bool
 	l         *log.ZapLogger
-	current   cache
-	new       cache
-	p         pubsub.PubSubInterface
+	// mu guards the caches, which both the refreshes and the link updates change.
+	mu      sync.Mutex
+	current cache
+	new     cache
+	p       pubsub.PubSubInterface
+	// done stops the subscription to the link updates, nil when not subscribed.
+	done chan struct{}
 }
 
 var e *EndpointWatcher
@@ -42,6 +46,13 @@ func (e *EndpointWatcher) Init(ctx context.Context) error {
 		return nil
 	}
 	e.isRunning = true
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// The endpoints are still refreshed periodically without the subscription.
+	if err := e.subscribe(); err != nil {
+		e.l.Warn("failed to subscribe to link updates, endpoints are only refreshed periodically", zap.Error(err))
+	}
 	return nil
 }
 
@@ -51,10 +62,26 @@ func (e *EndpointWatcher) Stop(ctx context.Context) error {
 		return nil
 	}
 	e.isRunning = false
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.unsubscribe()
 	return nil
 }
 
+// Refresh diffs the endpoints with the cache and publishes the changes. The link updates publish them as they happen,
+// the refresh resyncs the cache with the endpoints changed while not subscribed.
 func (e *EndpointWatcher) Refresh(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Subscribe again when the subscription failed.
+	if e.isRunning && e.done == nil {
+		if err := e.subscribe(); err != nil {
+			e.l.Warn("failed to subscribe to link updates", zap.Error(err))
+		}
+	}
+
 	// initNewCache is OS specific.
 	// Based on GOOS, will be implemented by either endpoint_linux, or
 	// endpoint_windows.
@@ -66,16 +93,17 @@ func (e *EndpointWatcher) Refresh(ctx context.Context) error {
 	// Compare the new veths with the old ones.
 	created, deleted := e.diffCache()
 
+	// Publish the deleted veths first, a changed veth is both deleted under its previous key and created under its
+	// new one.
+	for _, v := range deleted {
+		e.l.Debug("Endpoint deleted", zap.Any("veth", v))
+		EndpointsTopic.Publish(e.p, NewEndpointEvent(EndpointDeleted, v))
+	}
+
 	// Publish the new veths.
 	for _, v := range created {
 		e.l.Debug("Endpoint created", zap.Any("veth", v))
-		e.p.Publish(common.PubSubEndpoints, NewEndpointEvent(EndpointCreated, v))
-	}
-
-	// Publish the deleted veths.
-	for _, v := range deleted {
-		e.l.Debug("Endpoint deleted", zap.Any("veth", v))
-		e.p.Publish(common.PubSubEndpoints, NewEndpointEvent(EndpointDeleted, v))
+		EndpointsTopic.Publish(e.p, NewEndpointEvent(EndpointCreated, v))
 	}
 
 	// Update the cache and reset the new cache.
@@ -85,6 +113,14 @@ func (e *EndpointWatcher) Refresh(ctx context.Context) error {
 	return nil
 }
 
+// unsubscribe stops the subscription to the link updates. Must be called with e.mu held.
+func (e *EndpointWatcher) unsubscribe() {
+	if e.done != nil {
+		close(e.done)
+		e.done = nil
+	}
+}
+
 // Function to differentiate between two caches.
 func (e *EndpointWatcher) diffCache() (created, deleted []interface{}) {
 	// Check if there are any new veths.
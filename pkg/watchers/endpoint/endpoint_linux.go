@@ -5,9 +5,110 @@ package endpoint
 
 import (
 	"github.com/vishvananda/netlink"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
 )
 
-var showLink = netlink.LinkList
+// linkUpdatesBuffer is the number of link updates buffered while the previous ones are handled.
+const linkUpdatesBuffer = 1024
+
+var (
+	showLink      = netlink.LinkList
+	linkSubscribe = netlink.LinkSubscribeWithOptions
+)
+
+// subscribe subscribes to the link updates of the host, to publish the veths as soon as they are created or deleted
+// instead of at the next refresh. Must be called with e.mu held.
+func (e *EndpointWatcher) subscribe() error {
+	updates := make(chan netlink.LinkUpdate, linkUpdatesBuffer)
+	done := make(chan struct{})
+	if err := linkSubscribe(updates, done, netlink.LinkSubscribeOptions{
+		ErrorCallback: func(err error) {
+			e.l.Warn("link updates error", zap.Error(err))
+		},
+	}); err != nil {
+		close(done)
+		return err
+	}
+	e.done = done
+	go e.handleLinkUpdates(updates, done)
+	return nil
+}
+
+func (e *EndpointWatcher) handleLinkUpdates(updates <-chan netlink.LinkUpdate, done chan struct{}) {
+	for u := range updates {
+		e.mu.Lock()
+		e.handleLinkUpdate(u)
+		e.mu.Unlock()
+	}
+
+	// The updates are closed when unsubscribed, or when receiving them failed, e.g. when the socket overflowed.
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.done == done {
+		e.l.Warn("link updates stopped, subscribing again at the next refresh")
+		e.unsubscribe()
+	}
+}
+
+// handleLinkUpdate publishes the veth of the update when it was created or deleted. Must be called with e.mu held.
+func (e *EndpointWatcher) handleLinkUpdate(u netlink.LinkUpdate) {
+	if u.Link == nil || u.Link.Type() != "veth" {
+		return
+	}
+	veth := *u.Link.Attrs()
+	k := key{
+		name:         veth.Name,
+		hardwareAddr: veth.HardwareAddr.String(),
+		netNsID:      veth.NetNsID,
+	}
+
+	switch u.Header.Type {
+	case unix.RTM_NEWLINK:
+		// Links are also updated when their state changes.
+		if _, ok := e.current[k]; ok {
+			return
+		}
+		// A veth renamed, or whose MAC address or peer namespace changed, is deleted under its previous key before
+		// being created under the new one, otherwise the next refresh would delete it after it was created.
+		if prev, ok := e.findByIndex(veth.Index); ok {
+			v := e.current[prev]
+			delete(e.current, prev)
+			e.l.Debug("Endpoint updated", zap.String("veth", veth.Name))
+			EndpointsTopic.Publish(e.p, NewEndpointEvent(EndpointDeleted, v))
+		}
+		e.current[k] = veth
+		e.l.Debug("Endpoint created", zap.String("veth", veth.Name))
+		EndpointsTopic.Publish(e.p, NewEndpointEvent(EndpointCreated, veth))
+	case unix.RTM_DELLINK:
+		// The veth may have changed since it was cached.
+		if _, ok := e.current[k]; !ok {
+			prev, ok := e.findByIndex(veth.Index)
+			if !ok {
+				return
+			}
+			k = prev
+		}
+		v := e.current[k]
+		delete(e.current, k)
+		e.l.Debug("Endpoint deleted", zap.String("veth", veth.Name))
+		EndpointsTopic.Publish(e.p, NewEndpointEvent(EndpointDeleted, v))
+	}
+}
+
+// findByIndex returns the key of the cached veth with the given interface index. Must be called with e.mu held.
+func (e *EndpointWatcher) findByIndex(index int) (key, bool) {
+	// The index is only unset for links not read from the kernel.
+	if index == 0 {
+		return key{}, false
+	}
+	for k, v := range e.current {
+		if veth, ok := v.(netlink.LinkAttrs); ok && veth.Index == index {
+			return k, true
+		}
+	}
+	return key{}, false
+}
 
 func (e *EndpointWatcher) initNewCache() error {
 	veths, err := listVeths()
@@ -9,13 +9,32 @@ import (
 	"errors"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/microsoft/retina/pkg/common"
 	"github.com/microsoft/retina/pkg/log"
 	"github.com/microsoft/retina/pkg/pubsub"
 	"github.com/stretchr/testify/assert"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
+// fakeLinkSubscribe replaces the subscription to the link updates, and returns the channel of the updates and the
+// number of subscriptions.
+func fakeLinkSubscribe(t *testing.T) (updates func() chan<- netlink.LinkUpdate, subscriptions func() int) {
+	var (
+		ch chan<- netlink.LinkUpdate
+		n  int
+	)
+	linkSubscribe = func(c chan<- netlink.LinkUpdate, _ <-chan struct{}, _ netlink.LinkSubscribeOptions) error {
+		ch = c
+		n++
+		return nil
+	}
+	t.Cleanup(func() { linkSubscribe = netlink.LinkSubscribeWithOptions })
+	return func() chan<- netlink.LinkUpdate { return ch }, func() int { return n }
+}
+
 func TestGetWatcher(t *testing.T) {
 	log.SetupZapLogger(log.GetDefaultLogOpts())
 
@@ -28,6 +47,7 @@ func TestGetWatcher(t *testing.T) {
 
 func TestEndpointWatcherStart(t *testing.T) {
 	log.SetupZapLogger(log.GetDefaultLogOpts())
+	fakeLinkSubscribe(t)
 	c := context.Background()
 
 	// When veth is already running.
@@ -61,6 +81,7 @@ func TestEndpointWatcherStart(t *testing.T) {
 
 func TestEndpointWatcherStop(t *testing.T) {
 	log.SetupZapLogger(log.GetDefaultLogOpts())
+	fakeLinkSubscribe(t)
 	c := context.Background()
 
 	// When veth is already stopped.
@@ -226,3 +247,129 @@ func TestListVethsError(t *testing.T) {
 	_, err := listVeths()
 	assert.Error(t, err, "Expected an error when listing veths")
 }
+
+func TestLinkUpdates(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	c := context.Background()
+	updates, subscriptions := fakeLinkSubscribe(t)
+
+	events := make(chan *EndpointEvent, 10)
+	ps := pubsub.New()
+	fn := pubsub.CallBackFunc(func(obj interface{}) {
+		events <- obj.(*EndpointEvent)
+	})
+	uuid := ps.Subscribe(common.PubSubEndpoints, &fn)
+	defer ps.Unsubscribe(common.PubSubEndpoints, uuid)
+
+	v := &EndpointWatcher{
+		current: make(cache),
+		l:       log.Logger().Named("veth-watcher"),
+		p:       ps,
+	}
+	err := v.Init(c)
+	assert.NoError(t, err, "Expected no error when starting the veth watcher")
+	assert.Equal(t, 1, subscriptions(), "Expected to subscribe to link updates")
+
+	mac, _ := net.ParseMAC("00:00:00:00:00:01")
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "veth1", HardwareAddr: mac, NetNsID: 1}}
+	update := func(t uint16, link netlink.Link) netlink.LinkUpdate {
+		return netlink.LinkUpdate{Header: unix.NlMsghdr{Type: t}, Link: link}
+	}
+
+	// A created veth is published, other links are not.
+	updates() <- update(unix.RTM_NEWLINK, &netlink.Vxlan{LinkAttrs: netlink.LinkAttrs{Name: "vxlan0"}})
+	updates() <- update(unix.RTM_NEWLINK, veth)
+	event := <-events
+	assert.Equal(t, EndpointCreated, event.Type, "Expected veth1 to be created")
+	assert.Equal(t, "veth1", event.Obj.(netlink.LinkAttrs).Name, "Expected veth1 to be created")
+
+	// An updated veth is not published again.
+	updates() <- update(unix.RTM_NEWLINK, veth)
+	updates() <- update(unix.RTM_DELLINK, veth)
+	event = <-events
+	assert.Equal(t, EndpointDeleted, event.Type, "Expected veth1 to be deleted")
+	assert.Equal(t, "veth1", event.Obj.(netlink.LinkAttrs).Name, "Expected veth1 to be deleted")
+
+	// The refresh subscribes again when the link updates stopped.
+	close(updates())
+	assert.Eventually(t, func() bool {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		return v.done == nil
+	}, time.Second, 10*time.Millisecond, "Expected the subscription to stop")
+	showLink = func() ([]netlink.Link, error) {
+		return nil, nil
+	}
+	err = v.Refresh(c)
+	assert.NoError(t, err, "Expected no error when refreshing veth cache")
+	assert.Equal(t, 2, subscriptions(), "Expected to subscribe to link updates again")
+	assert.Empty(t, events, "Expected no other event")
+
+	err = v.Stop(c)
+	assert.NoError(t, err, "Expected no error when stopping the veth watcher")
+	assert.Nil(t, v.done, "Expected to unsubscribe from link updates")
+}
+
+func TestLinkUpdatesRenamedVeth(t *testing.T) {
+	log.SetupZapLogger(log.GetDefaultLogOpts())
+	c := context.Background()
+	updates, _ := fakeLinkSubscribe(t)
+
+	events := make(chan *EndpointEvent, 10)
+	ps := pubsub.New()
+	fn := pubsub.CallBackFunc(func(obj interface{}) {
+		events <- obj.(*EndpointEvent)
+	})
+	uuid := ps.Subscribe(common.PubSubEndpoints, &fn)
+	defer ps.Unsubscribe(common.PubSubEndpoints, uuid)
+
+	v := &EndpointWatcher{
+		current: make(cache),
+		l:       log.Logger().Named("veth-watcher"),
+		p:       ps,
+	}
+	err := v.Init(c)
+	assert.NoError(t, err, "Expected no error when starting the veth watcher")
+	defer v.Stop(c)
+
+	mac, _ := net.ParseMAC("00:00:00:00:00:01")
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 5, Name: "veth1", HardwareAddr: mac, NetNsID: -1}}
+	renamed := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 5, Name: "eth1", HardwareAddr: mac, NetNsID: 1}}
+	update := func(t uint16, link netlink.Link) netlink.LinkUpdate {
+		return netlink.LinkUpdate{Header: unix.NlMsghdr{Type: t}, Link: link}
+	}
+
+	updates() <- update(unix.RTM_NEWLINK, veth)
+	event := <-events
+	assert.Equal(t, EndpointCreated, event.Type, "Expected veth1 to be created")
+	assert.Equal(t, "veth1", event.Obj.(netlink.LinkAttrs).Name, "Expected veth1 to be created")
+
+	// A renamed veth, whose peer moved to another namespace, is deleted under its previous name before being created.
+	updates() <- update(unix.RTM_NEWLINK, renamed)
+	event = <-events
+	assert.Equal(t, EndpointDeleted, event.Type, "Expected veth1 to be deleted")
+	assert.Equal(t, "veth1", event.Obj.(netlink.LinkAttrs).Name, "Expected veth1 to be deleted")
+	event = <-events
+	assert.Equal(t, EndpointCreated, event.Type, "Expected eth1 to be created")
+	assert.Equal(t, "eth1", event.Obj.(netlink.LinkAttrs).Name, "Expected eth1 to be created")
+
+	// The refresh does not delete the renamed veth.
+	showLink = func() ([]netlink.Link, error) {
+		return []netlink.Link{renamed}, nil
+	}
+	err = v.Refresh(c)
+	assert.NoError(t, err, "Expected no error when refreshing veth cache")
+
+	// A veth changed again before being deleted is deleted as it was cached.
+	renamed.LinkAttrs.Name = "eth2"
+	updates() <- update(unix.RTM_DELLINK, renamed)
+	event = <-events
+	assert.Equal(t, EndpointDeleted, event.Type, "Expected eth1 to be deleted")
+	assert.Equal(t, "eth1", event.Obj.(netlink.LinkAttrs).Name, "Expected eth1 to be deleted")
+	assert.Eventually(t, func() bool {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		return len(v.current) == 0
+	}, time.Second, 10*time.Millisecond, "Expected no veth to be cached")
+	assert.Empty(t, events, "Expected no other event")
+}
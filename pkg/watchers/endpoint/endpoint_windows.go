@@ -9,6 +9,11 @@ import (
 	"github.com/Microsoft/hcsshim/hcn"
 )
 
+// subscribe does nothing on Windows, where the endpoints are only refreshed periodically.
+func (e *EndpointWatcher) subscribe() error {
+	return nil
+}
+
 func (e *EndpointWatcher) initNewCache() error {
 	return nil
 }
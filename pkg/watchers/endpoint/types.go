@@ -3,6 +3,11 @@
 
 package endpoint
 
+import (
+	"github.com/microsoft/retina/pkg/common"
+	"github.com/microsoft/retina/pkg/pubsub"
+)
+
 const (
 	endpointCreated string = "endpoint_created"
 	endpointDeleted string = "endpoint_deleted"
@@ -25,6 +30,9 @@ type EndpointEvent struct {
 	Obj interface{}
 }
 
+// EndpointsTopic is the topic of the endpoint events.
+var EndpointsTopic = pubsub.NewTopic[*EndpointEvent](common.PubSubEndpoints)
+
 func NewEndpointEvent(t EventType, obj interface{}) *EndpointEvent {
 	return &EndpointEvent{
 		Type: t,
@@ -70,6 +70,8 @@ const sidebars = {
       label: 'CRDs',
       items: [
         'CRDs/Capture',
+        'CRDs/CapturePolicy',
+        'CRDs/PluginConfiguration',
         'CRDs/RetinaEndpoint',
         'CRDs/MetricsConfiguration',
       ],
@@ -88,6 +90,7 @@ const sidebars = {
       items: [
         'contributing/readme',
         'contributing/developing',
+        'contributing/plugins',
       ],
     }
   ],
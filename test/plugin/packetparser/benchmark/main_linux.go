@@ -0,0 +1,201 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT license.
+// nolint
+
+// Benchmark for the packetparser event pipeline.
+// It runs the plugin once with perf buffers and once with the BPF ring buffer,
+// while generating UDP traffic towards an IP of interest, and reports events/sec and lost events for each run.
+//
+// Usage (as root, on a node with clang):
+//
+//	go run ./test/plugin/packetparser/benchmark -target 20.69.116.85 -duration 30s -pps 200000
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/cilium/cilium/pkg/hubble/api/v1"
+	kcfg "github.com/microsoft/retina/pkg/config"
+	"github.com/microsoft/retina/pkg/log"
+	"github.com/microsoft/retina/pkg/managers/filtermanager"
+	"github.com/microsoft/retina/pkg/managers/watchermanager"
+	"github.com/microsoft/retina/pkg/metrics"
+	"github.com/microsoft/retina/pkg/plugin/packetparser"
+	"github.com/microsoft/retina/pkg/utils"
+	"github.com/microsoft/retina/pkg/watchers/endpoint"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+)
+
+var (
+	target   = flag.String("target", "20.69.116.85", "IP of interest to send UDP traffic to")
+	port     = flag.Int("port", 9999, "UDP port to send traffic to")
+	duration = flag.Duration("duration", 30*time.Second, "duration of each run")
+	pps      = flag.Int("pps", 100000, "packets per second to generate, 0 to disable the generator")
+)
+
+type result struct {
+	mode     string
+	events   uint64
+	elapsed  time.Duration
+	kernel   float64
+	buffered float64
+	external float64
+}
+
+func main() {
+	flag.Parse()
+
+	opts := log.GetDefaultLogOpts()
+	opts.Level = "info"
+	log.SetupZapLogger(opts)
+	l := log.Logger().Named("benchmark-packetparser")
+
+	metrics.InitializeMetrics()
+
+	ip := net.ParseIP(*target).To4()
+	if ip == nil {
+		l.Fatal("Invalid IP address", zap.String("ip", *target))
+	}
+
+	// Watcher manager.
+	wm := watchermanager.NewWatcherManager()
+	wm.Watchers = []watchermanager.IWatcher{endpoint.Watcher()}
+	if err := wm.Start(context.Background()); err != nil {
+		l.Fatal("Start watcher manager failed", zap.Error(err))
+	}
+	defer wm.Stop(context.Background())
+
+	// Filtermanager.
+	f, err := filtermanager.Init(3)
+	if err != nil {
+		l.Fatal("Start filtermanager failed", zap.Error(err))
+	}
+	defer f.Stop()
+
+	if err = f.AddIPs([]net.IP{ip}, "packetparser-benchmark", filtermanager.RequestMetadata{RuleID: "benchmark"}); err != nil {
+		l.Fatal("AddIPs failed", zap.Error(err))
+	}
+
+	results := []result{}
+	for _, disableRingBuffer := range []bool{true, false} {
+		r, err := run(l, disableRingBuffer)
+		if err != nil {
+			l.Error("Benchmark run failed", zap.Bool("disableRingBuffer", disableRingBuffer), zap.Error(err))
+			continue
+		}
+		results = append(results, r)
+	}
+
+	fmt.Printf("\n%-12s %12s %14s %12s %12s %12s\n", "mode", "events", "events/sec", "lost_kernel", "lost_buffer", "lost_extchan")
+	for _, r := range results {
+		fmt.Printf("%-12s %12d %14.0f %12.0f %12.0f %12.0f\n",
+			r.mode, r.events, float64(r.events)/r.elapsed.Seconds(), r.kernel, r.buffered, r.external)
+	}
+}
+
+func run(l *log.ZapLogger, disableRingBuffer bool) (result, error) {
+	mode := "ringbuffer"
+	if disableRingBuffer {
+		mode = "perf"
+	}
+	res := result{mode: mode}
+
+	cfg := &kcfg.Config{
+		MetricsInterval:   1 * time.Second,
+		EnablePodLevel:    true,
+		DisableRingBuffer: disableRingBuffer,
+	}
+	p := packetparser.New(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	if err := p.Generate(ctx); err != nil {
+		return res, err
+	}
+	if err := p.Compile(ctx); err != nil {
+		return res, err
+	}
+	if err := p.Init(); err != nil {
+		return res, err
+	}
+	defer p.Stop()
+
+	ch := make(chan *v1.Event, 10000)
+	if err := p.SetupChannel(ch); err != nil {
+		return res, err
+	}
+
+	var events atomic.Uint64
+	go func() {
+		for range ch {
+			events.Add(1)
+		}
+	}()
+
+	if *pps > 0 {
+		go generate(ctx, l)
+	}
+
+	kernelBefore := lost(utils.Kernel)
+	bufferedBefore := lost(utils.BufferedChannel)
+	externalBefore := lost(utils.ExternalChannel)
+
+	l.Info("Starting benchmark run", zap.String("mode", mode), zap.Duration("duration", *duration))
+	start := time.Now()
+	if err := p.Start(ctx); err != nil {
+		return res, err
+	}
+
+	res.elapsed = time.Since(start)
+	res.events = events.Load()
+	res.kernel = lost(utils.Kernel) - kernelBefore
+	res.buffered = lost(utils.BufferedChannel) - bufferedBefore
+	res.external = lost(utils.ExternalChannel) - externalBefore
+	return res, nil
+}
+
+// generate sends UDP packets to the target at the configured rate until ctx is done.
+func generate(ctx context.Context, l *log.ZapLogger) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(*target, strconv.Itoa(*port)))
+	if err != nil {
+		l.Error("Failed to open UDP socket", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	payload := make([]byte, 64)
+	// Send in batches every millisecond to reach the packet rate without a timer per packet.
+	batch := *pps / 1000
+	if batch == 0 {
+		batch = 1
+	}
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for i := 0; i < batch; i++ {
+				_, _ = conn.Write(payload)
+			}
+		}
+	}
+}
+
+// lost returns the current value of the lost events counter for packetparser.
+func lost(lostType string) float64 {
+	m := &dto.Metric{}
+	if err := metrics.LostEventsCounter.WithLabelValues(lostType, string(packetparser.Name)).Write(m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}